@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/valentinpelus/k8flex/internal/app"
 	"github.com/valentinpelus/k8flex/internal/server"
 )
 
+// shutdownTimeout bounds how long requests in progress are waited for on
+// shutdown
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	// Initialize application
 	application, err := app.New()
@@ -17,9 +26,48 @@ func main() {
 	// Log startup information
 	application.LogStartupInfo()
 
+	// Stop on SIGTERM (pod termination) or SIGINT
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	// Load policy resources before queued alerts are processed
+	if application.PolicyController != nil {
+		application.PolicyController.Start(ctx)
+	}
+
+	// Start alert queue workers
+	application.AlertQueue.Start(ctx)
+
+	// Receive Slack events over Socket Mode when Slack can't reach the server
+	if application.SocketMode != nil {
+		go application.SocketMode.Run(ctx)
+	}
+
 	// Create and start HTTP server
-	srv := server.New(application.Config.Port, application.Config.WebhookAuthToken, application.AlertProcessor)
-	if err := srv.Start(); err != nil {
-		log.Fatalf("Server error: %v", err)
+	srv := server.New(application.Config.Port, application.Config.WebhookAuthToken, application.AlertQueue, application.Correlator, application.SlackHandler)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- srv.Start()
+	}()
+
+	// A server that fails to start stops the workers like a signal would
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+	case err := <-serverErr:
+		log.Printf("Server error: %v", err)
+		exitCode = 1
+		stop()
+	}
+	log.Printf("Shutting down: no new alerts are accepted, alerts in progress are kept in the queue journal")
+
+	// Stop accepting webhooks first, so no alert is queued after the workers stop
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Warning: %v", err)
 	}
+	application.AlertQueue.Wait()
+	log.Printf("Shutdown complete")
+	os.Exit(exitCode)
 }
@@ -0,0 +1,18 @@
+-- Track when the alert behind a validated case was resolved
+ALTER TABLE alert_cases ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMP;
+ALTER TABLE alert_cases ADD COLUMN IF NOT EXISTS resolution_seconds BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_alert_cases_resolved_at ON alert_cases(resolved_at);
+
+-- Mean time to resolve per alert name
+CREATE OR REPLACE VIEW alert_cases_mttr AS
+SELECT
+    alert_name,
+    COUNT(*) as resolved_cases,
+    AVG(resolution_seconds) as mttr_seconds,
+    MAX(resolved_at) as last_resolved
+FROM alert_cases
+WHERE validated = true AND resolution_seconds IS NOT NULL
+GROUP BY alert_name;
+
+COMMENT ON COLUMN alert_cases.resolution_seconds IS 'Seconds from alert start to resolution';
@@ -0,0 +1,8 @@
+-- Keep the fields of structured (JSON) analyses alongside the rendered text
+ALTER TABLE alert_cases ADD COLUMN IF NOT EXISTS root_cause TEXT;
+ALTER TABLE alert_cases ADD COLUMN IF NOT EXISTS confidence TEXT;
+ALTER TABLE alert_cases ADD COLUMN IF NOT EXISTS structured_analysis JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_alert_cases_confidence ON alert_cases(confidence);
+
+COMMENT ON COLUMN alert_cases.structured_analysis IS 'Analysis fields as returned by the LLM: root_cause, confidence, suggested_severity, evidence, impact, actions, prevention';
@@ -0,0 +1,6 @@
+-- Share of an analysis's quotes and values found in the debug info
+ALTER TABLE alert_cases ADD COLUMN IF NOT EXISTS grounding_score REAL;
+ALTER TABLE alert_cases ADD COLUMN IF NOT EXISTS grounding_checked INTEGER;
+
+COMMENT ON COLUMN alert_cases.grounding_score IS 'Verified quotes and values / checked (0-1), NULL when nothing was checked';
+COMMENT ON COLUMN alert_cases.grounding_checked IS 'Number of quotes and values checked against the debug info';
@@ -1,26 +1,46 @@
 package app
 
 import (
+	"context"
 	"log"
+	"strings"
 
 	"github.com/valentinpelus/k8flex/internal/config"
+	"github.com/valentinpelus/k8flex/internal/correlation"
 	"github.com/valentinpelus/k8flex/internal/debugger"
+	"github.com/valentinpelus/k8flex/internal/handler"
+	"github.com/valentinpelus/k8flex/internal/policy"
 	"github.com/valentinpelus/k8flex/internal/processor"
+	"github.com/valentinpelus/k8flex/internal/queue"
+	"github.com/valentinpelus/k8flex/pkg/conversation"
 	"github.com/valentinpelus/k8flex/pkg/feedback"
 	"github.com/valentinpelus/k8flex/pkg/knowledge"
 	"github.com/valentinpelus/k8flex/pkg/kubernetes"
 	"github.com/valentinpelus/k8flex/pkg/llm"
+	"github.com/valentinpelus/k8flex/pkg/notify"
+	"github.com/valentinpelus/k8flex/pkg/prometheus"
+	"github.com/valentinpelus/k8flex/pkg/redact"
+	"github.com/valentinpelus/k8flex/pkg/remediation"
+	"github.com/valentinpelus/k8flex/pkg/runbook"
 	"github.com/valentinpelus/k8flex/pkg/slack"
+	"github.com/valentinpelus/k8flex/pkg/threads"
 )
 
 // App holds all application dependencies
 type App struct {
-	Config          *config.Config
-	K8sClient       *kubernetes.Client
-	LLMProvider     llm.Provider
-	SlackClient     *slack.Client
-	FeedbackManager *feedback.Manager
-	AlertProcessor  *processor.AlertProcessor
+	Config           *config.Config
+	K8sClient        *kubernetes.Client
+	LLMProvider      llm.Provider
+	SlackClient      *slack.Client
+	Notifiers        *notify.Router
+	FeedbackManager  *feedback.Manager
+	AlertProcessor   *processor.AlertProcessor
+	AlertQueue       *queue.Queue
+	Correlator       *correlation.Correlator // nil when correlation is disabled
+	PolicyController *policy.Controller      // nil when policy resources are disabled
+	Remediator       *remediation.Executor   // nil when remediation actions are disabled
+	SlackHandler     *handler.SlackHandler   // nil when no Slack interactivity is enabled
+	SocketMode       *slack.SocketMode       // nil unless Slack events are received over Socket Mode
 }
 
 // New initializes a new application with all dependencies
@@ -29,43 +49,41 @@ func New() (*App, error) {
 	cfg := config.LoadConfig()
 
 	// Initialize Kubernetes client
-	clientset, err := kubernetes.GetClientset()
+	clientset, dynamicClient, err := kubernetes.GetClientset()
 	if err != nil {
 		return nil, err
 	}
-	k8sClient := kubernetes.NewClient(clientset)
+	k8sClient := kubernetes.NewClient(clientset, dynamicClient)
 
-	// Initialize LLM provider based on configuration
-	var llmProvider llm.Provider
+	// Initialize LLM provider (or fallback chain) based on configuration
 	llmConfig := llm.Config{
-		Provider:        cfg.LLMProvider,
-		OllamaURL:       cfg.OllamaURL,
-		OllamaModel:     cfg.OllamaModel,
-		OpenAIAPIKey:    cfg.OpenAIAPIKey,
-		OpenAIModel:     cfg.OpenAIModel,
-		AnthropicAPIKey: cfg.AnthropicAPIKey,
-		AnthropicModel:  cfg.AnthropicModel,
-		GeminiAPIKey:    cfg.GeminiAPIKey,
-		GeminiModel:     cfg.GeminiModel,
-		BedrockRegion:   cfg.BedrockRegion,
-		BedrockModel:    cfg.BedrockModel,
-	}
-
-	// For Ollama, use the new OllamaProvider from llm package
-	if llmConfig.Provider == "ollama" || llmConfig.Provider == "" {
-		llmProvider = llm.NewOllamaProvider(cfg.OllamaURL, cfg.OllamaModel)
-	} else {
-		factory := llm.NewFactory(llmConfig)
-		llmProvider, err = factory.CreateProvider()
-		if err != nil {
-			return nil, err
-		}
+		Provider:         cfg.LLMProvider,
+		BreakerThreshold: cfg.LLMBreakerThreshold,
+		BreakerCooldown:  cfg.LLMBreakerCooldown,
+		ContextWindow:    cfg.LLMContextWindow,
+		OllamaURL:        cfg.OllamaURL,
+		OllamaModel:      cfg.OllamaModel,
+		OpenAIAPIKey:     cfg.OpenAIAPIKey,
+		OpenAIModel:      cfg.OpenAIModel,
+		AnthropicAPIKey:  cfg.AnthropicAPIKey,
+		AnthropicModel:   cfg.AnthropicModel,
+		GeminiAPIKey:     cfg.GeminiAPIKey,
+		GeminiModel:      cfg.GeminiModel,
+		BedrockRegion:    cfg.BedrockRegion,
+		BedrockModel:     cfg.BedrockModel,
+	}
+
+	factory := llm.NewFactory(llmConfig)
+	llmProvider, err := factory.CreateProvider()
+	if err != nil {
+		return nil, err
 	}
 
 	log.Printf("Using LLM provider: %s", llmProvider.Name())
 
 	// Initialize Slack client
 	slackClient := slack.NewClient(cfg.SlackWebhookURL, cfg.SlackBotToken, cfg.SlackChannelID)
+	slackClient.SetAPIURL(cfg.SlackAPIURL)
 	if cfg.SlackWorkspaceID != "" {
 		slackClient.SetWorkspaceID(cfg.SlackWorkspaceID)
 		log.Printf("Slack workspace ID configured: %s", cfg.SlackWorkspaceID)
@@ -73,7 +91,7 @@ func New() (*App, error) {
 
 	// Validate Slack bot scopes if bot token is configured
 	if cfg.SlackBotToken != "" && cfg.SlackChannelID != "" {
-		if err := slackClient.ValidateScopes(); err != nil {
+		if err := slackClient.ValidateScopes(context.Background()); err != nil {
 			log.Printf("WARNING: Slack bot scope validation failed: %v", err)
 			log.Printf("Feedback detection requires 'reactions:read' scope. Add it at https://api.slack.com/apps")
 		} else {
@@ -81,8 +99,53 @@ func New() (*App, error) {
 		}
 	}
 
-	// Initialize feedback manager
+	// Alerts are posted to their team's channel by label, other alerts to
+	// SLACK_CHANNEL_ID
+	channelRoutes, err := slack.LoadChannelRoutes(cfg.SlackRoutesConfig)
+	if err != nil {
+		return nil, err
+	}
+	if channelRoutes != nil {
+		slackClient.SetChannelRoutes(channelRoutes)
+		log.Printf("Slack channel routes: %d loaded from %s", len(channelRoutes.Routes), cfg.SlackRoutesConfig)
+	}
+
+	// Alerts are routed by their labels to Slack, Teams, Mattermost or a
+	// generic webhook
+	mattermost := notify.NewMattermost(cfg.MattermostURL, cfg.MattermostToken, cfg.MattermostChannelID, cfg.MattermostWebhookURL)
+	routes, err := notify.LoadRouteConfig(cfg.NotifyRoutesConfig)
+	if err != nil {
+		return nil, err
+	}
+	notifiers, err := notify.NewRouter([]notify.Notifier{
+		slackClient,
+		notify.NewTeams(cfg.TeamsWebhookURL),
+		mattermost,
+		notify.NewWebhook(cfg.NotifyWebhookURL, cfg.NotifyWebhookToken),
+	}, routes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize feedback manager and the analyses awaiting a rating
 	feedbackManager := feedback.NewManager("/data/feedback.json")
+	pendingStore := feedback.NewPendingStore("/data/pending-feedback.json")
+
+	// Reactions and thread replies are received as Slack events, over the
+	// signed Events API endpoint or Socket Mode, or reactions are polled
+	eventsMode := cfg.SlackEventsMode
+	switch {
+	case eventsMode == "http" && cfg.SlackSigningSecret == "":
+		log.Printf("WARNING: SLACK_EVENTS_MODE=http but SLACK_SIGNING_SECRET not configured, polling reactions instead")
+		eventsMode = "poll"
+	case eventsMode == "socket" && cfg.SlackAppToken == "":
+		log.Printf("WARNING: SLACK_EVENTS_MODE=socket but SLACK_APP_TOKEN not configured, polling reactions instead")
+		eventsMode = "poll"
+	case eventsMode != "http" && eventsMode != "socket" && eventsMode != "poll":
+		log.Printf("WARNING: Unknown SLACK_EVENTS_MODE %q, polling reactions instead", eventsMode)
+		eventsMode = "poll"
+	}
+	cfg.SlackEventsMode = eventsMode
 
 	// Initialize knowledge base (if enabled)
 	var knowledgeBase *knowledge.KnowledgeBase
@@ -123,11 +186,211 @@ func New() (*App, error) {
 		}
 	}
 
+	// Initialize Slack thread store (maps alert fingerprints to threads for resolution)
+	threadStore := threads.NewStore("/data/threads.json")
+
 	// Initialize debugger
-	dbg := debugger.New(k8sClient)
+	var metricsClient *prometheus.Client
+	if cfg.PrometheusURL != "" {
+		metricsClient = prometheus.NewClient(cfg.PrometheusURL, cfg.PrometheusToken)
+	}
+	// Redact secrets and PII before anything reaches the LLM or Slack
+	var redactor *redact.Redactor
+	if cfg.RedactionEnabled {
+		redactionConfig, err := redact.LoadConfig(cfg.RedactionConfig)
+		if err != nil {
+			return nil, err
+		}
+		if redactor, err = redact.New(redactionConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	// AnalysisPolicy, Silence and Runbook resources adjust the configuration
+	// per namespace without a redeploy
+	var policies *policy.Store
+	if cfg.PolicyCRDsEnabled {
+		policies = policy.NewStore(cfg.PolicyNamespace, factory, redactor)
+		slackClient.SetChannelResolver(policies.SlackChannel)
+	}
+
+	collectors, err := debugger.LoadCollectorConfig(cfg.CollectorsConfig)
+	if err != nil {
+		return nil, err
+	}
+	debuggerConfig := debugger.Config{
+		LogMaxBytes:     cfg.LogMaxBytes,
+		LogTailLines:    int64(cfg.LogTailLines),
+		LogSummarize:    cfg.LogSummarize,
+		Metrics:         metricsClient,
+		MetricsLookback: cfg.PrometheusLookback,
+		HTTPErrorQuery:  cfg.PrometheusErrorQuery,
+		Collectors:      collectors,
+		Redactor:        redactor,
+	}
+	if policies != nil {
+		debuggerConfig.NamespaceCollectors = policies.Collectors
+	}
+	dbg, err := debugger.New(k8sClient, debuggerConfig)
+	if err != nil {
+		return nil, err
+	}
+	if collectors != nil {
+		log.Printf("✅ Collectors config loaded from %s: %d custom collectors, %d rules",
+			cfg.CollectorsConfig, len(collectors.Collectors), len(collectors.Rules))
+	}
+
+	// Tool-calling investigation replaces the single-shot analysis when enabled
+	var investigation *debugger.InvestigationBudget
+	if cfg.InvestigationEnabled {
+		investigation = &debugger.InvestigationBudget{
+			MaxSteps:  cfg.InvestigationMaxSteps,
+			MaxTokens: cfg.InvestigationMaxTokens,
+		}
+	}
+
+	// Structured analyses are requested as JSON and validated; "text" keeps
+	// the free-form streaming analysis
+	var structured *processor.StructuredAnalysis
+	if cfg.AnalysisFormat == "json" {
+		structured = &processor.StructuredAnalysis{MaxAttempts: cfg.AnalysisMaxAttempts}
+	}
+
+	// Runbooks from alert annotations, a mounted directory or a ConfigMap
+	var runbooks *runbook.Library
+	if cfg.RunbooksEnabled {
+		namespace, name, found := strings.Cut(cfg.RunbookConfigMap, "/")
+		if !found {
+			namespace, name = cfg.PolicyNamespace, cfg.RunbookConfigMap
+		}
+		runbooks, err = runbook.New(k8sClient, runbook.Config{
+			Directory:          cfg.RunbookDir,
+			ConfigMapNamespace: namespace,
+			ConfigMapName:      name,
+			CacheTTL:           cfg.RunbookCacheTTL,
+			MaxBytes:           cfg.RunbookMaxBytes,
+			AllowedHosts:       strings.Split(cfg.RunbookHosts, ","),
+			AllowedNetworks:    strings.Split(cfg.RunbookNetworks, ","),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Remediation actions proposed by analyses are approved with Slack
+	// buttons, whose requests must be signed unless they come over Socket Mode
+	var remediator *remediation.Executor
+	if cfg.RemediationEnabled {
+		switch {
+		case cfg.SlackSigningSecret == "" && eventsMode != "socket":
+			log.Printf("WARNING: Remediation enabled but neither SLACK_SIGNING_SECRET nor Socket Mode is configured, actions won't be proposed")
+		case !slackClient.HasBotToken():
+			log.Printf("WARNING: Remediation enabled but the Slack bot token or channel isn't configured, actions won't be proposed")
+		default:
+			remediationConfig, err := remediation.LoadConfig(cfg.RemediationConfig)
+			if err != nil {
+				return nil, err
+			}
+			options := remediation.Options{
+				DryRunOnly: cfg.RemediationDryRunOnly,
+				AuditLog:   cfg.RemediationAuditLog,
+			}
+			if policies != nil {
+				options.NamespaceRules = policies.Remediation
+			}
+			remediator = remediation.New(k8sClient, remediationConfig, options)
+		}
+	}
+
+	// Button clicks reach the app over Socket Mode, or on the interactivity
+	// endpoint with a signing secret. The thread context kept for the
+	// analysis buttons also answers follow-up questions, which arrive as
+	// app_mention events.
+	interactive := eventsMode == "socket" || cfg.SlackSigningSecret != ""
+	slackClient.SetInteractive(interactive && slackClient.HasBotToken())
+	slackClient.SetReportUploads(cfg.SlackUploadReports)
+	var conversations *conversation.Store
+	if interactive && slackClient.HasBotToken() {
+		conversations = conversation.NewStore("/data/conversations.json")
+	}
 
 	// Initialize alert processor
-	alertProcessor := processor.NewAlertProcessor(dbg, llmProvider, slackClient, feedbackManager, knowledgeBase)
+	alertProcessor := processor.NewAlertProcessor(processor.Config{
+		Debugger:      dbg,
+		LLMProvider:   llmProvider,
+		SlackClient:   slackClient,
+		Notifiers:     notifiers,
+		Feedback:      feedbackManager,
+		Pending:       pendingStore,
+		KnowledgeBase: knowledgeBase,
+		Threads:       threadStore,
+		Timeouts: processor.Timeouts{
+			Categorize: cfg.CategorizeTimeout,
+			Gather:     cfg.GatherTimeout,
+			Analyze:    cfg.AnalyzeTimeout,
+		},
+		Workers:       cfg.QueueWorkers,
+		Investigation: investigation,
+		Structured:    structured,
+		Redactor:      redactor,
+		Policies:      policies,
+		Runbooks:      runbooks,
+		Remediator:    remediator,
+		Conversations: conversations,
+	})
+
+	var slackHandler *handler.SlackHandler
+	var socketMode *slack.SocketMode
+	if interactive {
+		slackHandler = handler.NewSlackHandler(cfg.SlackSigningSecret, slackClient, alertProcessor, remediator, cfg.SlackFollowUps && eventsMode != "poll")
+	}
+	if eventsMode == "socket" {
+		socketMode = slackClient.NewSocketMode(cfg.SlackAppToken, slackHandler)
+	}
+
+	// Reactions are polled where they aren't delivered as events
+	var polled []string
+	if eventsMode == "poll" && slackClient.HasBotToken() {
+		polled = append(polled, slackClient.Name())
+	}
+	if cfg.MattermostURL != "" && cfg.MattermostToken != "" && cfg.MattermostChannelID != "" {
+		polled = append(polled, mattermost.Name())
+	}
+	if len(polled) > 0 {
+		alertProcessor.StartReactionPolling(polled)
+	}
+
+	var policyController *policy.Controller
+	if policies != nil {
+		policyController = policy.NewController(dynamicClient, clientset.Discovery(), policies, func(name string) bool {
+			_, ok := dbg.Registry().Get(name)
+			return ok
+		})
+	}
+
+	// Firing alerts sharing a node, workload, dependency or namespace within
+	// the correlation window are analyzed together as one incident
+	var correlator *correlation.Correlator
+	if cfg.CorrelationWindow > 0 {
+		correlator, err = correlation.New(k8sClient, strings.Split(cfg.CorrelationKeys, ","))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Initialize persistent alert queue
+	alertQueue, err := queue.New(queue.Config{
+		JournalPath:       cfg.QueueJournalPath,
+		Workers:           cfg.QueueWorkers,
+		MaxRetries:        cfg.QueueMaxRetries,
+		InitialBackoff:    cfg.QueueRetryBackoff,
+		MaxBackoff:        cfg.QueueMaxBackoff,
+		DedupWindow:       cfg.QueueDedupWindow,
+		CorrelationWindow: cfg.CorrelationWindow,
+	}, alertProcessor.HandleJob)
+	if err != nil {
+		return nil, err
+	}
 
 	// Log feedback stats
 	total, correct, incorrect := feedbackManager.GetStats()
@@ -136,12 +399,19 @@ func New() (*App, error) {
 	}
 
 	return &App{
-		Config:          cfg,
-		K8sClient:       k8sClient,
-		LLMProvider:     llmProvider,
-		SlackClient:     slackClient,
-		FeedbackManager: feedbackManager,
-		AlertProcessor:  alertProcessor,
+		Config:           cfg,
+		K8sClient:        k8sClient,
+		LLMProvider:      llmProvider,
+		SlackClient:      slackClient,
+		Notifiers:        notifiers,
+		FeedbackManager:  feedbackManager,
+		AlertProcessor:   alertProcessor,
+		AlertQueue:       alertQueue,
+		Correlator:       correlator,
+		PolicyController: policyController,
+		Remediator:       remediator,
+		SlackHandler:     slackHandler,
+		SocketMode:       socketMode,
 	}, nil
 }
 
@@ -156,11 +426,96 @@ func (a *App) LogStartupInfo() {
 		log.Printf("Webhook authentication: disabled (WARNING: anyone can send alerts)")
 	}
 
+	log.Printf("Alert queue: %d workers, %d retries, dedup window %s (journal: %s)",
+		a.Config.QueueWorkers, a.Config.QueueMaxRetries, a.Config.QueueDedupWindow, a.Config.QueueJournalPath)
+
+	if a.Correlator != nil {
+		log.Printf("Alert correlation: enabled (window %s, keys: %s)", a.Config.CorrelationWindow, a.Config.CorrelationKeys)
+	} else {
+		log.Printf("Alert correlation: disabled (CORRELATION_WINDOW=0), each alert is analyzed alone")
+	}
+
+	if a.PolicyController != nil {
+		log.Printf("Policy resources: enabled (defaults from namespace %s)", a.Config.PolicyNamespace)
+	} else {
+		log.Printf("Policy resources: disabled (POLICY_CRDS_ENABLED=false)")
+	}
+
+	if a.Config.RunbooksEnabled {
+		source := a.Config.RunbookDir
+		if a.Config.RunbookHosts != "" {
+			source = "runbook_url annotations from " + a.Config.RunbookHosts + ", " + source
+		} else {
+			log.Printf("Runbook downloads: disabled (set RUNBOOK_ALLOWED_HOSTS to fetch runbook_url links)")
+		}
+		if a.Config.RunbookConfigMap != "" {
+			source += ", ConfigMap " + a.Config.RunbookConfigMap
+		}
+		log.Printf("Runbooks: enabled (%s; cached %s)", source, a.Config.RunbookCacheTTL)
+	} else {
+		log.Printf("Runbooks: disabled (RUNBOOKS_ENABLED=false), only Runbook resources are used")
+	}
+
+	if a.Remediator != nil {
+		mode := "dry run, then applied"
+		if a.Config.RemediationDryRunOnly {
+			mode = "dry run only"
+		}
+		log.Printf("Remediation actions: enabled (%s; allowlist %s; audit log %s)", mode, a.Config.RemediationConfig, a.Config.RemediationAuditLog)
+	}
+
+	if a.Config.RedactionEnabled {
+		log.Printf("Redaction: enabled (custom rules from %s, if present)", a.Config.RedactionConfig)
+	} else {
+		log.Printf("Redaction: disabled (REDACTION_ENABLED=false), data is sent to the LLM verbatim")
+	}
+	if a.Config.PrometheusURL != "" {
+		log.Printf("Metrics enrichment: enabled (%s, lookback %s)", a.Config.PrometheusURL, a.Config.PrometheusLookback)
+	} else {
+		log.Printf("Metrics enrichment: disabled (PROMETHEUS_URL not set)")
+	}
+
+	if a.Config.InvestigationEnabled {
+		log.Printf("Tool-calling investigation: enabled (max %d steps, %d tokens)",
+			a.Config.InvestigationMaxSteps, a.Config.InvestigationMaxTokens)
+	}
+
+	if a.Config.AnalysisFormat == "json" {
+		log.Printf("Analysis format: structured JSON (up to %d attempts per analysis)", a.Config.AnalysisMaxAttempts)
+	} else {
+		log.Printf("Analysis format: free-form text (streaming)")
+	}
+
 	if a.SlackClient.HasBotToken() {
 		log.Printf("Slack notifications: enabled (Bot token with threading support)")
+		switch a.Config.SlackEventsMode {
+		case "http":
+			log.Printf("Slack events: Events API at /slack/events (signed requests)")
+		case "socket":
+			log.Printf("Slack events: Socket Mode")
+		default:
+			log.Printf("Slack events: disabled, reactions are polled every 30s (SLACK_EVENTS_MODE=poll)")
+		}
+		if a.SlackClient.IsInteractive() {
+			log.Printf("Slack buttons: rate, re-analyze and show evidence under analyses")
+		}
+		if a.Config.SlackFollowUps && a.Config.SlackEventsMode != "poll" {
+			log.Printf("Slack follow-ups: enabled, questions mentioning the bot in alert threads are answered")
+		}
+		if a.Config.SlackUploadReports {
+			log.Printf("Slack debug reports: uploaded as files in alert threads")
+		}
 	} else if a.SlackClient.IsConfigured() {
 		log.Printf("Slack notifications: enabled (Webhook - no threading)")
 	} else {
 		log.Printf("Slack notifications: disabled")
 	}
+
+	var configured []string
+	for _, n := range a.Notifiers.Configured() {
+		configured = append(configured, n.Name())
+	}
+	if len(configured) > 1 || a.Notifiers.Routes() > 0 {
+		log.Printf("Notifiers: %s (%d routes, default %s)", strings.Join(configured, ", "), a.Notifiers.Routes(), a.Notifiers.Default().Name())
+	}
 }
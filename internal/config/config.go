@@ -3,12 +3,13 @@ package config
 import (
 	"os"
 	"strconv"
+	"time"
 )
 
 // Config holds all application configuration
 type Config struct {
 	Port             string
-	LLMProvider      string // "ollama", "openai", "anthropic", "gemini", "bedrock"
+	LLMProvider      string // "ollama", "openai", "anthropic", "gemini", "bedrock", or a comma-separated fallback chain
 	OllamaURL        string
 	OllamaModel      string
 	OpenAIAPIKey     string
@@ -24,6 +25,23 @@ type Config struct {
 	SlackChannelID   string
 	SlackWorkspaceID string
 	WebhookAuthToken string
+	// Slack interactivity
+	SlackSigningSecret string // Verifies the requests Slack sends to the app
+	SlackAppToken      string // App-level token (xapp-...) for Socket Mode
+	SlackEventsMode    string // "poll" polls reactions, "http" serves the Events API endpoint, "socket" uses Socket Mode
+	SlackAPIURL        string // Web API base URL, e.g. a local fake Slack server
+	SlackFollowUps     bool   // Answer questions that mention the bot in alert threads
+	SlackUploadReports bool   // Upload the full debug report of each analysis as a file
+	SlackRoutesConfig  string // Path of the YAML mapping of alert labels to channels and owning teams
+	// Other notifiers, and the routing of alerts to them
+	TeamsWebhookURL      string // Microsoft Teams incoming or Workflows webhook
+	MattermostURL        string // Mattermost server URL, to post with a bot token
+	MattermostToken      string // Mattermost bot or personal access token
+	MattermostChannelID  string // Mattermost channel posted to with the token
+	MattermostWebhookURL string // Mattermost incoming webhook, without threads
+	NotifyWebhookURL     string // Generic webhook receiving every notification as a JSON event
+	NotifyWebhookToken   string // Bearer token sent to the generic webhook
+	NotifyRoutesConfig   string // Path of the YAML mapping of alert labels to notifiers
 	// Knowledge Base Configuration
 	KnowledgeBaseEnabled     bool
 	KnowledgeBaseDatabaseURL string
@@ -32,6 +50,60 @@ type Config struct {
 	KnowledgeBaseModel       string  // Embedding model name
 	KnowledgeBaseSimilarity  float64 // Similarity threshold (0-1)
 	KnowledgeBaseMaxResults  int     // Max similar cases to retrieve
+	// Provider fallback circuit breaker
+	LLMBreakerThreshold int           // Consecutive failures before a provider is skipped
+	LLMBreakerCooldown  time.Duration // How long a tripped provider is skipped
+	LLMContextWindow    int           // Overrides the model's context window in tokens; 0 uses the built-in table
+	// Debug info gathering
+	LogMaxBytes      int    // Byte budget for the logs of a single pod
+	LogTailLines     int    // Log lines fetched per container
+	LogSummarize     bool   // Cluster log lines by template, errors and stack traces first
+	CollectorsConfig string // Path of the YAML category/alert-name to collector mapping
+	// Redaction of secrets and PII before data leaves the cluster
+	RedactionEnabled bool   // Redact debug info, tool outputs and annotations
+	RedactionConfig  string // Path of the YAML custom rules and per-namespace policies
+	// Prometheus/Thanos metrics enrichment
+	PrometheusURL        string        // Prometheus-compatible API base URL; empty disables metrics
+	PrometheusToken      string        // Optional bearer token
+	PrometheusLookback   time.Duration // Time range of metric queries
+	PrometheusErrorQuery string        // PromQL for the HTTP error rate ($selector is replaced)
+	// Per-phase timeouts
+	CategorizeTimeout time.Duration // Timeout for the LLM categorization call
+	GatherTimeout     time.Duration // Timeout for gathering Kubernetes debug info
+	AnalyzeTimeout    time.Duration // Timeout for the LLM analysis (including streaming)
+	// Tool-calling investigation
+	InvestigationEnabled   bool // Let the LLM call read-only cluster tools instead of a single-shot analysis
+	InvestigationMaxSteps  int  // Maximum tool calls per alert
+	InvestigationMaxTokens int  // Maximum tokens spent per investigation
+	// Analysis output
+	AnalysisFormat      string // "json" for structured analyses validated against a schema, "text" for free-form streaming
+	AnalysisMaxAttempts int    // Requests per structured analysis, including retries on invalid output
+	// Alert Queue Configuration
+	QueueJournalPath  string        // Journal file for pending alerts (on the /data volume)
+	QueueWorkers      int           // Number of alerts processed concurrently
+	QueueMaxRetries   int           // Retries for a failed alert before giving up
+	QueueRetryBackoff time.Duration // Initial retry delay, doubled on each attempt
+	QueueMaxBackoff   time.Duration // Maximum retry delay
+	QueueDedupWindow  time.Duration // Window in which a repeated alert is analyzed only once
+	// Alert correlation
+	CorrelationWindow time.Duration // How long firing alerts wait for correlated alerts; 0 disables correlation
+	CorrelationKeys   string        // Comma-separated key kinds alerts are grouped by: node, workload, dependency, namespace
+	// Policy resources (AnalysisPolicy, Silence, Runbook)
+	PolicyCRDsEnabled bool   // Watch the k8flex.io custom resources
+	PolicyNamespace   string // Namespace whose resources apply to every namespace without its own
+	// Runbook retrieval
+	RunbooksEnabled  bool          // Add the runbooks found for alerts to their evidence
+	RunbookDir       string        // Directory of Markdown runbooks named <alertname>.md
+	RunbookConfigMap string        // ConfigMap of runbooks keyed <alertname>.md, "namespace/name" or a name in PolicyNamespace
+	RunbookCacheTTL  time.Duration // How long downloaded runbooks and the ConfigMap are reused
+	RunbookMaxBytes  int           // Budget of the relevant sections kept per runbook
+	RunbookHosts     string        // Comma-separated hosts runbook_url may link to, globs allowed; empty disables downloads
+	RunbookNetworks  string        // Comma-separated CIDRs of private addresses runbook_url may reach
+	// Remediation actions approved from Slack
+	RemediationEnabled    bool   // Offer the actions analyses propose for approval in Slack
+	RemediationConfig     string // Path of the YAML per-namespace action allowlist and approvers
+	RemediationDryRunOnly bool   // Only dry-run approved actions on the API server
+	RemediationAuditLog   string // JSON lines file approvals are appended to
 }
 
 // LoadConfig loads configuration from environment variables
@@ -54,6 +126,23 @@ func LoadConfig() *Config {
 		SlackChannelID:   getEnv("SLACK_CHANNEL_ID", ""),
 		SlackWorkspaceID: getEnv("SLACK_WORKSPACE_ID", ""),
 		WebhookAuthToken: getEnv("WEBHOOK_AUTH_TOKEN", ""),
+		// Slack interactivity
+		SlackSigningSecret: getEnv("SLACK_SIGNING_SECRET", ""),
+		SlackAppToken:      getEnv("SLACK_APP_TOKEN", ""),
+		SlackEventsMode:    getEnv("SLACK_EVENTS_MODE", "poll"),
+		SlackAPIURL:        getEnv("SLACK_API_URL", "https://slack.com/api"),
+		SlackFollowUps:     getEnv("SLACK_FOLLOWUPS_ENABLED", "false") == "true",
+		SlackUploadReports: getEnv("SLACK_UPLOAD_REPORTS", "false") == "true",
+		SlackRoutesConfig:  getEnv("SLACK_ROUTES_CONFIG", "/etc/k8flex/slack-routes.yaml"),
+		// Other notifiers
+		TeamsWebhookURL:      getEnv("TEAMS_WEBHOOK_URL", ""),
+		MattermostURL:        getEnv("MATTERMOST_URL", ""),
+		MattermostToken:      getEnv("MATTERMOST_TOKEN", ""),
+		MattermostChannelID:  getEnv("MATTERMOST_CHANNEL_ID", ""),
+		MattermostWebhookURL: getEnv("MATTERMOST_WEBHOOK_URL", ""),
+		NotifyWebhookURL:     getEnv("NOTIFY_WEBHOOK_URL", ""),
+		NotifyWebhookToken:   getEnv("NOTIFY_WEBHOOK_TOKEN", ""),
+		NotifyRoutesConfig:   getEnv("NOTIFY_ROUTES_CONFIG", "/etc/k8flex/notify-routes.yaml"),
 		// Knowledge Base
 		KnowledgeBaseEnabled:     getEnv("KB_ENABLED", "false") == "true",
 		KnowledgeBaseDatabaseURL: getEnv("KB_DATABASE_URL", ""),
@@ -62,6 +151,60 @@ func LoadConfig() *Config {
 		KnowledgeBaseModel:       getEnv("KB_EMBEDDING_MODEL", "text-embedding-3-small"),
 		KnowledgeBaseSimilarity:  getEnvFloat("KB_SIMILARITY_THRESHOLD", 0.75),
 		KnowledgeBaseMaxResults:  getEnvInt("KB_MAX_RESULTS", 5),
+		// Provider fallback
+		LLMBreakerThreshold: getEnvInt("LLM_BREAKER_THRESHOLD", 3),
+		LLMBreakerCooldown:  getEnvDuration("LLM_BREAKER_COOLDOWN", 5*time.Minute),
+		LLMContextWindow:    getEnvInt("LLM_CONTEXT_WINDOW", 0),
+		// Debug info
+		LogMaxBytes:      getEnvInt("LOG_MAX_BYTES", 32*1024),
+		LogTailLines:     getEnvInt("LOG_TAIL_LINES", 10000),
+		LogSummarize:     getEnv("LOG_SUMMARIZE", "false") == "true",
+		CollectorsConfig: getEnv("COLLECTORS_CONFIG", "/etc/k8flex/collectors.yaml"),
+		// Redaction
+		RedactionEnabled: getEnv("REDACTION_ENABLED", "true") == "true",
+		RedactionConfig:  getEnv("REDACTION_CONFIG", "/etc/k8flex/redaction.yaml"),
+		// Metrics
+		PrometheusURL:        getEnv("PROMETHEUS_URL", ""),
+		PrometheusToken:      getEnv("PROMETHEUS_BEARER_TOKEN", ""),
+		PrometheusLookback:   getEnvDuration("PROMETHEUS_LOOKBACK", time.Hour),
+		PrometheusErrorQuery: getEnv("PROMETHEUS_HTTP_ERROR_QUERY", ""),
+		// Timeouts
+		CategorizeTimeout: getEnvDuration("CATEGORIZE_TIMEOUT", 30*time.Second),
+		GatherTimeout:     getEnvDuration("GATHER_TIMEOUT", time.Minute),
+		AnalyzeTimeout:    getEnvDuration("ANALYZE_TIMEOUT", 5*time.Minute),
+		// Investigation
+		InvestigationEnabled:   getEnv("INVESTIGATION_ENABLED", "false") == "true",
+		InvestigationMaxSteps:  getEnvInt("INVESTIGATION_MAX_STEPS", 8),
+		InvestigationMaxTokens: getEnvInt("INVESTIGATION_MAX_TOKENS", 100000),
+		// Analysis output
+		AnalysisFormat:      getEnv("ANALYSIS_FORMAT", "text"),
+		AnalysisMaxAttempts: getEnvInt("ANALYSIS_MAX_ATTEMPTS", 3),
+		// Alert Queue
+		QueueJournalPath:  getEnv("QUEUE_JOURNAL_PATH", "/data/queue.json"),
+		QueueWorkers:      getEnvInt("QUEUE_WORKERS", 2),
+		QueueMaxRetries:   getEnvInt("QUEUE_MAX_RETRIES", 3),
+		QueueRetryBackoff: getEnvDuration("QUEUE_RETRY_BACKOFF", 30*time.Second),
+		QueueMaxBackoff:   getEnvDuration("QUEUE_MAX_BACKOFF", 10*time.Minute),
+		QueueDedupWindow:  getEnvDuration("QUEUE_DEDUP_WINDOW", time.Hour),
+		// Alert correlation
+		CorrelationWindow: getEnvDuration("CORRELATION_WINDOW", 0),
+		CorrelationKeys:   getEnv("CORRELATION_KEYS", "node,workload,dependency"),
+		// Policy resources
+		PolicyCRDsEnabled: getEnv("POLICY_CRDS_ENABLED", "false") == "true",
+		PolicyNamespace:   getEnv("POLICY_NAMESPACE", "k8flex"),
+		// Runbooks
+		RunbooksEnabled:  getEnv("RUNBOOKS_ENABLED", "true") == "true",
+		RunbookDir:       getEnv("RUNBOOK_DIR", "/etc/k8flex-runbooks"),
+		RunbookConfigMap: getEnv("RUNBOOK_CONFIGMAP", ""),
+		RunbookCacheTTL:  getEnvDuration("RUNBOOK_CACHE_TTL", 15*time.Minute),
+		RunbookMaxBytes:  getEnvInt("RUNBOOK_MAX_BYTES", 6000),
+		RunbookHosts:     getEnv("RUNBOOK_ALLOWED_HOSTS", ""),
+		RunbookNetworks:  getEnv("RUNBOOK_ALLOWED_NETWORKS", ""),
+		// Remediation
+		RemediationEnabled:    getEnv("REMEDIATION_ENABLED", "false") == "true",
+		RemediationConfig:     getEnv("REMEDIATION_CONFIG", "/etc/k8flex/remediation.yaml"),
+		RemediationDryRunOnly: getEnv("REMEDIATION_DRY_RUN_ONLY", "false") == "true",
+		RemediationAuditLog:   getEnv("REMEDIATION_AUDIT_LOG", "/data/remediation-audit.jsonl"),
 	}
 }
 
@@ -92,3 +235,13 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvDuration gets a duration environment variable (e.g. "30s", "5m") with a default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,172 @@
+package correlation
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/valentinpelus/k8flex/pkg/kubernetes"
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// Kinds of correlation key, in the order keys are reported
+const (
+	KindNode       = "node"       // Node the alert's pod runs on, or the node it names
+	KindWorkload   = "workload"   // Controller owning the alert's pods
+	KindDependency = "dependency" // Service the alert names or its pod backs
+	KindNamespace  = "namespace"  // Namespace of the alert
+)
+
+// Kinds lists every correlation key kind
+var Kinds = []string{KindNode, KindWorkload, KindDependency, KindNamespace}
+
+// severityRank orders alerts for picking an incident's lead, most severe first
+var severityRank = map[string]int{
+	"critical": 0,
+	"error":    1,
+	"warning":  2,
+	"info":     3,
+}
+
+// Correlator derives the keys alerts are grouped into incidents by. Two
+// firing alerts sharing a key within the correlation window are analyzed as
+// one incident.
+type Correlator struct {
+	k8sClient *kubernetes.Client
+	kinds     map[string]bool
+}
+
+// New creates a correlator that reports keys of the given kinds
+func New(k8sClient *kubernetes.Client, kinds []string) (*Correlator, error) {
+	c := &Correlator{k8sClient: k8sClient, kinds: make(map[string]bool)}
+	for _, kind := range kinds {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+		if !contains(Kinds, kind) {
+			return nil, fmt.Errorf("unknown correlation key %q (want %s)", kind, strings.Join(Kinds, ", "))
+		}
+		c.kinds[kind] = true
+	}
+	if len(c.kinds) == 0 {
+		return nil, fmt.Errorf("no correlation keys configured")
+	}
+	return c, nil
+}
+
+// Keys returns the correlation keys of an alert, e.g. "node:worker-3",
+// "workload:shop/Deployment/web", "dependency:shop/postgres" or
+// "namespace:shop". Labels are used first; the alert's pod is looked up for
+// what they don't name. Lookup errors are logged and leave those keys out.
+func (c *Correlator) Keys(ctx context.Context, alert types.Alert) []string {
+	namespace := alert.Labels["namespace"]
+	client := c.k8sClient.WithCache()
+
+	node := alert.Labels["node"]
+	var services []string
+	if svc := alert.Labels["service"]; svc != "" {
+		services = append(services, svc)
+	}
+
+	var workload *kubernetes.Workload
+	if namespace != "" && c.kinds[KindWorkload] {
+		w, err := client.ResolveWorkload(ctx, namespace, alert.Labels)
+		if err != nil {
+			log.Printf("Warning: failed to resolve workload for correlating %s: %v", alert.Labels["alertname"], err)
+		}
+		workload = w
+	}
+
+	if pod := alert.Labels["pod"]; pod != "" && namespace != "" && (c.kinds[KindNode] || c.kinds[KindWorkload] || c.kinds[KindDependency]) {
+		placement, err := client.GetPodPlacement(ctx, namespace, pod)
+		if err != nil {
+			log.Printf("Warning: failed to look up pod %s/%s for correlation: %v", namespace, pod, err)
+		}
+		if placement != nil {
+			if node == "" {
+				node = placement.Node
+			}
+			if workload == nil {
+				workload = placement.Workload
+			}
+			for _, svc := range placement.Services {
+				if !contains(services, svc) {
+					services = append(services, svc)
+				}
+			}
+		}
+	}
+
+	var keys []string
+	add := func(kind, value string) {
+		if c.kinds[kind] && value != "" {
+			keys = append(keys, kind+":"+value)
+		}
+	}
+	add(KindNode, node)
+	if workload != nil {
+		add(KindWorkload, fmt.Sprintf("%s/%s/%s", workload.Namespace, workload.Kind, workload.Name))
+	}
+	if namespace != "" {
+		for _, svc := range services {
+			add(KindDependency, namespace+"/"+svc)
+		}
+	}
+	add(KindNamespace, namespace)
+	return keys
+}
+
+// NewIncident groups correlated alerts into an incident. The lead is the most
+// severe alert with a namespace to debug, such as a pod alert rather than the
+// NodeNotReady alert it shares a node with, then the one that started first.
+func NewIncident(members []types.IncidentMember) *types.Incident {
+	sorted := append([]types.IncidentMember(nil), members...)
+	rank := func(a types.Alert) int {
+		if r, ok := severityRank[strings.ToLower(a.Labels["severity"])]; ok {
+			return r
+		}
+		return len(severityRank)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Alert, sorted[j].Alert
+		if (a.Labels["namespace"] == "") != (b.Labels["namespace"] == "") {
+			return a.Labels["namespace"] != ""
+		}
+		if rank(a) != rank(b) {
+			return rank(a) < rank(b)
+		}
+		return a.StartsAt.Before(b.StartsAt)
+	})
+
+	counts := make(map[string]int)
+	var order []string
+	for _, m := range sorted {
+		for _, k := range m.Keys {
+			if counts[k] == 0 {
+				order = append(order, k)
+			}
+			counts[k]++
+		}
+	}
+
+	incident := &types.Incident{Members: sorted}
+	for _, k := range order {
+		if counts[k] > 1 {
+			incident.Shared = append(incident.Shared, k)
+		}
+	}
+	return incident
+}
+
+// contains reports whether list contains s
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
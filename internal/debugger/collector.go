@@ -0,0 +1,129 @@
+package debugger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/kubernetes"
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// ErrNotApplicable is returned by a collector that has nothing to collect for
+// an alert (e.g. no pod label); the section is skipped silently
+var ErrNotApplicable = errors.New("collector not applicable")
+
+// AllCategories makes a collector run for every alert category
+const AllCategories = "*"
+
+// Section is one titled block of debug information, as returned by a
+// collector. The debugger adds the source, timestamp and error.
+type Section struct {
+	Title         string
+	Content       string
+	Truncated     bool        // Content was cut by the collector itself
+	Chronological bool        // Lines are oldest first, so the start is cut first to fit a prompt
+	Data          interface{} // Raw data behind Content, e.g. metric series; optional
+}
+
+// Limits bounds the output and run time of a collector
+type Limits struct {
+	MaxBytes int           // Content is truncated beyond this size; 0 means no limit
+	MaxItems int           // Collector-specific cap (events, pods, series); 0 means the default
+	Timeout  time.Duration // Per-collector deadline; 0 means the alert's deadline only
+}
+
+// Request is what a collector needs to know about the alert being debugged
+type Request struct {
+	Client    *kubernetes.Client // Shares one pod/node cache among the alert's collectors
+	Alert     types.Alert
+	Category  string
+	Namespace string
+	Pod       string // Pod named by the alert, or the most unhealthy pod of its workload
+	Pods      []string
+	Service   string
+	Container string
+	Workload  *kubernetes.Workload // nil when the alert doesn't name a workload
+	Limits    Limits
+}
+
+// Collector gathers one kind of debug information. Collectors of an alert run
+// concurrently; implementations must be read-only, safe for concurrent use
+// and return promptly when ctx is done.
+type Collector interface {
+	// Name identifies the collector in the collectors config
+	Name() string
+	// Categories lists the alert categories the collector runs for by default;
+	// AllCategories matches every category
+	Categories() []string
+	// Collect gathers the section for an alert
+	Collect(ctx context.Context, req *Request) (Section, error)
+}
+
+// collectorFunc adapts a function to the Collector interface
+type collectorFunc struct {
+	name       string
+	categories []string
+	collect    func(ctx context.Context, req *Request) (Section, error)
+}
+
+func (c *collectorFunc) Name() string         { return c.name }
+func (c *collectorFunc) Categories() []string { return c.categories }
+func (c *collectorFunc) Collect(ctx context.Context, req *Request) (Section, error) {
+	return c.collect(ctx, req)
+}
+
+// Registry holds the available collectors in registration order, which is
+// also the order of their sections in the debug info
+type Registry struct {
+	collectors []Collector
+	byName     map[string]Collector
+}
+
+// NewRegistry creates an empty collector registry
+func NewRegistry() *Registry {
+	return &Registry{byName: make(map[string]Collector)}
+}
+
+// Register adds a collector; names must be unique
+func (r *Registry) Register(c Collector) error {
+	if c.Name() == "" {
+		return fmt.Errorf("collector name is required")
+	}
+	if _, exists := r.byName[c.Name()]; exists {
+		return fmt.Errorf("collector %q is already registered", c.Name())
+	}
+	r.collectors = append(r.collectors, c)
+	r.byName[c.Name()] = c
+	return nil
+}
+
+// Get returns a collector by name
+func (r *Registry) Get(name string) (Collector, bool) {
+	c, ok := r.byName[name]
+	return c, ok
+}
+
+// Names returns the names of all registered collectors
+func (r *Registry) Names() []string {
+	names := make([]string, len(r.collectors))
+	for i, c := range r.collectors {
+		names[i] = c.Name()
+	}
+	return names
+}
+
+// ForCategory returns the collectors that run for a category by default
+func (r *Registry) ForCategory(category string) []Collector {
+	var matched []Collector
+	for _, c := range r.collectors {
+		for _, cat := range c.Categories() {
+			if cat == AllCategories || cat == category {
+				matched = append(matched, c)
+				break
+			}
+		}
+	}
+	return matched
+}
@@ -0,0 +1,224 @@
+package debugger
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// CollectorConfig declares which collectors run for which alerts, their
+// limits, and extra collectors defined without code
+type CollectorConfig struct {
+	// Limits applies to a collector wherever it runs, keyed by collector name
+	Limits map[string]LimitsConfig `json:"limits"`
+	// Collectors defines additional collectors (PromQL queries, custom resources)
+	Collectors []CustomCollectorConfig `json:"collectors"`
+	// Rules are checked in order; the first match picks the collectors.
+	// Alerts matching no rule use each collector's default categories.
+	Rules []CollectorRule `json:"rules"`
+
+	limits map[string]Limits
+}
+
+// LimitsConfig is the YAML form of Limits
+type LimitsConfig struct {
+	MaxBytes int    `json:"maxBytes"`
+	MaxItems int    `json:"maxItems"`
+	Timeout  string `json:"timeout"` // Go duration, e.g. "10s"
+}
+
+// CollectorRule maps alert categories and/or alert names to collectors
+type CollectorRule struct {
+	Categories []string                `json:"categories"` // Alert categories; "*" matches all
+	AlertNames []string                `json:"alertNames"` // Regular expressions matched against the alertname label
+	Collectors []string                `json:"collectors"` // Collector names, in section order
+	Limits     map[string]LimitsConfig `json:"limits"`     // Overrides the global limits for this rule
+
+	alertNames []*regexp.Regexp
+	limits     map[string]Limits
+}
+
+// CustomCollectorConfig defines a collector in YAML
+type CustomCollectorConfig struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`       // "promql" or "resource"
+	Categories []string `json:"categories"` // Default categories, used when no rule matches
+
+	// promql: a range query over the metrics lookback. $selector, $namespace,
+	// $pod, $service and $container are replaced from the alert.
+	Title string `json:"title"`
+	Query string `json:"query"`
+
+	// resource: lists objects of any resource type, e.g. cert-manager
+	// Certificates or Argo CD Applications, and renders their status
+	Group         string `json:"group"`
+	Version       string `json:"version"`
+	Resource      string `json:"resource"`
+	Namespace     string `json:"namespace"`     // Defaults to the alert's namespace
+	ClusterScoped bool   `json:"clusterScoped"` // Lists across the cluster, ignoring namespace
+	LabelSelector string `json:"labelSelector"` // Same placeholders as query
+}
+
+// LoadCollectorConfig reads and validates a collectors config file.
+// A missing file returns nil, meaning the built-in defaults.
+func LoadCollectorConfig(path string) (*CollectorConfig, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collectors config: %w", err)
+	}
+
+	var cfg CollectorConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse collectors config %s: %w", path, err)
+	}
+
+	if cfg.limits, err = parseLimits(cfg.Limits); err != nil {
+		return nil, fmt.Errorf("collectors config: %w", err)
+	}
+	for i := range cfg.Rules {
+		rule := &cfg.Rules[i]
+		if len(rule.Categories) == 0 && len(rule.AlertNames) == 0 {
+			return nil, fmt.Errorf("collectors config rule %d: categories or alertNames is required", i+1)
+		}
+		for _, pattern := range rule.AlertNames {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("collectors config rule %d: invalid alertNames pattern %q: %w", i+1, pattern, err)
+			}
+			rule.alertNames = append(rule.alertNames, re)
+		}
+		if rule.limits, err = parseLimits(rule.Limits); err != nil {
+			return nil, fmt.Errorf("collectors config rule %d: %w", i+1, err)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// parseLimits converts YAML limits, parsing timeouts
+func parseLimits(in map[string]LimitsConfig) (map[string]Limits, error) {
+	out := make(map[string]Limits, len(in))
+	for name, l := range in {
+		limits := Limits{MaxBytes: l.MaxBytes, MaxItems: l.MaxItems}
+		if l.Timeout != "" {
+			timeout, err := time.ParseDuration(l.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timeout %q for collector %s: %w", l.Timeout, name, err)
+			}
+			limits.Timeout = timeout
+		}
+		out[name] = limits
+	}
+	return out, nil
+}
+
+// matches reports whether a rule applies to an alert
+func (r *CollectorRule) matches(category, alertName string) bool {
+	if len(r.Categories) > 0 && !containsCategory(r.Categories, category) {
+		return false
+	}
+	if len(r.alertNames) == 0 {
+		return true
+	}
+	for _, re := range r.alertNames {
+		if re.MatchString(alertName) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsCategory reports whether categories includes category or AllCategories
+func containsCategory(categories []string, category string) bool {
+	for _, c := range categories {
+		if c == AllCategories || c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// newCustomCollector builds a collector from its YAML definition
+func (d *Debugger) newCustomCollector(cc CustomCollectorConfig) (Collector, error) {
+	if len(cc.Categories) == 0 {
+		return nil, fmt.Errorf("collector %s: categories is required", cc.Name)
+	}
+
+	switch cc.Type {
+	case "promql":
+		if cc.Query == "" {
+			return nil, fmt.Errorf("collector %s: query is required", cc.Name)
+		}
+		if cc.Title == "" {
+			cc.Title = cc.Name
+		}
+		return &collectorFunc{cc.Name, cc.Categories, func(ctx context.Context, req *Request) (Section, error) {
+			return d.collectPromQL(ctx, req, cc)
+		}}, nil
+
+	case "resource":
+		if cc.Version == "" || cc.Resource == "" {
+			return nil, fmt.Errorf("collector %s: version and resource are required", cc.Name)
+		}
+		return &collectorFunc{cc.Name, cc.Categories, func(ctx context.Context, req *Request) (Section, error) {
+			return d.collectResource(ctx, req, cc)
+		}}, nil
+
+	default:
+		return nil, fmt.Errorf("collector %s: unknown type %q (expected promql or resource)", cc.Name, cc.Type)
+	}
+}
+
+// collectPromQL runs a configured PromQL query
+func (d *Debugger) collectPromQL(ctx context.Context, req *Request, cc CustomCollectorConfig) (Section, error) {
+	if d.config.Metrics == nil {
+		return Section{}, ErrNotApplicable
+	}
+	section := Section{Title: fmt.Sprintf("Metrics: %s (last %s)", cc.Title, d.config.MetricsLookback)}
+
+	end := time.Now()
+	log.Printf("Querying Prometheus: %s", cc.Title)
+	summary, series, err := d.queryMetric(ctx, expandQuery(cc.Query, req), end.Add(-d.config.MetricsLookback), end, req.Limits.MaxItems)
+	if err != nil {
+		return section, fmt.Errorf("failed to query Prometheus: %w", err)
+	}
+	section.Content = summary
+	section.Data = series
+	return section, nil
+}
+
+// collectResource lists a configured resource type and its status
+func (d *Debugger) collectResource(ctx context.Context, req *Request, cc CustomCollectorConfig) (Section, error) {
+	gvr := schema.GroupVersionResource{Group: cc.Group, Version: cc.Version, Resource: cc.Resource}
+
+	namespace := cc.Namespace
+	if namespace == "" {
+		namespace = req.Namespace
+	}
+	if cc.ClusterScoped {
+		namespace = ""
+	}
+
+	limit := int64(req.Limits.MaxItems)
+	if limit <= 0 {
+		limit = 20
+	}
+
+	section := Section{Title: fmt.Sprintf("Resources: %s", gvr.GroupResource())}
+	log.Printf("Listing %s in namespace: %s", gvr.GroupResource(), namespace)
+	out, err := req.Client.ListResources(ctx, gvr, namespace, expandQuery(cc.LabelSelector, req), limit)
+	if err != nil {
+		return section, err
+	}
+	section.Content = out
+	return section, nil
+}
@@ -0,0 +1,253 @@
+package debugger
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/valentinpelus/k8flex/pkg/kubernetes"
+)
+
+// defaultCategory is used for categories no collector lists explicitly
+const defaultCategory = "unknown"
+
+// defaultMaxEvents is how many namespace events are listed by default
+const defaultMaxEvents = 50
+
+// builtinCollectors returns the collectors shipped with k8flex. Their default
+// categories decide what is gathered when no collectors config rule matches.
+func (d *Debugger) builtinCollectors() []Collector {
+	return []Collector{
+		// Namespace events provide crucial context for any alert
+		&collectorFunc{"events", []string{AllCategories}, d.collectEvents},
+		&collectorFunc{"hpa", []string{AllCategories}, d.collectHPA},
+		&collectorFunc{"workload", []string{AllCategories}, d.collectWorkload},
+		&collectorFunc{"pod-logs", []string{"pod-crash", "pod-restart", "unknown"}, d.collectPodLogs},
+		&collectorFunc{"pod-details", []string{AllCategories}, d.collectPodDetails},
+		&collectorFunc{"pod-resources", []string{"pod-crash", "pod-restart", "memory", "cpu", "disk", "hpa", "deployment"}, d.collectPodResources},
+		&collectorFunc{"node-resources", []string{"memory", "cpu", "disk", "node"}, d.collectNodeResources},
+		&collectorFunc{"node-status", []string{"node"}, d.collectNodeStatus},
+		&collectorFunc{"service", []string{"network", "service", "unknown"}, d.collectService},
+		&collectorFunc{"pod-network", []string{"network"}, d.collectPodNetwork},
+		// Actual usage and error rates from Prometheus, if configured
+		&collectorFunc{"metrics", []string{AllCategories}, d.collectMetrics},
+	}
+}
+
+// targetPods returns the pods a per-pod collector should cover
+func targetPods(req *Request, defaultLimit int) []string {
+	limit := req.Limits.MaxItems
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if len(req.Pods) > limit {
+		return req.Pods[:limit]
+	}
+	return req.Pods
+}
+
+// collectEvents lists recent events in the alert's namespace
+func (d *Debugger) collectEvents(ctx context.Context, req *Request) (Section, error) {
+	limit := req.Limits.MaxItems
+	if limit <= 0 {
+		limit = defaultMaxEvents
+	}
+
+	log.Printf("Fetching events in namespace: %s", req.Namespace)
+	events, err := req.Client.GetNamespaceEvents(ctx, req.Namespace, int64(limit))
+	if err != nil {
+		return Section{Title: "Recent Events"}, fmt.Errorf("failed to fetch events: %w", err)
+	}
+	return Section{Title: "Recent Events", Content: events, Chronological: true}, nil
+}
+
+// collectHPA describes the HPA scaling the alert's workload
+func (d *Debugger) collectHPA(ctx context.Context, req *Request) (Section, error) {
+	if req.Workload == nil || req.Workload.HPA == "" {
+		return Section{}, ErrNotApplicable
+	}
+
+	log.Printf("Describing HPA: %s/%s", req.Namespace, req.Workload.HPA)
+	hpa, err := req.Client.DescribeHPA(ctx, req.Namespace, req.Workload.HPA)
+	if err != nil {
+		return Section{Title: "HPA Status"}, fmt.Errorf("failed to describe HPA: %w", err)
+	}
+	return Section{Title: "HPA Status", Content: hpa}, nil
+}
+
+// collectWorkload reports the rollout status of the alert's workload and,
+// for Deployments, its revision history
+func (d *Debugger) collectWorkload(ctx context.Context, req *Request) (Section, error) {
+	if req.Workload == nil {
+		return Section{}, ErrNotApplicable
+	}
+	section := Section{Title: "Workload Status"}
+
+	log.Printf("Describing workload: %s/%s", req.Namespace, req.Workload)
+	desc, err := req.Client.DescribeWorkload(ctx, req.Workload)
+	if err != nil {
+		return section, fmt.Errorf("failed to describe %s: %w", req.Workload, err)
+	}
+
+	var content strings.Builder
+	content.WriteString(desc)
+
+	if req.Workload.Kind == "Deployment" {
+		history, err := req.Client.GetRolloutHistory(ctx, req.Namespace, req.Workload.Name)
+		if err != nil {
+			content.WriteString(fmt.Sprintf("\nError fetching rollout history: %v\n", err))
+		} else {
+			content.WriteString("\nRollout History:\n" + history)
+		}
+	}
+
+	if req.Pod == "" {
+		content.WriteString(fmt.Sprintf("\nNo pods found for %s\n", req.Workload))
+	} else if req.Alert.Labels["pod"] == "" {
+		content.WriteString("\nRepresentative pods (most unhealthy first): " + strings.Join(req.Pods, ", ") + "\n")
+	}
+
+	section.Content = content.String()
+	section.Data = req.Workload
+	return section, nil
+}
+
+// collectPodLogs retrieves the logs of each container of the target pods,
+// or only of the alert's container if set
+func (d *Debugger) collectPodLogs(ctx context.Context, req *Request) (Section, error) {
+	pods := targetPods(req, len(req.Pods))
+	if len(pods) == 0 {
+		return Section{}, ErrNotApplicable
+	}
+	// Summaries put errors and stack traces first; raw logs end with the latest lines
+	section := Section{Title: "Pod Logs: " + strings.Join(pods, ", "), Chronological: !d.config.LogSummarize}
+
+	maxBytes := d.config.LogMaxBytes
+	if req.Limits.MaxBytes > 0 {
+		maxBytes = req.Limits.MaxBytes / len(pods)
+	}
+
+	var content strings.Builder
+	for _, pod := range pods {
+		log.Printf("Fetching logs for pod: %s/%s", req.Namespace, pod)
+		logs, err := req.Client.GetPodLogs(ctx, req.Namespace, pod, kubernetes.LogOptions{
+			Container: req.Container,
+			MaxBytes:  maxBytes,
+			TailLines: d.config.LogTailLines,
+			Summarize: d.config.LogSummarize,
+		})
+		if len(pods) > 1 {
+			content.WriteString(fmt.Sprintf("Pod %s:\n", pod))
+		}
+		if err != nil {
+			if len(pods) == 1 {
+				return section, fmt.Errorf("failed to fetch logs: %w", err)
+			}
+			content.WriteString(fmt.Sprintf("Error fetching logs: %v\n\n", err))
+			continue
+		}
+		content.WriteString(logs)
+	}
+
+	section.Content = content.String()
+	return section, nil
+}
+
+// collectPodDetails describes the target pods
+func (d *Debugger) collectPodDetails(ctx context.Context, req *Request) (Section, error) {
+	pods := targetPods(req, len(req.Pods))
+	if len(pods) == 0 {
+		return Section{}, ErrNotApplicable
+	}
+	section := Section{Title: "Pod Details"}
+
+	var content strings.Builder
+	for i, pod := range pods {
+		log.Printf("Describing pod: %s/%s", req.Namespace, pod)
+		desc, err := req.Client.DescribePod(ctx, req.Namespace, pod)
+		if err != nil {
+			if len(pods) == 1 {
+				return section, fmt.Errorf("failed to describe pod: %w", err)
+			}
+			desc = fmt.Sprintf("Error describing pod %s: %v\n", pod, err)
+		}
+		if i > 0 {
+			content.WriteString("\n")
+		}
+		content.WriteString(desc)
+	}
+
+	section.Content = content.String()
+	return section, nil
+}
+
+// collectPodResources reports requests, limits and probes of the alert's pod
+func (d *Debugger) collectPodResources(ctx context.Context, req *Request) (Section, error) {
+	if req.Pod == "" {
+		return Section{}, ErrNotApplicable
+	}
+
+	log.Printf("Checking resources for pod: %s/%s", req.Namespace, req.Pod)
+	metrics, err := req.Client.CheckPodResources(ctx, req.Namespace, req.Pod)
+	if err != nil {
+		return Section{Title: "Resource Metrics"}, fmt.Errorf("failed to check resources: %w", err)
+	}
+	return Section{Title: "Resource Metrics", Content: metrics}, nil
+}
+
+// collectNodeResources reports capacity of the node running the alert's pod
+func (d *Debugger) collectNodeResources(ctx context.Context, req *Request) (Section, error) {
+	if req.Pod == "" {
+		return Section{}, ErrNotApplicable
+	}
+
+	log.Printf("Checking node resources for pod: %s/%s", req.Namespace, req.Pod)
+	nodeResources, err := req.Client.CheckNodeResources(ctx, req.Namespace, req.Pod)
+	if err != nil {
+		return Section{Title: "Node Resource Metrics"}, fmt.Errorf("failed to check node resources: %w", err)
+	}
+	return Section{Title: "Node Resource Metrics", Content: nodeResources}, nil
+}
+
+// collectNodeStatus reports conditions of the node running the alert's pod
+func (d *Debugger) collectNodeStatus(ctx context.Context, req *Request) (Section, error) {
+	if req.Pod == "" {
+		return Section{}, ErrNotApplicable
+	}
+
+	log.Printf("Checking node status for pod: %s/%s", req.Namespace, req.Pod)
+	nodeStatus, err := req.Client.CheckNodeStatus(ctx, req.Namespace, req.Pod)
+	if err != nil {
+		return Section{Title: "Node Status"}, fmt.Errorf("failed to check node status: %w", err)
+	}
+	return Section{Title: "Node Status", Content: nodeStatus}, nil
+}
+
+// collectService reports the alert's service and its endpoints
+func (d *Debugger) collectService(ctx context.Context, req *Request) (Section, error) {
+	if req.Service == "" {
+		return Section{}, ErrNotApplicable
+	}
+
+	log.Printf("Checking service: %s/%s", req.Namespace, req.Service)
+	svcCheck, err := req.Client.CheckService(ctx, req.Namespace, req.Service)
+	if err != nil {
+		return Section{Title: "Service Check"}, fmt.Errorf("failed to check service: %w", err)
+	}
+	return Section{Title: "Service Check", Content: svcCheck}, nil
+}
+
+// collectPodNetwork reports the alert pod's addresses and namespace network policies
+func (d *Debugger) collectPodNetwork(ctx context.Context, req *Request) (Section, error) {
+	if req.Pod == "" {
+		return Section{}, ErrNotApplicable
+	}
+
+	log.Printf("Checking network for pod: %s/%s", req.Namespace, req.Pod)
+	netCheck, err := req.Client.CheckPodNetwork(ctx, req.Namespace, req.Pod)
+	if err != nil {
+		return Section{Title: "Network Check"}, fmt.Errorf("failed to check network: %w", err)
+	}
+	return Section{Title: "Network Check", Content: netCheck}, nil
+}
@@ -2,229 +2,338 @@ package debugger
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
 	"github.com/valentinpelus/k8flex/pkg/kubernetes"
+	"github.com/valentinpelus/k8flex/pkg/prometheus"
+	"github.com/valentinpelus/k8flex/pkg/redact"
 	"github.com/valentinpelus/k8flex/pkg/types"
 )
 
+// Config holds debugger settings
+type Config struct {
+	LogMaxBytes     int                // Byte budget for the logs of a single pod
+	LogTailLines    int64              // Log lines fetched per container
+	LogSummarize    bool               // Cluster log lines by template instead of keeping the raw tail
+	Metrics         *prometheus.Client // Prometheus-compatible metrics source; nil disables metrics
+	MetricsLookback time.Duration      // How far back metric queries look
+	HTTPErrorQuery  string             // PromQL template for the HTTP error rate ($selector is replaced)
+	Collectors      *CollectorConfig   // Category/alert-name to collector mapping; nil uses the defaults
+	Redactor        *redact.Redactor   // Redacts gathered data and tool outputs; nil disables redaction
+
+	// NamespaceCollectors returns the collectors a namespace's policy picks
+	// over the rules and categories; nil, or an empty result, keeps them
+	NamespaceCollectors func(namespace string) []string
+}
+
 // Debugger handles gathering debug information for alerts
 type Debugger struct {
 	k8sClient *kubernetes.Client
+	config    Config
+	registry  *Registry
 }
 
-// New creates a new debugger
-func New(k8sClient *kubernetes.Client) *Debugger {
-	return &Debugger{
+// New creates a new debugger with the built-in collectors and those defined
+// in the collectors config
+func New(k8sClient *kubernetes.Client, config Config) (*Debugger, error) {
+	if config.MetricsLookback <= 0 {
+		config.MetricsLookback = time.Hour
+	}
+	d := &Debugger{
 		k8sClient: k8sClient,
+		config:    config,
+		registry:  NewRegistry(),
 	}
-}
-
-// GatherDebugInfo collects contextually relevant debug information based on alert category
-func (d *Debugger) GatherDebugInfo(ctx context.Context, alert types.Alert, category string) string {
-	namespace := alert.Labels["namespace"]
-	podName := alert.Labels["pod"]
-	serviceName := alert.Labels["service"]
-
-	var debugInfo strings.Builder
-
-	d.writeHeader(&debugInfo, alert, namespace)
-
-	// Always gather namespace events - they provide crucial context for any alert
-	d.gatherNamespaceEvents(ctx, &debugInfo, namespace)
-
-	// Gather debug info based on the category determined by Ollama
-	log.Printf("Gathering debug info for category: %s", category)
-
-	switch category {
-	case "pod-crash", "pod-restart":
-		// Pod issues: logs, pod details, resource constraints
-		d.gatherPodLogs(ctx, &debugInfo, namespace, podName)
-		d.gatherPodDetails(ctx, &debugInfo, namespace, podName)
-		d.gatherResourceInfo(ctx, &debugInfo, namespace, podName)
-
-	case "memory", "cpu", "disk":
-		// Resource issues: pod resources, node capacity
-		d.gatherPodDetails(ctx, &debugInfo, namespace, podName)
-		d.gatherResourceInfo(ctx, &debugInfo, namespace, podName)
-		d.gatherNodeResources(ctx, &debugInfo, namespace, podName)
-
-	case "network":
-		// Network issues: service info, network policies, pod network
-		d.gatherServiceInfo(ctx, &debugInfo, namespace, serviceName)
-		d.gatherNetworkInfo(ctx, &debugInfo, namespace, podName)
-		if podName != "" {
-			d.gatherPodDetails(ctx, &debugInfo, namespace, podName)
-		}
-
-	case "service":
-		// Service issues: service info, endpoints
-		d.gatherServiceInfo(ctx, &debugInfo, namespace, serviceName)
-		if podName != "" {
-			d.gatherPodDetails(ctx, &debugInfo, namespace, podName)
-		}
 
-	case "hpa", "deployment":
-		// HPA/Deployment issues: resource metrics, pod details (not node capacity)
-		d.gatherResourceInfo(ctx, &debugInfo, namespace, podName)
-		if podName != "" {
-			d.gatherPodDetails(ctx, &debugInfo, namespace, podName)
+	for _, c := range d.builtinCollectors() {
+		if err := d.registry.Register(c); err != nil {
+			return nil, err
 		}
+	}
 
-	case "node":
-		// Node issues: node status, node resources
-		if podName != "" {
-			d.gatherPodDetails(ctx, &debugInfo, namespace, podName)
-			d.gatherNodeStatus(ctx, &debugInfo, namespace, podName)
-			d.gatherNodeResources(ctx, &debugInfo, namespace, podName)
+	if cfg := config.Collectors; cfg != nil {
+		for _, cc := range cfg.Collectors {
+			c, err := d.newCustomCollector(cc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid collectors config: %w", err)
+			}
+			if err := d.registry.Register(c); err != nil {
+				return nil, fmt.Errorf("invalid collectors config: %w", err)
+			}
 		}
 
-	default:
-		// Unknown alert type: gather pod and service basics
-		log.Printf("Unknown category '%s', gathering basic info", category)
-		if podName != "" {
-			d.gatherPodLogs(ctx, &debugInfo, namespace, podName)
-			d.gatherPodDetails(ctx, &debugInfo, namespace, podName)
+		// Catch typos at startup rather than when an alert fires
+		for name := range cfg.limits {
+			if _, ok := d.registry.Get(name); !ok {
+				return nil, fmt.Errorf("invalid collectors config: limits for unknown collector %q", name)
+			}
 		}
-		if serviceName != "" {
-			d.gatherServiceInfo(ctx, &debugInfo, namespace, serviceName)
+		for i, rule := range cfg.Rules {
+			for _, name := range rule.Collectors {
+				if _, ok := d.registry.Get(name); !ok {
+					return nil, fmt.Errorf("invalid collectors config: rule %d uses unknown collector %q", i+1, name)
+				}
+			}
 		}
 	}
 
-	return debugInfo.String()
+	return d, nil
 }
 
-// writeHeader writes the debug report header
-func (d *Debugger) writeHeader(debugInfo *strings.Builder, alert types.Alert, namespace string) {
-	debugInfo.WriteString("=== AI-Powered Debug Analysis ===\n")
-	debugInfo.WriteString(fmt.Sprintf("Alert: %s\n", alert.Labels["alertname"]))
-	debugInfo.WriteString(fmt.Sprintf("Severity: %s\n", alert.Labels["severity"]))
-	debugInfo.WriteString(fmt.Sprintf("Namespace: %s\n", namespace))
-	debugInfo.WriteString(fmt.Sprintf("Time: %s\n\n", alert.StartsAt.Format(time.RFC3339)))
+// Registry returns the collector registry, e.g. to register collectors
+// implemented in Go before alerts are processed
+func (d *Debugger) Registry() *Registry {
+	return d.registry
+}
 
-	if summary := alert.Annotations["summary"]; summary != "" {
-		debugInfo.WriteString(fmt.Sprintf("Summary: %s\n", summary))
+// GatherDebugInfo collects contextually relevant debug information by running
+// the collectors selected for the alert's category and name concurrently.
+// Sections keep collector order; collectors still running when ctx expires are
+// reported as timed out, so ctx is the alert's gathering budget.
+// Render the result with the report package.
+func (d *Debugger) GatherDebugInfo(ctx context.Context, alert types.Alert, category string) *types.DebugResult {
+	result := &types.DebugResult{
+		Alert:     alert,
+		Category:  category,
+		StartedAt: time.Now(),
 	}
-	if description := alert.Annotations["description"]; description != "" {
-		debugInfo.WriteString(fmt.Sprintf("Description: %s\n\n", description))
+	req := d.newRequest(ctx, result, alert, category)
+
+	collectors, limits := d.plan(alert, category)
+	names := make([]string, len(collectors))
+	for i, c := range collectors {
+		names[i] = c.Name()
+	}
+	log.Printf("Gathering debug info for category %s with collectors: %s", category, strings.Join(names, ", "))
+
+	// Buffered so that collectors finishing after the deadline don't block
+	results := make(chan collectorResult, len(collectors))
+	for i, c := range collectors {
+		creq := *req
+		creq.Limits = limits[c.Name()]
+		go func(index int, c Collector, req *Request) {
+			section, ok := d.runCollector(ctx, c, req)
+			results <- collectorResult{index: index, section: section, applicable: ok}
+		}(i, c, &creq)
 	}
-}
 
-// gatherPodLogs retrieves and appends pod logs
-func (d *Debugger) gatherPodLogs(ctx context.Context, debugInfo *strings.Builder, namespace, podName string) {
-	if podName == "" {
-		return
+	sections := make([]collectorResult, len(collectors))
+	finished := make([]bool, len(collectors))
+	for remaining := len(collectors); remaining > 0; remaining-- {
+		select {
+		case r := <-results:
+			sections[r.index] = r
+			finished[r.index] = true
+		case <-ctx.Done():
+			remaining = 0
+		}
+	}
+	// Take results that arrived together with the deadline
+	for drained := false; !drained; {
+		select {
+		case r := <-results:
+			sections[r.index] = r
+			finished[r.index] = true
+		default:
+			drained = true
+		}
 	}
 
-	log.Printf("Fetching logs for pod: %s/%s", namespace, podName)
-	logs, err := d.k8sClient.GetPodLogs(ctx, namespace, podName, 100)
-	if err != nil {
-		debugInfo.WriteString(fmt.Sprintf("=== Pod Logs ===\nError fetching logs: %v\n\n", err))
-	} else {
-		debugInfo.WriteString(fmt.Sprintf("=== Pod Logs (last 100 lines) ===\n%s\n\n", logs))
+	for i, r := range sections {
+		if !finished[i] {
+			result.Sections = append(result.Sections, types.DebugSection{
+				Title:     names[i],
+				Source:    names[i],
+				Timestamp: time.Now(),
+				Error:     fmt.Sprintf("not finished within the gathering budget (%s)", time.Since(result.StartedAt).Round(time.Millisecond)),
+				TimedOut:  true,
+			})
+			continue
+		}
+		if r.applicable {
+			result.Sections = append(result.Sections, r.section)
+		}
 	}
-}
 
-// gatherPodDetails retrieves and appends pod description
-func (d *Debugger) gatherPodDetails(ctx context.Context, debugInfo *strings.Builder, namespace, podName string) {
-	if podName == "" {
-		return
+	// Nothing leaves the debugger unredacted
+	if d.config.Redactor != nil {
+		if counts := d.config.Redactor.RedactResult(result); counts.Total() > 0 {
+			log.Printf("Redacted %d values from debug info: %s", counts.Total(), counts)
+		}
 	}
+	result.FinishedAt = time.Now()
 
-	log.Printf("Describing pod: %s/%s", namespace, podName)
-	desc, err := d.k8sClient.DescribePod(ctx, namespace, podName)
-	if err != nil {
-		debugInfo.WriteString(fmt.Sprintf("=== Pod Details ===\nError describing pod: %v\n\n", err))
+	elapsed := result.FinishedAt.Sub(result.StartedAt).Round(time.Millisecond)
+	if timedOut := result.TimedOutSections(); len(timedOut) > 0 {
+		log.Printf("Debug info gathering timed out after %s; unfinished: %s", elapsed, strings.Join(timedOut, ", "))
 	} else {
-		debugInfo.WriteString(fmt.Sprintf("=== Pod Details ===\n%s\n\n", desc))
+		log.Printf("Gathered %d collectors in %s", len(collectors), elapsed)
 	}
+
+	return result
 }
 
-// gatherNamespaceEvents retrieves and appends namespace events
-func (d *Debugger) gatherNamespaceEvents(ctx context.Context, debugInfo *strings.Builder, namespace string) {
-	log.Printf("Fetching events in namespace: %s", namespace)
-	events, err := d.k8sClient.GetNamespaceEvents(ctx, namespace, 50)
-	if err != nil {
-		debugInfo.WriteString(fmt.Sprintf("=== Recent Events ===\nError fetching events: %v\n\n", err))
-	} else {
-		debugInfo.WriteString(fmt.Sprintf("=== Recent Events ===\n%s\n\n", events))
-	}
+// collectorResult is the section of one collector
+type collectorResult struct {
+	index      int
+	section    types.DebugSection
+	applicable bool
 }
 
-// gatherServiceInfo retrieves and appends service information
-func (d *Debugger) gatherServiceInfo(ctx context.Context, debugInfo *strings.Builder, namespace, serviceName string) {
-	if serviceName == "" {
-		return
+// newRequest resolves what the collectors should look at. kube-state-metrics
+// alerts name the owning workload instead of a pod; its most unhealthy pods
+// are debugged in that case. Workload resolution errors are added to result.
+func (d *Debugger) newRequest(ctx context.Context, result *types.DebugResult, alert types.Alert, category string) *Request {
+	req := &Request{
+		Client:    d.k8sClient.WithCache(),
+		Alert:     alert,
+		Category:  category,
+		Namespace: alert.Labels["namespace"],
+		Pod:       alert.Labels["pod"],
+		Service:   alert.Labels["service"],
+		Container: alert.Labels["container"],
+	}
+	if req.Pod != "" {
+		req.Pods = []string{req.Pod}
 	}
 
-	log.Printf("Checking service: %s/%s", namespace, serviceName)
-	svcCheck, err := d.k8sClient.CheckService(ctx, namespace, serviceName)
+	workload, err := req.Client.ResolveWorkload(ctx, req.Namespace, alert.Labels)
 	if err != nil {
-		debugInfo.WriteString(fmt.Sprintf("=== Service Check ===\nError checking service: %v\n\n", err))
-	} else {
-		debugInfo.WriteString(fmt.Sprintf("=== Service Check ===\n%s\n\n", svcCheck))
+		result.Sections = append(result.Sections, types.DebugSection{
+			Title:     "Workload",
+			Source:    "workload",
+			Timestamp: time.Now(),
+			Error:     fmt.Sprintf("failed to resolve workload: %v", err),
+		})
+		return req
+	}
+	req.Workload = workload
+
+	if workload != nil && req.Pod == "" {
+		pods, err := req.Client.FindRepresentativePods(ctx, workload, 2)
+		if err != nil {
+			log.Printf("Warning: failed to list pods of %s: %v", workload, err)
+		} else if len(pods) > 0 {
+			log.Printf("Selected representative pods for %s: %s", workload, strings.Join(pods, ", "))
+			req.Pod = pods[0]
+			req.Pods = pods
+		}
 	}
+
+	return req
 }
 
-// gatherNetworkInfo retrieves and appends network information
-func (d *Debugger) gatherNetworkInfo(ctx context.Context, debugInfo *strings.Builder, namespace, podName string) {
-	if podName == "" {
-		return
+// plan picks the collectors for an alert: those of its namespace's policy,
+// then those of the first matching config rule, otherwise every collector
+// whose default categories include the category. Categories no collector
+// lists explicitly fall back to "unknown".
+func (d *Debugger) plan(alert types.Alert, category string) ([]Collector, map[string]Limits) {
+	limits := make(map[string]Limits)
+	cfg := d.config.Collectors
+	if cfg != nil {
+		for name, l := range cfg.limits {
+			limits[name] = l
+		}
 	}
 
-	log.Printf("Checking network for pod: %s/%s", namespace, podName)
-	netCheck, err := d.k8sClient.CheckPodNetwork(ctx, namespace, podName)
-	if err != nil {
-		debugInfo.WriteString(fmt.Sprintf("=== Network Check ===\nError checking network: %v\n\n", err))
-	} else {
-		debugInfo.WriteString(fmt.Sprintf("=== Network Check ===\n%s\n\n", netCheck))
+	if d.config.NamespaceCollectors != nil {
+		if names := d.config.NamespaceCollectors(alert.Labels["namespace"]); len(names) > 0 {
+			collectors := make([]Collector, 0, len(names))
+			for _, name := range names {
+				if c, ok := d.registry.Get(name); ok {
+					collectors = append(collectors, c)
+				}
+			}
+			return collectors, limits
+		}
 	}
-}
 
-// gatherResourceInfo retrieves and appends resource information
-func (d *Debugger) gatherResourceInfo(ctx context.Context, debugInfo *strings.Builder, namespace, podName string) {
-	if podName == "" {
-		return
+	if cfg != nil {
+		for _, rule := range cfg.Rules {
+			if !rule.matches(category, alert.Labels["alertname"]) {
+				continue
+			}
+			for name, l := range rule.limits {
+				limits[name] = l
+			}
+			collectors := make([]Collector, 0, len(rule.Collectors))
+			for _, name := range rule.Collectors {
+				if c, ok := d.registry.Get(name); ok {
+					collectors = append(collectors, c)
+				}
+			}
+			return collectors, limits
+		}
 	}
 
-	log.Printf("Checking resources for pod: %s/%s", namespace, podName)
-	metrics, err := d.k8sClient.CheckPodResources(ctx, namespace, podName)
-	if err != nil {
-		debugInfo.WriteString(fmt.Sprintf("=== Resource Metrics ===\nError checking resources: %v\n\n", err))
-	} else {
-		debugInfo.WriteString(fmt.Sprintf("=== Resource Metrics ===\n%s\n\n", metrics))
+	if !d.knownCategory(category) {
+		log.Printf("Unknown category '%s', gathering basic info", category)
+		category = defaultCategory
 	}
+	return d.registry.ForCategory(category), limits
 }
 
-// gatherNodeResources retrieves and appends node resource information
-func (d *Debugger) gatherNodeResources(ctx context.Context, debugInfo *strings.Builder, namespace, podName string) {
-	if podName == "" {
-		return
+// knownCategory reports whether any collector lists the category explicitly
+func (d *Debugger) knownCategory(category string) bool {
+	for _, c := range d.registry.collectors {
+		for _, cat := range c.Categories() {
+			if cat == category {
+				return true
+			}
+		}
 	}
+	return false
+}
 
-	log.Printf("Checking node resources for pod: %s/%s", namespace, podName)
-	nodeResources, err := d.k8sClient.CheckNodeResources(ctx, namespace, podName)
-	if err != nil {
-		debugInfo.WriteString(fmt.Sprintf("=== Node Resource Metrics ===\nError checking node resources: %v\n\n", err))
-	} else {
-		debugInfo.WriteString(fmt.Sprintf("=== Node Resource Metrics ===\n%s\n\n", nodeResources))
+// runCollector runs one collector within its limits. ok is false if the
+// collector doesn't apply to the alert.
+func (d *Debugger) runCollector(ctx context.Context, c Collector, req *Request) (section types.DebugSection, ok bool) {
+	if req.Limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, req.Limits.Timeout)
+		defer cancel()
 	}
-}
 
-// gatherNodeStatus retrieves and appends node status information
-func (d *Debugger) gatherNodeStatus(ctx context.Context, debugInfo *strings.Builder, namespace, podName string) {
-	if podName == "" {
-		return
+	start := time.Now()
+	collected, err := c.Collect(ctx, req)
+	if errors.Is(err, ErrNotApplicable) {
+		return section, false
 	}
 
-	log.Printf("Checking node status for pod: %s/%s", namespace, podName)
-	nodeStatus, err := d.k8sClient.CheckNodeStatus(ctx, namespace, podName)
-	if err != nil {
-		debugInfo.WriteString(fmt.Sprintf("=== Node Status ===\nError checking node status: %v\n\n", err))
-	} else {
-		debugInfo.WriteString(fmt.Sprintf("=== Node Status ===\n%s\n\n", nodeStatus))
+	section = types.DebugSection{
+		Title:         collected.Title,
+		Source:        c.Name(),
+		Timestamp:     time.Now(),
+		Content:       strings.TrimRight(collected.Content, "\n"),
+		Truncated:     collected.Truncated,
+		Chronological: collected.Chronological,
+		Data:          collected.Data,
+	}
+	if section.Title == "" {
+		section.Title = c.Name()
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Printf("Collector %s timed out after %s", c.Name(), time.Since(start).Round(time.Millisecond))
+		section.Error = fmt.Sprintf("timed out after %s", time.Since(start).Round(time.Millisecond))
+		section.TimedOut = true
+	case err != nil:
+		log.Printf("Collector %s failed: %v", c.Name(), err)
+		section.Error = err.Error()
+	}
+
+	if max := req.Limits.MaxBytes; max > 0 && len(section.Content) > max {
+		if section.Chronological {
+			section.Content = section.Content[len(section.Content)-max:]
+		} else {
+			section.Content = section.Content[:max]
+		}
+		section.Truncated = true
 	}
+	return section, true
 }
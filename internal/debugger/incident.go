@@ -0,0 +1,107 @@
+package debugger
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/kubernetes"
+	"github.com/valentinpelus/k8flex/pkg/redact"
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// maxSharedResources bounds the shared nodes and workloads described per incident
+const maxSharedResources = 3
+
+// GatherIncidentInfo adds what the lead alert's debug info doesn't cover about
+// an incident to its result, ahead of the other sections: the member alerts
+// with the keys they share, and the status of the nodes and workloads they
+// share. ctx is the incident's gathering budget.
+func (d *Debugger) GatherIncidentInfo(ctx context.Context, result *types.DebugResult, incident *types.Incident) {
+	client := d.k8sClient.WithCache()
+	sections := []types.DebugSection{{
+		Title:     "Incident Members",
+		Source:    "incident",
+		Timestamp: time.Now(),
+		Content:   incidentMembers(incident),
+	}}
+
+	nodes, workloads := 0, 0
+	for _, key := range incident.Shared {
+		kind, value, _ := strings.Cut(key, ":")
+		switch {
+		case kind == "node" && nodes < maxSharedResources:
+			nodes++
+			log.Printf("Checking status of shared node: %s", value)
+			section := types.DebugSection{Title: "Node Status: " + value, Source: "node-status", Timestamp: time.Now()}
+			if status, err := client.DescribeNodeStatus(ctx, value); err != nil {
+				section.Error = fmt.Sprintf("failed to check node status: %v", err)
+			} else {
+				section.Content = status
+			}
+			sections = append(sections, section)
+		case kind == "workload" && workloads < maxSharedResources:
+			parts := strings.SplitN(value, "/", 3)
+			if len(parts) != 3 {
+				continue
+			}
+			w := &kubernetes.Workload{Namespace: parts[0], Kind: parts[1], Name: parts[2]}
+			workloads++
+			log.Printf("Describing shared workload: %s/%s", w.Namespace, w)
+			section := types.DebugSection{Title: "Workload: " + w.String(), Source: "workload", Timestamp: time.Now()}
+			if status, err := client.DescribeWorkload(ctx, w); err != nil {
+				section.Error = fmt.Sprintf("failed to describe workload: %v", err)
+			} else {
+				section.Content = status
+			}
+			sections = append(sections, section)
+		}
+	}
+
+	if d.config.Redactor != nil {
+		namespace := result.Alert.Labels["namespace"]
+		counts := redact.Counts{}
+		for i := range sections {
+			var c redact.Counts
+			sections[i].Content, c = d.config.Redactor.Redact(namespace, sections[i].Content)
+			counts.Add(c)
+		}
+		if result.Redactions == nil {
+			result.Redactions = make(map[string]int)
+		}
+		redact.Counts(result.Redactions).Add(counts)
+	}
+
+	result.Sections = append(sections, result.Sections...)
+}
+
+// incidentMembers lists the alerts of an incident for the prompt, lead first
+func incidentMembers(incident *types.Incident) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%d alerts fired together and were grouped into this incident because they share: %s\n",
+		len(incident.Members), strings.Join(incident.Shared, ", ")))
+	b.WriteString("The other sections were gathered for alert 1; give a note for every alert listed here.\n")
+
+	for i, m := range incident.Members {
+		alert := m.Alert
+		b.WriteString(fmt.Sprintf("\n%d. %s [%s]", i+1, alert.Labels["alertname"], alert.Labels["severity"]))
+		for _, label := range []string{"namespace", "pod", "node", "service", "deployment", "statefulset", "daemonset"} {
+			if value := alert.Labels[label]; value != "" {
+				b.WriteString(fmt.Sprintf(" %s=%s", label, value))
+			}
+		}
+		b.WriteString("\n")
+		if summary := alert.Annotations["summary"]; summary != "" {
+			b.WriteString("   Summary: " + summary + "\n")
+		}
+		if !alert.StartsAt.IsZero() {
+			b.WriteString("   Started: " + alert.StartsAt.Format(time.RFC3339) + "\n")
+		}
+		if shared := incident.SharedKeys(m); len(shared) > 0 {
+			b.WriteString("   Shares: " + strings.Join(shared, ", ") + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
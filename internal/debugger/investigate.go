@@ -0,0 +1,200 @@
+package debugger
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/llm"
+	"github.com/valentinpelus/k8flex/pkg/redact"
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// InvestigationBudget limits how much work a tool-calling investigation may do
+type InvestigationBudget struct {
+	MaxSteps  int // Maximum number of tool calls
+	MaxTokens int // Maximum input + output tokens across all model turns
+}
+
+// InvestigationStep records a single tool call made during an investigation
+type InvestigationStep struct {
+	Number    int
+	Call      llm.ToolCall
+	Output    string
+	Error     string
+	StartedAt time.Time
+	Duration  time.Duration
+}
+
+// Investigation is the outcome of a tool-calling investigation
+type Investigation struct {
+	Analysis     string
+	Steps        []InvestigationStep
+	InputTokens  int
+	OutputTokens int
+	StopReason   string        // Why the loop ended: "answered", "step budget", "token budget"
+	Redactions   redact.Counts // Values redacted from tool outputs
+}
+
+// Investigate lets the model iterate over the read-only cluster tools, starting
+// from the analysis prompt with the initial debug info, until it answers or the
+// budget runs out. When the budget is exhausted the model is asked for a final
+// answer, in the format the prompt requested.
+// The returned Investigation holds the steps taken so far even on error.
+func (d *Debugger) Investigate(ctx context.Context, provider llm.ToolCallingProvider, alert types.Alert, prompt string, budget InvestigationBudget, progressFn func(step InvestigationStep)) (*Investigation, error) {
+	namespace := alert.Labels["namespace"]
+	system := llm.BuildInvestigationSystemPrompt(namespace, budget.MaxSteps)
+	messages := []llm.Message{
+		{Role: "user", Content: prompt},
+	}
+	tools := d.Tools()
+	inv := &Investigation{Redactions: redact.Counts{}}
+
+	for {
+		// Tools stay declared on the final turn since providers reject tool
+		// history without them; any further calls are ignored
+		final := false
+		if reason := inv.budgetExhausted(budget); reason != "" {
+			inv.StopReason = reason
+			final = true
+			messages = append(messages, llm.Message{
+				Role:    "user",
+				Content: fmt.Sprintf("The %s is exhausted. Give your final analysis now, based on the evidence gathered so far.", reason),
+			})
+		}
+
+		resp, err := provider.ChatWithTools(ctx, system, messages, tools)
+		if err != nil {
+			return inv, fmt.Errorf("investigation failed after %d steps: %w", len(inv.Steps), err)
+		}
+		inv.InputTokens += resp.InputTokens
+		inv.OutputTokens += resp.OutputTokens
+
+		if len(resp.ToolCalls) == 0 || final {
+			inv.Analysis = resp.Content
+			if inv.StopReason == "" {
+				inv.StopReason = "answered"
+			}
+			log.Printf("Investigation finished (%s) after %d tool calls, %d tokens",
+				inv.StopReason, len(inv.Steps), inv.InputTokens+inv.OutputTokens)
+			return inv, nil
+		}
+
+		messages = append(messages, llm.Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls})
+
+		// Every call must get a result, even those over the step budget
+		for _, call := range resp.ToolCalls {
+			result := llm.Message{Role: "tool", ToolCallID: call.ID, ToolName: call.Name}
+
+			if len(inv.Steps) >= budget.MaxSteps {
+				result.Content = "Not executed: step budget exhausted."
+				messages = append(messages, result)
+				continue
+			}
+
+			step := d.runStep(ctx, call, namespace, len(inv.Steps)+1)
+			inv.redactStep(d.config.Redactor, &step, namespace)
+			inv.Steps = append(inv.Steps, step)
+			if progressFn != nil {
+				progressFn(step)
+			}
+
+			if step.Error != "" {
+				result.Content = "Error: " + step.Error
+			} else {
+				result.Content = step.Output
+			}
+			messages = append(messages, result)
+		}
+
+		if err := ctx.Err(); err != nil {
+			return inv, fmt.Errorf("investigation cancelled after %d steps: %w", len(inv.Steps), err)
+		}
+	}
+}
+
+// runStep executes one tool call and records it
+func (d *Debugger) runStep(ctx context.Context, call llm.ToolCall, namespace string, number int) InvestigationStep {
+	log.Printf("Investigation step %d: %s", number, call)
+
+	start := time.Now()
+	output, err := d.ExecuteTool(ctx, call, namespace)
+	step := InvestigationStep{
+		Number:    number,
+		Call:      call,
+		Output:    output,
+		StartedAt: start,
+		Duration:  time.Since(start),
+	}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	return step
+}
+
+// redactStep redacts a tool call's output before it's sent to the model,
+// using the policy of the namespace the call targeted
+func (inv *Investigation) redactStep(r *redact.Redactor, step *InvestigationStep, defaultNamespace string) {
+	if r == nil {
+		return
+	}
+	namespace := step.Call.StringArg("namespace")
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+
+	var counts redact.Counts
+	step.Output, counts = r.Redact(namespace, step.Output)
+	inv.Redactions.Add(counts)
+	step.Error, counts = r.Redact(namespace, step.Error)
+	inv.Redactions.Add(counts)
+}
+
+// budgetExhausted returns the name of the exhausted budget, or "" if there's room left
+func (inv *Investigation) budgetExhausted(budget InvestigationBudget) string {
+	if len(inv.Steps) >= budget.MaxSteps {
+		return "step budget"
+	}
+	if budget.MaxTokens > 0 && inv.InputTokens+inv.OutputTokens >= budget.MaxTokens {
+		return "token budget"
+	}
+	return ""
+}
+
+// Summary renders the tool calls as a compact list for the analysis report
+func (inv *Investigation) Summary() string {
+	if len(inv.Steps) == 0 {
+		return fmt.Sprintf("*🔍 Investigation:* no tool calls (%d tokens)", inv.InputTokens+inv.OutputTokens)
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("*🔍 Investigation:* %d tool calls, %d tokens, stopped: %s\n",
+		len(inv.Steps), inv.InputTokens+inv.OutputTokens, inv.StopReason))
+	for _, step := range inv.Steps {
+		if step.Error != "" {
+			b.WriteString(fmt.Sprintf("%d. `%s` → error: %s\n", step.Number, step.Call, step.Error))
+		} else {
+			b.WriteString(fmt.Sprintf("%d. `%s` → %d bytes in %s\n",
+				step.Number, step.Call, len(step.Output), step.Duration.Round(time.Millisecond)))
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Sections returns every tool call with its full output as debug sections,
+// to be appended to the alert's DebugResult
+func (inv *Investigation) Sections() []types.DebugSection {
+	sections := make([]types.DebugSection, 0, len(inv.Steps))
+	for _, step := range inv.Steps {
+		sections = append(sections, types.DebugSection{
+			Title:     fmt.Sprintf("Tool Call %d: %s", step.Number, step.Call),
+			Source:    "investigation",
+			Timestamp: step.StartedAt,
+			Content:   strings.TrimRight(step.Output, "\n"),
+			Error:     step.Error,
+		})
+	}
+	return sections
+}
@@ -0,0 +1,138 @@
+package debugger
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/prometheus"
+)
+
+// maxMetricSeries limits how many series of one query are shown in the prompt
+const maxMetricSeries = 5
+
+// metricQuery is a PromQL template, filled in by expandQuery
+type metricQuery struct {
+	title string
+	query string
+}
+
+// Built-in queries, based on cAdvisor and kube-state-metrics
+var (
+	memoryQuery = metricQuery{"Container Memory (working set bytes)",
+		`sum by (pod, container) (container_memory_working_set_bytes{$selector,container!="",container!="POD"})`}
+	memoryLimitQuery = metricQuery{"Container Memory Usage / Limit",
+		`sum by (pod, container) (container_memory_working_set_bytes{$selector,container!="",container!="POD"}) / sum by (pod, container) (kube_pod_container_resource_limits{$selector,resource="memory"})`}
+	cpuQuery = metricQuery{"Container CPU Usage (cores)",
+		`sum by (pod, container) (rate(container_cpu_usage_seconds_total{$selector,container!="",container!="POD"}[5m]))`}
+	throttlingQuery = metricQuery{"Container CPU Throttling (throttled periods ratio)",
+		`sum by (pod, container) (rate(container_cpu_cfs_throttled_periods_total{$selector}[5m])) / sum by (pod, container) (rate(container_cpu_cfs_periods_total{$selector}[5m]))`}
+	restartQuery = metricQuery{"Container Restarts (per 5m)",
+		`sum by (pod, container) (increase(kube_pod_container_status_restarts_total{$selector}[5m]))`}
+)
+
+// defaultHTTPErrorQuery is used when no HTTP error query is configured
+const defaultHTTPErrorQuery = `sum by (service) (rate(http_requests_total{$selector,code=~"5.."}[5m])) / sum by (service) (rate(http_requests_total{$selector}[5m]))`
+
+// metricQueries returns the queries relevant to an alert category
+func (d *Debugger) metricQueries(category string) []metricQuery {
+	httpErrors := metricQuery{"HTTP Error Rate (5xx ratio)", d.config.HTTPErrorQuery}
+	if httpErrors.query == "" {
+		httpErrors.query = defaultHTTPErrorQuery
+	}
+
+	switch category {
+	case "pod-crash", "pod-restart":
+		return []metricQuery{restartQuery, memoryQuery, memoryLimitQuery}
+	case "memory":
+		return []metricQuery{memoryQuery, memoryLimitQuery}
+	case "cpu":
+		return []metricQuery{cpuQuery, throttlingQuery}
+	case "hpa", "deployment":
+		return []metricQuery{cpuQuery, memoryQuery, restartQuery, httpErrors}
+	case "service", "network":
+		return []metricQuery{httpErrors}
+	default:
+		return []metricQuery{restartQuery}
+	}
+}
+
+// collectMetrics runs the alert's own expression and the category's queries
+// against Prometheus and summarizes the results compactly
+func (d *Debugger) collectMetrics(ctx context.Context, req *Request) (Section, error) {
+	if d.config.Metrics == nil {
+		return Section{}, ErrNotApplicable
+	}
+	section := Section{Title: fmt.Sprintf("Metrics (last %s)", d.config.MetricsLookback)}
+
+	end := time.Now()
+	start := end.Add(-d.config.MetricsLookback)
+
+	var content strings.Builder
+	data := make(map[string][]prometheus.Series)
+	if expr := prometheus.ExprFromGeneratorURL(req.Alert.GeneratorURL); expr != "" {
+		d.writeMetric(ctx, &content, data, "Alert Expression: "+expr, expr, start, end, req.Limits.MaxItems)
+	}
+	for _, mq := range d.metricQueries(req.Category) {
+		d.writeMetric(ctx, &content, data, mq.title, expandQuery(mq.query, req), start, end, req.Limits.MaxItems)
+	}
+
+	section.Content = content.String()
+	section.Data = data
+	return section, nil
+}
+
+// expandQuery fills in a PromQL template: $selector becomes the alert's label
+// matchers, and $namespace, $pod, $service and $container the label values
+func expandQuery(query string, req *Request) string {
+	selector := fmt.Sprintf(`namespace=%q`, req.Namespace)
+	if req.Pod != "" {
+		selector += fmt.Sprintf(`,pod=%q`, req.Pod)
+	}
+	if req.Container != "" {
+		selector += fmt.Sprintf(`,container=%q`, req.Container)
+	}
+
+	return strings.NewReplacer(
+		"$selector", selector,
+		"$namespace", req.Namespace,
+		"$pod", req.Pod,
+		"$service", req.Service,
+		"$container", req.Container,
+	).Replace(query)
+}
+
+// writeMetric runs one range query, appends its summary to b and its series to data
+func (d *Debugger) writeMetric(ctx context.Context, b *strings.Builder, data map[string][]prometheus.Series, title, query string, start, end time.Time, maxSeries int) {
+	log.Printf("Querying Prometheus: %s", title)
+	summary, series, err := d.queryMetric(ctx, query, start, end, maxSeries)
+	if err != nil {
+		b.WriteString(fmt.Sprintf("%s:\nError querying Prometheus: %v\n\n", title, err))
+		return
+	}
+	b.WriteString(fmt.Sprintf("%s:\n%s\n", title, summary))
+	data[title] = series
+}
+
+// queryMetric runs a range query and summarizes it as compact text. At most
+// maxSeries series are shown, maxMetricSeries if unset. The series are
+// returned as well.
+func (d *Debugger) queryMetric(ctx context.Context, query string, start, end time.Time, maxSeries int) (string, []prometheus.Series, error) {
+	if maxSeries <= 0 {
+		maxSeries = maxMetricSeries
+	}
+
+	// About 60 samples per series regardless of the lookback
+	step := end.Sub(start) / 60
+	if step < 15*time.Second {
+		step = 15 * time.Second
+	}
+
+	series, err := d.config.Metrics.QueryRange(ctx, query, start, end, step)
+	if err != nil {
+		return "", nil, err
+	}
+	return prometheus.Summarize(series, maxSeries), series, nil
+}
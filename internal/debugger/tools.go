@@ -0,0 +1,217 @@
+package debugger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/kubernetes"
+	"github.com/valentinpelus/k8flex/pkg/llm"
+)
+
+// maxToolOutput caps the size of a single tool result sent back to the model
+const maxToolOutput = 8000
+
+// Schema helpers for tool parameters
+func stringParam(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description}
+}
+
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// Tools returns the read-only Kubernetes tools available during an investigation
+func (d *Debugger) Tools() []llm.ToolDefinition {
+	namespace := stringParam("Namespace of the resource. Defaults to the alert's namespace.")
+	pod := stringParam("Name of the pod")
+
+	tools := []llm.ToolDefinition{
+		{
+			Name:        "get_pod_logs",
+			Description: "Fetch the most recent logs of each container in a pod, including init containers and the previous instance of restarted containers.",
+			Parameters: objectSchema(map[string]interface{}{
+				"namespace": namespace,
+				"pod":       pod,
+				"container": stringParam("Only fetch logs of this container"),
+			}, "pod"),
+		},
+		{
+			Name:        "describe_pod",
+			Description: "Describe a pod: phase, conditions, container states, restart counts and exit codes.",
+			Parameters:  objectSchema(map[string]interface{}{"namespace": namespace, "pod": pod}, "pod"),
+		},
+		{
+			Name:        "get_namespace_events",
+			Description: "List recent Kubernetes events in a namespace.",
+			Parameters:  objectSchema(map[string]interface{}{"namespace": namespace}),
+		},
+		{
+			Name:        "describe_workload",
+			Description: "Show rollout status of a Deployment, StatefulSet, DaemonSet or Job, the revision history and latest changes (Deployments), and its most unhealthy pods.",
+			Parameters: objectSchema(map[string]interface{}{
+				"namespace": namespace,
+				"kind":      map[string]interface{}{"type": "string", "enum": []string{"Deployment", "StatefulSet", "DaemonSet", "Job"}},
+				"name":      stringParam("Name of the workload"),
+			}, "kind", "name"),
+		},
+		{
+			Name:        "describe_hpa",
+			Description: "Show a HorizontalPodAutoscaler's replica bounds, current vs target metrics and conditions.",
+			Parameters:  objectSchema(map[string]interface{}{"namespace": namespace, "name": stringParam("Name of the HPA")}, "name"),
+		},
+		{
+			Name:        "check_service",
+			Description: "Show a service's type, ports, selector and ready/not-ready endpoints.",
+			Parameters:  objectSchema(map[string]interface{}{"namespace": namespace, "service": stringParam("Name of the service")}, "service"),
+		},
+		{
+			Name:        "check_pod_network",
+			Description: "Show a pod's IP, host IP and the network policies in its namespace.",
+			Parameters:  objectSchema(map[string]interface{}{"namespace": namespace, "pod": pod}, "pod"),
+		},
+		{
+			Name:        "check_pod_resources",
+			Description: "Show a pod's container resource requests, limits and configured probes.",
+			Parameters:  objectSchema(map[string]interface{}{"namespace": namespace, "pod": pod}, "pod"),
+		},
+		{
+			Name:        "check_node_resources",
+			Description: "Show capacity and allocatable resources of the node a pod runs on.",
+			Parameters:  objectSchema(map[string]interface{}{"namespace": namespace, "pod": pod}, "pod"),
+		},
+		{
+			Name:        "check_node_status",
+			Description: "Show the conditions of the node a pod runs on.",
+			Parameters:  objectSchema(map[string]interface{}{"namespace": namespace, "pod": pod}, "pod"),
+		},
+	}
+
+	if d.config.Metrics != nil {
+		tools = append(tools, llm.ToolDefinition{
+			Name:        "query_prometheus",
+			Description: fmt.Sprintf("Run a PromQL range query over the last %s and return min/avg/max/last and a trend per series.", d.config.MetricsLookback),
+			Parameters:  objectSchema(map[string]interface{}{"query": stringParam("PromQL expression")}, "query"),
+		})
+	}
+
+	return tools
+}
+
+// ExecuteTool runs a tool call against the cluster. defaultNamespace is used
+// when the call doesn't specify one. All tools are read-only.
+func (d *Debugger) ExecuteTool(ctx context.Context, call llm.ToolCall, defaultNamespace string) (string, error) {
+	namespace := call.StringArg("namespace")
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	pod := call.StringArg("pod")
+
+	var output string
+	var err error
+
+	switch call.Name {
+	case "get_pod_logs":
+		if pod == "" {
+			return "", fmt.Errorf("missing required argument: pod")
+		}
+		output, err = d.k8sClient.GetPodLogs(ctx, namespace, pod, kubernetes.LogOptions{
+			Container: call.StringArg("container"),
+			MaxBytes:  maxToolOutput - 1024, // Leave room for the per-container headers
+			TailLines: d.config.LogTailLines,
+			Summarize: d.config.LogSummarize,
+		})
+	case "describe_pod":
+		if pod == "" {
+			return "", fmt.Errorf("missing required argument: pod")
+		}
+		output, err = d.k8sClient.DescribePod(ctx, namespace, pod)
+	case "get_namespace_events":
+		output, err = d.k8sClient.GetNamespaceEvents(ctx, namespace, 50)
+	case "describe_workload":
+		output, err = d.describeWorkloadTool(ctx, namespace, call.StringArg("kind"), call.StringArg("name"))
+	case "describe_hpa":
+		name := call.StringArg("name")
+		if name == "" {
+			return "", fmt.Errorf("missing required argument: name")
+		}
+		output, err = d.k8sClient.DescribeHPA(ctx, namespace, name)
+	case "check_service":
+		service := call.StringArg("service")
+		if service == "" {
+			return "", fmt.Errorf("missing required argument: service")
+		}
+		output, err = d.k8sClient.CheckService(ctx, namespace, service)
+	case "check_pod_network":
+		if pod == "" {
+			return "", fmt.Errorf("missing required argument: pod")
+		}
+		output, err = d.k8sClient.CheckPodNetwork(ctx, namespace, pod)
+	case "check_pod_resources":
+		if pod == "" {
+			return "", fmt.Errorf("missing required argument: pod")
+		}
+		output, err = d.k8sClient.CheckPodResources(ctx, namespace, pod)
+	case "check_node_resources":
+		if pod == "" {
+			return "", fmt.Errorf("missing required argument: pod")
+		}
+		output, err = d.k8sClient.CheckNodeResources(ctx, namespace, pod)
+	case "check_node_status":
+		if pod == "" {
+			return "", fmt.Errorf("missing required argument: pod")
+		}
+		output, err = d.k8sClient.CheckNodeStatus(ctx, namespace, pod)
+	case "query_prometheus":
+		query := call.StringArg("query")
+		if d.config.Metrics == nil || query == "" {
+			return "", fmt.Errorf("metrics are not configured or query is empty")
+		}
+		end := time.Now()
+		output, _, err = d.queryMetric(ctx, query, end.Add(-d.config.MetricsLookback), end, 0)
+	default:
+		return "", fmt.Errorf("unknown tool: %s", call.Name)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(output) > maxToolOutput {
+		output = output[len(output)-maxToolOutput:]
+		output = fmt.Sprintf("[truncated to last %d bytes]\n%s", maxToolOutput, output)
+	}
+	return output, nil
+}
+
+// describeWorkloadTool combines workload status, rollout history and representative pods
+func (d *Debugger) describeWorkloadTool(ctx context.Context, namespace, kind, name string) (string, error) {
+	if kind == "" || name == "" {
+		return "", fmt.Errorf("missing required arguments: kind and name")
+	}
+	workload := &kubernetes.Workload{Kind: kind, Name: name, Namespace: namespace}
+
+	output, err := d.k8sClient.DescribeWorkload(ctx, workload)
+	if err != nil {
+		return "", err
+	}
+
+	if kind == "Deployment" {
+		if history, err := d.k8sClient.GetRolloutHistory(ctx, namespace, name); err == nil {
+			output += "\n" + history
+		}
+	}
+	if pods, err := d.k8sClient.FindRepresentativePods(ctx, workload, 3); err == nil && len(pods) > 0 {
+		output += "\nRepresentative pods (most unhealthy first): " + strings.Join(pods, ", ") + "\n"
+	}
+
+	return output, nil
+}
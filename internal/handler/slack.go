@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/valentinpelus/k8flex/internal/processor"
+	"github.com/valentinpelus/k8flex/pkg/remediation"
+	"github.com/valentinpelus/k8flex/pkg/slack"
+)
+
+// maxSlackBodyBytes bounds the body of a Slack request
+const maxSlackBodyBytes = 1024 * 1024
+
+// eventDedupWindow is how long delivered event IDs are remembered; Slack
+// retries an event for about an hour
+const eventDedupWindow = time.Hour
+
+// SlackHandler handles what Slack sends to the app: reactions, thread replies
+// and follow-up questions about analyses, and button clicks rating,
+// re-analyzing and showing the evidence of analyses or approving remediation
+// actions.
+// Requests to the HTTP endpoints must be signed with the app's signing
+// secret; Socket Mode delivers to OnEvent and OnInteraction directly.
+type SlackHandler struct {
+	signingSecret string
+	slackClient   *slack.Client
+	processor     *processor.AlertProcessor
+	remediator    *remediation.Executor // nil ignores approvals
+	followUps     bool                  // Answer questions mentioning the bot
+	mu            sync.Mutex
+	delivered     map[string]time.Time // Event ID to delivery time
+}
+
+// NewSlackHandler creates a handler for Slack events and interactions
+func NewSlackHandler(signingSecret string, slackClient *slack.Client, alertProcessor *processor.AlertProcessor, remediator *remediation.Executor, followUps bool) *SlackHandler {
+	return &SlackHandler{
+		signingSecret: signingSecret,
+		slackClient:   slackClient,
+		processor:     alertProcessor,
+		remediator:    remediator,
+		followUps:     followUps,
+		delivered:     make(map[string]time.Time),
+	}
+}
+
+// HandleEvents processes Events API requests: the Request URL verification
+// and event callbacks, which are acknowledged before being handled
+func (h *SlackHandler) HandleEvents(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.readSigned(w, r)
+	if !ok {
+		return
+	}
+
+	callback, err := slack.ParseEventCallback(body)
+	if err != nil {
+		log.Printf("Failed to parse Slack event: %v", err)
+		http.Error(w, "Failed to parse event", http.StatusBadRequest)
+		return
+	}
+
+	if callback.Type == "url_verification" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"challenge": callback.Challenge})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	go h.OnEvent(callback)
+}
+
+// HandleInteraction processes button clicks. Slack expects an answer within 3
+// seconds, so actions run after the request is acknowledged.
+func (h *SlackHandler) HandleInteraction(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.readSigned(w, r)
+	if !ok {
+		return
+	}
+
+	interaction, err := slack.ParseInteraction(body)
+	if err != nil {
+		log.Printf("Failed to parse Slack interaction: %v", err)
+		http.Error(w, "Failed to parse interaction", http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	go h.OnInteraction(interaction)
+}
+
+// readSigned reads the body of a request and checks its Slack signature,
+// answering the request if it's rejected
+func (h *SlackHandler) readSigned(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSlackBodyBytes))
+	if err != nil {
+		log.Printf("Failed to read Slack request body: %v", err)
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return nil, false
+	}
+	defer r.Body.Close()
+
+	if err := slack.VerifySignature(h.signingSecret, r.Header, body, time.Now()); err != nil {
+		log.Printf("Rejected Slack request: %v", err)
+		http.Error(w, "Unauthorized: Invalid Slack signature", http.StatusUnauthorized)
+		return nil, false
+	}
+	return body, true
+}
+
+// OnEvent handles an event callback: ✅ or ❌ reactions rate analyses,
+// questions mentioning the bot are answered and other thread replies are kept
+// with their feedback. Redelivered events are ignored.
+func (h *SlackHandler) OnEvent(callback *slack.EventCallback) {
+	if callback.Type != "event_callback" || !h.firstDelivery(callback.EventID) {
+		return
+	}
+
+	event := callback.Event
+	switch {
+	case event.Type == "reaction_added" && event.Item.Type == "message":
+		h.processor.HandleReaction(event.Item.Channel, event.Item.TS, event.Reaction)
+	case event.IsThreadMention() && h.followUps:
+		h.slackClient.TrackChannel(event.ThreadTS, event.Channel)
+		h.processor.HandleFollowUp(event.ThreadTS, event.User, event.Text)
+	case event.IsThreadReply() && !h.slackClient.MentionsBot(event.Text):
+		// Questions to the bot also arrive as app_mention events
+		h.slackClient.TrackChannel(event.ThreadTS, event.Channel)
+		h.processor.HandleThreadReply(event.ThreadTS, event.Text)
+	}
+}
+
+// OnInteraction handles the buttons clicked in an interaction: remediation
+// approvals and the buttons under analyses
+func (h *SlackHandler) OnInteraction(interaction *slack.Interaction) {
+	if interaction.Type != "block_actions" {
+		return
+	}
+	channel := interaction.Channel.ID
+	for _, action := range interaction.Actions {
+		switch action.ActionID {
+		case slack.ApproveActionID:
+			if h.remediator != nil {
+				h.approve(interaction, action)
+			}
+		case slack.RateCorrectActionID, slack.RateIncorrectActionID:
+			// Buttons under a single-message analysis rate that message
+			analysisTS := action.Value
+			if analysisTS == "" {
+				analysisTS = interaction.Message.TS
+			}
+			h.processor.HandleRating(channel, analysisTS, action.ActionID == slack.RateCorrectActionID)
+		case slack.ReanalyzeActionID:
+			h.processor.Reanalyze(channel, action.Value, interaction.User.ID)
+		case slack.ShowEvidenceActionID:
+			h.processor.ShowEvidence(channel, action.Value)
+		}
+	}
+}
+
+// firstDelivery reports whether an event is delivered for the first time
+func (h *SlackHandler) firstDelivery(eventID string) bool {
+	if eventID == "" {
+		return true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	for id, at := range h.delivered {
+		if now.Sub(at) > eventDedupWindow {
+			delete(h.delivered, id)
+		}
+	}
+	if _, ok := h.delivered[eventID]; ok {
+		return false
+	}
+	h.delivered[eventID] = now
+	return true
+}
+
+// approve runs an approved remediation and reports the outcome in the thread
+// and on the proposal
+func (h *SlackHandler) approve(interaction *slack.Interaction, action slack.InteractionAction) {
+	approver := remediation.Approver{ID: interaction.User.ID, Name: interaction.UserName(), Team: interaction.Team.ID}
+
+	req, err := remediation.DecodeRequest(action.Value)
+	if err != nil {
+		log.Printf("Ignoring approval from %s: %v", approver.Name, err)
+		return
+	}
+	log.Printf("%s approved %q for alert %s", approver.Name, req.Command(), req.Alert)
+
+	result := h.remediator.Execute(context.Background(), req, approver)
+
+	status := outcomeStatus(req, approver, result)
+	threadTS := interaction.Message.ThreadTS
+	if threadTS == "" {
+		threadTS = req.ThreadTS
+	}
+	h.slackClient.TrackChannel(threadTS, interaction.Channel.ID)
+	if err := h.slackClient.ReplyToThread(context.Background(), threadTS, status); err != nil {
+		log.Printf("Failed to post action outcome to Slack: %v", err)
+	}
+
+	// Proposals that ran are done; the others can be approved again
+	if result.Outcome == remediation.OutcomeExecuted || result.Outcome == remediation.OutcomeFailed {
+		if err := h.slackClient.CompleteActionProposal(context.Background(), interaction.Channel.ID, interaction.Message.TS,
+			interaction.Message.Blocks, action.BlockID, status); err != nil {
+			log.Printf("Failed to update action proposal in Slack: %v", err)
+		}
+	}
+}
+
+// outcomeStatus renders the outcome of an approval for Slack
+func outcomeStatus(req remediation.Request, approver remediation.Approver, result remediation.Result) string {
+	command := req.Command()
+	switch result.Outcome {
+	case remediation.OutcomeExecuted:
+		return fmt.Sprintf("✅ `%s` approved by <@%s>: %s", command, approver.ID, result.Message)
+	case remediation.OutcomeDryRun:
+		return fmt.Sprintf("🧪 `%s` approved by <@%s>: %s", command, approver.ID, result.Message)
+	case remediation.OutcomeDryRunFailed:
+		return fmt.Sprintf("❌ Dry run of `%s` failed, nothing was changed: %s", command, result.Message)
+	case remediation.OutcomeDenied:
+		return fmt.Sprintf("🚫 <@%s> can't run `%s`: %s", approver.ID, command, result.Message)
+	default:
+		return fmt.Sprintf("❌ `%s` approved by <@%s> failed: %s", command, approver.ID, result.Message)
+	}
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/slack"
+	"github.com/valentinpelus/k8flex/pkg/slack/slacktest"
+)
+
+func TestHandleEventsURLVerification(t *testing.T) {
+	fake := slacktest.NewServer()
+	defer fake.Close()
+	h := NewSlackHandler(fake.SigningSecret, nil, nil, nil, false)
+	endpoint := httptest.NewServer(http.HandlerFunc(h.HandleEvents))
+	defer endpoint.Close()
+
+	resp, err := fake.PostEvent(endpoint.URL, &slack.EventCallback{Type: "url_verification", Challenge: "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body["challenge"] != "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P" {
+		t.Errorf("challenge = %q, want the one sent", body["challenge"])
+	}
+}
+
+func TestHandleEventsRejectsUnsignedRequests(t *testing.T) {
+	fake := slacktest.NewServer()
+	defer fake.Close()
+	body := []byte(`{"type":"url_verification","challenge":"abc"}`)
+
+	tests := []struct {
+		name   string
+		method string
+		sign   func(http.Header)
+		want   int
+	}{
+		{name: "signed", method: http.MethodPost, sign: func(h http.Header) { fake.Sign(h, body) }, want: http.StatusOK},
+		{name: "unsigned", method: http.MethodPost, sign: func(http.Header) {}, want: http.StatusUnauthorized},
+		{name: "signed with another secret", method: http.MethodPost, sign: func(h http.Header) {
+			other := slacktest.NewServer()
+			defer other.Close()
+			other.SigningSecret = "another-secret"
+			other.Sign(h, body)
+		}, want: http.StatusUnauthorized},
+		{name: "GET", method: http.MethodGet, sign: func(h http.Header) { fake.Sign(h, body) }, want: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewSlackHandler(fake.SigningSecret, nil, nil, nil, false)
+			req := httptest.NewRequest(tt.method, "/slack/events", bytes.NewReader(body))
+			tt.sign(req.Header)
+			rec := httptest.NewRecorder()
+			h.HandleEvents(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirstDelivery(t *testing.T) {
+	h := NewSlackHandler("secret", nil, nil, nil, false)
+
+	if !h.firstDelivery("Ev1") {
+		t.Error("first delivery of Ev1 was treated as a redelivery")
+	}
+	if h.firstDelivery("Ev1") {
+		t.Error("redelivery of Ev1 was not ignored")
+	}
+	if !h.firstDelivery("Ev2") {
+		t.Error("first delivery of Ev2 was treated as a redelivery")
+	}
+	// Events without an ID can't be deduplicated
+	if !h.firstDelivery("") || !h.firstDelivery("") {
+		t.Error("events without an ID were ignored")
+	}
+
+	// IDs older than the dedup window are forgotten
+	h.delivered["Ev1"] = time.Now().Add(-eventDedupWindow - time.Minute)
+	if !h.firstDelivery("Ev1") {
+		t.Error("Ev1 was still remembered after the dedup window")
+	}
+}
+
+func TestOnEventIgnoresRedeliveries(t *testing.T) {
+	fake := slacktest.NewServer()
+	defer fake.Close()
+	h := NewSlackHandler(fake.SigningSecret, nil, nil, nil, false)
+
+	// A reaction that isn't a rating is delivered without reaching the processor
+	event := fake.ReactionAdded("C0DEFAULT", "1700000000.000100", "eyes", "U0ALICE")
+	h.OnEvent(event)
+	if _, ok := h.delivered[event.EventID]; !ok {
+		t.Fatalf("event %s was not recorded as delivered", event.EventID)
+	}
+	if h.firstDelivery(event.EventID) {
+		t.Errorf("event %s would be handled again", event.EventID)
+	}
+
+	// Envelopes other than event callbacks aren't recorded
+	h.OnEvent(&slack.EventCallback{Type: "app_rate_limited", EventID: "EvOther"})
+	if _, ok := h.delivered["EvOther"]; ok {
+		t.Error("an app_rate_limited envelope was recorded as an event")
+	}
+}
@@ -1,24 +1,35 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"time"
 
-	"github.com/valentinpelus/k8flex/internal/processor"
+	"github.com/valentinpelus/k8flex/internal/correlation"
+	"github.com/valentinpelus/k8flex/internal/queue"
 	"github.com/valentinpelus/k8flex/pkg/types"
 )
 
+// correlationTimeout bounds the Kubernetes lookups correlating a webhook's
+// alerts, so Alertmanager isn't kept waiting
+const correlationTimeout = 5 * time.Second
+
 // WebhookHandler handles incoming Alertmanager webhooks
 type WebhookHandler struct {
-	processor *processor.AlertProcessor
+	queue      *queue.Queue
+	correlator *correlation.Correlator // nil disables alert correlation
 }
 
-// NewWebhookHandler creates a new webhook handler
-func NewWebhookHandler(proc *processor.AlertProcessor) *WebhookHandler {
+// NewWebhookHandler creates a new webhook handler. If correlator is set,
+// firing alerts are queued with their correlation keys.
+func NewWebhookHandler(alertQueue *queue.Queue, correlator *correlation.Correlator) *WebhookHandler {
 	return &WebhookHandler{
-		processor: proc,
+		queue:      alertQueue,
+		correlator: correlator,
 	}
 }
 
@@ -46,18 +57,46 @@ func (h *WebhookHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Received webhook with %d alerts, status: %s", len(webhook.Alerts), webhook.Status)
 
-	// Process each alert asynchronously
-	go func() {
-		for _, alert := range webhook.Alerts {
-			// Process if status is "firing" or empty (default to firing)
-			if alert.Status == "firing" || alert.Status == "" {
-				h.processor.ProcessAlert(alert)
-			}
+	ctx, cancel := context.WithTimeout(r.Context(), correlationTimeout)
+	defer cancel()
+
+	// Queue each alert; workers process them with bounded concurrency
+	queued, duplicates := 0, 0
+	for _, alert := range webhook.Alerts {
+		// Process "firing" (or empty, default to firing) and "resolved" alerts
+		if alert.Status != "firing" && alert.Status != "" && alert.Status != "resolved" {
+			continue
+		}
+
+		// Skip duplicates before the Kubernetes lookups of their correlation keys
+		if h.queue.IsDuplicate(webhook.GroupKey, alert) {
+			duplicates++
+			log.Printf("Skipping duplicate alert %s (already queued or recently analyzed)", alert.Labels["alertname"])
+			continue
+		}
+
+		var correlationKeys []string
+		if h.correlator != nil && alert.Status != "resolved" {
+			correlationKeys = h.correlator.Keys(ctx, alert)
+		}
+
+		added, err := h.queue.Enqueue(webhook.GroupKey, alert, correlationKeys)
+		if err != nil {
+			// Let Alertmanager retry the whole notification
+			log.Printf("Failed to queue alert %s: %v", alert.Labels["alertname"], err)
+			http.Error(w, "Failed to queue alert", http.StatusServiceUnavailable)
+			return
 		}
-	}()
+		if added {
+			queued++
+		} else {
+			duplicates++
+			log.Printf("Skipping duplicate alert %s (already queued or recently analyzed)", alert.Labels["alertname"])
+		}
+	}
 
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"status":"accepted"}`))
+	w.Write([]byte(fmt.Sprintf(`{"status":"accepted","queued":%d,"duplicates":%d}`, queued, duplicates)))
 }
 
 // HandleHealth handles health check requests
@@ -0,0 +1,242 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/redact"
+	"github.com/valentinpelus/k8flex/pkg/remediation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// resyncPeriod is how often every resource is reconciled again, e.g. to
+	// retry a policy whose provider failed to initialize
+	resyncPeriod = 10 * time.Minute
+	// syncTimeout bounds the initial load, which never completes when RBAC
+	// denies listing the resources
+	syncTimeout = 30 * time.Second
+)
+
+// Phases reported in the status of the resources
+const (
+	PhaseAccepted = "Accepted"
+	PhaseInvalid  = "Invalid"
+)
+
+// Controller watches the k8flex custom resources, validates them, keeps the
+// store up to date and reports in each resource's status whether it applies
+type Controller struct {
+	client         dynamic.Interface
+	discovery      discovery.DiscoveryInterface
+	store          *Store
+	knownCollector func(string) bool // Reports whether a collector name is registered
+}
+
+// NewController creates a controller feeding store
+func NewController(client dynamic.Interface, discovery discovery.DiscoveryInterface, store *Store, knownCollector func(string) bool) *Controller {
+	return &Controller{
+		client:         client,
+		discovery:      discovery,
+		store:          store,
+		knownCollector: knownCollector,
+	}
+}
+
+// Start watches the resources until ctx is done, returning once the existing
+// ones are loaded or after syncTimeout. If the CRDs aren't installed, it logs
+// a warning and returns without watching, so k8flex keeps its environment
+// configuration.
+func (c *Controller) Start(ctx context.Context) {
+	if _, err := c.discovery.ServerResourcesForGroupVersion(Group + "/" + Version); err != nil {
+		log.Printf("WARNING: k8flex CRDs not available (%v), policy resources are ignored", err)
+		return
+	}
+
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(c.client, resyncPeriod)
+	for _, gvr := range []schema.GroupVersionResource{AnalysisPolicyResource, SilenceResource, RunbookResource} {
+		gvr := gvr
+		informer := factory.ForResource(gvr).Informer()
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { c.reconcile(ctx, gvr, obj) },
+			UpdateFunc: func(_, obj interface{}) { c.reconcile(ctx, gvr, obj) },
+			DeleteFunc: func(obj interface{}) { c.remove(gvr, obj) },
+		})
+	}
+
+	factory.Start(ctx.Done())
+	syncCtx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+	for gvr, synced := range factory.WaitForCacheSync(syncCtx.Done()) {
+		if !synced {
+			log.Printf("WARNING: failed to sync %s within %s (check the k8flex.io RBAC rules), some policy resources may be missing", gvr.Resource, syncTimeout)
+		}
+	}
+
+	c.store.mu.RLock()
+	log.Printf("✅ Policy resources loaded: %d analysis policies, %d silences, %d runbooks",
+		len(c.store.policies), len(c.store.silences), len(c.store.runbooks))
+	c.store.mu.RUnlock()
+}
+
+// reconcile validates a created or updated resource, applies it if valid and
+// reports the outcome in its status
+func (c *Controller) reconcile(ctx context.Context, gvr schema.GroupVersionResource, obj interface{}) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	namespace, name := u.GetNamespace(), u.GetName()
+	spec, _, _ := unstructured.NestedMap(u.Object, "spec")
+
+	var err error
+	switch gvr {
+	case AnalysisPolicyResource:
+		var s AnalysisPolicySpec
+		if err = fromUnstructured(spec, &s); err == nil {
+			if err = c.validatePolicy(namespace, &s); err == nil {
+				c.store.setPolicy(&AnalysisPolicy{Namespace: namespace, Name: name, Spec: s})
+			}
+		}
+	case SilenceResource:
+		var s SilenceSpec
+		var silence *Silence
+		if err = fromUnstructured(spec, &s); err == nil {
+			if silence, err = newSilence(namespace, name, s); err == nil {
+				c.store.setSilence(silence)
+			}
+		}
+	case RunbookResource:
+		var s RunbookSpec
+		if err = fromUnstructured(spec, &s); err == nil {
+			if err = validateRunbook(&s); err == nil {
+				c.store.setRunbook(&Runbook{Namespace: namespace, Name: name, Spec: s})
+			}
+		}
+	}
+
+	phase, message := PhaseAccepted, ""
+	if err != nil {
+		// An invalid update must not leave the previous version applied
+		c.remove(gvr, obj)
+		phase, message = PhaseInvalid, err.Error()
+		log.Printf("WARNING: %s %s/%s is invalid: %v", kindOf(gvr), namespace, name, err)
+	}
+	c.updateStatus(ctx, gvr, u, phase, message)
+}
+
+// remove drops a deleted resource from the store
+func (c *Controller) remove(gvr schema.GroupVersionResource, obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+
+	switch gvr {
+	case AnalysisPolicyResource:
+		c.store.deletePolicy(u.GetNamespace(), u.GetName())
+	case SilenceResource:
+		c.store.deleteSilence(u.GetNamespace(), u.GetName())
+	case RunbookResource:
+		c.store.deleteRunbook(u.GetNamespace(), u.GetName())
+	}
+}
+
+// validatePolicy checks that everything an analysis policy names exists,
+// creating its provider so a bad key or model is reported right away
+func (c *Controller) validatePolicy(namespace string, spec *AnalysisPolicySpec) error {
+	if spec.LLM != nil && (spec.LLM.Provider != "" || spec.LLM.Model != "") {
+		if _, err := c.store.provider(spec.LLM); err != nil {
+			return fmt.Errorf("llm: %w", err)
+		}
+	}
+	for _, name := range spec.Collectors {
+		if c.knownCollector != nil && !c.knownCollector(name) {
+			return fmt.Errorf("collectors: unknown collector %q", name)
+		}
+	}
+	if spec.Redaction != nil {
+		if err := redact.ValidatePolicy(redactionPolicy(namespace, spec.Redaction)); err != nil {
+			return fmt.Errorf("redaction: %w", err)
+		}
+	}
+	if spec.Remediation != nil {
+		if err := remediation.ValidateNamespacedActions(spec.Remediation.Actions); err != nil {
+			return fmt.Errorf("remediation: %w", err)
+		}
+	}
+	if spec.Slack != nil && strings.TrimSpace(spec.Slack.Channel) == "" {
+		return fmt.Errorf("slack: channel is required")
+	}
+	return nil
+}
+
+// validateRunbook checks that a runbook is attached to an alert and has something to show
+func validateRunbook(spec *RunbookSpec) error {
+	if len(spec.AlertNames) == 0 {
+		return fmt.Errorf("alertNames is required")
+	}
+	if spec.URL == "" && strings.TrimSpace(spec.Content) == "" {
+		return fmt.Errorf("url or content is required")
+	}
+	return nil
+}
+
+// updateStatus writes the phase of a resource to its status subresource,
+// unless it's already up to date; writing it triggers another update event
+func (c *Controller) updateStatus(ctx context.Context, gvr schema.GroupVersionResource, u *unstructured.Unstructured, phase, message string) {
+	currentPhase, _, _ := unstructured.NestedString(u.Object, "status", "phase")
+	currentMessage, _, _ := unstructured.NestedString(u.Object, "status", "message")
+	observed, _, _ := unstructured.NestedInt64(u.Object, "status", "observedGeneration")
+	if currentPhase == phase && currentMessage == message && observed == u.GetGeneration() {
+		return
+	}
+
+	updated := u.DeepCopy()
+	status := map[string]interface{}{
+		"phase":              phase,
+		"message":            message,
+		"observedGeneration": u.GetGeneration(),
+	}
+	if err := unstructured.SetNestedField(updated.Object, status, "status"); err != nil {
+		log.Printf("Warning: failed to set status of %s %s/%s: %v", kindOf(gvr), u.GetNamespace(), u.GetName(), err)
+		return
+	}
+	if _, err := c.client.Resource(gvr).Namespace(u.GetNamespace()).UpdateStatus(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		log.Printf("Warning: failed to update status of %s %s/%s: %v", kindOf(gvr), u.GetNamespace(), u.GetName(), err)
+	}
+}
+
+// fromUnstructured converts the spec of a resource to its typed form
+func fromUnstructured(spec map[string]interface{}, out interface{}) error {
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, out); err != nil {
+		return fmt.Errorf("invalid spec: %w", err)
+	}
+	return nil
+}
+
+// kindOf names the kind of a resource for logs
+func kindOf(gvr schema.GroupVersionResource) string {
+	switch gvr {
+	case AnalysisPolicyResource:
+		return "AnalysisPolicy"
+	case SilenceResource:
+		return "Silence"
+	case RunbookResource:
+		return "Runbook"
+	}
+	return gvr.Resource
+}
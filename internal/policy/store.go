@@ -0,0 +1,297 @@
+package policy
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/llm"
+	"github.com/valentinpelus/k8flex/pkg/redact"
+	"github.com/valentinpelus/k8flex/pkg/remediation"
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// Store holds the accepted policy resources and answers what applies to an
+// alert. Resources apply to their own namespace; those in the default
+// namespace, k8flex's own, apply to namespaces without one of their own.
+// A nil store applies no policy.
+type Store struct {
+	namespace string           // Default namespace
+	factory   *llm.Factory     // Creates the providers policies pick
+	redactor  *redact.Redactor // Receives the redaction policies; nil disables them
+
+	mu        sync.RWMutex
+	policies  map[string]*AnalysisPolicy // Key: namespace/name
+	silences  map[string]*Silence
+	runbooks  map[string]*Runbook
+	providers map[string]llm.Provider // Key: provider/model
+}
+
+// NewStore creates an empty store. Policies' providers are created with
+// factory, and their redaction rules are pushed to redactor.
+func NewStore(namespace string, factory *llm.Factory, redactor *redact.Redactor) *Store {
+	return &Store{
+		namespace: namespace,
+		factory:   factory,
+		redactor:  redactor,
+		policies:  make(map[string]*AnalysisPolicy),
+		silences:  make(map[string]*Silence),
+		runbooks:  make(map[string]*Runbook),
+		providers: make(map[string]llm.Provider),
+	}
+}
+
+// key identifies a resource in the store
+func key(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// Policy returns the analysis policy applying to a namespace: the first of
+// its own by name, otherwise the first of the default namespace, or nil
+func (s *Store) Policy(namespace string) *AnalysisPolicy {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.policyLocked(namespace)
+}
+
+// policyLocked implements Policy; s.mu must be held
+func (s *Store) policyLocked(namespace string) *AnalysisPolicy {
+	var own, fallback *AnalysisPolicy
+	for _, p := range s.policies {
+		switch p.Namespace {
+		case namespace:
+			if own == nil || p.Name < own.Name {
+				own = p
+			}
+		case s.namespace:
+			if fallback == nil || p.Name < fallback.Name {
+				fallback = p
+			}
+		}
+	}
+	if own != nil {
+		return own
+	}
+	return fallback
+}
+
+// Provider returns the LLM provider a namespace's policy picks, or fallback
+// when it picks none or its provider can't be created
+func (s *Store) Provider(namespace string, fallback llm.Provider) llm.Provider {
+	p := s.Policy(namespace)
+	if p == nil || p.Spec.LLM == nil {
+		return fallback
+	}
+	provider, err := s.provider(p.Spec.LLM)
+	if err != nil {
+		log.Printf("Warning: policy %s/%s: %v, using %s", p.Namespace, p.Name, err, fallback.Name())
+		return fallback
+	}
+	return provider
+}
+
+// provider returns the provider of an LLM policy, creating it on first use
+func (s *Store) provider(l *LLMPolicy) (llm.Provider, error) {
+	k := l.Provider + "/" + l.Model
+	s.mu.RLock()
+	provider, ok := s.providers[k]
+	s.mu.RUnlock()
+	if ok {
+		return provider, nil
+	}
+
+	provider, err := s.factory.Override(l.Provider, l.Model).CreateProvider()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.providers[k] = provider
+	s.mu.Unlock()
+	return provider, nil
+}
+
+// Collectors returns the collectors a namespace's policy picks, or nil
+func (s *Store) Collectors(namespace string) []string {
+	if p := s.Policy(namespace); p != nil {
+		return p.Spec.Collectors
+	}
+	return nil
+}
+
+// SlackChannel returns the Slack channel an alert's namespace policy picks, or ""
+func (s *Store) SlackChannel(alert types.Alert) string {
+	if p := s.Policy(alert.Labels["namespace"]); p != nil && p.Spec.Slack != nil {
+		return p.Spec.Slack.Channel
+	}
+	return ""
+}
+
+// Remediation returns the remediation rules a namespace's policy sets, or nil
+func (s *Store) Remediation(namespace string) *remediation.NamespaceRules {
+	p := s.Policy(namespace)
+	if p == nil || p.Spec.Remediation == nil {
+		return nil
+	}
+	return &remediation.NamespaceRules{
+		Match:     namespace,
+		Actions:   p.Spec.Remediation.Actions,
+		Approvers: p.Spec.Remediation.Approvers,
+	}
+}
+
+// Silenced returns the first active silence matching an alert, or nil.
+// Silences in the default namespace match alerts of every namespace.
+func (s *Store) Silenced(alert types.Alert) *Silence {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	namespace := alert.Labels["namespace"]
+	for _, k := range sortedKeys(s.silences) {
+		silence := s.silences[k]
+		if silence.Namespace != namespace && silence.Namespace != s.namespace {
+			continue
+		}
+		if silence.matches(alert, now) {
+			return silence
+		}
+	}
+	return nil
+}
+
+// Runbooks returns the runbooks attached to an alert's name, those of its
+// namespace first
+func (s *Store) Runbooks(alert types.Alert) []*Runbook {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	namespace := alert.Labels["namespace"]
+	var own, fallback []*Runbook
+	for _, k := range sortedKeys(s.runbooks) {
+		runbook := s.runbooks[k]
+		if !containsName(runbook.Spec.AlertNames, alert.Labels["alertname"]) {
+			continue
+		}
+		switch runbook.Namespace {
+		case namespace:
+			own = append(own, runbook)
+		case s.namespace:
+			fallback = append(fallback, runbook)
+		}
+	}
+	return append(own, fallback...)
+}
+
+// setPolicy adds or replaces an analysis policy
+func (s *Store) setPolicy(p *AnalysisPolicy) {
+	s.mu.Lock()
+	s.policies[key(p.Namespace, p.Name)] = p
+	s.mu.Unlock()
+	s.syncRedaction()
+}
+
+// deletePolicy removes an analysis policy
+func (s *Store) deletePolicy(namespace, name string) {
+	s.mu.Lock()
+	delete(s.policies, key(namespace, name))
+	s.mu.Unlock()
+	s.syncRedaction()
+}
+
+// setSilence adds or replaces a silence
+func (s *Store) setSilence(silence *Silence) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.silences[key(silence.Namespace, silence.Name)] = silence
+}
+
+// deleteSilence removes a silence
+func (s *Store) deleteSilence(namespace, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.silences, key(namespace, name))
+}
+
+// setRunbook adds or replaces a runbook
+func (s *Store) setRunbook(runbook *Runbook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runbooks[key(runbook.Namespace, runbook.Name)] = runbook
+}
+
+// deleteRunbook removes a runbook
+func (s *Store) deleteRunbook(namespace, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.runbooks, key(namespace, name))
+}
+
+// syncRedaction pushes the redaction of the policy applying to each namespace
+// to the redactor. The default namespace's policy becomes the fallback for
+// namespaces neither a policy nor the redaction config file covers.
+func (s *Store) syncRedaction() {
+	if s.redactor == nil {
+		return
+	}
+
+	s.mu.RLock()
+	namespaces := make(map[string]bool)
+	for _, p := range s.policies {
+		namespaces[p.Namespace] = true
+	}
+	var policies []redact.NamespacePolicy
+	var fallback *redact.NamespacePolicy
+	for _, namespace := range sortedKeys(namespaces) {
+		p := s.policyLocked(namespace)
+		if p.Spec.Redaction == nil {
+			continue
+		}
+		np := redactionPolicy(namespace, p.Spec.Redaction)
+		if namespace == s.namespace {
+			fallback = &redact.NamespacePolicy{Match: "*", Skip: np.Skip, Disable: np.Disable, Rules: np.Rules}
+		}
+		policies = append(policies, np)
+	}
+	s.mu.RUnlock()
+
+	// Policies were validated when accepted, so this only fails on a bug
+	if err := s.redactor.SetNamespacePolicies(policies, fallback); err != nil {
+		log.Printf("Warning: failed to apply redaction policies: %v", err)
+	}
+}
+
+// redactionPolicy converts a policy's redaction to a redactor namespace policy
+func redactionPolicy(namespace string, r *RedactionPolicy) redact.NamespacePolicy {
+	return redact.NamespacePolicy{Match: namespace, Skip: r.Skip, Disable: r.Disable, Rules: r.Rules}
+}
+
+// sortedKeys returns the keys of a map in order, so lookups are deterministic
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// containsName reports whether names contains name, ignoring surrounding spaces
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.TrimSpace(n) == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/redact"
+	"github.com/valentinpelus/k8flex/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Group and version of the k8flex custom resources
+const (
+	Group   = "k8flex.io"
+	Version = "v1alpha1"
+)
+
+// Resources of the k8flex custom resources
+var (
+	AnalysisPolicyResource = schema.GroupVersionResource{Group: Group, Version: Version, Resource: "analysispolicies"}
+	SilenceResource        = schema.GroupVersionResource{Group: Group, Version: Version, Resource: "silences"}
+	RunbookResource        = schema.GroupVersionResource{Group: Group, Version: Version, Resource: "runbooks"}
+)
+
+// AnalysisPolicySpec overrides how alerts of the policy's namespace are
+// analyzed. Unset fields keep the environment configuration.
+type AnalysisPolicySpec struct {
+	LLM         *LLMPolicy         `json:"llm,omitempty"`
+	Collectors  []string           `json:"collectors,omitempty"` // Replaces the collectors picked by category and the collectors config
+	Redaction   *RedactionPolicy   `json:"redaction,omitempty"`
+	Slack       *SlackPolicy       `json:"slack,omitempty"`
+	Remediation *RemediationPolicy `json:"remediation,omitempty"`
+}
+
+// LLMPolicy picks the provider analyzing a namespace's alerts
+type LLMPolicy struct {
+	Provider string `json:"provider,omitempty"` // Provider name or comma-separated fallback chain, as LLM_PROVIDER
+	Model    string `json:"model,omitempty"`    // Replaces the configured model of the providers named
+}
+
+// RedactionPolicy adjusts redaction for a namespace, like a namespace entry
+// of the redaction config file
+type RedactionPolicy struct {
+	Skip    bool          `json:"skip,omitempty"`    // No redaction at all
+	Disable []string      `json:"disable,omitempty"` // Detectors (built-in or rule names) turned off
+	Rules   []redact.Rule `json:"rules,omitempty"`   // Additional rules
+}
+
+// SlackPolicy picks where a namespace's alerts are posted
+type SlackPolicy struct {
+	Channel string `json:"channel,omitempty"` // Channel ID, instead of SLACK_CHANNEL_ID
+}
+
+// RemediationPolicy sets the actions that can be approved from Slack for a
+// namespace, replacing its rules of the remediation config file
+type RemediationPolicy struct {
+	Actions   []string `json:"actions,omitempty"`   // Namespaced actions of the catalog allowed; empty allows none
+	Approvers []string `json:"approvers,omitempty"` // Slack user IDs allowed to approve; empty keeps the default approvers
+}
+
+// SilenceSpec skips the analysis of matching alerts, optionally within a time range
+type SilenceSpec struct {
+	MatchLabels map[string]string `json:"matchLabels,omitempty"` // Labels that must be equal
+	MatchRegex  map[string]string `json:"matchRegex,omitempty"`  // Labels that must fully match a regex
+	StartsAt    *metav1.Time      `json:"startsAt,omitempty"`    // Not active before, if set
+	EndsAt      *metav1.Time      `json:"endsAt,omitempty"`      // Not active after, if set
+	Comment     string            `json:"comment,omitempty"`
+}
+
+// RunbookSpec attaches a runbook to alert names
+type RunbookSpec struct {
+	AlertNames []string `json:"alertNames"`
+	URL        string   `json:"url,omitempty"`     // Link to the full runbook
+	Content    string   `json:"content,omitempty"` // Runbook text, in Markdown
+}
+
+// AnalysisPolicy is an accepted AnalysisPolicy resource
+type AnalysisPolicy struct {
+	Namespace string
+	Name      string
+	Spec      AnalysisPolicySpec
+}
+
+// Silence is an accepted Silence resource
+type Silence struct {
+	Namespace string
+	Name      string
+	Spec      SilenceSpec
+	regexes   map[string]*regexp.Regexp
+}
+
+// Runbook is an accepted Runbook resource
+type Runbook struct {
+	Namespace string
+	Name      string
+	Spec      RunbookSpec
+}
+
+// newSilence validates a silence spec and compiles its regexes
+func newSilence(namespace, name string, spec SilenceSpec) (*Silence, error) {
+	if len(spec.MatchLabels) == 0 && len(spec.MatchRegex) == 0 {
+		return nil, fmt.Errorf("matchLabels or matchRegex is required")
+	}
+	if spec.StartsAt != nil && spec.EndsAt != nil && !spec.EndsAt.After(spec.StartsAt.Time) {
+		return nil, fmt.Errorf("endsAt must be after startsAt")
+	}
+
+	s := &Silence{Namespace: namespace, Name: name, Spec: spec, regexes: make(map[string]*regexp.Regexp)}
+	for label, pattern := range spec.MatchRegex {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("matchRegex %s: %w", label, err)
+		}
+		s.regexes[label] = re
+	}
+	return s, nil
+}
+
+// matches reports whether the silence is active at now and matches the alert
+func (s *Silence) matches(alert types.Alert, now time.Time) bool {
+	if s.Spec.StartsAt != nil && now.Before(s.Spec.StartsAt.Time) {
+		return false
+	}
+	if s.Spec.EndsAt != nil && now.After(s.Spec.EndsAt.Time) {
+		return false
+	}
+	for label, value := range s.Spec.MatchLabels {
+		if alert.Labels[label] != value {
+			return false
+		}
+	}
+	for label, re := range s.regexes {
+		if !re.MatchString(alert.Labels[label]) {
+			return false
+		}
+	}
+	return true
+}
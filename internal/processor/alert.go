@@ -2,95 +2,299 @@ package processor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/valentinpelus/k8flex/internal/correlation"
 	"github.com/valentinpelus/k8flex/internal/debugger"
+	"github.com/valentinpelus/k8flex/internal/policy"
+	"github.com/valentinpelus/k8flex/internal/queue"
+	"github.com/valentinpelus/k8flex/pkg/citation"
+	"github.com/valentinpelus/k8flex/pkg/conversation"
 	"github.com/valentinpelus/k8flex/pkg/feedback"
 	"github.com/valentinpelus/k8flex/pkg/knowledge"
 	"github.com/valentinpelus/k8flex/pkg/llm"
+	"github.com/valentinpelus/k8flex/pkg/notify"
+	"github.com/valentinpelus/k8flex/pkg/redact"
+	"github.com/valentinpelus/k8flex/pkg/remediation"
+	"github.com/valentinpelus/k8flex/pkg/report"
+	"github.com/valentinpelus/k8flex/pkg/runbook"
 	"github.com/valentinpelus/k8flex/pkg/slack"
+	"github.com/valentinpelus/k8flex/pkg/threads"
 	"github.com/valentinpelus/k8flex/pkg/types"
 )
 
-// PendingFeedback tracks analysis waiting for user reaction
-type PendingFeedback struct {
-	Alert      types.Alert
-	Category   string
-	Analysis   string
-	ThreadTS   string
-	AnalysisTS string // The message timestamp for the analysis
-	Timestamp  time.Time
+// Timeouts bounds each phase of alert processing
+type Timeouts struct {
+	Categorize time.Duration // LLM categorization call
+	Gather     time.Duration // Kubernetes debug info gathering
+	Analyze    time.Duration // LLM analysis, including streaming
+}
+
+// Total bounds a whole analysis, every phase included
+func (t Timeouts) Total() time.Duration {
+	return t.Categorize + t.Gather + t.Analyze
+}
+
+// StructuredAnalysis configures JSON analyses validated against llm.AnalysisSchema
+type StructuredAnalysis struct {
+	MaxAttempts int // Requests per analysis, including retries on invalid output
+}
+
+// Thread is where an alert and its analysis are posted. It's kept across
+// retries, so they update the same messages instead of posting new ones.
+type Thread struct {
+	TS         string // Alert message starting the thread; empty posts the alert
+	AnalysisTS string // Analysis or failure message in the thread; empty posts a new one
 }
 
 // AlertProcessor handles the processing of alerts
 type AlertProcessor struct {
 	debugger        *debugger.Debugger
 	llmProvider     llm.Provider
-	slackClient     *slack.Client
+	slackClient     *slack.Client  // Receives the ratings, buttons and follow-ups of Slack threads
+	notifiers       *notify.Router // Picks where each alert and its analysis are posted
 	feedbackManager *feedback.Manager
 	knowledgeBase   *knowledge.KnowledgeBase
-	pendingFeedback map[string]*PendingFeedback // Key: analysis message TS
-	pendingMutex    sync.RWMutex
+	threadStore     *threads.Store
+	timeouts        Timeouts
+	investigation   *debugger.InvestigationBudget // nil for single-shot analysis
+	structured      *StructuredAnalysis           // nil for free-form text analyses
+	redactor        *redact.Redactor              // nil disables redaction
+	policies        *policy.Store                 // nil applies no policy resources
+	runbooks        *runbook.Library              // nil only adds the runbooks of Runbook resources
+	remediator      *remediation.Executor         // nil proposes no remediation actions
+	pending         *feedback.PendingStore        // Analyses awaiting a ✅ or ❌ reaction
+	conversations   *conversation.Store           // nil disables follow-up questions and the analysis buttons
+	reanalyzing     sync.Map                      // Threads being analyzed again
+	slots           chan struct{}                 // Analyses and follow-up answers running at once
+}
+
+// Config holds the dependencies of the alert processor. Optional features
+// are disabled when their field is nil.
+type Config struct {
+	Debugger      *debugger.Debugger
+	LLMProvider   llm.Provider           // Default provider; a namespace's policy may pick another
+	SlackClient   *slack.Client          // Answers the ratings, buttons and follow-ups of Slack threads; one of the notifiers
+	Notifiers     *notify.Router         // Picks where each alert and its analysis are posted
+	Feedback      *feedback.Manager      // Past feedback added to prompts
+	Pending       *feedback.PendingStore // Analyses awaiting a ✅ or ❌ reaction
+	KnowledgeBase *knowledge.KnowledgeBase
+	Threads       *threads.Store // Thread of each alert, to close it out when resolved
+	Timeouts      Timeouts
+	// Workers bounds the analyses and follow-up answers running at once,
+	// queued alerts and Slack requests alike; the queue's workers
+	Workers int
+
+	Investigation *debugger.InvestigationBudget // Tool-calling investigation with providers supporting function calling
+	Structured    *StructuredAnalysis           // JSON analyses rendered from their fields
+	Redactor      *redact.Redactor              // Redacts annotations before Slack and the LLM see them
+	Policies      *policy.Store                 // Silences, and the provider and runbooks of each namespace
+	Runbooks      *runbook.Library              // Runbooks added to the evidence
+	Remediator    *remediation.Executor         // Offers the actions analyses propose for approval in Slack
+	Conversations *conversation.Store           // Thread context for HandleFollowUp and the analysis buttons
 }
 
-// NewAlertProcessor creates a new alert processor
-func NewAlertProcessor(dbg *debugger.Debugger, llmProvider llm.Provider, slackClient *slack.Client, feedbackMgr *feedback.Manager, kb *knowledge.KnowledgeBase) *AlertProcessor {
+// NewAlertProcessor creates a new alert processor. Reactions to analyses are
+// delivered by HandleReaction, or polled once StartReactionPolling is called.
+func NewAlertProcessor(config Config) *AlertProcessor {
 	processor := &AlertProcessor{
-		debugger:        dbg,
-		llmProvider:     llmProvider,
-		slackClient:     slackClient,
-		feedbackManager: feedbackMgr,
-		knowledgeBase:   kb,
-		pendingFeedback: make(map[string]*PendingFeedback),
+		debugger:        config.Debugger,
+		llmProvider:     config.LLMProvider,
+		slackClient:     config.SlackClient,
+		notifiers:       config.Notifiers,
+		feedbackManager: config.Feedback,
+		knowledgeBase:   config.KnowledgeBase,
+		threadStore:     config.Threads,
+		timeouts:        config.Timeouts,
+		investigation:   config.Investigation,
+		structured:      config.Structured,
+		redactor:        config.Redactor,
+		policies:        config.Policies,
+		runbooks:        config.Runbooks,
+		remediator:      config.Remediator,
+		pending:         config.Pending,
+		conversations:   config.Conversations,
+		slots:           make(chan struct{}, max(config.Workers, 1)),
 	}
 
-	// Start background reaction checker if Slack is configured
-	if slackClient.IsConfigured() && slackClient.HasBotToken() {
-		go processor.reactionChecker()
+	// Policies may pick a provider that supports function calling, so
+	// investigation stays enabled and is checked per alert
+	if _, ok := config.LLMProvider.(llm.ToolCallingProvider); config.Investigation != nil && !ok {
+		log.Printf("Warning: %s doesn't support function calling, using single-shot analysis", config.LLMProvider.Name())
 	}
 
 	return processor
 }
 
-// ProcessAlert processes a single alert
-func (p *AlertProcessor) ProcessAlert(alert types.Alert) {
+// HandleJob processes queued alert jobs, reusing the thread and analysis
+// message from a previous attempt so retries don't post them again. Several jobs are
+// correlated alerts, analyzed together as one incident. Firing alerts
+// matching a Silence resource are skipped.
+func (p *AlertProcessor) HandleJob(ctx context.Context, jobs []*queue.Job) error {
+	active := jobs[:0:0]
+	for _, job := range jobs {
+		if job.Alert.Status != "resolved" {
+			if silence := p.policies.Silenced(job.Alert); silence != nil {
+				log.Printf("Alert %s silenced by %s/%s, skipping analysis",
+					job.Alert.Labels["alertname"], silence.Namespace, silence.Name)
+				continue
+			}
+		}
+		active = append(active, job)
+	}
+	if len(active) == 0 {
+		return nil
+	}
+	jobs = active
+
+	if !p.acquire(ctx) {
+		return ctx.Err()
+	}
+	defer p.release()
+
+	if len(jobs) == 1 {
+		thread := &Thread{TS: jobs[0].ThreadTS, AnalysisTS: jobs[0].AnalysisTS}
+		err := p.ProcessAlert(ctx, jobs[0].Alert, thread)
+		jobs[0].ThreadTS, jobs[0].AnalysisTS = thread.TS, thread.AnalysisTS
+		return err
+	}
+
+	members := make([]types.IncidentMember, len(jobs))
+	thread := &Thread{}
+	for i, job := range jobs {
+		members[i] = types.IncidentMember{Alert: job.Alert, Keys: job.CorrelationKeys}
+		if thread.TS == "" {
+			thread.TS, thread.AnalysisTS = job.ThreadTS, job.AnalysisTS
+		}
+	}
+	err := p.ProcessIncident(ctx, correlation.NewIncident(members), thread)
+	for _, job := range jobs {
+		job.ThreadTS, job.AnalysisTS = thread.TS, thread.AnalysisTS
+	}
+	return err
+}
+
+// acquire waits for a free worker slot, or returns false once ctx is done
+func (p *AlertProcessor) acquire(ctx context.Context) bool {
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release frees the worker slot taken by acquire
+func (p *AlertProcessor) release() {
+	<-p.slots
+}
+
+// ProcessAlert processes a single alert and records the messages it was
+// posted in on thread. Messages already set on thread are updated instead of
+// posted again. An error is returned when the analysis failed and the alert
+// should be retried.
+func (p *AlertProcessor) ProcessAlert(ctx context.Context, alert types.Alert, thread *Thread) error {
+	if alert.Status == "resolved" {
+		return p.ResolveAlert(ctx, alert)
+	}
+
 	log.Printf("Processing alert: %s", alert.Labels["alertname"])
+	return p.analyze(ctx, alert, nil, thread)
+}
 
+// analyze debugs and analyzes a firing alert, or the lead alert of an
+// incident together with the evidence about its members, and posts the
+// analysis in the alert's thread with the notifier it's routed to
+func (p *AlertProcessor) analyze(ctx context.Context, alert types.Alert, incident *types.Incident, thread *Thread) error {
 	// Extract parameters from alert labels
 	namespace := alert.Labels["namespace"]
 
 	if namespace == "" {
 		log.Printf("Alert %s missing namespace label, skipping", alert.Labels["alertname"])
-		return
+		return nil
+	}
+
+	// Annotations may carry secrets or PII; redact them before Slack and the LLM see the alert
+	redactions := redact.Counts{}
+	if p.redactor != nil {
+		if incident != nil {
+			incident, redactions = p.redactIncident(incident)
+			alert = incident.Lead()
+		} else {
+			alert, redactions = p.redactor.RedactAlert(alert)
+		}
 	}
 
-	// Send alert to Slack FIRST before starting debug work
-	var slackThreadTS string
-	if p.slackClient.IsConfigured() {
-		ts, err := p.slackClient.SendAlert(alert)
+	// Send the alert FIRST before starting debug work
+	n := p.notifiers.Route(alert)
+	alertThreadTS := thread.TS
+	if n.IsConfigured() && alertThreadTS == "" {
+		var ts string
+		var err error
+		if incident != nil {
+			ts, err = n.SendIncident(ctx, incident)
+		} else {
+			ts, err = n.SendAlert(ctx, alert)
+		}
 		if err != nil {
-			log.Printf("Failed to send alert to Slack: %v", err)
+			log.Printf("Failed to send alert to %s: %v", n.Name(), err)
 		} else {
-			slackThreadTS = ts
-			log.Printf("Alert sent to Slack successfully")
+			alertThreadTS = ts
+			thread.TS = ts
+			log.Printf("Alert sent to %s successfully", n.Name())
+		}
+	}
+
+	// The namespace's analysis policy may pick another provider
+	provider := p.policies.Provider(namespace, p.llmProvider)
+	var toolProvider llm.ToolCallingProvider
+	if p.investigation != nil {
+		var ok bool
+		if toolProvider, ok = provider.(llm.ToolCallingProvider); !ok && provider != p.llmProvider {
+			log.Printf("Warning: %s doesn't support function calling, using single-shot analysis", provider.Name())
 		}
 	}
 
 	// Phase 1: Ask LLM provider to categorize the alert
-	log.Printf("Asking %s to categorize alert: %s", p.llmProvider.Name(), alert.Labels["alertname"])
-	category, err := p.llmProvider.CategorizeAlert(alert)
+	log.Printf("Asking %s to categorize alert: %s", provider.Name(), alert.Labels["alertname"])
+	categorizeCtx, cancelCategorize := context.WithTimeout(ctx, p.timeouts.Categorize)
+	category, err := provider.CategorizeAlert(categorizeCtx, alert)
 	if err != nil {
-		log.Printf("Error categorizing alert: %v, using 'unknown'", err)
+		if categorizeCtx.Err() == context.DeadlineExceeded {
+			log.Printf("Categorization timed out after %s, using 'unknown'", p.timeouts.Categorize)
+		} else {
+			log.Printf("Error categorizing alert: %v, using 'unknown'", err)
+		}
 		category = "unknown"
 	}
-	log.Printf("%s categorized alert as: %s", p.llmProvider.Name(), category)
+	cancelCategorize()
+	log.Printf("%s categorized alert as: %s", provider.Name(), category)
+
+	// Remember the thread so a later resolved notification can close it out
+	if alertThreadTS != "" && incident != nil {
+		p.saveIncidentThread(n, incident, alertThreadTS, category)
+	} else if alertThreadTS != "" {
+		if err := p.threadStore.Save(threads.Record{
+			Fingerprint: types.AlertFingerprint(alert),
+			ThreadTS:    alertThreadTS,
+			AlertName:   alert.Labels["alertname"],
+			Namespace:   namespace,
+			Severity:    alert.Labels["severity"],
+			Category:    category,
+			StartsAt:    alert.StartsAt,
+			Channel:     channelOf(n, alertThreadTS),
+			Notifier:    n.Name(),
+		}); err != nil {
+			log.Printf("Warning: failed to record alert thread: %v", err)
+		}
+	}
 
 	// Phase 2: Search knowledge base for similar cases (if enabled)
-	ctx := context.Background()
 	var similarCases []*knowledge.SimilarCase
 	if p.knowledgeBase != nil {
 		searchText := fmt.Sprintf("%s %s %s",
@@ -107,8 +311,19 @@ func (p *AlertProcessor) ProcessAlert(alert types.Alert) {
 		}
 	}
 
-	// Phase 3: Gather only relevant debug information based on category
-	debugInfo := p.debugger.GatherDebugInfo(ctx, alert, category)
+	// Phase 3: Gather only relevant debug information based on category.
+	// Collectors run concurrently within the gather budget and the debugger
+	// reports which sections timed out.
+	gatherCtx, cancelGather := context.WithTimeout(ctx, p.timeouts.Gather)
+	debugResult := p.debugger.GatherDebugInfo(gatherCtx, alert, category)
+	if incident != nil {
+		p.debugger.GatherIncidentInfo(gatherCtx, debugResult, incident)
+	}
+	cancelGather()
+	p.addRunbooks(ctx, debugResult, category)
+	p.addActionCatalog(debugResult)
+	redactions.Add(debugResult.Redactions)
+	debugResult.Redactions = redactions
 
 	// Get past feedback for similar alerts to improve analysis (limit to 1 to reduce prompt size)
 	pastFeedback := p.feedbackManager.GetRelevantFeedback(category, alert.Labels["alertname"], 1)
@@ -117,7 +332,7 @@ func (p *AlertProcessor) ProcessAlert(alert types.Alert) {
 		// Enhance feedback with Slack links if available
 		for i := range pastFeedback {
 			if pastFeedback[i].SlackThread != "" && p.slackClient.HasBotToken() {
-				channelID := p.slackClient.GetChannelID()
+				channelID := p.slackClient.ChannelOf(pastFeedback[i].SlackThread)
 				workspaceID := p.slackClient.GetWorkspaceID()
 				if workspaceID != "" {
 					slackLink := fmt.Sprintf("https://%s.slack.com/archives/%s/p%s",
@@ -130,123 +345,371 @@ func (p *AlertProcessor) ProcessAlert(alert types.Alert) {
 
 	// Add similar cases context to prompt if available
 	if len(similarCases) > 0 {
-		similarCasesText := "\n\n=== SIMILAR PAST CASES (from Knowledge Base) ===\n"
+		var similarCasesText string
 		for i, sc := range similarCases {
 			if i >= 3 { // Limit to top 3 to avoid prompt bloat
 				break
 			}
 			similarCasesText += fmt.Sprintf("\n%d. [%.0f%% similar] %s - Category: %s\n",
 				i+1, sc.Similarity*100, sc.Case.AlertName, sc.Case.Category)
-			// Truncate analysis to first 150 chars
-			analysis := sc.Case.Analysis
-			if len(analysis) > 150 {
-				analysis = analysis[:150] + "..."
+			if sc.Case.RootCause != "" {
+				similarCasesText += fmt.Sprintf("   Previous Root Cause (%s confidence): %s\n", sc.Case.Confidence, sc.Case.RootCause)
+			} else {
+				similarCasesText += fmt.Sprintf("   Previous Analysis: %s\n", sc.Case.Analysis)
 			}
-			similarCasesText += fmt.Sprintf("   Previous Analysis: %s\n", analysis)
 		}
-		similarCasesText += "\nUse these similar cases to inform your analysis if patterns match.\n"
-		debugInfo = debugInfo + similarCasesText
+		similarCasesText += "\nUse these similar cases to inform your analysis if patterns match."
+		debugResult.Sections = append(debugResult.Sections, types.DebugSection{
+			Title:     "SIMILAR PAST CASES (from Knowledge Base)",
+			Source:    "knowledge-base",
+			Timestamp: time.Now(),
+			Content:   strings.TrimLeft(similarCasesText, "\n"),
+		})
+	}
+
+	// Fit the evidence into the model's context window, cutting what matters
+	// least for this category first. Tool results of an investigation share
+	// the window, so it only gets half for the initial debug info.
+	budget := llm.DebugInfoBudget(provider, pastFeedback, p.structured != nil)
+	if toolProvider != nil {
+		budget /= 2
 	}
+	fitted := report.Fit(debugResult, budget)
+	debugResult.Trimmed = fitted.Trimmed
+	if trimmed := report.Trimmed(fitted); trimmed != "" {
+		log.Printf("Trimmed debug info to fit %d tokens for %s: %s", budget, provider.Name(), trimmed)
+	}
+	debugInfo := report.Prompt(fitted)
 
-	// Phase 4: Stream analysis from LLM provider with real-time Slack updates
-	log.Printf("Starting streaming analysis from %s", p.llmProvider.Name())
+	// Evidence of a structured analysis must cite one of the sections sent
+	sections := make([]string, len(fitted.Sections))
+	for i, s := range fitted.Sections {
+		sections[i] = s.Title
+	}
+	prompt := llm.BuildAnalysisPrompt(debugInfo, pastFeedback)
+	if p.structured != nil {
+		prompt = llm.BuildStructuredAnalysisPrompt(debugInfo, pastFeedback, sections)
+	}
 
 	var fullAnalysis strings.Builder
-	var analysisMessageTS string // Track the THREAD message timestamp for updates (not the parent)
+	analysisMessageTS := thread.AnalysisTS // Track the THREAD message timestamp for updates (not the parent)
 	updateCount := 0
 
-	err = p.llmProvider.AnalyzeDebugInfoStream(debugInfo, pastFeedback, func(chunk string) {
-		fullAnalysis.WriteString(chunk)
-		updateCount++
+	// A retry replaces the failure notice of the previous attempt
+	if n.IsConfigured() && analysisMessageTS != "" {
+		n.UpdateMessage(ctx, analysisMessageTS, "🔄 *Analysis in progress...*")
+	}
 
-		// Update Slack every 10 chunks or when we have substantial content
-		if p.slackClient.IsConfigured() && slackThreadTS != "" && updateCount%10 == 0 {
-			currentAnalysis := fullAnalysis.String()
+	analyzeCtx, cancelAnalyze := context.WithTimeout(ctx, p.timeouts.Analyze)
+	defer cancelAnalyze()
+	analyzeCtx, providerUsed := llm.WithProviderTracking(analyzeCtx)
+
+	// Phase 4: Analyze, either by letting the model investigate with tools, by
+	// requesting a structured analysis, or by streaming a single-shot analysis
+	// with real-time updates
+	var investigation *debugger.Investigation
+	var structured *types.Analysis
+	if toolProvider != nil {
+		log.Printf("Starting tool-calling investigation with %s", provider.Name())
+		var progress strings.Builder
+		investigation, err = p.debugger.Investigate(analyzeCtx, toolProvider, alert, prompt, *p.investigation, func(step debugger.InvestigationStep) {
+			progress.WriteString(fmt.Sprintf("%d. `%s`\n", step.Number, step.Call))
+			if !n.IsConfigured() || alertThreadTS == "" {
+				return
+			}
+			progressMsg := "🔍 *Investigating...*\n\n" + progress.String()
 			if analysisMessageTS == "" {
-				// First update - send initial message IN THE THREAD and capture its timestamp
-				analysisMsg := "🔄 *Analysis in progress...*\n\n" + currentAnalysis
-				ts, sendErr := p.slackClient.SendAnalysisInThread(alert, analysisMsg, slackThreadTS)
-				if sendErr == nil {
-					analysisMessageTS = ts // Save the thread message timestamp for future updates
-					log.Printf("Started streaming analysis in thread message: %s", analysisMessageTS)
+				if ts, sendErr := n.SendAnalysisInThread(ctx, alert, progressMsg, alertThreadTS); sendErr == nil {
+					analysisMessageTS = ts
 				}
 			} else {
-				// Update the THREAD message (not the parent alert message)
-				analysisMsg := "🔄 *Analysis in progress...*\n\n" + currentAnalysis
-				p.slackClient.UpdateMessage(analysisMessageTS, analysisMsg)
+				n.UpdateMessage(ctx, analysisMessageTS, progressMsg)
 			}
+		})
+		fullAnalysis.WriteString(investigation.Analysis)
+		if p.structured != nil && err == nil {
+			for _, s := range investigation.Sections() {
+				sections = append(sections, s.Title)
+			}
+			structured, err = p.structureInvestigation(analyzeCtx, provider, investigation.Analysis, sections)
 		}
-	})
+	} else if p.structured != nil {
+		log.Printf("Starting structured analysis with %s", provider.Name())
+		if n.IsConfigured() && alertThreadTS != "" && analysisMessageTS == "" {
+			if ts, sendErr := n.SendAnalysisInThread(ctx, alert, "🔄 *Analysis in progress...*", alertThreadTS); sendErr == nil {
+				analysisMessageTS = ts
+			}
+		}
+		var raw string
+		structured, raw, err = llm.AnalyzeStructured(analyzeCtx, provider, prompt, sections, p.structured.MaxAttempts)
+		fullAnalysis.WriteString(raw)
+	} else {
+		log.Printf("Starting streaming analysis from %s", provider.Name())
+		err = provider.AnalyzeDebugInfoStream(analyzeCtx, debugInfo, pastFeedback, func(chunk string) {
+			fullAnalysis.WriteString(chunk)
+			updateCount++
+
+			// Update the thread every 10 chunks or when we have substantial content
+			if n.IsConfigured() && alertThreadTS != "" && updateCount%10 == 0 {
+				currentAnalysis := fullAnalysis.String()
+				if analysisMessageTS == "" {
+					// First update - send initial message IN THE THREAD and capture its timestamp
+					analysisMsg := "🔄 *Analysis in progress...*\n\n" + currentAnalysis
+					ts, sendErr := n.SendAnalysisInThread(ctx, alert, analysisMsg, alertThreadTS)
+					if sendErr == nil {
+						analysisMessageTS = ts // Save the thread message timestamp for future updates
+						log.Printf("Started streaming analysis in thread message: %s", analysisMessageTS)
+					}
+				} else {
+					// Update the THREAD message (not the parent alert message)
+					analysisMsg := "🔄 *Analysis in progress...*\n\n" + currentAnalysis
+					n.UpdateMessage(ctx, analysisMessageTS, analysisMsg)
+				}
+			}
+		})
+	}
+
+	// A reply that never matched the schema is still worth showing as is
+	if errors.Is(err, llm.ErrInvalidAnalysis) {
+		log.Printf("Showing unstructured analysis from %s: %v", provider.Name(), err)
+		fullAnalysis.WriteString(fmt.Sprintf("\n\n_⚠️ This analysis didn't match the expected format (%v), so it's shown as written._", err))
+		err = nil
+	}
 
 	analysis := fullAnalysis.String()
-	if err != nil {
-		log.Printf("Error analyzing with Ollama: %v", err)
-		analysis = fmt.Sprintf("Error: %v", err)
+	analysisErr := err
+
+	// With a fallback chain, report the provider that actually produced the analysis
+	providerName := providerUsed()
+	if providerName == "" {
+		providerName = provider.Name()
+	}
+	completeHeader := "✅ *Analysis Complete*"
+	var notice string // Replaces the analysis when it failed
+	switch {
+	case err != nil && analyzeCtx.Err() == context.DeadlineExceeded:
+		log.Printf("Analysis with %s timed out after %s", provider.Name(), p.timeouts.Analyze)
+		completeHeader = "⏱️ *Analysis Timed Out*"
+		timeoutNote := fmt.Sprintf("_%s did not finish the analysis within %s._", provider.Name(), p.timeouts.Analyze)
+		if analysis != "" {
+			analysis = timeoutNote + "\n\nPartial analysis:\n" + analysis
+		} else {
+			analysis = timeoutNote
+		}
+	case err != nil && ctx.Err() != nil:
+		log.Printf("Analysis with %s interrupted: %v", provider.Name(), err)
+		completeHeader = "⏸️ *Analysis Interrupted*"
+		notice = "_k8flex stopped before the analysis finished. It resumes when k8flex is back._"
+	case err != nil:
+		// The raw error may be a long API response; it's only logged
+		log.Printf("Error analyzing with %s: %v", provider.Name(), err)
+		completeHeader = "❌ *Analysis Failed*"
+		notice = fmt.Sprintf("_%s couldn't analyze this alert. The error is in the k8flex logs; failed analyses are retried while attempts remain._", providerName)
+	}
+
+	// Record every tool call the investigation made in the report
+	if investigation != nil {
+		debugResult.Sections = append(debugResult.Sections, investigation.Sections()...)
+		redactions.Add(investigation.Redactions)
+		debugInfo = report.Prompt(debugResult)
+	}
+
+	// Check the quotes and values the analysis claims against the evidence,
+	// flagging those that aren't there. A reply that didn't match the JSON
+	// schema isn't checked, since its quoted strings are mostly JSON.
+	var grounding *types.Grounding
+	switch {
+	case structured != nil:
+		grounding = citation.VerifyAnalysis(structured, debugResult)
+		analysis = report.Analysis(structured)
+	case err == nil && p.structured == nil:
+		analysis, grounding = citation.VerifyText(analysis, debugResult)
+	}
+	if grounding != nil && grounding.Checked > 0 {
+		log.Printf("Analysis grounding for %s: %d/%d quotes and values verified",
+			alert.Labels["alertname"], grounding.Verified, grounding.Checked)
+	}
+
+	if investigation != nil {
+		analysis += "\n\n" + investigation.Summary()
+	}
+
+	// Report what was redacted or left out before anything was sent to the LLM
+	if redactions.Total() > 0 {
+		analysis += fmt.Sprintf("\n\n*🔒 Redacted before analysis:* %s", redactions)
+	}
+	if trimmed := report.Trimmed(debugResult); trimmed != "" {
+		analysis += fmt.Sprintf("\n\n*✂️ Left out to fit the context window:* %s", trimmed)
+	}
+	if summary := citation.Summary(grounding); summary != "" {
+		analysis += fmt.Sprintf("\n\n*🔎 Grounding:* %s", summary)
 	}
 
 	// Log the complete analysis
 	log.Printf("\n=== COMPLETE ANALYSIS FOR %s ===\n%s\n=== AI ANALYSIS ===\n%s\n=== END ===\n",
 		alert.Labels["alertname"], debugInfo, analysis)
 
-	// Send final analysis to Slack thread
-	if p.slackClient.IsConfigured() && slackThreadTS != "" {
-		// Add feedback instructions
-		analysisWithInstructions := "✅ *Analysis Complete*\n\n" + analysis + "\n\n_💡 Rate this analysis: React with ✅ if correct or ❌ if incorrect to help improve future debugging_"
+	if notice != "" {
+		analysis = notice
+	}
 
-		if analysisMessageTS != "" {
-			// Update the existing streaming message with final analysis
-			if err := p.slackClient.UpdateMessage(analysisMessageTS, analysisWithInstructions); err != nil {
-				log.Printf("Failed to update final analysis in Slack: %v", err)
-			} else {
-				log.Printf("Final analysis updated in Slack thread message: %s", analysisMessageTS)
-			}
+	// Post the final analysis in the thread, replacing the streamed message or
+	// the notice of a failed attempt if any. An interrupted analysis is
+	// reported too, so the post outlives ctx.
+	if n.IsConfigured() {
+		postCtx := context.WithoutCancel(ctx)
+		footer := "_🤖 Analysis by " + providerName + "_"
+		if _, ok := n.(notify.FeedbackCollector); ok && alertThreadTS != "" && analysisErr == nil {
+			footer += "\n_💡 Rate this analysis: React with ✅ if correct or ❌ if incorrect to help improve future debugging_"
+		}
+		ts, err := n.PostAnalysis(postCtx, alertThreadTS, analysisMessageTS, types.AnalysisMessage{
+			Alert:    alert,
+			Title:    completeHeader,
+			Analysis: analysis,
+			Footer:   footer,
+		})
+		if err != nil {
+			log.Printf("Failed to post final analysis to %s: %v", n.Name(), err)
 		} else {
-			// No streaming message exists, send as new message in thread
-			ts, err := p.slackClient.SendAnalysisInThread(alert, analysisWithInstructions, slackThreadTS)
-			if err != nil {
-				log.Printf("Failed to send analysis to Slack thread: %v", err)
-			} else {
-				analysisMessageTS = ts
-				log.Printf("Analysis posted to Slack thread: %s", slackThreadTS)
+			log.Printf("Final analysis posted to %s (thread: %s)", n.Name(), alertThreadTS)
+		}
+		if ts != "" {
+			analysisMessageTS = ts
+			thread.AnalysisTS = ts
+		}
+
+		// Store pending feedback with the analysis message timestamp. A failed
+		// analysis isn't rated; its message is replaced when it's retried.
+		if analysisMessageTS != "" && analysisErr == nil {
+			p.storePendingFeedback(feedback.Pending{
+				Alert:      alert,
+				Category:   category,
+				Analysis:   analysis,
+				Structured: structured,
+				Grounding:  grounding,
+				ThreadTS:   alertThreadTS,
+				AnalysisTS: analysisMessageTS,
+				Channel:    channelOf(n, analysisMessageTS),
+				Notifier:   n.Name(),
+			})
+		}
+
+		// Slack delivers the buttons, follow-ups and approvals of its threads
+		if p.isSlack(n) && p.slackClient.HasBotToken() && alertThreadTS != "" && analysisErr == nil {
+			p.uploadReport(postCtx, debugResult, alertThreadTS)
+			p.saveConversation(alert, incident, category, report.Fit(debugResult, budget), analysis, alertThreadTS)
+			if p.remediator != nil {
+				p.proposeActions(postCtx, alert, structured, analysis, alertThreadTS)
 			}
 		}
+	}
+
+	if analysisErr != nil {
+		return fmt.Errorf("analysis failed: %w", analysisErr)
+	}
+	return nil
+}
+
+// structureInvestigation turns the final answer of an investigation into a
+// structured analysis. The answer is already JSON unless the model ignored the
+// requested format, in which case it's converted with GenerateJSON.
+func (p *AlertProcessor) structureInvestigation(ctx context.Context, provider llm.Provider, answer string, sections []string) (*types.Analysis, error) {
+	analysis, err := llm.ParseAnalysis(answer, sections)
+	if err == nil {
+		return analysis, nil
+	}
+	log.Printf("Investigation answer isn't a valid analysis (%v), converting it", err)
+	analysis, _, err = llm.AnalyzeStructured(ctx, provider, llm.BuildAnalysisConversionPrompt(answer, sections), sections, p.structured.MaxAttempts)
+	return analysis, err
+}
+
+// ResolveAlert closes out the thread of a resolved alert and records its
+// time to resolve on the knowledge base case, if one was stored
+func (p *AlertProcessor) ResolveAlert(ctx context.Context, alert types.Alert) error {
+	alertName := alert.Labels["alertname"]
+	fingerprint := types.AlertFingerprint(alert)
+
+	record, ok := p.threadStore.Get(fingerprint)
+	if !ok {
+		log.Printf("Resolved alert %s has no known thread, skipping", alertName)
+		return nil
+	}
+	if record.IsResolved() {
+		log.Printf("Alert %s already marked as resolved", alertName)
+		return nil
+	}
+	resolvedAt := alert.EndsAt
+	if resolvedAt.IsZero() {
+		resolvedAt = time.Now()
+	}
+
+	record, err := p.threadStore.MarkResolved(fingerprint, resolvedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record resolution: %w", err)
+	}
+	if record.Incident {
+		return p.resolveIncidentMember(ctx, record)
+	}
+	duration := record.ResolutionDuration()
+	log.Printf("Alert %s resolved after %s", alertName, duration.Round(time.Second))
 
-		// Store pending feedback with the analysis message timestamp
-		if analysisMessageTS != "" {
-			p.storePendingFeedback(alert, category, analysis, slackThreadTS, analysisMessageTS)
+	if n := p.threadNotifier(record); n.IsConfigured() {
+		if err := n.SendResolution(ctx, alert, record.ThreadTS, duration); err != nil {
+			log.Printf("Failed to send resolution to %s: %v", n.Name(), err)
 		}
-	} else if p.slackClient.IsConfigured() {
-		// If no thread ID, send as separate message
-		analysisWithInstructions := analysis + "\n\n_💡 Rate this analysis: React with ✅ if correct or ❌ if incorrect to help improve future debugging_"
+	}
 
-		if err := p.slackClient.SendAnalysis(alert, analysisWithInstructions, ""); err != nil {
-			log.Printf("Failed to send analysis to Slack: %v", err)
-		} else {
-			log.Printf("Analysis sent to Slack")
+	// The case may not exist yet; it picks up the resolution when feedback arrives
+	if p.knowledgeBase != nil && record.CaseID != "" {
+		if err := p.knowledgeBase.RecordResolution(ctx, record.CaseID, resolvedAt, duration); err != nil {
+			log.Printf("Warning: failed to record resolution in knowledge base: %v", err)
 		}
 	}
+
+	return nil
 }
 
-// storePendingFeedback stores analysis info for future feedback collection
-func (p *AlertProcessor) storePendingFeedback(alert types.Alert, category, analysis, threadTS, analysisTS string) {
-	p.pendingMutex.Lock()
-	defer p.pendingMutex.Unlock()
+// storeValidatedCase stores a positively rated analysis in the knowledge base,
+// including the resolution time if the alert has already been resolved
+func (p *AlertProcessor) storeValidatedCase(rated *feedback.Pending, debugInfo string) {
+	alertCase := knowledge.FromAlert(&rated.Alert, rated.Category, rated.Analysis, debugInfo)
+	alertCase.SetStructuredAnalysis(rated.Structured)
+	alertCase.SetGrounding(rated.Grounding)
+
+	// An incident's case is resolved once all of its alerts are
+	records := p.threadStore.ListByThread(rated.ThreadTS)
+	if resolvedAt, duration, ok := threads.Resolution(records); ok {
+		alertCase.ResolvedAt = resolvedAt
+		alertCase.ResolutionDuration = duration
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := p.knowledgeBase.Store(ctx, alertCase); err != nil {
+		log.Printf("Warning: failed to store case in knowledge base: %v", err)
+		return
+	}
+	log.Printf("✅ Stored validated case in knowledge base: %s (%s)", alertCase.AlertName, alertCase.Category)
 
-	p.pendingFeedback[analysisTS] = &PendingFeedback{
-		Alert:      alert,
-		Category:   category,
-		Analysis:   analysis,
-		ThreadTS:   threadTS,
-		AnalysisTS: analysisTS,
-		Timestamp:  time.Now(),
+	for _, record := range records {
+		if err := p.threadStore.SetCaseID(record.Fingerprint, alertCase.ID); err != nil {
+			log.Printf("Warning: failed to link case to Slack thread: %v", err)
+		}
 	}
+}
 
-	log.Printf("Stored pending feedback for message: %s (thread: %s)", analysisTS, threadTS)
+// storePendingFeedback stores analysis info for future feedback collection
+func (p *AlertProcessor) storePendingFeedback(pending feedback.Pending) {
+	if err := p.pending.Add(pending); err != nil {
+		log.Printf("Warning: failed to save pending feedback: %v", err)
+	}
+	log.Printf("Stored pending feedback for message: %s (thread: %s)", pending.AnalysisTS, pending.ThreadTS)
 }
 
 // RecordManualFeedback allows manual feedback recording (can be called from API endpoint)
 func (p *AlertProcessor) RecordManualFeedback(alert types.Alert, category, analysis, slackThread string, isCorrect bool) error {
-	feedback := types.Feedback{
+	fb := types.Feedback{
 		Timestamp:   time.Now(),
 		AlertName:   alert.Labels["alertname"],
 		Category:    category,
@@ -259,129 +722,16 @@ func (p *AlertProcessor) RecordManualFeedback(alert types.Alert, category, analy
 	}
 
 	// Store in feedback manager
-	if err := p.feedbackManager.RecordFeedback(feedback); err != nil {
+	if err := p.feedbackManager.RecordFeedback(fb); err != nil {
 		return err
 	}
 
 	// If feedback is positive and knowledge base is enabled, store the case
+	// Don't fail the feedback recording if knowledge base storage fails
 	if isCorrect && p.knowledgeBase != nil {
 		debugInfo := "" // We don't have debug info in manual feedback, but could add it
-		alertCase := knowledge.FromAlert(&alert, category, analysis, debugInfo)
-
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		if err := p.knowledgeBase.Store(ctx, alertCase); err != nil {
-			log.Printf("Warning: failed to store case in knowledge base: %v", err)
-			// Don't fail the feedback recording if knowledge base storage fails
-		} else {
-			log.Printf("✅ Stored validated case in knowledge base: %s (%s)", alertCase.AlertName, alertCase.Category)
-		}
+		p.storeValidatedCase(&feedback.Pending{Alert: alert, Category: category, Analysis: analysis, ThreadTS: slackThread}, debugInfo)
 	}
 
 	return nil
 }
-
-// reactionChecker periodically checks for emoji reactions on analysis messages
-func (p *AlertProcessor) reactionChecker() {
-	ticker := time.NewTicker(30 * time.Second) // Check every 30 seconds
-	defer ticker.Stop()
-
-	log.Printf("Started reaction checker - polling every 30 seconds")
-
-	for range ticker.C {
-		p.checkPendingReactions()
-	}
-}
-
-// checkPendingReactions checks all pending feedback for reactions
-func (p *AlertProcessor) checkPendingReactions() {
-	p.pendingMutex.RLock()
-	pendingList := make([]*PendingFeedback, 0, len(p.pendingFeedback))
-	for _, pending := range p.pendingFeedback {
-		pendingList = append(pendingList, pending)
-	}
-	p.pendingMutex.RUnlock()
-
-	for _, pending := range pendingList {
-		// Skip if too old (older than 24 hours)
-		if time.Since(pending.Timestamp) > 24*time.Hour {
-			p.pendingMutex.Lock()
-			delete(p.pendingFeedback, pending.AnalysisTS)
-			p.pendingMutex.Unlock()
-			continue
-		}
-
-		// Check for reactions
-		reactions, err := p.slackClient.GetMessageReactions(pending.AnalysisTS)
-		if err != nil {
-			log.Printf("Error checking reactions for %s: %v", pending.AnalysisTS, err)
-			continue
-		}
-
-		// Check for ✅ or ❌ reactions
-		var isCorrect *bool
-		for _, reaction := range reactions {
-			if reaction == "white_check_mark" || reaction == "coche_blanche" || reaction == "heavy_check_mark" {
-				val := true
-				isCorrect = &val
-				break
-			} else if reaction == "x" || reaction == "cross" || reaction == "negative_squared_cross_mark" {
-				val := false
-				isCorrect = &val
-				break
-			}
-		}
-
-		if isCorrect != nil {
-			// Record feedback
-			feedback := types.Feedback{
-				Timestamp:   time.Now(),
-				AlertName:   pending.Alert.Labels["alertname"],
-				Category:    pending.Category,
-				Namespace:   pending.Alert.Labels["namespace"],
-				Summary:     pending.Alert.Annotations["summary"],
-				Analysis:    pending.Analysis,
-				IsCorrect:   *isCorrect,
-				SlackThread: pending.ThreadTS,
-				Labels:      pending.Alert.Labels,
-			}
-
-			if err := p.feedbackManager.RecordFeedback(feedback); err != nil {
-				log.Printf("Error recording feedback: %v", err)
-			} else {
-				emoji := "✅"
-				if !*isCorrect {
-					emoji = "❌"
-				}
-
-				// If feedback is positive and knowledge base is enabled, store the case
-				if *isCorrect && p.knowledgeBase != nil {
-					debugInfo := "" // We could enhance this by storing debug info in PendingFeedback
-					alertCase := knowledge.FromAlert(&pending.Alert, pending.Category, pending.Analysis, debugInfo)
-
-					ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-					defer cancel()
-
-					if err := p.knowledgeBase.Store(ctx, alertCase); err != nil {
-						log.Printf("Warning: failed to store case in knowledge base: %v", err)
-					} else {
-						log.Printf("✅ Stored validated case in knowledge base: %s (%s)", alertCase.AlertName, alertCase.Category)
-					}
-				}
-
-				// Notify user that feedback was recorded
-				confirmMsg := fmt.Sprintf("_Thank you! Your feedback (%s) has been recorded and will help improve future analyses._", emoji)
-				if err := p.slackClient.ReplyToThread(pending.ThreadTS, confirmMsg); err != nil {
-					log.Printf("Error sending confirmation: %v", err)
-				}
-				log.Printf("Recorded %s feedback for alert '%s' via reaction", emoji, pending.Alert.Labels["alertname"])
-			}
-
-			// Remove from pending
-			p.pendingMutex.Lock()
-			delete(p.pendingFeedback, pending.AnalysisTS)
-			p.pendingMutex.Unlock()
-		}
-	}
-}
@@ -0,0 +1,174 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/notify"
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+const (
+	// pollInterval is how often reactions are polled without Slack events
+	pollInterval = 30 * time.Second
+	// pollWindow is how long an analysis' reactions are polled for; events
+	// are received for as long as the analysis is pending
+	pollWindow = 24 * time.Hour
+	// maxCommentChars bounds a thread reply kept with the feedback
+	maxCommentChars = 500
+)
+
+// StartReactionPolling polls the reactions of the pending analyses posted
+// with the given notifiers, for those that don't deliver events to the app,
+// e.g. Mattermost, or Slack without events
+func (p *AlertProcessor) StartReactionPolling(notifiers []string) {
+	polled := make(map[string]bool, len(notifiers))
+	for _, name := range notifiers {
+		polled[name] = true
+	}
+
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		log.Printf("Started reaction checker for %s - polling every %s", strings.Join(notifiers, ", "), pollInterval)
+
+		for range ticker.C {
+			p.checkPendingReactions(polled)
+		}
+	}()
+}
+
+// checkPendingReactions checks recent pending analyses for reactions
+func (p *AlertProcessor) checkPendingReactions(polled map[string]bool) {
+	for _, pending := range p.pending.List() {
+		if time.Since(pending.Timestamp) > pollWindow {
+			continue
+		}
+		collector, ok := p.notifiers.Get(pending.Notifier).(notify.FeedbackCollector)
+		if !ok || !polled[collector.Name()] {
+			continue
+		}
+
+		// The channel of messages posted before a restart isn't known otherwise
+		trackChannel(collector, pending.AnalysisTS, pending.Channel)
+		reactions, err := collector.GetMessageReactions(context.Background(), pending.AnalysisTS)
+		if err != nil {
+			log.Printf("Error checking reactions for %s: %v", pending.AnalysisTS, err)
+			continue
+		}
+
+		for _, reaction := range reactions {
+			if isCorrect, ok := reactionVerdict(reaction); ok {
+				p.rate(pending.AnalysisTS, isCorrect)
+				break
+			}
+		}
+	}
+}
+
+// HandleReaction records the rating of an analysis from a reaction added to
+// its message. Other messages and reactions are ignored.
+func (p *AlertProcessor) HandleReaction(channel, messageTS, reaction string) {
+	isCorrect, ok := reactionVerdict(reaction)
+	if !ok {
+		return
+	}
+	p.HandleRating(channel, messageTS, isCorrect)
+}
+
+// HandleRating records the rating of an analysis from its ✅ Correct or ❌
+// Incorrect button, or a reaction
+func (p *AlertProcessor) HandleRating(channel, messageTS string, isCorrect bool) {
+	p.slackClient.TrackChannel(messageTS, channel)
+	p.rate(messageTS, isCorrect)
+}
+
+// HandleThreadReply keeps a reply posted in the thread of an analysis
+// awaiting a rating, so the feedback records why it was right or wrong
+func (p *AlertProcessor) HandleThreadReply(threadTS, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	record, ok := p.threadStore.FindByThread(threadTS)
+	if !ok {
+		return
+	}
+
+	if len(text) > maxCommentChars {
+		text = text[:maxCommentChars] + "..."
+	}
+	if p.redactor != nil {
+		text, _ = p.redactor.Redact(record.Namespace, text)
+	}
+
+	added, err := p.pending.AddComment(threadTS, text)
+	if err != nil {
+		log.Printf("Warning: failed to save thread reply: %v", err)
+	}
+	if added {
+		log.Printf("Kept thread reply for alert '%s' with its pending feedback", record.AlertName)
+	}
+}
+
+// rate records the rating of the analysis posted as messageTS, once
+func (p *AlertProcessor) rate(messageTS string, isCorrect bool) {
+	pending, ok := p.pending.Take(messageTS)
+	if !ok {
+		return
+	}
+	n := p.notifiers.Get(pending.Notifier)
+	trackChannel(n, pending.ThreadTS, pending.Channel)
+
+	fb := types.Feedback{
+		Timestamp:   time.Now(),
+		AlertName:   pending.Alert.Labels["alertname"],
+		Category:    pending.Category,
+		Namespace:   pending.Alert.Labels["namespace"],
+		Summary:     pending.Alert.Annotations["summary"],
+		Analysis:    pending.Analysis,
+		IsCorrect:   isCorrect,
+		SlackThread: pending.ThreadTS,
+		Labels:      pending.Alert.Labels,
+		Grounding:   pending.Grounding,
+		Comments:    pending.Comments,
+	}
+
+	if err := p.feedbackManager.RecordFeedback(fb); err != nil {
+		log.Printf("Error recording feedback: %v", err)
+		return
+	}
+
+	emoji := "✅"
+	if !isCorrect {
+		emoji = "❌"
+	}
+
+	// If feedback is positive and knowledge base is enabled, store the case
+	if isCorrect && p.knowledgeBase != nil {
+		debugInfo := "" // We could enhance this by storing debug info in feedback.Pending
+		p.storeValidatedCase(&pending, debugInfo)
+	}
+
+	// Notify user that feedback was recorded
+	confirmMsg := fmt.Sprintf("_Thank you! Your feedback (%s) has been recorded and will help improve future analyses._", emoji)
+	if err := n.ReplyToThread(context.Background(), pending.ThreadTS, confirmMsg); err != nil {
+		log.Printf("Error sending confirmation: %v", err)
+	}
+	log.Printf("Recorded %s feedback for alert '%s' via reaction", emoji, pending.Alert.Labels["alertname"])
+}
+
+// reactionVerdict maps a ✅ or ❌ reaction to a rating
+func reactionVerdict(reaction string) (isCorrect bool, ok bool) {
+	switch reaction {
+	case "white_check_mark", "coche_blanche", "heavy_check_mark":
+		return true, true
+	case "x", "cross", "negative_squared_cross_mark":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,172 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/valentinpelus/k8flex/internal/debugger"
+	"github.com/valentinpelus/k8flex/pkg/citation"
+	"github.com/valentinpelus/k8flex/pkg/conversation"
+	"github.com/valentinpelus/k8flex/pkg/llm"
+	"github.com/valentinpelus/k8flex/pkg/report"
+	"github.com/valentinpelus/k8flex/pkg/slack"
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// maxQuestionChars bounds a follow-up question sent to the LLM
+const maxQuestionChars = 500
+
+// saveConversation keeps the context of an analyzed thread for follow-up
+// questions and the analysis buttons. The evidence is the debug info the
+// analysis was given, with the tool calls of its investigation.
+func (p *AlertProcessor) saveConversation(alert types.Alert, incident *types.Incident, category string, evidence *types.DebugResult, analysis, threadTS string) {
+	if p.conversations == nil || threadTS == "" {
+		return
+	}
+	if err := p.conversations.Save(conversation.Conversation{
+		ThreadTS: threadTS,
+		Channel:  p.slackClient.ChannelOf(threadTS),
+		Alert:    alert,
+		Incident: incident,
+		Category: category,
+		Evidence: evidence,
+		Analysis: analysis,
+	}); err != nil {
+		log.Printf("Warning: failed to save conversation: %v", err)
+	}
+}
+
+// HandleFollowUp answers a question asked by mentioning the bot in the thread
+// of an analysis, from the thread's evidence and, if investigation is enabled,
+// more evidence gathered with the cluster tools. The answer is streamed into
+// a reply in the thread once a worker is free, like queued alerts.
+func (p *AlertProcessor) HandleFollowUp(threadTS, user, text string) {
+	if p.conversations == nil || !p.slackClient.HasBotToken() {
+		return
+	}
+	question := slack.StripMentions(text)
+	if question == "" {
+		return
+	}
+
+	conv, ok := p.threadContext(threadTS, "_I don't have the context of this thread anymore, so I can't answer follow-up questions here._")
+	if !ok {
+		return
+	}
+
+	alert := conv.Alert
+	namespace := alert.Labels["namespace"]
+	if len(question) > maxQuestionChars {
+		question = question[:maxQuestionChars] + "..."
+	}
+	if p.redactor != nil {
+		question, _ = p.redactor.Redact(namespace, question)
+	}
+	log.Printf("Answering follow-up for alert '%s' in thread %s", alert.Labels["alertname"], threadTS)
+
+	answerTS, err := p.slackClient.PostThreadReply(context.Background(), threadTS, "🔄 *Looking into it...*")
+	if err != nil {
+		log.Printf("Failed to post follow-up answer to Slack: %v", err)
+		return
+	}
+
+	p.acquire(context.Background())
+	defer p.release()
+
+	provider := p.policies.Provider(namespace, p.llmProvider)
+	var toolProvider llm.ToolCallingProvider
+	if p.investigation != nil {
+		toolProvider, _ = provider.(llm.ToolCallingProvider)
+	}
+
+	// The previous answers share the window, and tool results get half of
+	// what's left, as for the analysis
+	budget := llm.FollowUpBudget(provider, conv.Analysis, conv.Turns, question)
+	if toolProvider != nil {
+		budget /= 2
+	}
+	evidence := &types.DebugResult{Alert: alert, Category: conv.Category}
+	if conv.Evidence != nil {
+		evidence = report.Fit(conv.Evidence, budget)
+	}
+	prompt := llm.BuildFollowUpPrompt(report.Prompt(evidence), conv.Analysis, conv.Turns, question)
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeouts.Analyze)
+	defer cancel()
+	ctx, providerUsed := llm.WithProviderTracking(ctx)
+
+	var answer strings.Builder
+	var investigation *debugger.Investigation
+	if toolProvider != nil {
+		var progress strings.Builder
+		investigation, err = p.debugger.Investigate(ctx, toolProvider, alert, prompt, *p.investigation, func(step debugger.InvestigationStep) {
+			progress.WriteString(fmt.Sprintf("%d. `%s`\n", step.Number, step.Call))
+			p.slackClient.UpdateMessage(ctx, answerTS, "🔍 *Investigating...*\n\n"+progress.String())
+		})
+		answer.WriteString(investigation.Analysis)
+	} else {
+		updateCount := 0
+		err = provider.StreamPrompt(ctx, prompt, func(chunk string) {
+			answer.WriteString(chunk)
+			updateCount++
+			if updateCount%10 == 0 {
+				p.slackClient.UpdateMessage(ctx, answerTS, "🔄 *Answering...*\n\n"+answer.String())
+			}
+		})
+	}
+
+	providerName := providerUsed()
+	if providerName == "" {
+		providerName = provider.Name()
+	}
+
+	text = answer.String()
+	var newEvidence []types.DebugSection
+	switch {
+	case err != nil && ctx.Err() == context.DeadlineExceeded:
+		log.Printf("Follow-up with %s timed out after %s", provider.Name(), p.timeouts.Analyze)
+		text = fmt.Sprintf("_%s did not finish answering within %s._", provider.Name(), p.timeouts.Analyze)
+		if answer.Len() > 0 {
+			text += "\n\nPartial answer:\n" + answer.String()
+		}
+	case err != nil:
+		// The raw error may be a long API response; it's only logged
+		log.Printf("Error answering follow-up with %s: %v", provider.Name(), err)
+		text = fmt.Sprintf("_%s couldn't answer this question. The error is in the k8flex logs; mention me again to retry._", providerName)
+	default:
+		// Check the answer's quotes against the evidence, like an analysis
+		if investigation != nil {
+			newEvidence = investigation.Sections()
+			evidence.Sections = append(evidence.Sections, newEvidence...)
+		}
+		var grounding *types.Grounding
+		text, grounding = citation.VerifyText(text, evidence)
+		if summary := citation.Summary(grounding); summary != "" {
+			text += fmt.Sprintf("\n\n*🔎 Grounding:* %s", summary)
+		}
+	}
+	if investigation != nil && len(investigation.Steps) > 0 {
+		text += "\n\n" + investigation.Summary()
+	}
+
+	// The answer is posted even when ctx timed out
+	answerErr := err
+	if err := p.slackClient.UpdateMessage(context.Background(), answerTS, text+"\n\n_🤖 Answered by "+providerName+"_"); err != nil {
+		log.Printf("Failed to update follow-up answer in Slack: %v", err)
+	}
+	if answerErr != nil {
+		return
+	}
+
+	if err := p.conversations.AddTurn(threadTS, types.FollowUp{
+		User:      user,
+		Question:  question,
+		Answer:    answer.String(),
+		Timestamp: time.Now(),
+	}, newEvidence); err != nil {
+		log.Printf("Warning: failed to save follow-up: %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/valentinpelus/k8flex/internal/correlation"
+	"github.com/valentinpelus/k8flex/pkg/notify"
+	"github.com/valentinpelus/k8flex/pkg/redact"
+	"github.com/valentinpelus/k8flex/pkg/threads"
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// ProcessIncident analyzes correlated alerts as one incident: the lead alert
+// is categorized and debugged, the other members are listed as evidence, and
+// a single thread covers them all. Its messages are recorded on thread, like
+// ProcessAlert.
+func (p *AlertProcessor) ProcessIncident(ctx context.Context, incident *types.Incident, thread *Thread) error {
+	log.Printf("Processing incident of %d correlated alerts led by %s (shared: %v)",
+		len(incident.Members), incident.Lead().Labels["alertname"], incident.Shared)
+	return p.analyze(ctx, incident.Lead(), incident, thread)
+}
+
+// redactIncident returns a copy of the incident with the annotations of every
+// member redacted
+func (p *AlertProcessor) redactIncident(incident *types.Incident) (*types.Incident, redact.Counts) {
+	counts := redact.Counts{}
+	redacted := &types.Incident{
+		Members: make([]types.IncidentMember, len(incident.Members)),
+		Shared:  incident.Shared,
+	}
+	for i, m := range incident.Members {
+		var c redact.Counts
+		m.Alert, c = p.redactor.RedactAlert(m.Alert)
+		counts.Add(c)
+		redacted.Members[i] = m
+	}
+	return redacted, counts
+}
+
+// saveIncidentThread records the incident thread for every member alert, so
+// each one's resolution is reported there
+func (p *AlertProcessor) saveIncidentThread(n notify.Notifier, incident *types.Incident, threadTS, category string) {
+	for _, m := range incident.Members {
+		if err := p.threadStore.Save(threads.Record{
+			Fingerprint: types.AlertFingerprint(m.Alert),
+			ThreadTS:    threadTS,
+			AlertName:   m.Alert.Labels["alertname"],
+			Namespace:   m.Alert.Labels["namespace"],
+			Severity:    m.Alert.Labels["severity"],
+			Category:    category,
+			StartsAt:    m.Alert.StartsAt,
+			Incident:    true,
+			Labels:      m.Alert.Labels,
+			Keys:        m.Keys,
+			Channel:     channelOf(n, threadTS),
+			Notifier:    n.Name(),
+		}); err != nil {
+			log.Printf("Warning: failed to record thread for %s: %v", m.Alert.Labels["alertname"], err)
+		}
+	}
+}
+
+// resolveIncidentMember reports a resolved member of an incident in its
+// thread. Once every member is resolved, the incident message is marked as
+// resolved and its time to resolve is recorded on the knowledge base case.
+func (p *AlertProcessor) resolveIncidentMember(ctx context.Context, record threads.Record) error {
+	records := p.threadStore.ListByThread(record.ThreadTS)
+	resolvedAt, duration, ok := threads.Resolution(records)
+	n := p.threadNotifier(record)
+
+	if !ok {
+		firing := 0
+		for _, r := range records {
+			if !r.IsResolved() {
+				firing++
+			}
+		}
+		log.Printf("Alert %s of incident resolved after %s, %d of %d alerts still firing",
+			record.AlertName, record.ResolutionDuration().Round(time.Second), firing, len(records))
+		if n.IsConfigured() {
+			msg := fmt.Sprintf("✅ `%s` resolved after %s — %d of %d alerts still firing",
+				record.AlertName, record.ResolutionDuration().Round(time.Second), firing, len(records))
+			if err := n.ReplyToThread(ctx, record.ThreadTS, msg); err != nil {
+				log.Printf("Failed to send resolution to %s: %v", n.Name(), err)
+			}
+		}
+		return nil
+	}
+
+	log.Printf("All %d alerts of incident resolved after %s", len(records), duration.Round(time.Second))
+
+	if n.IsConfigured() {
+		members := make([]types.IncidentMember, len(records))
+		for i, r := range records {
+			members[i] = types.IncidentMember{
+				Alert: types.Alert{Labels: r.Labels, StartsAt: r.StartsAt},
+				Keys:  r.Keys,
+			}
+		}
+		if err := n.SendIncidentResolution(ctx, correlation.NewIncident(members), record.ThreadTS, duration); err != nil {
+			log.Printf("Failed to send resolution to %s: %v", n.Name(), err)
+		}
+	}
+
+	// Members share the case; any of them may carry it
+	if p.knowledgeBase != nil {
+		for _, r := range records {
+			if r.CaseID == "" {
+				continue
+			}
+			if err := p.knowledgeBase.RecordResolution(ctx, r.CaseID, resolvedAt, duration); err != nil {
+				log.Printf("Warning: failed to record resolution in knowledge base: %v", err)
+			}
+			break
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/valentinpelus/k8flex/pkg/conversation"
+	"github.com/valentinpelus/k8flex/pkg/report"
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// Reanalyze analyzes the alert of a thread again when its 🔄 Re-analyze
+// button is clicked, posting the new analysis in the same thread. It waits
+// for a free worker like queued alerts. Clicks while the thread is being
+// analyzed again are ignored.
+func (p *AlertProcessor) Reanalyze(channel, threadTS, user string) {
+	p.slackClient.TrackChannel(threadTS, channel)
+	conv, ok := p.threadContext(threadTS, "_This alert isn't known anymore, so it can't be analyzed again._")
+	if !ok {
+		return
+	}
+	if _, busy := p.reanalyzing.LoadOrStore(threadTS, true); busy {
+		return
+	}
+	defer p.reanalyzing.Delete(threadTS)
+
+	log.Printf("Re-analysis of alert '%s' requested by %s", conv.Alert.Labels["alertname"], user)
+	if err := p.slackClient.ReplyToThread(context.Background(), threadTS, fmt.Sprintf("🔄 Re-analysis requested by <@%s>", user)); err != nil {
+		log.Printf("Failed to reply to re-analysis request: %v", err)
+	}
+
+	p.acquire(context.Background())
+	defer p.release()
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeouts.Total())
+	defer cancel()
+
+	var err error
+	if conv.Incident != nil {
+		err = p.analyze(ctx, conv.Incident.Lead(), conv.Incident, &Thread{TS: threadTS})
+	} else {
+		err = p.analyze(ctx, conv.Alert, nil, &Thread{TS: threadTS})
+	}
+	if err != nil {
+		log.Printf("Re-analysis of alert '%s' failed: %v", conv.Alert.Labels["alertname"], err)
+	}
+}
+
+// ShowEvidence posts the evidence the analysis of a thread was based on when
+// its 🔎 Show evidence button is clicked
+func (p *AlertProcessor) ShowEvidence(channel, threadTS string) {
+	p.slackClient.TrackChannel(threadTS, channel)
+	conv, ok := p.threadContext(threadTS, "_The evidence of this analysis isn't kept anymore._")
+	if !ok || conv.Evidence == nil {
+		return
+	}
+
+	text := "🔎 Evidence: " + conv.Alert.Labels["alertname"]
+	if _, err := p.slackClient.PostBlocks(context.Background(), threadTS, text, report.SlackBlocks(conv.Evidence)); err != nil {
+		log.Printf("Failed to post evidence to Slack: %v", err)
+	}
+}
+
+// threadContext returns the conversation of a thread, or replies with
+// missing when it isn't kept
+func (p *AlertProcessor) threadContext(threadTS, missing string) (conversation.Conversation, bool) {
+	if p.conversations == nil {
+		return conversation.Conversation{}, false
+	}
+	conv, ok := p.conversations.Get(threadTS)
+	if !ok {
+		log.Printf("No conversation kept for Slack thread %s", threadTS)
+		if err := p.slackClient.ReplyToThread(context.Background(), threadTS, missing); err != nil {
+			log.Printf("Failed to reply in Slack thread: %v", err)
+		}
+		return conversation.Conversation{}, false
+	}
+	p.slackClient.TrackChannel(threadTS, conv.Channel)
+	return conv, true
+}
+
+// uploadReport uploads the full debug report next to the analysis, if report
+// uploads are enabled
+func (p *AlertProcessor) uploadReport(ctx context.Context, r *types.DebugResult, threadTS string) {
+	name := r.Alert.Labels["alertname"]
+	filename := fmt.Sprintf("k8flex-%s-%s.md", name, r.StartedAt.Format("20060102-150405"))
+	if err := p.slackClient.UploadReport(ctx, threadTS, filename, "Debug report: "+name, report.Markdown(r)); err != nil {
+		log.Printf("Failed to upload debug report to Slack: %v", err)
+	}
+}
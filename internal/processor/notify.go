@@ -0,0 +1,37 @@
+package processor
+
+import (
+	"github.com/valentinpelus/k8flex/pkg/notify"
+	"github.com/valentinpelus/k8flex/pkg/threads"
+)
+
+// isSlack reports whether n is the Slack client, whose threads get the
+// buttons, follow-ups and remediation approvals Slack delivers to the app
+func (p *AlertProcessor) isSlack(n notify.Notifier) bool {
+	return n == notify.Notifier(p.slackClient)
+}
+
+// threadNotifier returns the notifier a thread was posted with, knowing the
+// thread's channel again
+func (p *AlertProcessor) threadNotifier(record threads.Record) notify.Notifier {
+	n := p.notifiers.Get(record.Notifier)
+	trackChannel(n, record.ThreadTS, record.Channel)
+	return n
+}
+
+// channelOf returns the channel of a thread or message, for notifiers
+// posting to several channels
+func channelOf(n notify.Notifier, ts string) string {
+	if tracker, ok := n.(notify.ChannelTracker); ok {
+		return tracker.ChannelOf(ts)
+	}
+	return ""
+}
+
+// trackChannel records the channel of a thread or message posted before a
+// restart, for notifiers posting to several channels
+func trackChannel(n notify.Notifier, ts, channel string) {
+	if tracker, ok := n.(notify.ChannelTracker); ok {
+		tracker.TrackChannel(ts, channel)
+	}
+}
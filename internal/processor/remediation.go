@@ -0,0 +1,77 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/remediation"
+	"github.com/valentinpelus/k8flex/pkg/slack"
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// addActionCatalog lists the remediation actions allowed in the alert's
+// namespace in its debug result, so the analysis can propose them
+func (p *AlertProcessor) addActionCatalog(result *types.DebugResult) {
+	namespace := result.Alert.Labels["namespace"]
+	allowed := p.remediator.AllowedActions(namespace)
+	if len(allowed) == 0 {
+		return
+	}
+	result.Sections = append(result.Sections, types.DebugSection{
+		Title:     "Available Actions",
+		Source:    "remediation",
+		Timestamp: time.Now(),
+		Content: fmt.Sprintf("An operator can approve these actions from Slack for alerts of namespace %s:\n%s",
+			namespace, strings.TrimRight(remediation.Describe(allowed), "\n")),
+	})
+}
+
+// proposeActions offers the actions an analysis proposes for approval in the
+// alert thread. Proposals outside the namespace's allowlist are left out.
+func (p *AlertProcessor) proposeActions(ctx context.Context, alert types.Alert, structured *types.Analysis, analysis, threadTS string) {
+	proposed := remediation.Parse(analysis)
+	if structured != nil {
+		proposed = structured.ProposedActions
+	}
+	if len(proposed) == 0 {
+		return
+	}
+
+	namespace := alert.Labels["namespace"]
+	var proposals []slack.ActionProposal
+	var refused []string
+	for i, a := range proposed {
+		if err := p.remediator.Allowed(&a, namespace); err != nil {
+			log.Printf("Not proposing %q for alert %s: %v", a.Command(), alert.Labels["alertname"], err)
+			refused = append(refused, fmt.Sprintf("`%s` (%v)", a.Command(), err))
+			continue
+		}
+		proposals = append(proposals, slack.ActionProposal{
+			BlockID: fmt.Sprintf("action-%d", i),
+			Command: a.Command(),
+			Reason:  a.Reason,
+			Value:   remediation.NewRequest(a, alert, threadTS).Encode(),
+			Danger:  a.Action == remediation.ActionDrain,
+		})
+	}
+
+	note := "Approving runs a server-side dry run first, then the action. Approvals are audit-logged."
+	if p.remediator.DryRunOnly() {
+		note = "Dry-run-only mode: approving runs a server-side dry run and changes nothing. Approvals are audit-logged."
+	}
+	if len(refused) > 0 {
+		note += "\nNot offered: " + strings.Join(refused, ", ")
+	}
+	if len(proposals) == 0 {
+		return
+	}
+
+	if _, err := p.slackClient.SendActionProposals(ctx, threadTS, proposals, note); err != nil {
+		log.Printf("Failed to send action proposals to Slack: %v", err)
+		return
+	}
+	log.Printf("Proposed %d actions for alert %s", len(proposals), alert.Labels["alertname"])
+}
@@ -0,0 +1,79 @@
+package processor
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/redact"
+	"github.com/valentinpelus/k8flex/pkg/runbook"
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// maxRunbooks bounds the runbooks added to the evidence of an alert
+const maxRunbooks = 3
+
+// addRunbooks adds the runbooks of an alert to its debug result: those
+// attached by Runbook resources, then those the runbook library finds from
+// its runbook_url annotation, directory and ConfigMap. Only the sections
+// relevant to the alert are kept, redacted like the gathered data.
+func (p *AlertProcessor) addRunbooks(ctx context.Context, result *types.DebugResult, category string) {
+	alert := result.Alert
+	var found []runbook.Runbook
+	for _, r := range p.policies.Runbooks(alert) {
+		rb := runbook.Runbook{Name: r.Name, Source: "resource", URL: r.Spec.URL, Content: r.Spec.Content}
+		if strings.TrimSpace(rb.Content) == "" && p.runbooks != nil {
+			content, err := p.runbooks.Fetch(ctx, rb.URL)
+			if err != nil {
+				log.Printf("Warning: failed to fetch runbook %s/%s: %v", r.Namespace, r.Name, err)
+				continue
+			}
+			rb.Content = content
+		}
+		found = append(found, rb)
+	}
+	if p.runbooks != nil {
+		found = append(found, p.runbooks.Find(ctx, alert)...)
+	}
+
+	namespace := alert.Labels["namespace"]
+	seen := make(map[string]bool)
+	added := 0
+	for _, rb := range found {
+		if added == maxRunbooks {
+			break
+		}
+		// A resource may link to the same page as the annotation
+		if key := rb.URL; key != "" {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		added++
+		log.Printf("Adding runbook %s (%s) for alert %s", rb.Name, rb.Source, alert.Labels["alertname"])
+
+		var content strings.Builder
+		if rb.URL != "" {
+			content.WriteString("Full runbook: " + rb.URL + "\n\n")
+		}
+		content.WriteString(p.runbooks.Excerpt(rb, alert, category))
+
+		section := types.DebugSection{
+			Title:     "Runbook: " + rb.Name,
+			Source:    "runbook",
+			Timestamp: time.Now(),
+			Content:   strings.TrimSpace(content.String()),
+		}
+		if p.redactor != nil {
+			var counts redact.Counts
+			section.Content, counts = p.redactor.Redact(namespace, section.Content)
+			if result.Redactions == nil {
+				result.Redactions = make(map[string]int)
+			}
+			redact.Counts(result.Redactions).Add(counts)
+		}
+		result.Sections = append(result.Sections, section)
+	}
+}
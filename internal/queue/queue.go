@@ -0,0 +1,445 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// maxGroupSize bounds how many correlated jobs are handled together
+const maxGroupSize = 20
+
+// Job is a single alert waiting to be processed
+type Job struct {
+	Key             string      `json:"key"` // Dedup key: groupKey + alert fingerprint
+	GroupKey        string      `json:"group_key"`
+	Alert           types.Alert `json:"alert"`
+	CorrelationKeys []string    `json:"correlation_keys,omitempty"` // Jobs sharing a key are handled together
+	Attempts        int         `json:"attempts"`
+	LastError       string      `json:"last_error,omitempty"`
+	ThreadTS        string      `json:"thread_ts,omitempty"`   // Slack thread reused across retries
+	AnalysisTS      string      `json:"analysis_ts,omitempty"` // Analysis or failure message in the thread, updated by retries
+	EnqueuedAt      time.Time   `json:"enqueued_at"`
+	NextAttempt     time.Time   `json:"next_attempt"`
+}
+
+// Handler processes a job, or several firing jobs correlated into one
+// incident. Returning an error schedules a retry of every job with backoff.
+// The handler may update fields such as ThreadTS and AnalysisTS; they are
+// persisted with the jobs.
+type Handler func(ctx context.Context, jobs []*Job) error
+
+// Config holds configuration for the alert queue
+type Config struct {
+	JournalPath    string        // File used to persist pending jobs across restarts
+	Workers        int           // Number of concurrent workers
+	MaxRetries     int           // Retries after the first attempt before a job is dropped
+	InitialBackoff time.Duration // Delay before the first retry, doubled on each attempt
+	MaxBackoff     time.Duration // Upper bound for the retry delay
+	DedupWindow    time.Duration // How long a completed job keeps suppressing duplicates
+	// CorrelationWindow is how long a firing job with correlation keys waits
+	// for correlated alerts before it's handled; 0 handles every job alone
+	CorrelationWindow time.Duration
+}
+
+// journal is the on-disk representation of the queue
+type journal struct {
+	Jobs      []*Job               `json:"jobs"`
+	Completed map[string]time.Time `json:"completed"` // Key -> completion time
+}
+
+// Queue is a bounded, deduplicated work queue persisted to a JSON journal
+type Queue struct {
+	config    Config
+	handler   Handler
+	jobs      map[string]*Job      // Pending and in-flight jobs by key
+	inFlight  map[string]bool      // Keys currently held by a worker
+	completed map[string]time.Time // Recently completed keys for dedup
+	mu        sync.Mutex
+	wake      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// New creates a queue and restores any jobs left in the journal
+func New(config Config, handler Handler) (*Queue, error) {
+	if config.Workers <= 0 {
+		config.Workers = 2
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = 30 * time.Second
+	}
+	if config.MaxBackoff < config.InitialBackoff {
+		config.MaxBackoff = config.InitialBackoff
+	}
+
+	q := &Queue{
+		config:    config,
+		handler:   handler,
+		jobs:      make(map[string]*Job),
+		inFlight:  make(map[string]bool),
+		completed: make(map[string]time.Time),
+		wake:      make(chan struct{}, 1),
+	}
+
+	if err := q.load(); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to load queue journal: %w", err)
+		}
+		log.Printf("No existing queue journal, starting empty")
+	} else if len(q.jobs) > 0 {
+		log.Printf("Restored %d pending alerts from queue journal", len(q.jobs))
+	}
+
+	return q, nil
+}
+
+// Key builds the dedup key for an alert within an Alertmanager group.
+// Resolved notifications get their own key so they aren't deduplicated
+// against the firing notification of the same alert.
+func Key(groupKey string, alert types.Alert) string {
+	key := groupKey + "/" + types.AlertFingerprint(alert)
+	if alert.Status == "resolved" {
+		key += "/resolved"
+	}
+	return key
+}
+
+// Enqueue adds an alert to the queue. It returns false without error when an
+// identical alert is already queued or was processed within the dedup window.
+// Firing alerts with correlation keys are held for the correlation window so
+// that alerts sharing a key can be handled together.
+func (q *Queue) Enqueue(groupKey string, alert types.Alert, correlationKeys []string) (bool, error) {
+	key := Key(groupKey, alert)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.duplicate(key) {
+		return false, nil
+	}
+
+	now := time.Now()
+	job := &Job{
+		Key:         key,
+		GroupKey:    groupKey,
+		Alert:       alert,
+		EnqueuedAt:  now,
+		NextAttempt: now,
+	}
+	if q.config.CorrelationWindow > 0 && alert.Status != "resolved" && len(correlationKeys) > 0 {
+		job.CorrelationKeys = correlationKeys
+		job.NextAttempt = now.Add(q.config.CorrelationWindow)
+	}
+	q.jobs[key] = job
+
+	if err := q.save(); err != nil {
+		delete(q.jobs, key)
+		return false, err
+	}
+
+	q.signal()
+	return true, nil
+}
+
+// IsDuplicate reports whether an alert is already queued or was analyzed
+// within the dedup window, so Enqueue would skip it
+func (q *Queue) IsDuplicate(groupKey string, alert types.Alert) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.duplicate(Key(groupKey, alert))
+}
+
+// duplicate implements IsDuplicate; q.mu must be held
+func (q *Queue) duplicate(key string) bool {
+	if _, exists := q.jobs[key]; exists {
+		return true
+	}
+	doneAt, ok := q.completed[key]
+	return ok && time.Since(doneAt) < q.config.DedupWindow
+}
+
+// Start launches the worker pool. Workers stop when ctx is cancelled.
+func (q *Queue) Start(ctx context.Context) {
+	log.Printf("Starting alert queue with %d workers (max retries: %d)", q.config.Workers, q.config.MaxRetries)
+
+	for i := 0; i < q.config.Workers; i++ {
+		q.wg.Add(1)
+		go q.worker(ctx)
+	}
+}
+
+// Wait blocks until all workers have exited
+func (q *Queue) Wait() {
+	q.wg.Wait()
+}
+
+// Len returns the number of pending and in-flight jobs
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
+
+// worker takes ready jobs until the context is cancelled
+func (q *Queue) worker(ctx context.Context) {
+	defer q.wg.Done()
+
+	for {
+		jobs, wait := q.next()
+		if jobs == nil {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-q.wake:
+				timer.Stop()
+			case <-timer.C:
+			}
+			continue
+		}
+
+		// Let another idle worker pick up the next ready job
+		q.signal()
+
+		err := q.handler(ctx, jobs)
+		for _, job := range jobs {
+			q.finish(job, err)
+		}
+	}
+}
+
+// next claims the oldest ready job together with the jobs correlated with it,
+// or returns how long to wait for one
+func (q *Queue) next() ([]*Job, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	wait := time.Minute
+	var ready []*Job
+	for key, job := range q.jobs {
+		if q.inFlight[key] {
+			continue
+		}
+		if !job.NextAttempt.After(now) {
+			ready = append(ready, job)
+		} else if d := job.NextAttempt.Sub(now); d < wait {
+			wait = d
+		}
+	}
+
+	if len(ready) == 0 {
+		return nil, wait
+	}
+
+	sort.Slice(ready, func(i, j int) bool {
+		return ready[i].EnqueuedAt.Before(ready[j].EnqueuedAt)
+	})
+	group := q.correlated(ready[0], now)
+	claimed := make([]*Job, len(group))
+	for i, job := range group {
+		q.inFlight[job.Key] = true
+		job.Attempts++
+
+		// Hand the worker a copy so the journal can be saved while it runs
+		c := *job
+		claimed[i] = &c
+	}
+	if len(claimed) > 1 {
+		log.Printf("Correlated %d alerts into one incident with %s", len(claimed), ready[0].Alert.Labels["alertname"])
+	}
+	return claimed, 0
+}
+
+// correlated returns lead and the idle firing jobs sharing a correlation key
+// with it, directly or through another member, oldest first. Jobs held for
+// the correlation window join early; jobs backing off a retry join only if
+// due within the window. Caller must hold q.mu.
+func (q *Queue) correlated(lead *Job, now time.Time) []*Job {
+	group := []*Job{lead}
+	if len(lead.CorrelationKeys) == 0 {
+		return group
+	}
+
+	keys := make(map[string]bool)
+	for _, k := range lead.CorrelationKeys {
+		keys[k] = true
+	}
+	joined := map[string]bool{lead.Key: true}
+	horizon := now.Add(q.config.CorrelationWindow)
+
+	for grown := true; grown && len(group) < maxGroupSize; {
+		grown = false
+		for key, job := range q.jobs {
+			if joined[key] || q.inFlight[key] || len(job.CorrelationKeys) == 0 || job.NextAttempt.After(horizon) {
+				continue
+			}
+			shares := false
+			for _, k := range job.CorrelationKeys {
+				if keys[k] {
+					shares = true
+					break
+				}
+			}
+			if !shares {
+				continue
+			}
+			for _, k := range job.CorrelationKeys {
+				keys[k] = true
+			}
+			joined[key] = true
+			group = append(group, job)
+			grown = true
+			if len(group) == maxGroupSize {
+				break
+			}
+		}
+	}
+
+	sort.Slice(group, func(i, j int) bool {
+		return group[i].EnqueuedAt.Before(group[j].EnqueuedAt)
+	})
+	return group
+}
+
+// finish records the outcome of a job and persists the journal
+func (q *Queue) finish(job *Job, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.inFlight, job.Key)
+	alertName := job.Alert.Labels["alertname"]
+
+	stored, ok := q.jobs[job.Key]
+	if !ok {
+		return
+	}
+	stored.ThreadTS = job.ThreadTS
+	stored.AnalysisTS = job.AnalysisTS
+
+	// A cancelled context means shutdown, not a failed alert; don't burn a retry
+	if err != nil && errors.Is(err, context.Canceled) {
+		stored.Attempts--
+		if saveErr := q.save(); saveErr != nil {
+			log.Printf("Warning: failed to persist queue journal: %v", saveErr)
+		}
+		return
+	}
+
+	switch {
+	case err == nil:
+		delete(q.jobs, job.Key)
+		q.completed[job.Key] = time.Now()
+	case job.Attempts > q.config.MaxRetries:
+		log.Printf("Giving up on alert %s after %d attempts: %v", alertName, job.Attempts, err)
+		delete(q.jobs, job.Key)
+		q.completed[job.Key] = time.Now()
+	default:
+		backoff := q.backoff(job.Attempts)
+		stored.LastError = err.Error()
+		stored.NextAttempt = time.Now().Add(backoff)
+		log.Printf("Alert %s failed (attempt %d/%d), retrying in %s: %v",
+			alertName, job.Attempts, q.config.MaxRetries+1, backoff, err)
+	}
+
+	q.pruneCompleted()
+	if saveErr := q.save(); saveErr != nil {
+		log.Printf("Warning: failed to persist queue journal: %v", saveErr)
+	}
+}
+
+// backoff returns the exponential retry delay for the given attempt
+func (q *Queue) backoff(attempt int) time.Duration {
+	d := q.config.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= q.config.MaxBackoff {
+			return q.config.MaxBackoff
+		}
+	}
+	return d
+}
+
+// pruneCompleted drops dedup entries older than the dedup window
+func (q *Queue) pruneCompleted() {
+	for key, doneAt := range q.completed {
+		if time.Since(doneAt) >= q.config.DedupWindow {
+			delete(q.completed, key)
+		}
+	}
+}
+
+// signal wakes one idle worker without blocking
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// load reads the journal from disk
+func (q *Queue) load() error {
+	data, err := os.ReadFile(q.config.JournalPath)
+	if err != nil {
+		return err
+	}
+
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	for _, job := range j.Jobs {
+		q.jobs[job.Key] = job
+	}
+	for key, doneAt := range j.Completed {
+		q.completed[key] = doneAt
+	}
+	q.pruneCompleted()
+	return nil
+}
+
+// save writes the journal to disk atomically. Caller must hold q.mu.
+func (q *Queue) save() error {
+	j := journal{
+		Jobs:      make([]*Job, 0, len(q.jobs)),
+		Completed: q.completed,
+	}
+	for _, job := range q.jobs {
+		j.Jobs = append(j.Jobs, job)
+	}
+
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal queue journal: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(q.config.JournalPath), ".queue-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create queue journal: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write queue journal: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write queue journal: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), q.config.JournalPath)
+}
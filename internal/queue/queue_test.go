@@ -0,0 +1,219 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// newTestQueue creates a queue journaled to a temporary directory
+func newTestQueue(t *testing.T, config Config) *Queue {
+	t.Helper()
+	if config.JournalPath == "" {
+		config.JournalPath = filepath.Join(t.TempDir(), "queue.json")
+	}
+	q, err := New(config, func(context.Context, []*Job) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func alert(name, status string) types.Alert {
+	return types.Alert{
+		Status: status,
+		Labels: map[string]string{"alertname": name, "namespace": "default"},
+	}
+}
+
+func TestKey(t *testing.T) {
+	firing := Key("group", alert("HighErrorRate", "firing"))
+	resolved := Key("group", alert("HighErrorRate", "resolved"))
+
+	if resolved != firing+"/resolved" {
+		t.Errorf("resolved key = %q, want %q", resolved, firing+"/resolved")
+	}
+	if other := Key("other-group", alert("HighErrorRate", "firing")); other == firing {
+		t.Errorf("alerts in different groups share the key %q", firing)
+	}
+	withFingerprint := alert("HighErrorRate", "firing")
+	withFingerprint.Fingerprint = "abc123"
+	if got := Key("group", withFingerprint); got != "group/abc123" {
+		t.Errorf("Key() = %q, want the Alertmanager fingerprint", got)
+	}
+}
+
+func TestEnqueueDeduplicates(t *testing.T) {
+	q := newTestQueue(t, Config{DedupWindow: time.Hour})
+	firing := alert("HighErrorRate", "firing")
+
+	tests := []struct {
+		name  string
+		alert types.Alert
+		want  bool
+	}{
+		{name: "first firing", alert: firing, want: true},
+		{name: "duplicate firing", alert: firing, want: false},
+		{name: "resolved", alert: alert("HighErrorRate", "resolved"), want: true},
+		{name: "duplicate resolved", alert: alert("HighErrorRate", "resolved"), want: false},
+		{name: "other alert", alert: alert("PodCrashLooping", "firing"), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, err := q.Enqueue("group", tt.alert, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if added != tt.want {
+				t.Errorf("Enqueue() = %v, want %v", added, tt.want)
+			}
+		})
+	}
+	if q.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", q.Len())
+	}
+
+	// Completed jobs keep suppressing duplicates for the dedup window
+	key := Key("group", firing)
+	q.finish(&Job{Key: key, Alert: firing, Attempts: 1}, nil)
+	if !q.IsDuplicate("group", firing) {
+		t.Error("alert completed within the dedup window isn't a duplicate")
+	}
+	q.completed[key] = time.Now().Add(-2 * time.Hour)
+	if q.IsDuplicate("group", firing) {
+		t.Error("alert completed before the dedup window is still a duplicate")
+	}
+}
+
+func TestJournalReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	q := newTestQueue(t, Config{JournalPath: path, MaxRetries: 3, DedupWindow: time.Hour})
+
+	pending := alert("HighErrorRate", "firing")
+	done := alert("PodCrashLooping", "firing")
+	for _, a := range []types.Alert{pending, done} {
+		if _, err := q.Enqueue("group", a, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	q.finish(&Job{Key: Key("group", done), Alert: done, Attempts: 1}, nil)
+	q.finish(&Job{Key: Key("group", pending), Alert: pending, Attempts: 1, ThreadTS: "1700000000.000100"}, errors.New("LLM unavailable"))
+
+	restored := newTestQueue(t, Config{JournalPath: path, DedupWindow: time.Hour})
+	if restored.Len() != 1 {
+		t.Fatalf("restored %d jobs, want 1", restored.Len())
+	}
+	job := restored.jobs[Key("group", pending)]
+	if job == nil {
+		t.Fatal("pending job wasn't restored")
+	}
+	if job.ThreadTS != "1700000000.000100" || job.LastError != "LLM unavailable" {
+		t.Errorf("restored job = %+v, want its thread and last error", job)
+	}
+	if !restored.IsDuplicate("group", done) {
+		t.Error("completed job wasn't restored for dedup")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	q := newTestQueue(t, Config{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second})
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 3, want: 4 * time.Second},
+		{attempt: 4, want: 8 * time.Second},
+		{attempt: 5, want: 10 * time.Second},
+		{attempt: 20, want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("attempt %d", tt.attempt), func(t *testing.T) {
+			if got := q.backoff(tt.attempt); got != tt.want {
+				t.Errorf("backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFinishRetriesWithBackoff(t *testing.T) {
+	q := newTestQueue(t, Config{MaxRetries: 1, InitialBackoff: time.Minute, MaxBackoff: time.Hour, DedupWindow: time.Hour})
+	a := alert("HighErrorRate", "firing")
+	if _, err := q.Enqueue("group", a, nil); err != nil {
+		t.Fatal(err)
+	}
+	key := Key("group", a)
+
+	// A cancelled context doesn't burn a retry
+	jobs, _ := q.next()
+	q.finish(jobs[0], context.Canceled)
+	if got := q.jobs[key].Attempts; got != 0 {
+		t.Errorf("attempts after shutdown = %d, want 0", got)
+	}
+
+	// A failure schedules a retry after the backoff
+	jobs, _ = q.next()
+	before := time.Now()
+	q.finish(jobs[0], errors.New("LLM unavailable"))
+	stored := q.jobs[key]
+	if stored.Attempts != 1 || stored.LastError != "LLM unavailable" {
+		t.Errorf("job after failure = %+v, want 1 attempt and the error", stored)
+	}
+	if stored.NextAttempt.Before(before.Add(time.Minute)) {
+		t.Errorf("retry at %s, want at least a minute later", stored.NextAttempt)
+	}
+	if jobs, wait := q.next(); jobs != nil || wait <= 0 {
+		t.Errorf("next() = %v, %s, want nothing ready", jobs, wait)
+	}
+
+	// The job is dropped once it's out of retries
+	stored.NextAttempt = time.Now()
+	jobs, _ = q.next()
+	q.finish(jobs[0], errors.New("LLM unavailable"))
+	if q.Len() != 0 {
+		t.Errorf("Len() = %d after the last retry, want 0", q.Len())
+	}
+	if !q.IsDuplicate("group", a) {
+		t.Error("dropped job isn't deduplicated")
+	}
+}
+
+func TestCorrelatedGroupIsCapped(t *testing.T) {
+	q := newTestQueue(t, Config{CorrelationWindow: time.Minute})
+	for i := 0; i < maxGroupSize+5; i++ {
+		a := alert(fmt.Sprintf("Alert%d", i), "firing")
+		if _, err := q.Enqueue("group", a, []string{"node/worker-1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A resolved alert is never held for correlation
+	if _, err := q.Enqueue("group", alert("Alert0", "resolved"), []string{"node/worker-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if job := q.jobs[Key("group", alert("Alert0", "resolved"))]; len(job.CorrelationKeys) != 0 {
+		t.Errorf("resolved job has correlation keys %v", job.CorrelationKeys)
+	}
+
+	for _, job := range q.jobs {
+		job.NextAttempt = time.Now().Add(-time.Second)
+	}
+	first, _ := q.next()
+	if len(first) != maxGroupSize {
+		t.Fatalf("first group has %d jobs, want %d", len(first), maxGroupSize)
+	}
+	second, _ := q.next()
+	if len(second) != 5 {
+		t.Errorf("second group has %d jobs, want the 5 remaining", len(second))
+	}
+	third, _ := q.next()
+	if len(third) != 1 || third[0].Alert.Status != "resolved" {
+		t.Errorf("third group = %v, want the resolved alert alone", third)
+	}
+}
@@ -1,28 +1,36 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 
+	"github.com/valentinpelus/k8flex/internal/correlation"
 	"github.com/valentinpelus/k8flex/internal/handler"
 	"github.com/valentinpelus/k8flex/internal/middleware"
-	"github.com/valentinpelus/k8flex/internal/processor"
+	"github.com/valentinpelus/k8flex/internal/queue"
 )
 
 // Server wraps the HTTP server
 type Server struct {
 	port           string
 	webhookHandler *handler.WebhookHandler
+	slackHandler   *handler.SlackHandler // nil when no Slack interactivity is enabled
 	authMiddleware *middleware.AuthMiddleware
+	httpServer     *http.Server
 }
 
-// New creates a new HTTP server
-func New(port string, authToken string, alertProcessor *processor.AlertProcessor) *Server {
+// New creates a new HTTP server. correlator may be nil to queue alerts
+// without correlation keys, and slackHandler nil to serve no Slack endpoint.
+func New(port string, authToken string, alertQueue *queue.Queue, correlator *correlation.Correlator, slackHandler *handler.SlackHandler) *Server {
 	return &Server{
 		port:           port,
-		webhookHandler: handler.NewWebhookHandler(alertProcessor),
+		webhookHandler: handler.NewWebhookHandler(alertQueue, correlator),
+		slackHandler:   slackHandler,
 		authMiddleware: middleware.NewAuthMiddleware(authToken),
+		httpServer:     &http.Server{Addr: ":" + port},
 	}
 }
 
@@ -30,16 +38,30 @@ func New(port string, authToken string, alertProcessor *processor.AlertProcessor
 func (s *Server) SetupRoutes() {
 	http.HandleFunc("/webhook", s.authMiddleware.Authenticate(s.webhookHandler.HandleWebhook))
 	http.HandleFunc("/health", handler.HandleHealth)
+	// Slack requests are authenticated by their signature, not the bearer token
+	if s.slackHandler != nil {
+		http.HandleFunc("/slack/events", s.slackHandler.HandleEvents)
+		http.HandleFunc("/slack/interactions", s.slackHandler.HandleInteraction)
+	}
 }
 
-// Start starts the HTTP server
+// Start starts the HTTP server and serves until Shutdown
 func (s *Server) Start() error {
 	s.SetupRoutes()
 
 	log.Printf("HTTP server listening on :%s", s.port)
-	if err := http.ListenAndServe(":"+s.port, nil); err != nil {
+	if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return fmt.Errorf("failed to start server: %w", err)
 	}
 
 	return nil
 }
+
+// Shutdown stops accepting requests and waits for those in progress until
+// ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shut down server: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package citation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/valentinpelus/k8flex/pkg/report"
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// Quoted spans of a free-form analysis. Code blocks are matched first so their
+// content isn't split into inline spans.
+var (
+	codeBlock   = regexp.MustCompile("(?s)```(?:[a-z]*\n)?(.*?)```")
+	inlineCode  = regexp.MustCompile("`([^`\n]+)`")
+	doubleQuote = regexp.MustCompile(`"([^"\n]{4,300})"|“([^”\n]{4,300})”`)
+)
+
+// claimedValue matches quantities an analysis may state as observed: sizes,
+// CPU, durations, percentages and exit codes
+var claimedValue = regexp.MustCompile(`(?i)\b(\d+(?:\.\d+)?) ?(%|(?:mi|gi|ki|ti|mib|gib|kib|mb|gb|kb|ms|m|s|cores?)\b)|\bexit code (\d+)\b`)
+
+// actionsHeader starts the part of a free-form analysis that recommends rather
+// than observes; commands and target values there aren't claims
+var actionsHeader = regexp.MustCompile(`(?m)^\*(?:Actions|Prevention):\*`)
+
+// unverifiedMark flags an unverified quote inline
+const unverifiedMark = " ⚠️ _not found in the debug info_"
+
+// minQuoteLength is the shortest quote worth checking; shorter ones are
+// usually field names or words rather than evidence
+const minQuoteLength = 3
+
+// evidence is the debug info a claim is looked up in
+type evidence struct {
+	all      string            // Normalized prompt text, header and notes included
+	sections map[string]string // Normalized content of each section, by title
+}
+
+// newEvidence normalizes a debug result for lookups
+func newEvidence(r *types.DebugResult) *evidence {
+	e := &evidence{all: normalize(report.Prompt(r)), sections: make(map[string]string, len(r.Sections))}
+	for _, s := range r.Sections {
+		e.sections[s.Title] = normalize(s.Content + "\n" + s.Error)
+	}
+	return e
+}
+
+// VerifyText checks the quotes and observed values of a free-form analysis
+// against the debug info it was based on. It returns the analysis with
+// unverified quotes flagged inline, and the grounding measured.
+func VerifyText(analysis string, r *types.DebugResult) (string, *types.Grounding) {
+	e := newEvidence(r)
+	g := &types.Grounding{}
+
+	claims := analysis
+	if loc := actionsHeader.FindStringIndex(analysis); loc != nil {
+		claims = analysis[:loc[0]]
+	}
+
+	checked := make(map[string]bool)
+	var flagged []string
+	check := func(span, quote string) {
+		key := normalize(quote)
+		if len(key) < minQuoteLength || checked[key] {
+			return
+		}
+		checked[key] = true
+		if !record(g, quote, e.contains(quote)) {
+			flagged = append(flagged, span)
+		}
+	}
+
+	rest := codeBlock.ReplaceAllStringFunc(claims, func(span string) string {
+		check(span, codeBlock.FindStringSubmatch(span)[1])
+		return " "
+	})
+	rest = inlineCode.ReplaceAllStringFunc(rest, func(span string) string {
+		check(span, inlineCode.FindStringSubmatch(span)[1])
+		return " "
+	})
+	rest = doubleQuote.ReplaceAllStringFunc(rest, func(span string) string {
+		m := doubleQuote.FindStringSubmatch(span)
+		if quote := m[1] + m[2]; looksLikeData(quote) {
+			check(span, quote)
+		}
+		return " "
+	})
+	for _, value := range claimedValue.FindAllString(rest, -1) {
+		key := normalize(value)
+		if !checked[key] {
+			checked[key] = true
+			record(g, value, e.containsValue(value))
+		}
+	}
+
+	for _, span := range flagged {
+		analysis = strings.Replace(analysis, span, span+unverifiedMark, 1)
+	}
+	return analysis, g
+}
+
+// VerifyAnalysis checks each evidence quote of a structured analysis against
+// the section it cites, marking those that aren't found, and the quotes and
+// observed values of its root cause, impact and alert notes against the whole
+// debug info
+func VerifyAnalysis(a *types.Analysis, r *types.DebugResult) *types.Grounding {
+	e := newEvidence(r)
+	g := &types.Grounding{}
+
+	for i := range a.Evidence {
+		ev := &a.Evidence[i]
+		found := e.containsIn(ev.Section, ev.Quote) || e.contains(ev.Quote)
+		ev.Unverified = !record(g, ev.Quote, found)
+	}
+
+	// Quotes in the prose are checked too, but there's no inline flag to set
+	texts := []string{a.RootCause, a.Impact}
+	for _, n := range a.AlertNotes {
+		texts = append(texts, n.Note)
+	}
+	for _, s := range a.RunbookSteps {
+		texts = append(texts, s.Reason)
+	}
+	for _, p := range a.ProposedActions {
+		texts = append(texts, p.Reason)
+	}
+	for _, text := range texts {
+		_, prose := VerifyText(text, r)
+		g.Checked += prose.Checked
+		g.Verified += prose.Verified
+		g.Unverified = append(g.Unverified, prose.Unverified...)
+	}
+	return g
+}
+
+// Summary describes a grounding for the Slack analysis, e.g. "5 of 6 quotes and
+// values found in the debug info (83%). Not found: `...`", or returns "" if
+// there was nothing to check
+func Summary(g *types.Grounding) string {
+	if g == nil || g.Checked == 0 {
+		return ""
+	}
+	summary := fmt.Sprintf("%d of %d quotes and values found in the debug info (%.0f%%)",
+		g.Verified, g.Checked, g.Score()*100)
+	if len(g.Unverified) == 0 {
+		return summary
+	}
+
+	const maxListed = 5
+	listed := make([]string, 0, maxListed)
+	for i, claim := range g.Unverified {
+		if i == maxListed {
+			listed = append(listed, fmt.Sprintf("and %d more", len(g.Unverified)-maxListed))
+			break
+		}
+		listed = append(listed, "`"+shorten(strings.ReplaceAll(claim, "\n", " "), 80)+"`")
+	}
+	return summary + ". Not found: " + strings.Join(listed, ", ")
+}
+
+// looksLikeData tells quoted data apart from prose in double quotes, such as
+// scare quotes around a word: data has digits or symbols, or several words
+func looksLikeData(quote string) bool {
+	return strings.ContainsAny(quote, "0123456789:=/_[(") || len(strings.Fields(quote)) >= 4
+}
+
+// record counts a claim and whether it was found, and returns found
+func record(g *types.Grounding, claim string, found bool) bool {
+	g.Checked++
+	if found {
+		g.Verified++
+	} else {
+		g.Unverified = append(g.Unverified, strings.TrimSpace(claim))
+	}
+	return found
+}
+
+// contains reports whether every line of a quote is in the debug info. Parts
+// elided with "..." are skipped.
+func (e *evidence) contains(quote string) bool {
+	return containsQuote(e.all, quote)
+}
+
+// containsIn reports whether a quote is in the section with the given title
+func (e *evidence) containsIn(title, quote string) bool {
+	section, ok := e.sections[title]
+	return ok && containsQuote(section, quote)
+}
+
+// containsValue reports whether a claimed value is in the debug info, allowing
+// for decimals the analysis rounded away ("95%" for "95.3%") and spacing
+func (e *evidence) containsValue(value string) bool {
+	m := claimedValue.FindStringSubmatch(value)
+	if m == nil {
+		return false
+	}
+	if m[3] != "" {
+		// Exit codes show up as "exit code 137" or "exitCode: 137"
+		for _, prefix := range []string{"exit code ", "exit code: ", "exitcode ", "exitcode: "} {
+			if containsNumber(e.all, prefix+m[3], func(rest string) bool {
+				return rest == "" || !isWordChar(rest[0])
+			}) {
+				return true
+			}
+		}
+		return false
+	}
+	unit := strings.ToLower(m[2])
+	rounded := !strings.Contains(m[1], ".")
+	return containsNumber(e.all, m[1], func(rest string) bool {
+		if rounded {
+			rest = skipDecimals(rest)
+		}
+		return strings.HasPrefix(strings.TrimPrefix(rest, " "), unit)
+	})
+}
+
+// containsNumber reports whether text has number, not preceded by a digit or
+// a decimal point, followed by text that suffix accepts
+func containsNumber(text, number string, suffix func(rest string) bool) bool {
+	for i := 0; i < len(text); {
+		j := strings.Index(text[i:], number)
+		if j < 0 {
+			return false
+		}
+		start := i + j
+		i = start + 1
+		if start > 0 && (isDigit(text[start-1]) || text[start-1] == '.') {
+			continue
+		}
+		if suffix(text[start+len(number):]) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipDecimals drops the decimal part a number in text starts with, if any
+func skipDecimals(text string) string {
+	if len(text) < 2 || text[0] != '.' || !isDigit(text[1]) {
+		return text
+	}
+	i := 1
+	for i < len(text) && isDigit(text[i]) {
+		i++
+	}
+	return text[i:]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordChar(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+// containsQuote reports whether every fragment of a quote is in text, which
+// must be normalized
+func containsQuote(text, quote string) bool {
+	fragments := 0
+	for _, line := range strings.Split(quote, "\n") {
+		for _, fragment := range strings.FieldsFunc(line, func(r rune) bool { return r == '…' }) {
+			for _, part := range strings.Split(fragment, "...") {
+				part = strings.Trim(normalize(part), ` "'.,;:`)
+				if part == "" {
+					continue
+				}
+				fragments++
+				if !strings.Contains(text, part) {
+					return false
+				}
+			}
+		}
+	}
+	return fragments > 0
+}
+
+// normalize lowercases text and collapses whitespace and typographic quotes,
+// which models rarely reproduce exactly
+func normalize(text string) string {
+	text = strings.NewReplacer("“", `"`, "”", `"`, "‘", "'", "’", "'").Replace(text)
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
+
+// shorten cuts text to at most maxLen bytes with an ellipsis, without
+// splitting a rune
+func shorten(text string, maxLen int) string {
+	if len(text) <= maxLen {
+		return text
+	}
+	cut := maxLen
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "…"
+}
@@ -0,0 +1,211 @@
+package citation
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// debugResult returns a debug result with pod logs and status sections
+func debugResult() *types.DebugResult {
+	return &types.DebugResult{
+		Alert: types.Alert{Labels: map[string]string{"alertname": "PodCrashLooping", "namespace": "shop"}},
+		Sections: []types.DebugSection{
+			{
+				Title: "Pod Logs",
+				Content: "2024-01-15T10:30:00Z ERROR failed to connect to database: connection refused\n" +
+					"2024-01-15T10:30:05Z INFO retrying in 5s\n" +
+					"2024-01-15T10:30:10Z FATAL giving up after 3 attempts",
+			},
+			{
+				Title:   "Pod Status",
+				Content: "State: Terminated (OOMKilled)\nexitCode: 137\nMemory usage: 95.3% of 512Mi limit\nCPU: 250m",
+			},
+		},
+	}
+}
+
+func TestVerifyText(t *testing.T) {
+	tests := []struct {
+		name         string
+		analysis     string
+		wantChecked  int
+		wantVerified int
+		wantFlagged  string // Span expected to be flagged inline
+	}{
+		{
+			name:         "inline quote found",
+			analysis:     "The pod logs show `failed to connect to database: connection refused`.",
+			wantChecked:  1,
+			wantVerified: 1,
+		},
+		{
+			name:         "quote not found",
+			analysis:     "The pod logs show `disk quota exceeded`.",
+			wantChecked:  1,
+			wantVerified: 0,
+			wantFlagged:  "`disk quota exceeded`",
+		},
+		{
+			name:         "case and spacing differ",
+			analysis:     "Logs: `FAILED  to connect to   Database`",
+			wantChecked:  1,
+			wantVerified: 1,
+		},
+		{
+			name:         "elided quote",
+			analysis:     "```\nERROR failed to connect ... connection refused\nFATAL giving up…3 attempts\n```",
+			wantChecked:  1,
+			wantVerified: 1,
+		},
+		{
+			name:         "elided quote with a missing part",
+			analysis:     "`ERROR failed to connect ... timeout`",
+			wantChecked:  1,
+			wantVerified: 0,
+			wantFlagged:  "`ERROR failed to connect ... timeout`",
+		},
+		{
+			name:         "data in double quotes",
+			analysis:     `The container logged "retrying in 5s" before it exited.`,
+			wantChecked:  1, // "5s" isn't checked again as a value
+			wantVerified: 1,
+		},
+		{
+			name:         "scare quotes aren't checked",
+			analysis:     `The pod is "unhealthy".`,
+			wantChecked:  0,
+			wantVerified: 0,
+		},
+		{
+			name:         "values",
+			analysis:     "Memory reached 95% of the 512Mi limit and the container failed with exit code 137.",
+			wantChecked:  3,
+			wantVerified: 3,
+		},
+		{
+			name:         "values not found",
+			analysis:     "Memory reached 80% and the container failed with exit code 1.",
+			wantChecked:  2,
+			wantVerified: 0,
+		},
+		{
+			name:         "actions aren't claims",
+			analysis:     "Memory reached 95%.\n\n*Actions:*\n• Raise the limit to `1Gi`\n• Set requests to 300m",
+			wantChecked:  1,
+			wantVerified: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, g := VerifyText(tt.analysis, debugResult())
+			if g.Checked != tt.wantChecked || g.Verified != tt.wantVerified {
+				t.Errorf("grounding = %d of %d verified (unverified: %v), want %d of %d",
+					g.Verified, g.Checked, g.Unverified, tt.wantVerified, tt.wantChecked)
+			}
+			if tt.wantFlagged != "" {
+				if !strings.Contains(got, tt.wantFlagged+unverifiedMark) {
+					t.Errorf("VerifyText() = %q, want %q flagged", got, tt.wantFlagged)
+				}
+			} else if strings.Contains(got, unverifiedMark) {
+				t.Errorf("VerifyText() = %q, want nothing flagged", got)
+			}
+		})
+	}
+}
+
+func TestContainsValue(t *testing.T) {
+	e := newEvidence(debugResult())
+
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{value: "95.3%", want: true},
+		{value: "95%", want: true}, // Decimals rounded away
+		{value: "95.0%", want: false},
+		{value: "5.3%", want: false}, // Part of 95.3%
+		{value: "3%", want: false},   // Part of 95.3%
+		{value: "512Mi", want: true},
+		{value: "512 mi", want: true},
+		{value: "250m", want: true},
+		{value: "5s", want: true},
+		{value: "50m", want: false},
+		{value: "exit code 137", want: true},
+		{value: "exit code 13", want: false},
+		{value: "exit code 1", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := e.containsValue(tt.value); got != tt.want {
+				t.Errorf("containsValue(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyAnalysis(t *testing.T) {
+	a := &types.Analysis{
+		RootCause: "The container was OOMKilled with exit code 137 at 95% of its memory limit",
+		Evidence: []types.Evidence{
+			{Section: "Pod Status", Quote: "State: Terminated (OOMKilled)"},
+			{Section: "Pod Logs", Quote: "Memory usage: 95.3% of 512Mi limit"}, // Wrong section, found elsewhere
+			{Section: "Pod Logs", Quote: "out of memory: killed process 1234"},
+		},
+		Impact:     "Checkout requests fail with `connection reset by peer`",
+		AlertNotes: []types.AlertNote{{Note: "Restarted 3 times"}},
+	}
+
+	g := VerifyAnalysis(a, debugResult())
+	if a.Evidence[0].Unverified || a.Evidence[1].Unverified {
+		t.Errorf("evidence found in the debug info was marked unverified: %+v", a.Evidence)
+	}
+	if !a.Evidence[2].Unverified {
+		t.Error("evidence missing from the debug info wasn't marked")
+	}
+	// 3 evidence quotes, 2 root cause values and the impact quote
+	if g.Checked != 6 || g.Verified != 4 {
+		t.Errorf("grounding = %d of %d verified (unverified: %v), want 4 of 6", g.Verified, g.Checked, g.Unverified)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	if got := Summary(&types.Grounding{}); got != "" {
+		t.Errorf("Summary() = %q for nothing checked, want empty", got)
+	}
+
+	g := &types.Grounding{Checked: 8, Verified: 2, Unverified: []string{
+		"a", "b\nc", "d", "e", "f", "g", strings.Repeat("é", 60),
+	}}
+	got := Summary(g)
+	want := "2 of 8 quotes and values found in the debug info (25%). Not found: `a`, `b c`, `d`, `e`, `f`, and 2 more"
+	if got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+}
+
+func TestShorten(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "short", text: "refused", want: "refused"},
+		{name: "ASCII", text: "connection refused", want: "connection …"},
+		{name: "multi-byte rune at the cut", text: "connectioné refused", want: "connection…"},
+		{name: "multi-byte runes", text: "ééééééé", want: "ééééé…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := shorten(tt.text, 11)
+			if got != tt.want {
+				t.Errorf("shorten(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("shorten(%q) = %q, which isn't valid UTF-8", tt.text, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package conversation
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// retention is how long a thread can be asked about after its last activity
+const retention = 7 * 24 * time.Hour
+
+// maxTurns bounds the follow-ups kept per thread; older ones are dropped
+const maxTurns = 10
+
+// Conversation is the context of an alert thread: the evidence the analysis
+// was based on, the analysis and the follow-ups answered since
+type Conversation struct {
+	ThreadTS  string             `json:"thread_ts"`
+	Channel   string             `json:"channel,omitempty"`
+	Alert     types.Alert        `json:"alert"`              // The alert, or the lead alert of an incident
+	Incident  *types.Incident    `json:"incident,omitempty"` // The correlated alerts analyzed together, if any
+	Category  string             `json:"category"`
+	Evidence  *types.DebugResult `json:"evidence"` // Redacted debug info, fitted to the analysis prompt
+	Analysis  string             `json:"analysis"`
+	Turns     []types.FollowUp   `json:"turns,omitempty"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// Store persists conversations by thread
+type Store struct {
+	filePath      string
+	conversations map[string]*Conversation // Key: thread TS
+	mu            sync.Mutex
+}
+
+// NewStore creates a conversation store backed by the given file
+func NewStore(filePath string) *Store {
+	s := &Store{
+		filePath:      filePath,
+		conversations: make(map[string]*Conversation),
+	}
+
+	if err := s.load(); err != nil {
+		log.Printf("No existing conversations, starting fresh: %v", err)
+	}
+
+	return s
+}
+
+// Save stores the context of a thread. Follow-ups already answered in the
+// thread, e.g. before the alert was analyzed again, are kept.
+func (s *Store) Save(conversation Conversation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.conversations[conversation.ThreadTS]; ok && len(conversation.Turns) == 0 {
+		conversation.Turns = existing.Turns
+	}
+	conversation.UpdatedAt = time.Now()
+	s.conversations[conversation.ThreadTS] = &conversation
+
+	return s.save()
+}
+
+// Get returns a copy of the conversation of a thread
+func (s *Store) Get(threadTS string) (Conversation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conversation, ok := s.conversations[threadTS]
+	if !ok {
+		return Conversation{}, false
+	}
+	copied := *conversation
+	copied.Turns = append([]types.FollowUp(nil), conversation.Turns...)
+	return copied, true
+}
+
+// AddTurn records a follow-up answered in a thread, with the evidence gathered
+// to answer it
+func (s *Store) AddTurn(threadTS string, turn types.FollowUp, evidence []types.DebugSection) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conversation, ok := s.conversations[threadTS]
+	if !ok {
+		return fmt.Errorf("no conversation for thread %s", threadTS)
+	}
+
+	conversation.Turns = append(conversation.Turns, turn)
+	if len(conversation.Turns) > maxTurns {
+		conversation.Turns = conversation.Turns[len(conversation.Turns)-maxTurns:]
+	}
+	if len(evidence) > 0 && conversation.Evidence != nil {
+		result := *conversation.Evidence
+		result.Sections = append(append([]types.DebugSection(nil), result.Sections...), evidence...)
+		conversation.Evidence = &result
+	}
+	conversation.UpdatedAt = time.Now()
+
+	return s.save()
+}
+
+// load reads conversations from disk
+func (s *Store) load() error {
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		return err
+	}
+
+	var list []*Conversation
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	for _, conversation := range list {
+		s.conversations[conversation.ThreadTS] = conversation
+	}
+	return nil
+}
+
+// save prunes inactive conversations and writes the others to disk
+func (s *Store) save() error {
+	list := make([]*Conversation, 0, len(s.conversations))
+	for ts, conversation := range s.conversations {
+		if time.Since(conversation.UpdatedAt) > retention {
+			delete(s.conversations, ts)
+			continue
+		}
+		list = append(list, conversation)
+	}
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversations: %w", err)
+	}
+
+	return os.WriteFile(s.filePath, data, 0644)
+}
@@ -0,0 +1,158 @@
+package feedback
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// pendingRetention is how long an analysis can be rated before it's pruned
+const pendingRetention = 7 * 24 * time.Hour
+
+// maxComments bounds the thread replies kept per analysis
+const maxComments = 10
+
+// Pending is an analysis posted to Slack or Mattermost awaiting a ✅ or ❌
+// reaction
+type Pending struct {
+	Alert      types.Alert      `json:"alert"`
+	Category   string           `json:"category"`
+	Analysis   string           `json:"analysis"`
+	Structured *types.Analysis  `json:"structured,omitempty"` // nil for free-form analyses
+	Grounding  *types.Grounding `json:"grounding,omitempty"`  // nil if the analysis wasn't verified
+	ThreadTS   string           `json:"thread_ts"`
+	AnalysisTS string           `json:"analysis_ts"`        // The message timestamp for the analysis
+	Channel    string           `json:"channel,omitempty"`  // Channel of the analysis message
+	Notifier   string           `json:"notifier,omitempty"` // Notifier the analysis was posted with; empty for Slack
+	Comments   []string         `json:"comments,omitempty"` // Replies posted in the thread before the rating
+	Timestamp  time.Time        `json:"timestamp"`
+}
+
+// PendingStore persists the analyses awaiting a rating, so reactions added
+// after a restart are still recorded
+type PendingStore struct {
+	filePath string
+	pending  map[string]*Pending // Key: analysis message TS
+	mu       sync.Mutex
+}
+
+// NewPendingStore creates a pending analysis store backed by the given file
+func NewPendingStore(filePath string) *PendingStore {
+	s := &PendingStore{
+		filePath: filePath,
+		pending:  make(map[string]*Pending),
+	}
+
+	if err := s.load(); err != nil {
+		log.Printf("No existing pending feedback, starting fresh: %v", err)
+	}
+
+	return s
+}
+
+// Add stores an analysis awaiting a rating
+func (s *PendingStore) Add(pending Pending) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if pending.Timestamp.IsZero() {
+		pending.Timestamp = time.Now()
+	}
+	s.pending[pending.AnalysisTS] = &pending
+
+	return s.save()
+}
+
+// Take removes and returns the analysis posted as the given message, so a
+// reaction delivered twice is recorded once
+func (s *PendingStore) Take(analysisTS string) (Pending, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending, ok := s.pending[analysisTS]
+	if !ok {
+		return Pending{}, false
+	}
+	delete(s.pending, analysisTS)
+
+	if err := s.save(); err != nil {
+		log.Printf("Warning: failed to save pending feedback: %v", err)
+	}
+	return *pending, true
+}
+
+// AddComment attaches a thread reply to the analyses of a thread awaiting a
+// rating. It reports whether there were any.
+func (s *PendingStore) AddComment(threadTS, comment string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := false
+	for _, pending := range s.pending {
+		if pending.ThreadTS != threadTS {
+			continue
+		}
+		found = true
+		if len(pending.Comments) < maxComments {
+			pending.Comments = append(pending.Comments, comment)
+		}
+	}
+	if !found {
+		return false, nil
+	}
+	return true, s.save()
+}
+
+// List returns copies of the analyses awaiting a rating
+func (s *PendingStore) List() []Pending {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Pending, 0, len(s.pending))
+	for _, pending := range s.pending {
+		list = append(list, *pending)
+	}
+	return list
+}
+
+// load reads pending analyses from disk
+func (s *PendingStore) load() error {
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		return err
+	}
+
+	var list []*Pending
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	for _, pending := range list {
+		s.pending[pending.AnalysisTS] = pending
+	}
+	return nil
+}
+
+// save prunes expired analyses and writes the others to disk
+func (s *PendingStore) save() error {
+	list := make([]*Pending, 0, len(s.pending))
+	for ts, pending := range s.pending {
+		if time.Since(pending.Timestamp) > pendingRetention {
+			delete(s.pending, ts)
+			continue
+		}
+		list = append(list, pending)
+	}
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal pending feedback: %w", err)
+	}
+
+	return os.WriteFile(s.filePath, data, 0644)
+}
@@ -3,12 +3,15 @@ package knowledge
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/google/uuid"
 	_ "github.com/lib/pq" // PostgreSQL driver
+
+	"github.com/valentinpelus/k8flex/pkg/types"
 )
 
 // KnowledgeBase manages storage and retrieval of alert cases
@@ -98,17 +101,48 @@ func (kb *KnowledgeBase) Store(ctx context.Context, alertCase *AlertCase) error
 		INSERT INTO alert_cases (
 			id, alert_name, severity, category, summary, namespace, 
 			pod_name, container_name, analysis, debug_info, validated, 
-			embedding, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			embedding, created_at, updated_at, resolved_at, resolution_seconds,
+			root_cause, confidence, structured_analysis, grounding_score, grounding_checked
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
 		ON CONFLICT (id) DO UPDATE SET
 			category = EXCLUDED.category,
 			analysis = EXCLUDED.analysis,
+			root_cause = EXCLUDED.root_cause,
+			confidence = EXCLUDED.confidence,
+			structured_analysis = EXCLUDED.structured_analysis,
+			grounding_score = EXCLUDED.grounding_score,
+			grounding_checked = EXCLUDED.grounding_checked,
 			debug_info = EXCLUDED.debug_info,
 			validated = EXCLUDED.validated,
 			embedding = EXCLUDED.embedding,
-			updated_at = EXCLUDED.updated_at
+			updated_at = EXCLUDED.updated_at,
+			resolved_at = COALESCE(EXCLUDED.resolved_at, alert_cases.resolved_at),
+			resolution_seconds = COALESCE(EXCLUDED.resolution_seconds, alert_cases.resolution_seconds)
 	`
 
+	var resolvedAt sql.NullTime
+	var resolutionSeconds sql.NullInt64
+	if !alertCase.ResolvedAt.IsZero() {
+		resolvedAt = sql.NullTime{Time: alertCase.ResolvedAt, Valid: true}
+		resolutionSeconds = sql.NullInt64{Int64: int64(alertCase.ResolutionDuration.Seconds()), Valid: true}
+	}
+
+	var structured sql.NullString
+	if alertCase.Structured != nil {
+		data, err := json.Marshal(alertCase.Structured)
+		if err != nil {
+			return fmt.Errorf("failed to marshal structured analysis: %w", err)
+		}
+		structured = sql.NullString{String: string(data), Valid: true}
+	}
+
+	var groundingScore sql.NullFloat64
+	var groundingChecked sql.NullInt64
+	if alertCase.GroundingChecked > 0 {
+		groundingScore = sql.NullFloat64{Float64: alertCase.GroundingScore, Valid: true}
+		groundingChecked = sql.NullInt64{Int64: int64(alertCase.GroundingChecked), Valid: true}
+	}
+
 	_, err = kb.db.ExecContext(ctx, query,
 		alertCase.ID,
 		alertCase.AlertName,
@@ -124,6 +158,13 @@ func (kb *KnowledgeBase) Store(ctx context.Context, alertCase *AlertCase) error
 		pgvectorString(embedding), // Convert to pgvector format
 		alertCase.CreatedAt,
 		alertCase.UpdatedAt,
+		resolvedAt,
+		resolutionSeconds,
+		alertCase.RootCause,
+		alertCase.Confidence,
+		structured,
+		groundingScore,
+		groundingChecked,
 	)
 
 	if err != nil {
@@ -148,7 +189,9 @@ func (kb *KnowledgeBase) FindSimilar(ctx context.Context, searchText string) ([]
 		SELECT 
 			id, alert_name, severity, category, summary, namespace,
 			pod_name, container_name, analysis, debug_info, validated,
-			created_at, updated_at,
+			created_at, updated_at, resolved_at, resolution_seconds,
+			COALESCE(root_cause, ''), COALESCE(confidence, ''), structured_analysis,
+			COALESCE(grounding_score, 0), COALESCE(grounding_checked, 0),
 			1 - (embedding <=> $1::vector) as similarity
 		FROM alert_cases
 		WHERE validated = true
@@ -171,6 +214,9 @@ func (kb *KnowledgeBase) FindSimilar(ctx context.Context, searchText string) ([]
 	for rows.Next() {
 		var ac AlertCase
 		var similarity float32
+		var resolvedAt sql.NullTime
+		var resolutionSeconds sql.NullInt64
+		var structured sql.NullString
 
 		err := rows.Scan(
 			&ac.ID,
@@ -186,12 +232,31 @@ func (kb *KnowledgeBase) FindSimilar(ctx context.Context, searchText string) ([]
 			&ac.Validated,
 			&ac.CreatedAt,
 			&ac.UpdatedAt,
+			&resolvedAt,
+			&resolutionSeconds,
+			&ac.RootCause,
+			&ac.Confidence,
+			&structured,
+			&ac.GroundingScore,
+			&ac.GroundingChecked,
 			&similarity,
 		)
 		if err != nil {
 			log.Printf("Warning: failed to scan row: %v", err)
 			continue
 		}
+		if resolvedAt.Valid {
+			ac.ResolvedAt = resolvedAt.Time
+			ac.ResolutionDuration = time.Duration(resolutionSeconds.Int64) * time.Second
+		}
+		if structured.Valid {
+			var analysis types.Analysis
+			if err := json.Unmarshal([]byte(structured.String), &analysis); err != nil {
+				log.Printf("Warning: failed to decode structured analysis of case %s: %v", ac.ID, err)
+			} else {
+				ac.Structured = &analysis
+			}
+		}
 
 		similarCases = append(similarCases, &SimilarCase{
 			Case:       &ac,
@@ -207,6 +272,27 @@ func (kb *KnowledgeBase) FindSimilar(ctx context.Context, searchText string) ([]
 	return similarCases, nil
 }
 
+// RecordResolution stores when the alert behind a case was resolved and how long it took
+func (kb *KnowledgeBase) RecordResolution(ctx context.Context, caseID string, resolvedAt time.Time, duration time.Duration) error {
+	query := `
+		UPDATE alert_cases
+		SET resolved_at = $2, resolution_seconds = $3
+		WHERE id = $1
+	`
+
+	result, err := kb.db.ExecContext(ctx, query, caseID, resolvedAt, int64(duration.Seconds()))
+	if err != nil {
+		return fmt.Errorf("failed to record resolution: %w", err)
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("alert case %s not found", caseID)
+	}
+
+	log.Printf("Recorded resolution for case %s (time to resolve: %s)", caseID, duration.Round(time.Second))
+	return nil
+}
+
 // GetStats returns statistics about the knowledge base
 func (kb *KnowledgeBase) GetStats(ctx context.Context) (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
@@ -244,6 +330,40 @@ func (kb *KnowledgeBase) GetStats(ctx context.Context) (map[string]interface{},
 	}
 	stats["by_category"] = categoryCounts
 
+	// Mean time to resolve by alert name
+	mttrQuery := `
+		SELECT alert_name, AVG(resolution_seconds)
+		FROM alert_cases
+		WHERE validated = true AND resolution_seconds IS NOT NULL
+		GROUP BY alert_name
+	`
+	mttrRows, err := kb.db.QueryContext(ctx, mttrQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MTTR stats: %w", err)
+	}
+	defer mttrRows.Close()
+
+	mttrByAlert := make(map[string]time.Duration)
+	for mttrRows.Next() {
+		var alertName string
+		var avgSeconds float64
+		if err := mttrRows.Scan(&alertName, &avgSeconds); err != nil {
+			continue
+		}
+		mttrByAlert[alertName] = time.Duration(avgSeconds * float64(time.Second))
+	}
+	stats["mttr_by_alert"] = mttrByAlert
+
+	// Mean grounding of the cases whose analysis was verified
+	var avgGrounding sql.NullFloat64
+	err = kb.db.QueryRowContext(ctx, "SELECT AVG(grounding_score) FROM alert_cases WHERE validated = true AND grounding_checked > 0").Scan(&avgGrounding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get grounding stats: %w", err)
+	}
+	if avgGrounding.Valid {
+		stats["avg_grounding_score"] = avgGrounding.Float64
+	}
+
 	// Latest case timestamp
 	var latestCase time.Time
 	err = kb.db.QueryRowContext(ctx, "SELECT MAX(created_at) FROM alert_cases WHERE validated = true").Scan(&latestCase)
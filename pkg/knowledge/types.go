@@ -22,6 +22,17 @@ type AlertCase struct {
 	Embedding     []float32 `db:"embedding"`  // Vector embedding for similarity search
 	CreatedAt     time.Time `db:"created_at"`
 	UpdatedAt     time.Time `db:"updated_at"`
+	// Resolution tracking (zero until the alert is resolved)
+	ResolvedAt         time.Time     `db:"resolved_at"`
+	ResolutionDuration time.Duration `db:"resolution_seconds"` // Time from alert start to resolution
+	// Structured analysis fields (empty for free-form analyses)
+	RootCause  string          `db:"root_cause"`
+	Confidence string          `db:"confidence"`
+	Structured *types.Analysis `db:"structured_analysis"`
+	// Share of the analysis's quotes and values found in the debug info (0-1),
+	// meaningful only when GroundingChecked > 0
+	GroundingScore   float64 `db:"grounding_score"`
+	GroundingChecked int     `db:"grounding_checked"`
 }
 
 // SimilarCase represents a similar past case with similarity score
@@ -59,6 +70,27 @@ func FromAlert(alert *types.Alert, category, analysis, debugInfo string) *AlertC
 	}
 }
 
+// SetStructuredAnalysis records the fields of a structured analysis; nil leaves
+// the case free-form
+func (ac *AlertCase) SetStructuredAnalysis(analysis *types.Analysis) {
+	if analysis == nil {
+		return
+	}
+	ac.RootCause = analysis.RootCause
+	ac.Confidence = analysis.Confidence
+	ac.Structured = analysis
+}
+
+// SetGrounding records how well the analysis is backed by the debug info; nil
+// leaves the case unscored
+func (ac *AlertCase) SetGrounding(g *types.Grounding) {
+	if g == nil {
+		return
+	}
+	ac.GroundingScore = g.Score()
+	ac.GroundingChecked = g.Checked
+}
+
 // GetSearchText returns a text representation for embedding generation
 func (ac *AlertCase) GetSearchText() string {
 	return ac.AlertName + " " + ac.Severity + " " + ac.Summary + " " +
@@ -0,0 +1,229 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+)
+
+// The write operations below back the remediation actions approved from
+// Slack. With dryRun set, the API server validates and admits the change
+// without persisting it, so RBAC, admission webhooks and PodDisruptionBudgets
+// are all checked before the real call.
+
+// restartedAtAnnotation is the pod template annotation kubectl rollout restart sets
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// mirrorPodAnnotation marks static pods managed by the kubelet, which can't be evicted
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// dryRunOption returns the DryRun field of write options
+func dryRunOption(dryRun bool) []string {
+	if dryRun {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
+// RestartWorkload triggers a rolling restart of a Deployment, StatefulSet or
+// DaemonSet, like kubectl rollout restart
+func (c *Client) RestartWorkload(ctx context.Context, w *Workload, dryRun bool) (string, error) {
+	patch, _ := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{restartedAtAnnotation: time.Now().Format(time.RFC3339)},
+				},
+			},
+		},
+	})
+	opts := metav1.PatchOptions{DryRun: dryRunOption(dryRun)}
+
+	var err error
+	switch w.Kind {
+	case "Deployment":
+		_, err = c.clientset.AppsV1().Deployments(w.Namespace).Patch(ctx, w.Name, apitypes.StrategicMergePatchType, patch, opts)
+	case "StatefulSet":
+		_, err = c.clientset.AppsV1().StatefulSets(w.Namespace).Patch(ctx, w.Name, apitypes.StrategicMergePatchType, patch, opts)
+	case "DaemonSet":
+		_, err = c.clientset.AppsV1().DaemonSets(w.Namespace).Patch(ctx, w.Name, apitypes.StrategicMergePatchType, patch, opts)
+	default:
+		return "", fmt.Errorf("cannot restart a %s", w.Kind)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to restart %s: %w", w, err)
+	}
+	return fmt.Sprintf("Restarted %s", w), nil
+}
+
+// ScaleWorkload sets the replicas of a Deployment or StatefulSet
+func (c *Client) ScaleWorkload(ctx context.Context, w *Workload, replicas int32, dryRun bool) (string, error) {
+	opts := metav1.UpdateOptions{DryRun: dryRunOption(dryRun)}
+
+	switch w.Kind {
+	case "Deployment":
+		scale, err := c.clientset.AppsV1().Deployments(w.Namespace).GetScale(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get scale of %s: %w", w, err)
+		}
+		previous := scale.Spec.Replicas
+		scale.Spec.Replicas = replicas
+		if _, err := c.clientset.AppsV1().Deployments(w.Namespace).UpdateScale(ctx, w.Name, scale, opts); err != nil {
+			return "", fmt.Errorf("failed to scale %s: %w", w, err)
+		}
+		return fmt.Sprintf("Scaled %s from %d to %d replicas", w, previous, replicas), nil
+	case "StatefulSet":
+		scale, err := c.clientset.AppsV1().StatefulSets(w.Namespace).GetScale(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get scale of %s: %w", w, err)
+		}
+		previous := scale.Spec.Replicas
+		scale.Spec.Replicas = replicas
+		if _, err := c.clientset.AppsV1().StatefulSets(w.Namespace).UpdateScale(ctx, w.Name, scale, opts); err != nil {
+			return "", fmt.Errorf("failed to scale %s: %w", w, err)
+		}
+		return fmt.Sprintf("Scaled %s from %d to %d replicas", w, previous, replicas), nil
+	default:
+		return "", fmt.Errorf("cannot scale a %s", w.Kind)
+	}
+}
+
+// RollbackDeployment rolls a deployment back to the pod template of its
+// previous revision, like kubectl rollout undo
+func (c *Client) RollbackDeployment(ctx context.Context, namespace, name string, dryRun bool) (string, error) {
+	d, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get deployment: %w", err)
+	}
+	if d.Spec.Paused {
+		return "", fmt.Errorf("deployment %s is paused", name)
+	}
+
+	owned, err := c.ownedReplicaSets(ctx, d)
+	if err != nil {
+		return "", err
+	}
+	current, _ := strconv.ParseInt(d.Annotations[revisionAnnotation], 10, 64)
+	if current == 0 && len(owned) > 0 {
+		current = replicaSetRevision(&owned[0])
+	}
+	var previous *appsv1.ReplicaSet
+	for i := range owned {
+		if rev := replicaSetRevision(&owned[i]); rev > 0 && rev < current {
+			previous = &owned[i]
+			break
+		}
+	}
+	if previous == nil {
+		return "", fmt.Errorf("deployment %s has no previous revision to roll back to", name)
+	}
+
+	template := previous.Spec.Template.DeepCopy()
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	patch, err := json.Marshal([]map[string]interface{}{
+		{"op": "replace", "path": "/spec/template", "value": template},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to build rollback patch: %w", err)
+	}
+
+	opts := metav1.PatchOptions{DryRun: dryRunOption(dryRun)}
+	if _, err := c.clientset.AppsV1().Deployments(namespace).Patch(ctx, name, apitypes.JSONPatchType, patch, opts); err != nil {
+		return "", fmt.Errorf("failed to roll back deployment %s: %w", name, err)
+	}
+	return fmt.Sprintf("Rolled back Deployment/%s from revision %d to revision %d (images=%s)",
+		name, current, replicaSetRevision(previous), strings.Join(templateImages(previous.Spec.Template), ",")), nil
+}
+
+// CordonNode marks a node unschedulable
+func (c *Client) CordonNode(ctx context.Context, name string, dryRun bool) (string, error) {
+	patch := []byte(`{"spec":{"unschedulable":true}}`)
+	opts := metav1.PatchOptions{DryRun: dryRunOption(dryRun)}
+	if _, err := c.clientset.CoreV1().Nodes().Patch(ctx, name, apitypes.StrategicMergePatchType, patch, opts); err != nil {
+		return "", fmt.Errorf("failed to cordon node %s: %w", name, err)
+	}
+	return fmt.Sprintf("Cordoned node %s", name), nil
+}
+
+// DrainNode cordons a node and evicts its pods, except DaemonSet and mirror
+// pods, like kubectl drain. Evictions respect PodDisruptionBudgets; pods that
+// can't be evicted are reported in the error, the others are still evicted.
+func (c *Client) DrainNode(ctx context.Context, name string, dryRun bool) (string, error) {
+	if _, err := c.CordonNode(ctx, name, dryRun); err != nil {
+		return "", err
+	}
+
+	pods, err := c.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: "spec.nodeName=" + name,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list pods on node %s: %w", name, err)
+	}
+
+	evicted, skipped := 0, 0
+	var failed []string
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !evictable(pod) {
+			skipped++
+			continue
+		}
+		eviction := &policyv1.Eviction{
+			ObjectMeta:    metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+			DeleteOptions: &metav1.DeleteOptions{DryRun: dryRunOption(dryRun)},
+		}
+		if err := c.clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction); err != nil {
+			failed = append(failed, fmt.Sprintf("%s/%s: %v", pod.Namespace, pod.Name, err))
+			continue
+		}
+		evicted++
+	}
+
+	summary := fmt.Sprintf("Drained node %s: %d pods evicted, %d DaemonSet, mirror or finished pods left", name, evicted, skipped)
+	if len(failed) > 0 {
+		return summary, fmt.Errorf("failed to evict %d pods from node %s: %s", len(failed), name, strings.Join(failed, "; "))
+	}
+	return summary, nil
+}
+
+// evictable reports whether drain should evict a pod
+func evictable(pod *corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+		return false
+	}
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "DaemonSet" {
+		return false
+	}
+	return true
+}
+
+// DeletePod deletes a pod so its controller recreates it. Pods without a
+// controller are refused, since nothing would bring them back.
+func (c *Client) DeletePod(ctx context.Context, namespace, name string, dryRun bool) (string, error) {
+	pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get pod: %w", err)
+	}
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "", fmt.Errorf("pod %s has no controller to recreate it", name)
+	}
+
+	opts := metav1.DeleteOptions{DryRun: dryRunOption(dryRun)}
+	if err := c.clientset.CoreV1().Pods(namespace).Delete(ctx, name, opts); err != nil {
+		return "", fmt.Errorf("failed to delete pod %s: %w", name, err)
+	}
+	return fmt.Sprintf("Deleted pod %s (recreated by %s/%s)", name, owner.Kind, owner.Name), nil
+}
@@ -0,0 +1,156 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// errorTTL is how long a failed lookup is cached, so collectors hitting a
+// missing or forbidden object don't each retry it
+const errorTTL = 30 * time.Second
+
+// lookupCache memoizes pod and node reads so that concurrent checks of the
+// same alert share one API round-trip. Concurrent lookups of the same key wait
+// for the first; failed lookups are cached for errorTTL, unless the caller's
+// context cut them short.
+type lookupCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedLookup
+}
+
+// cachedLookup is a lookup in flight or completed
+type cachedLookup struct {
+	done    chan struct{}
+	obj     interface{}
+	err     error
+	expires time.Time // When a failed lookup may be retried
+}
+
+// expired reports whether a completed lookup failed more than errorTTL ago
+func (l *cachedLookup) expired() bool {
+	select {
+	case <-l.done:
+		return l.err != nil && !time.Now().Before(l.expires)
+	default:
+		return false
+	}
+}
+
+// WithCache returns a client sharing this client's connections that caches
+// pod and node reads. Use one per alert: cached objects are never refreshed.
+func (c *Client) WithCache() *Client {
+	return &Client{
+		clientset: c.clientset,
+		dynamic:   c.dynamic,
+		cache:     &lookupCache{entries: make(map[string]*cachedLookup)},
+	}
+}
+
+// get returns the cached object for key, calling fetch at most once at a time
+func (lc *lookupCache) get(ctx context.Context, key string, fetch func() (interface{}, error)) (interface{}, error) {
+	lc.mu.Lock()
+	if l, ok := lc.entries[key]; ok && !l.expired() {
+		lc.mu.Unlock()
+		select {
+		case <-l.done:
+			if l.err == nil || time.Now().Before(l.expires) {
+				return l.obj, l.err
+			}
+			// The first caller's failure was its own deadline; retry
+			return lc.get(ctx, key, fetch)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	l := &cachedLookup{done: make(chan struct{})}
+	lc.entries[key] = l
+	lc.mu.Unlock()
+
+	l.obj, l.err = fetch()
+	if l.err != nil {
+		if ctx.Err() != nil {
+			lc.mu.Lock()
+			delete(lc.entries, key)
+			lc.mu.Unlock()
+		} else {
+			l.expires = time.Now().Add(errorTTL)
+		}
+	}
+	close(l.done)
+	return l.obj, l.err
+}
+
+// put stores an object fetched by other means, e.g. a list
+func (lc *lookupCache) put(key string, obj interface{}) {
+	l := &cachedLookup{done: make(chan struct{}), obj: obj}
+	close(l.done)
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if cached, ok := lc.entries[key]; !ok || cached.expired() {
+		lc.entries[key] = l
+	}
+}
+
+// getPod reads a pod, through the cache if there is one
+func (c *Client) getPod(ctx context.Context, namespace, name string) (*corev1.Pod, error) {
+	fetch := func() (interface{}, error) {
+		pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pod: %w", err)
+		}
+		return pod, nil
+	}
+	if c.cache == nil {
+		obj, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		return obj.(*corev1.Pod), nil
+	}
+
+	obj, err := c.cache.get(ctx, "pod/"+namespace+"/"+name, fetch)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*corev1.Pod), nil
+}
+
+// getNode reads a node, through the cache if there is one
+func (c *Client) getNode(ctx context.Context, name string) (*corev1.Node, error) {
+	fetch := func() (interface{}, error) {
+		node, err := c.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get node: %w", err)
+		}
+		return node, nil
+	}
+	if c.cache == nil {
+		obj, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		return obj.(*corev1.Node), nil
+	}
+
+	obj, err := c.cache.get(ctx, "node/"+name, fetch)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*corev1.Node), nil
+}
+
+// cachePods stores listed pods so later reads of them are free
+func (c *Client) cachePods(pods []corev1.Pod) {
+	if c.cache == nil {
+		return
+	}
+	for i := range pods {
+		c.cache.put("pod/"+pods[i].Namespace+"/"+pods[i].Name, &pods[i])
+	}
+}
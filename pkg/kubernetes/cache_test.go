@@ -0,0 +1,44 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLookupCacheErrors(t *testing.T) {
+	lc := &lookupCache{entries: make(map[string]*cachedLookup)}
+	calls := 0
+	notFound := func() (interface{}, error) {
+		calls++
+		return nil, errors.New(`pods "api-1" not found`)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := lc.get(context.Background(), "pod/default/api-1", notFound); err == nil {
+			t.Fatal("get() returned no error for a failed lookup")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("fetched %d times, want the error cached after 1", calls)
+	}
+
+	// The error is retried once it expires
+	lc.entries["pod/default/api-1"].expires = time.Now().Add(-time.Second)
+	found := func() (interface{}, error) { calls++; return "api-1", nil }
+	if obj, err := lc.get(context.Background(), "pod/default/api-1", found); err != nil || obj != "api-1" {
+		t.Errorf("get() = %v, %v after the error expired, want the pod", obj, err)
+	}
+
+	// A failure cut short by the caller's context isn't cached
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cancelled := func() (interface{}, error) { return nil, ctx.Err() }
+	if _, err := lc.get(ctx, "node/worker-1", cancelled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("get() error = %v, want context.Canceled", err)
+	}
+	if _, ok := lc.entries["node/worker-1"]; ok {
+		t.Error("cancelled lookup was cached")
+	}
+}
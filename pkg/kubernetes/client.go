@@ -5,52 +5,226 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/valentinpelus/k8flex/pkg/logsummary"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
 // Client wraps the Kubernetes clientset
 type Client struct {
 	clientset *kubernetes.Clientset
+	dynamic   dynamic.Interface
+	cache     *lookupCache // Per-alert pod/node cache, see WithCache
 }
 
-// NewClient creates a new Kubernetes client
-func NewClient(clientset *kubernetes.Clientset) *Client {
+// NewClient creates a new Kubernetes client. dynamicClient is used for custom
+// resources and may be nil.
+func NewClient(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface) *Client {
 	return &Client{
 		clientset: clientset,
+		dynamic:   dynamicClient,
 	}
 }
 
-// GetPodLogs retrieves the logs for a pod
+// LogOptions controls which container logs GetPodLogs collects
+type LogOptions struct {
+	Container string // Only this container; all containers when empty
+	MaxBytes  int    // Byte budget shared by all log streams
+	TailLines int64  // Lines requested per stream; 0 means defaultLogTailLines
+	Summarize bool   // Cluster lines by template, errors and stack traces first, instead of keeping the raw tail
+}
+
+// logStream is a single container log to fetch
+type logStream struct {
+	container string
+	previous  bool
+	header    string
+	weight    int
+}
+
+const (
+	// defaultLogTailLines is how many lines the API server returns per stream
+	// when LogOptions.TailLines is unset
+	defaultLogTailLines = int64(2000)
+	// defaultLogBytes is the log budget when LogOptions.MaxBytes is unset
+	defaultLogBytes = 32 * 1024
+	// maxSummarizedLogBytes bounds how much of a stream is read to be summarized
+	maxSummarizedLogBytes = 4 * 1024 * 1024
+)
+
+// GetPodLogs retrieves the logs of every container in a pod, including init
+// containers and sidecars that have run. For containers that restarted, the
+// previous instance's logs are included since they usually hold the crash.
+// Each stream keeps its most recent lines, or its summary if opts.Summarize
+// is set, within a share of opts.MaxBytes; crashed and unready containers get
+// a larger share.
 // Reference: https://pkg.go.dev/k8s.io/client-go/kubernetes/typed/core/v1#PodInterface
-func (c *Client) GetPodLogs(ctx context.Context, namespace, podName string, tailLines int64) (string, error) {
+func (c *Client) GetPodLogs(ctx context.Context, namespace, podName string, opts LogOptions) (string, error) {
+	pod, err := c.getPod(ctx, namespace, podName)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultLogBytes
+	}
+	if opts.TailLines <= 0 {
+		opts.TailLines = defaultLogTailLines
+	}
+
+	streams := podLogStreams(pod, opts.Container)
+	if len(streams) == 0 {
+		if opts.Container != "" {
+			return "", fmt.Errorf("container %s not found in pod %s", opts.Container, podName)
+		}
+		return "No container has started yet", nil
+	}
+
+	totalWeight := 0
+	for _, stream := range streams {
+		totalWeight += stream.weight
+	}
+
+	var out strings.Builder
+	for _, stream := range streams {
+		budget := opts.MaxBytes * stream.weight / totalWeight
+		readBytes := budget
+		if opts.Summarize {
+			readBytes = maxSummarizedLogBytes
+		}
+		logs, truncated, err := c.fetchLogs(ctx, namespace, podName, stream, opts.TailLines, readBytes)
+
+		out.WriteString(fmt.Sprintf("--- %s ---\n", stream.header))
+		switch {
+		case err != nil:
+			out.WriteString(fmt.Sprintf("Error fetching logs: %v\n", err))
+		case logs == "":
+			out.WriteString("(no output)\n")
+		case opts.Summarize:
+			if truncated {
+				out.WriteString(fmt.Sprintf("[earlier lines omitted, summarizing the last %d bytes]\n", len(logs)))
+			}
+			out.WriteString(logsummary.Summarize(logs).Render(budget))
+		default:
+			if truncated {
+				out.WriteString(fmt.Sprintf("[earlier lines omitted, showing last %d bytes]\n", len(logs)))
+			}
+			out.WriteString(logs)
+			if !strings.HasSuffix(logs, "\n") {
+				out.WriteString("\n")
+			}
+		}
+	}
+
+	return out.String(), nil
+}
+
+// podLogStreams lists the log streams worth fetching for a pod
+func podLogStreams(pod *corev1.Pod, onlyContainer string) []logStream {
+	var streams []logStream
+
+	add := func(cs corev1.ContainerStatus, kind string) {
+		if onlyContainer != "" && cs.Name != onlyContainer {
+			return
+		}
+
+		started := cs.State.Running != nil || cs.State.Terminated != nil || cs.RestartCount > 0
+		if started {
+			weight := 1
+			if !cs.Ready && (cs.State.Terminated == nil || cs.State.Terminated.ExitCode != 0) {
+				weight = 2
+			}
+			streams = append(streams, logStream{
+				container: cs.Name,
+				header:    fmt.Sprintf("%s %s (current)", kind, cs.Name),
+				weight:    weight,
+			})
+		}
+
+		if cs.RestartCount > 0 {
+			header := fmt.Sprintf("%s %s (previous instance, %d restarts)", kind, cs.Name, cs.RestartCount)
+			if last := cs.LastTerminationState.Terminated; last != nil {
+				header = fmt.Sprintf("%s %s (previous instance, exit code %d %s, %d restarts)",
+					kind, cs.Name, last.ExitCode, last.Reason, cs.RestartCount)
+			}
+			streams = append(streams, logStream{container: cs.Name, previous: true, header: header, weight: 2})
+		}
+	}
+
+	// Init containers that completed successfully rarely explain an alert
+	for _, cs := range pod.Status.InitContainerStatuses {
+		if t := cs.State.Terminated; t != nil && t.ExitCode == 0 && cs.RestartCount == 0 && onlyContainer == "" {
+			continue
+		}
+		add(cs, "init container")
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		add(cs, "container")
+	}
+
+	return streams
+}
+
+// fetchLogs streams one container log, keeping only its last maxBytes bytes
+func (c *Client) fetchLogs(ctx context.Context, namespace, podName string, stream logStream, tailLines int64, maxBytes int) (string, bool, error) {
 	req := c.clientset.CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{
+		Container: stream.container,
+		Previous:  stream.previous,
 		TailLines: &tailLines,
 	})
 
 	logs, err := req.Stream(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to get logs: %w", err)
+		return "", false, fmt.Errorf("failed to get logs: %w", err)
 	}
 	defer logs.Close()
 
-	buf := new(bytes.Buffer)
+	buf := &tailBuffer{max: maxBytes}
 	if _, err := io.Copy(buf, logs); err != nil {
-		return "", fmt.Errorf("failed to read logs: %w", err)
+		return "", false, fmt.Errorf("failed to read logs: %w", err)
+	}
+
+	return buf.String(), buf.truncated, nil
+}
+
+// tailBuffer is an io.Writer that keeps only the last max bytes written to it
+type tailBuffer struct {
+	buf       []byte
+	max       int
+	truncated bool
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.max {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.max:]...)
+		t.truncated = true
 	}
+	return len(p), nil
+}
 
-	return buf.String(), nil
+// String returns the kept bytes, starting at a line boundary if truncated
+func (t *tailBuffer) String() string {
+	if t.truncated {
+		if i := bytes.IndexByte(t.buf, '\n'); i >= 0 {
+			return string(t.buf[i+1:])
+		}
+	}
+	return string(t.buf)
 }
 
 // DescribePod retrieves detailed information about a pod
 // Reference: https://pkg.go.dev/k8s.io/client-go/kubernetes/typed/core/v1#PodInterface
 func (c *Client) DescribePod(ctx context.Context, namespace, podName string) (string, error) {
-	pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	pod, err := c.getPod(ctx, namespace, podName)
 	if err != nil {
-		return "", fmt.Errorf("failed to get pod: %w", err)
+		return "", err
 	}
 
 	var desc strings.Builder
@@ -98,10 +272,15 @@ func (c *Client) GetNamespaceEvents(ctx context.Context, namespace string, limit
 		return "No recent events found", nil
 	}
 
+	// Oldest first, so the most recent events end the list and survive trimming
+	sort.SliceStable(events.Items, func(i, j int) bool {
+		return eventTime(&events.Items[i]).Before(eventTime(&events.Items[j]))
+	})
+
 	var eventDesc strings.Builder
 	for _, event := range events.Items {
 		eventDesc.WriteString(fmt.Sprintf("[%s] %s %s/%s: %s - %s\n",
-			event.LastTimestamp.Format("15:04:05"),
+			eventTime(&event).Format("15:04:05"),
 			event.Type,
 			event.InvolvedObject.Kind,
 			event.InvolvedObject.Name,
@@ -112,6 +291,18 @@ func (c *Client) GetNamespaceEvents(ctx context.Context, namespace string, limit
 	return eventDesc.String(), nil
 }
 
+// eventTime returns when an event last occurred; newer events only set EventTime
+func eventTime(event *corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
 // CheckService retrieves information about a service and its endpoints
 // Reference: https://pkg.go.dev/k8s.io/client-go/kubernetes/typed/core/v1#ServiceInterface
 func (c *Client) CheckService(ctx context.Context, namespace, serviceName string) (string, error) {
@@ -161,9 +352,9 @@ func (c *Client) CheckService(ctx context.Context, namespace, serviceName string
 
 // CheckPodNetwork retrieves network information for a pod
 func (c *Client) CheckPodNetwork(ctx context.Context, namespace, podName string) (string, error) {
-	pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	pod, err := c.getPod(ctx, namespace, podName)
 	if err != nil {
-		return "", fmt.Errorf("failed to get pod: %w", err)
+		return "", err
 	}
 
 	var netDesc strings.Builder
@@ -186,9 +377,9 @@ func (c *Client) CheckPodNetwork(ctx context.Context, namespace, podName string)
 
 // CheckPodResources retrieves resource information for a pod
 func (c *Client) CheckPodResources(ctx context.Context, namespace, podName string) (string, error) {
-	pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	pod, err := c.getPod(ctx, namespace, podName)
 	if err != nil {
-		return "", fmt.Errorf("failed to get pod: %w", err)
+		return "", err
 	}
 
 	var resDesc strings.Builder
@@ -225,9 +416,9 @@ func (c *Client) CheckPodResources(ctx context.Context, namespace, podName strin
 // CheckNodeResources retrieves resource information for a node on which a pod is running
 func (c *Client) CheckNodeResources(ctx context.Context, namespace, podName string) (string, error) {
 	// Get pod to extract node name
-	pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	pod, err := c.getPod(ctx, namespace, podName)
 	if err != nil {
-		return "", fmt.Errorf("failed to get pod: %w", err)
+		return "", err
 	}
 
 	if pod.Spec.NodeName == "" {
@@ -235,9 +426,9 @@ func (c *Client) CheckNodeResources(ctx context.Context, namespace, podName stri
 	}
 
 	// Get node information
-	node, err := c.clientset.CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{})
+	node, err := c.getNode(ctx, pod.Spec.NodeName)
 	if err != nil {
-		return "", fmt.Errorf("failed to get node: %w", err)
+		return "", err
 	}
 
 	var resDesc strings.Builder
@@ -259,19 +450,23 @@ func (c *Client) CheckNodeResources(ctx context.Context, namespace, podName stri
 // CheckNodeStatus retrieves node conditions and status for the node on which a pod is running
 func (c *Client) CheckNodeStatus(ctx context.Context, namespace, podName string) (string, error) {
 	// Get pod to extract node name
-	pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	pod, err := c.getPod(ctx, namespace, podName)
 	if err != nil {
-		return "", fmt.Errorf("failed to get pod: %w", err)
+		return "", err
 	}
 
 	if pod.Spec.NodeName == "" {
 		return "Pod is not scheduled to any node yet", nil
 	}
 
-	// Get node information
-	node, err := c.clientset.CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{})
+	return c.DescribeNodeStatus(ctx, pod.Spec.NodeName)
+}
+
+// DescribeNodeStatus retrieves the conditions of a node
+func (c *Client) DescribeNodeStatus(ctx context.Context, nodeName string) (string, error) {
+	node, err := c.getNode(ctx, nodeName)
 	if err != nil {
-		return "", fmt.Errorf("failed to get node: %w", err)
+		return "", err
 	}
 
 	var statusDesc strings.Builder
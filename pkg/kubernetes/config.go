@@ -4,14 +4,15 @@ import (
 	"fmt"
 	"os"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
-// GetClientset creates a Kubernetes clientset
+// GetClientset creates a Kubernetes clientset and a dynamic client for custom resources
 // Reference: https://pkg.go.dev/k8s.io/client-go/rest#InClusterConfig
-func GetClientset() (*kubernetes.Clientset, error) {
+func GetClientset() (*kubernetes.Clientset, dynamic.Interface, error) {
 	// Try in-cluster config first
 	config, err := rest.InClusterConfig()
 	if err != nil {
@@ -22,14 +23,19 @@ func GetClientset() (*kubernetes.Clientset, error) {
 		}
 		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
 		if err != nil {
-			return nil, fmt.Errorf("failed to build config: %w", err)
+			return nil, nil, fmt.Errorf("failed to build config: %w", err)
 		}
 	}
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create clientset: %w", err)
+		return nil, nil, fmt.Errorf("failed to create clientset: %w", err)
 	}
 
-	return clientset, nil
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	return clientset, dynamicClient, nil
 }
@@ -0,0 +1,18 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetConfigMapData returns the data of a ConfigMap, e.g. runbooks keyed by
+// alert name
+func (c *Client) GetConfigMapData(ctx context.Context, namespace, name string) (map[string]string, error) {
+	cm, err := c.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get configmap %s/%s: %w", namespace, name, err)
+	}
+	return cm.Data, nil
+}
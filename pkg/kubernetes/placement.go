@@ -0,0 +1,68 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PodPlacement is where a pod runs and what it belongs to, used to correlate
+// alerts that share a cause
+type PodPlacement struct {
+	Node     string    // Node the pod is scheduled on, "" if pending
+	Workload *Workload // Controller owning the pod, nil for bare pods
+	Services []string  // Services whose selector matches the pod
+}
+
+// GetPodPlacement resolves a pod's node, owning workload and services. Pods of
+// a ReplicaSet are attributed to its Deployment using the pod-template-hash
+// label, without reading the ReplicaSet.
+func (c *Client) GetPodPlacement(ctx context.Context, namespace, podName string) (*PodPlacement, error) {
+	pod, err := c.getPod(ctx, namespace, podName)
+	if err != nil {
+		return nil, err
+	}
+
+	placement := &PodPlacement{
+		Node:     pod.Spec.NodeName,
+		Workload: podOwner(pod),
+	}
+
+	services, err := c.clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return placement, fmt.Errorf("failed to list services: %w", err)
+	}
+	for _, svc := range services.Items {
+		if len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		if labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(pod.Labels)) {
+			placement.Services = append(placement.Services, svc.Name)
+		}
+	}
+	sort.Strings(placement.Services)
+
+	return placement, nil
+}
+
+// podOwner returns the workload controlling a pod, or nil if there's none
+func podOwner(pod *corev1.Pod) *Workload {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return nil
+	}
+
+	w := &Workload{Kind: owner.Kind, Name: owner.Name, Namespace: pod.Namespace}
+	if owner.Kind == "ReplicaSet" {
+		if hash := pod.Labels["pod-template-hash"]; hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+			w.Kind = "Deployment"
+			w.Name = strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+	}
+	return w
+}
@@ -0,0 +1,97 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ListResources lists objects of any resource type, including custom resources,
+// and renders each object's status: scalar status fields, nested status objects
+// one level deep (e.g. Argo CD's health and sync) and conditions.
+// namespace may be empty for cluster-scoped resources.
+// Reference: https://pkg.go.dev/k8s.io/client-go/dynamic#ResourceInterface
+func (c *Client) ListResources(ctx context.Context, gvr schema.GroupVersionResource, namespace, labelSelector string, limit int64) (string, error) {
+	if c.dynamic == nil {
+		return "", fmt.Errorf("dynamic client is not configured")
+	}
+
+	opts := metav1.ListOptions{LabelSelector: labelSelector, Limit: limit}
+	var list *unstructured.UnstructuredList
+	var err error
+	if namespace == "" {
+		list, err = c.dynamic.Resource(gvr).List(ctx, opts)
+	} else {
+		list, err = c.dynamic.Resource(gvr).Namespace(namespace).List(ctx, opts)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to list %s: %w", gvr.Resource, err)
+	}
+
+	if len(list.Items) == 0 {
+		return fmt.Sprintf("No %s found", gvr.Resource), nil
+	}
+
+	var desc strings.Builder
+	for _, item := range list.Items {
+		desc.WriteString(fmt.Sprintf("%s %s (age %s)\n", item.GetKind(), item.GetName(),
+			time.Since(item.GetCreationTimestamp().Time).Round(time.Minute)))
+		desc.WriteString(formatResourceStatus(item.Object))
+	}
+
+	return desc.String(), nil
+}
+
+// formatResourceStatus renders the status of an unstructured object
+func formatResourceStatus(obj map[string]interface{}) string {
+	status, ok, _ := unstructured.NestedMap(obj, "status")
+	if !ok || len(status) == 0 {
+		return "  (no status)\n"
+	}
+
+	keys := make([]string, 0, len(status))
+	for k := range status {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var desc strings.Builder
+	for _, k := range keys {
+		switch v := status[k].(type) {
+		case string, bool, int64, float64:
+			desc.WriteString(fmt.Sprintf("  %s: %v\n", k, v))
+		case map[string]interface{}:
+			var fields []string
+			for field, fv := range v {
+				switch fv.(type) {
+				case string, bool, int64, float64:
+					fields = append(fields, fmt.Sprintf("%s=%v", field, fv))
+				}
+			}
+			if len(fields) > 0 {
+				sort.Strings(fields)
+				desc.WriteString(fmt.Sprintf("  %s: %s\n", k, strings.Join(fields, ", ")))
+			}
+		}
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(obj, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		desc.WriteString(fmt.Sprintf("  - %v: %v (%v)\n", cond["type"], cond["status"], cond["reason"]))
+		if msg, ok := cond["message"].(string); ok && msg != "" {
+			desc.WriteString(fmt.Sprintf("    Message: %s\n", msg))
+		}
+	}
+
+	return desc.String()
+}
@@ -0,0 +1,634 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// revisionAnnotation is set by the Deployment controller on each ReplicaSet
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// Workload identifies the controller that owns an alert's pods
+type Workload struct {
+	Kind      string // Deployment, StatefulSet, DaemonSet or Job
+	Name      string
+	Namespace string
+	HPA       string // HorizontalPodAutoscaler the workload was resolved from, if any
+}
+
+// String returns the workload as Kind/name
+func (w *Workload) String() string {
+	return fmt.Sprintf("%s/%s", w.Kind, w.Name)
+}
+
+// workloadLabels maps kube-state-metrics alert labels to workload kinds, in lookup order
+var workloadLabels = []struct {
+	label string
+	kind  string
+}{
+	{"deployment", "Deployment"},
+	{"statefulset", "StatefulSet"},
+	{"daemonset", "DaemonSet"},
+	{"job_name", "Job"},
+}
+
+// ResolveWorkload finds the workload named by an alert's labels. An
+// horizontalpodautoscaler label is resolved to its scale target. It returns
+// nil without error when the alert names no workload.
+func (c *Client) ResolveWorkload(ctx context.Context, namespace string, alertLabels map[string]string) (*Workload, error) {
+	for _, wl := range workloadLabels {
+		if name := alertLabels[wl.label]; name != "" {
+			return &Workload{Kind: wl.kind, Name: name, Namespace: namespace}, nil
+		}
+	}
+
+	hpaName := alertLabels["horizontalpodautoscaler"]
+	if hpaName == "" {
+		return nil, nil
+	}
+
+	hpa, err := c.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Get(ctx, hpaName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HPA: %w", err)
+	}
+
+	return &Workload{
+		Kind:      hpa.Spec.ScaleTargetRef.Kind,
+		Name:      hpa.Spec.ScaleTargetRef.Name,
+		Namespace: namespace,
+		HPA:       hpaName,
+	}, nil
+}
+
+// DescribeWorkload reports the rollout status of a workload
+func (c *Client) DescribeWorkload(ctx context.Context, w *Workload) (string, error) {
+	switch w.Kind {
+	case "Deployment":
+		return c.describeDeployment(ctx, w.Namespace, w.Name)
+	case "StatefulSet":
+		return c.describeStatefulSet(ctx, w.Namespace, w.Name)
+	case "DaemonSet":
+		return c.describeDaemonSet(ctx, w.Namespace, w.Name)
+	case "Job":
+		return c.describeJob(ctx, w.Namespace, w.Name)
+	default:
+		return "", fmt.Errorf("unsupported workload kind: %s", w.Kind)
+	}
+}
+
+// describeDeployment reports replica counts, rollout progress and conditions
+func (c *Client) describeDeployment(ctx context.Context, namespace, name string) (string, error) {
+	d, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get deployment: %w", err)
+	}
+
+	desired := int32(1)
+	if d.Spec.Replicas != nil {
+		desired = *d.Spec.Replicas
+	}
+
+	var desc strings.Builder
+	desc.WriteString(fmt.Sprintf("Deployment: %s\n", d.Name))
+	desc.WriteString(fmt.Sprintf("Strategy: %s\n", d.Spec.Strategy.Type))
+	desc.WriteString(fmt.Sprintf("Replicas: %d desired, %d updated, %d ready, %d available, %d unavailable\n",
+		desired, d.Status.UpdatedReplicas, d.Status.ReadyReplicas, d.Status.AvailableReplicas, d.Status.UnavailableReplicas))
+
+	switch {
+	case d.Spec.Paused:
+		desc.WriteString("Rollout: paused\n")
+	case d.Status.ObservedGeneration < d.Generation:
+		desc.WriteString("Rollout: waiting for the controller to observe the latest spec\n")
+	case d.Status.UpdatedReplicas < desired:
+		desc.WriteString(fmt.Sprintf("Rollout: in progress (%d of %d replicas updated)\n", d.Status.UpdatedReplicas, desired))
+	case d.Status.Replicas > d.Status.UpdatedReplicas:
+		desc.WriteString(fmt.Sprintf("Rollout: in progress (%d old replicas pending termination)\n", d.Status.Replicas-d.Status.UpdatedReplicas))
+	case d.Status.AvailableReplicas < d.Status.UpdatedReplicas:
+		desc.WriteString(fmt.Sprintf("Rollout: in progress (%d of %d updated replicas available)\n", d.Status.AvailableReplicas, d.Status.UpdatedReplicas))
+	default:
+		desc.WriteString("Rollout: complete\n")
+	}
+
+	desc.WriteString("Conditions:\n")
+	for _, cond := range d.Status.Conditions {
+		desc.WriteString(fmt.Sprintf("  - %s: %s (%s)\n", cond.Type, cond.Status, cond.Reason))
+		if cond.Message != "" {
+			desc.WriteString(fmt.Sprintf("    Message: %s\n", cond.Message))
+		}
+	}
+
+	return desc.String(), nil
+}
+
+// describeStatefulSet reports replica counts and revision status
+func (c *Client) describeStatefulSet(ctx context.Context, namespace, name string) (string, error) {
+	s, err := c.clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get statefulset: %w", err)
+	}
+
+	desired := int32(1)
+	if s.Spec.Replicas != nil {
+		desired = *s.Spec.Replicas
+	}
+
+	var desc strings.Builder
+	desc.WriteString(fmt.Sprintf("StatefulSet: %s\n", s.Name))
+	desc.WriteString(fmt.Sprintf("Update Strategy: %s\n", s.Spec.UpdateStrategy.Type))
+	desc.WriteString(fmt.Sprintf("Replicas: %d desired, %d current, %d updated, %d ready\n",
+		desired, s.Status.CurrentReplicas, s.Status.UpdatedReplicas, s.Status.ReadyReplicas))
+	desc.WriteString(fmt.Sprintf("Current Revision: %s\n", s.Status.CurrentRevision))
+	desc.WriteString(fmt.Sprintf("Update Revision: %s\n", s.Status.UpdateRevision))
+
+	if s.Status.UpdateRevision != "" && s.Status.CurrentRevision != s.Status.UpdateRevision {
+		desc.WriteString(fmt.Sprintf("Rollout: in progress (%d of %d replicas updated)\n", s.Status.UpdatedReplicas, desired))
+	} else if s.Status.ReadyReplicas < desired {
+		desc.WriteString(fmt.Sprintf("Rollout: complete, but only %d of %d replicas ready\n", s.Status.ReadyReplicas, desired))
+	} else {
+		desc.WriteString("Rollout: complete\n")
+	}
+
+	return desc.String(), nil
+}
+
+// describeDaemonSet reports scheduling and rollout status across nodes
+func (c *Client) describeDaemonSet(ctx context.Context, namespace, name string) (string, error) {
+	ds, err := c.clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get daemonset: %w", err)
+	}
+
+	var desc strings.Builder
+	desc.WriteString(fmt.Sprintf("DaemonSet: %s\n", ds.Name))
+	desc.WriteString(fmt.Sprintf("Update Strategy: %s\n", ds.Spec.UpdateStrategy.Type))
+	desc.WriteString(fmt.Sprintf("Nodes: %d desired, %d scheduled, %d updated, %d ready, %d available, %d unavailable, %d misscheduled\n",
+		ds.Status.DesiredNumberScheduled, ds.Status.CurrentNumberScheduled, ds.Status.UpdatedNumberScheduled,
+		ds.Status.NumberReady, ds.Status.NumberAvailable, ds.Status.NumberUnavailable, ds.Status.NumberMisscheduled))
+
+	if ds.Status.UpdatedNumberScheduled < ds.Status.DesiredNumberScheduled {
+		desc.WriteString(fmt.Sprintf("Rollout: in progress (%d of %d nodes updated)\n",
+			ds.Status.UpdatedNumberScheduled, ds.Status.DesiredNumberScheduled))
+	} else {
+		desc.WriteString("Rollout: complete\n")
+	}
+
+	return desc.String(), nil
+}
+
+// describeJob reports job progress and conditions
+func (c *Client) describeJob(ctx context.Context, namespace, name string) (string, error) {
+	job, err := c.clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get job: %w", err)
+	}
+
+	var desc strings.Builder
+	desc.WriteString(fmt.Sprintf("Job: %s\n", job.Name))
+	for _, ref := range job.OwnerReferences {
+		desc.WriteString(fmt.Sprintf("Owner: %s/%s\n", ref.Kind, ref.Name))
+	}
+	if job.Spec.Completions != nil {
+		desc.WriteString(fmt.Sprintf("Completions: %d\n", *job.Spec.Completions))
+	}
+	if job.Spec.BackoffLimit != nil {
+		desc.WriteString(fmt.Sprintf("Backoff Limit: %d\n", *job.Spec.BackoffLimit))
+	}
+	desc.WriteString(fmt.Sprintf("Pods: %d active, %d succeeded, %d failed\n",
+		job.Status.Active, job.Status.Succeeded, job.Status.Failed))
+	if job.Status.StartTime != nil {
+		desc.WriteString(fmt.Sprintf("Started: %s\n", job.Status.StartTime.Format(time.RFC3339)))
+	}
+	if job.Status.CompletionTime != nil {
+		desc.WriteString(fmt.Sprintf("Completed: %s\n", job.Status.CompletionTime.Format(time.RFC3339)))
+	}
+
+	desc.WriteString("Conditions:\n")
+	for _, cond := range job.Status.Conditions {
+		desc.WriteString(fmt.Sprintf("  - %s: %s (%s)\n", cond.Type, cond.Status, cond.Reason))
+		if cond.Message != "" {
+			desc.WriteString(fmt.Sprintf("    Message: %s\n", cond.Message))
+		}
+	}
+
+	return desc.String(), nil
+}
+
+// GetRolloutHistory lists a deployment's ReplicaSets by revision and diffs the
+// pod template of the latest revision against the previous one
+func (c *Client) GetRolloutHistory(ctx context.Context, namespace, deploymentName string) (string, error) {
+	d, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get deployment: %w", err)
+	}
+
+	owned, err := c.ownedReplicaSets(ctx, d)
+	if err != nil {
+		return "", err
+	}
+	if len(owned) == 0 {
+		return "No ReplicaSets found", nil
+	}
+
+	var desc strings.Builder
+	desc.WriteString("Revisions (newest first):\n")
+	for i, rs := range owned {
+		if i >= 5 {
+			desc.WriteString(fmt.Sprintf("  ... %d older revisions\n", len(owned)-i))
+			break
+		}
+		desc.WriteString(fmt.Sprintf("  - Revision %d: %s (%d replicas, %d ready, created %s ago) images=%s\n",
+			replicaSetRevision(&rs), rs.Name, rs.Status.Replicas, rs.Status.ReadyReplicas,
+			time.Since(rs.CreationTimestamp.Time).Round(time.Minute), strings.Join(templateImages(rs.Spec.Template), ",")))
+	}
+
+	if len(owned) >= 2 {
+		changes := diffPodTemplates(owned[1].Spec.Template, owned[0].Spec.Template)
+		desc.WriteString(fmt.Sprintf("\nChanges in revision %d (vs %d):\n",
+			replicaSetRevision(&owned[0]), replicaSetRevision(&owned[1])))
+		if len(changes) == 0 {
+			desc.WriteString("  No container changes (metadata or pod-level fields only)\n")
+		}
+		for _, change := range changes {
+			desc.WriteString(fmt.Sprintf("  - %s\n", change))
+		}
+	}
+
+	return desc.String(), nil
+}
+
+// ownedReplicaSets returns the ReplicaSets of a deployment, newest revision first
+func (c *Client) ownedReplicaSets(ctx context.Context, d *appsv1.Deployment) ([]appsv1.ReplicaSet, error) {
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deployment selector: %w", err)
+	}
+
+	rsList, err := c.clientset.AppsV1().ReplicaSets(d.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replicasets: %w", err)
+	}
+
+	var owned []appsv1.ReplicaSet
+	for _, rs := range rsList.Items {
+		if metav1.IsControlledBy(&rs, d) {
+			owned = append(owned, rs)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool {
+		return replicaSetRevision(&owned[i]) > replicaSetRevision(&owned[j])
+	})
+	return owned, nil
+}
+
+// replicaSetRevision returns the deployment revision of a ReplicaSet, or 0 if unknown
+func replicaSetRevision(rs *appsv1.ReplicaSet) int64 {
+	rev, err := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return rev
+}
+
+// templateImages returns the container images of a pod template
+func templateImages(tmpl corev1.PodTemplateSpec) []string {
+	images := make([]string, 0, len(tmpl.Spec.Containers))
+	for _, container := range tmpl.Spec.Containers {
+		images = append(images, container.Image)
+	}
+	return images
+}
+
+// diffPodTemplates describes container-level changes between two pod templates.
+// Env values from Secrets or ConfigMaps are compared by reference, not content.
+func diffPodTemplates(old, new corev1.PodTemplateSpec) []string {
+	var changes []string
+
+	oldContainers := make(map[string]corev1.Container)
+	for _, container := range old.Spec.Containers {
+		oldContainers[container.Name] = container
+	}
+
+	for _, container := range new.Spec.Containers {
+		prev, ok := oldContainers[container.Name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("container %s added (image %s)", container.Name, container.Image))
+			continue
+		}
+		delete(oldContainers, container.Name)
+
+		if prev.Image != container.Image {
+			changes = append(changes, fmt.Sprintf("%s: image %s → %s", container.Name, prev.Image, container.Image))
+		}
+		if strings.Join(prev.Command, " ") != strings.Join(container.Command, " ") {
+			changes = append(changes, fmt.Sprintf("%s: command %q → %q", container.Name, prev.Command, container.Command))
+		}
+		if strings.Join(prev.Args, " ") != strings.Join(container.Args, " ") {
+			changes = append(changes, fmt.Sprintf("%s: args %q → %q", container.Name, prev.Args, container.Args))
+		}
+		for _, resource := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			if a, b := prev.Resources.Requests[resource], container.Resources.Requests[resource]; a.Cmp(b) != 0 {
+				changes = append(changes, fmt.Sprintf("%s: %s request %s → %s", container.Name, resource, a.String(), b.String()))
+			}
+			if a, b := prev.Resources.Limits[resource], container.Resources.Limits[resource]; a.Cmp(b) != 0 {
+				changes = append(changes, fmt.Sprintf("%s: %s limit %s → %s", container.Name, resource, a.String(), b.String()))
+			}
+		}
+		changes = append(changes, diffEnv(container.Name, prev.Env, container.Env)...)
+	}
+
+	for name := range oldContainers {
+		changes = append(changes, fmt.Sprintf("container %s removed", name))
+	}
+
+	return changes
+}
+
+// diffEnv describes env var changes of a container
+func diffEnv(container string, old, new []corev1.EnvVar) []string {
+	var changes []string
+
+	oldEnv := make(map[string]string)
+	for _, env := range old {
+		oldEnv[env.Name] = envValue(env)
+	}
+	for _, env := range new {
+		value := envValue(env)
+		prev, ok := oldEnv[env.Name]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("%s: env %s added (%s)", container, env.Name, value))
+		case prev != value:
+			changes = append(changes, fmt.Sprintf("%s: env %s changed (%s → %s)", container, env.Name, prev, value))
+		}
+		delete(oldEnv, env.Name)
+	}
+	for name := range oldEnv {
+		changes = append(changes, fmt.Sprintf("%s: env %s removed", container, name))
+	}
+
+	return changes
+}
+
+// envValue renders an env var's value or source reference
+func envValue(env corev1.EnvVar) string {
+	if env.ValueFrom == nil {
+		return fmt.Sprintf("%q", env.Value)
+	}
+	switch {
+	case env.ValueFrom.SecretKeyRef != nil:
+		return fmt.Sprintf("secret %s/%s", env.ValueFrom.SecretKeyRef.Name, env.ValueFrom.SecretKeyRef.Key)
+	case env.ValueFrom.ConfigMapKeyRef != nil:
+		return fmt.Sprintf("configmap %s/%s", env.ValueFrom.ConfigMapKeyRef.Name, env.ValueFrom.ConfigMapKeyRef.Key)
+	case env.ValueFrom.FieldRef != nil:
+		return "field " + env.ValueFrom.FieldRef.FieldPath
+	default:
+		return "from source"
+	}
+}
+
+// DescribeHPA reports an autoscaler's bounds, current metrics and conditions
+func (c *Client) DescribeHPA(ctx context.Context, namespace, name string) (string, error) {
+	hpa, err := c.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get HPA: %w", err)
+	}
+
+	minReplicas := int32(1)
+	if hpa.Spec.MinReplicas != nil {
+		minReplicas = *hpa.Spec.MinReplicas
+	}
+
+	var desc strings.Builder
+	desc.WriteString(fmt.Sprintf("HPA: %s\n", hpa.Name))
+	desc.WriteString(fmt.Sprintf("Target: %s/%s\n", hpa.Spec.ScaleTargetRef.Kind, hpa.Spec.ScaleTargetRef.Name))
+	desc.WriteString(fmt.Sprintf("Replicas: %d current, %d desired (min %d, max %d)\n",
+		hpa.Status.CurrentReplicas, hpa.Status.DesiredReplicas, minReplicas, hpa.Spec.MaxReplicas))
+	if hpa.Status.CurrentReplicas >= hpa.Spec.MaxReplicas {
+		desc.WriteString("WARNING: running at max replicas\n")
+	}
+
+	desc.WriteString("Metrics (current / target):\n")
+	for i, metric := range hpa.Spec.Metrics {
+		current := "<unknown>"
+		if i < len(hpa.Status.CurrentMetrics) {
+			current = formatMetricStatus(hpa.Status.CurrentMetrics[i])
+		}
+		desc.WriteString(fmt.Sprintf("  - %s: %s / %s\n", metricName(metric), current, formatMetricTarget(metric)))
+	}
+
+	desc.WriteString("Conditions:\n")
+	for _, cond := range hpa.Status.Conditions {
+		desc.WriteString(fmt.Sprintf("  - %s: %s (%s)\n", cond.Type, cond.Status, cond.Reason))
+		if cond.Message != "" {
+			desc.WriteString(fmt.Sprintf("    Message: %s\n", cond.Message))
+		}
+	}
+
+	return desc.String(), nil
+}
+
+// FindRepresentativePods returns up to limit pods of a workload, most unhealthy
+// first. When all pods are healthy, the first healthy pod is returned so there
+// is still something to describe.
+func (c *Client) FindRepresentativePods(ctx context.Context, w *Workload, limit int) ([]string, error) {
+	selector, err := c.workloadSelector(ctx, w)
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := c.clientset.CoreV1().Pods(w.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, nil
+	}
+	c.cachePods(pods.Items)
+
+	type scored struct {
+		name  string
+		score int
+	}
+	ranked := make([]scored, 0, len(pods.Items))
+	for i := range pods.Items {
+		ranked = append(ranked, scored{name: pods.Items[i].Name, score: podUnhealthiness(&pods.Items[i])})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+
+	var names []string
+	for _, p := range ranked {
+		if len(names) >= limit || (p.score == 0 && len(names) > 0) {
+			break
+		}
+		names = append(names, p.name)
+	}
+
+	return names, nil
+}
+
+// workloadSelector returns the label selector for a workload's pods
+func (c *Client) workloadSelector(ctx context.Context, w *Workload) (labels.Selector, error) {
+	var selector *metav1.LabelSelector
+
+	switch w.Kind {
+	case "Deployment":
+		d, err := c.clientset.AppsV1().Deployments(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deployment: %w", err)
+		}
+		selector = d.Spec.Selector
+	case "StatefulSet":
+		s, err := c.clientset.AppsV1().StatefulSets(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get statefulset: %w", err)
+		}
+		selector = s.Spec.Selector
+	case "DaemonSet":
+		ds, err := c.clientset.AppsV1().DaemonSets(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get daemonset: %w", err)
+		}
+		selector = ds.Spec.Selector
+	case "Job":
+		job, err := c.clientset.BatchV1().Jobs(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get job: %w", err)
+		}
+		selector = job.Spec.Selector
+	default:
+		return nil, fmt.Errorf("unsupported workload kind: %s", w.Kind)
+	}
+
+	if selector == nil {
+		return nil, fmt.Errorf("%s has no pod selector", w)
+	}
+	return metav1.LabelSelectorAsSelector(selector)
+}
+
+// podUnhealthiness scores how unhealthy a pod looks; 0 means healthy
+func podUnhealthiness(pod *corev1.Pod) int {
+	score := 0
+
+	switch pod.Status.Phase {
+	case corev1.PodFailed:
+		score += 50
+	case corev1.PodPending:
+		score += 30
+	case corev1.PodUnknown:
+		score += 20
+	}
+
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		if cs.State.Waiting != nil {
+			switch cs.State.Waiting.Reason {
+			case "CrashLoopBackOff", "ImagePullBackOff", "ErrImagePull", "CreateContainerConfigError":
+				score += 100
+			default:
+				score += 10
+			}
+		}
+		if cs.State.Terminated != nil && cs.State.Terminated.ExitCode != 0 {
+			score += 40
+		}
+		if cs.LastTerminationState.Terminated != nil && cs.LastTerminationState.Terminated.Reason == "OOMKilled" {
+			score += 40
+		}
+		if pod.Status.Phase == corev1.PodRunning && !cs.Ready {
+			score += 20
+		}
+		score += int(cs.RestartCount)
+	}
+
+	return score
+}
+
+// metricName returns a readable name for an HPA metric
+func metricName(metric autoscalingv2.MetricSpec) string {
+	switch {
+	case metric.Resource != nil:
+		return string(metric.Resource.Name)
+	case metric.ContainerResource != nil:
+		return fmt.Sprintf("%s (container %s)", metric.ContainerResource.Name, metric.ContainerResource.Container)
+	case metric.Pods != nil:
+		return metric.Pods.Metric.Name
+	case metric.Object != nil:
+		return fmt.Sprintf("%s on %s/%s", metric.Object.Metric.Name, metric.Object.DescribedObject.Kind, metric.Object.DescribedObject.Name)
+	case metric.External != nil:
+		return metric.External.Metric.Name + " (external)"
+	default:
+		return string(metric.Type)
+	}
+}
+
+// formatMetricTarget renders an HPA metric target
+func formatMetricTarget(metric autoscalingv2.MetricSpec) string {
+	var target autoscalingv2.MetricTarget
+	switch {
+	case metric.Resource != nil:
+		target = metric.Resource.Target
+	case metric.ContainerResource != nil:
+		target = metric.ContainerResource.Target
+	case metric.Pods != nil:
+		target = metric.Pods.Target
+	case metric.Object != nil:
+		target = metric.Object.Target
+	case metric.External != nil:
+		target = metric.External.Target
+	}
+
+	switch {
+	case target.AverageUtilization != nil:
+		return fmt.Sprintf("%d%%", *target.AverageUtilization)
+	case target.AverageValue != nil:
+		return target.AverageValue.String() + " (avg)"
+	case target.Value != nil:
+		return target.Value.String()
+	default:
+		return "<unset>"
+	}
+}
+
+// formatMetricStatus renders the current value of an HPA metric
+func formatMetricStatus(status autoscalingv2.MetricStatus) string {
+	var current autoscalingv2.MetricValueStatus
+	switch {
+	case status.Resource != nil:
+		current = status.Resource.Current
+	case status.ContainerResource != nil:
+		current = status.ContainerResource.Current
+	case status.Pods != nil:
+		current = status.Pods.Current
+	case status.Object != nil:
+		current = status.Object.Current
+	case status.External != nil:
+		current = status.External.Current
+	}
+
+	switch {
+	case current.AverageUtilization != nil:
+		return fmt.Sprintf("%d%%", *current.AverageUtilization)
+	case current.AverageValue != nil:
+		return current.AverageValue.String() + " (avg)"
+	case current.Value != nil:
+		return current.Value.String()
+	default:
+		return "<unknown>"
+	}
+}
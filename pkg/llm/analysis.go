@@ -0,0 +1,312 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// ErrInvalidAnalysis is returned when a reply still doesn't match
+// AnalysisSchema after every attempt
+var ErrInvalidAnalysis = errors.New("invalid analysis")
+
+// Allowed values of the analysis enums
+var (
+	analysisConfidences = []string{"high", "medium", "low"}
+	analysisSeverities  = []string{"critical", "warning", "info"}
+)
+
+// AnalysisSchema returns the JSON schema of types.Analysis. It's sent as the
+// tool schema to providers that support one and quoted in the prompt for the
+// others; ParseAnalysis enforces it.
+func AnalysisSchema() map[string]interface{} {
+	text := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "description": description}
+	}
+	list := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":        "array",
+			"description": description,
+			"items":       map[string]interface{}{"type": "string"},
+			"minItems":    1,
+		}
+	}
+
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"root_cause": text("Most likely cause, based on the evidence"),
+			"confidence": map[string]interface{}{
+				"type":        "string",
+				"enum":        analysisConfidences,
+				"description": "How strongly the evidence supports the root cause",
+			},
+			"suggested_severity": map[string]interface{}{
+				"type":        "string",
+				"enum":        analysisSeverities,
+				"description": "Severity the alert deserves given the evidence, which may differ from its label",
+			},
+			"evidence": map[string]interface{}{
+				"type":        "array",
+				"description": "Quotes from the debug info supporting the root cause",
+				"minItems":    1,
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"section": text("Exact title of the debug info section quoted, without the === markers"),
+						"quote":   text("Line(s) copied verbatim from that section"),
+					},
+					"required":             []string{"section", "quote"},
+					"additionalProperties": false,
+				},
+			},
+			"impact": text("What's affected, based on the status and metrics provided"),
+			"alert_notes": map[string]interface{}{
+				"type":        "array",
+				"description": "Only when the debug info lists Incident Members: one note per member alert on how it relates to the root cause",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"alert": text("Alert name as listed in Incident Members"),
+						"note":  text("How this alert relates to the root cause"),
+					},
+					"required":             []string{"alert", "note"},
+					"additionalProperties": false,
+				},
+			},
+			"actions": list("Remediation steps, specific to the evidence, in order"),
+			"runbook_steps": map[string]interface{}{
+				"type":        "array",
+				"description": "Only when the debug info has a Runbook section: its diagnosis and remediation steps, each with whether the evidence calls for it",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"step":    text("The runbook step, as worded in the runbook"),
+						"applies": map[string]interface{}{"type": "boolean", "description": "Whether the evidence calls for this step"},
+						"reason":  text("Evidence for or against the step"),
+					},
+					"required":             []string{"step", "applies", "reason"},
+					"additionalProperties": false,
+				},
+			},
+			"prevention": list("Measures to prevent recurrence or improve detection"),
+			"proposed_actions": map[string]interface{}{
+				"type":        "array",
+				"description": "Only when the debug info has an Available Actions section: those of its actions the evidence clearly calls for, to be approved by an operator",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"action":   text("Action as listed in Available Actions, e.g. restart"),
+						"kind":     text("Kind of the target: Deployment, StatefulSet, DaemonSet, Node or Pod"),
+						"name":     text("Name of the target, as it appears in the debug info"),
+						"replicas": map[string]interface{}{"type": "integer", "description": "Target replicas, for scale only"},
+						"reason":   text("Evidence calling for the action"),
+					},
+					"required":             []string{"action", "kind", "name", "reason"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"root_cause", "confidence", "suggested_severity", "evidence", "impact", "actions", "prevention"},
+		"additionalProperties": false,
+	}
+}
+
+// BuildStructuredAnalysisPrompt creates the analysis prompt asking for a JSON
+// object matching AnalysisSchema, with evidence citing the given sections
+func BuildStructuredAnalysisPrompt(debugInfo string, pastFeedback []types.Feedback, sections []string) string {
+	schema, _ := json.MarshalIndent(AnalysisSchema(), "", "  ")
+
+	return fmt.Sprintf(`K8s SRE expert: Analyze this incident. Debug info is pre-filtered for this alert only.
+%s
+%s
+
+Reply with a single JSON object matching this schema, and nothing else:
+%s
+
+Each evidence "section" must be one of these debug info section titles:
+%s
+
+Ground everything in provided data.%s
+
+Debug Info:
+%s`, feedbackContext(pastFeedback), analysisRules, schema, sectionList(sections), feedbackHint(pastFeedback), debugInfo)
+}
+
+// BuildAnalysisConversionPrompt asks for a free-form analysis to be restated
+// as a JSON object matching AnalysisSchema
+func BuildAnalysisConversionPrompt(analysis string, sections []string) string {
+	schema, _ := json.MarshalIndent(AnalysisSchema(), "", "  ")
+
+	return fmt.Sprintf(`Restate this Kubernetes incident analysis as a single JSON object matching the schema below, and nothing else.
+Keep its content; don't add conclusions it doesn't make. Quotes must be copied verbatim from the analysis.
+
+Schema:
+%s
+
+Each evidence "section" must be one of these debug info section titles:
+%s
+
+Analysis:
+%s`, schema, sectionList(sections), analysis)
+}
+
+// sectionList renders section titles as a bullet list
+func sectionList(sections []string) string {
+	var b strings.Builder
+	for _, s := range sections {
+		b.WriteString("- " + s + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// AnalyzeStructured asks the provider for a JSON analysis, retrying with the
+// validation error until the reply is valid or maxAttempts is reached. On
+// failure the last raw reply is returned with the error so it can be shown.
+func AnalyzeStructured(ctx context.Context, provider Provider, prompt string, sections []string, maxAttempts int) (*types.Analysis, string, error) {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var raw string
+	var err error
+	attemptPrompt := prompt
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		raw, err = provider.GenerateJSON(ctx, attemptPrompt, AnalysisSchema())
+		if err != nil {
+			return nil, raw, err
+		}
+
+		var analysis *types.Analysis
+		analysis, err = ParseAnalysis(raw, sections)
+		if err == nil {
+			return analysis, raw, nil
+		}
+		log.Printf("Invalid structured analysis from %s (attempt %d/%d): %v", provider.Name(), attempt, maxAttempts, err)
+		attemptPrompt = fmt.Sprintf("%s\n\nYour previous reply was rejected: %v\nReply with only a JSON object that matches the schema.", prompt, err)
+	}
+	return nil, raw, fmt.Errorf("%w after %d attempts: %v", ErrInvalidAnalysis, maxAttempts, err)
+}
+
+// ParseAnalysis decodes a JSON analysis and validates it against
+// AnalysisSchema. Evidence must cite one of sections; cited titles are
+// normalized to the exact section title.
+func ParseAnalysis(text string, sections []string) (*types.Analysis, error) {
+	text = extractJSON(text)
+	if text == "" {
+		return nil, fmt.Errorf("reply contains no JSON object")
+	}
+
+	var analysis types.Analysis
+	decoder := json.NewDecoder(bytes.NewReader([]byte(text)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&analysis); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var problems []string
+	require := func(field, value string) {
+		if strings.TrimSpace(value) == "" {
+			problems = append(problems, field+" is required")
+		}
+	}
+	oneOf := func(field string, value *string, allowed []string) {
+		*value = strings.ToLower(strings.TrimSpace(*value))
+		for _, a := range allowed {
+			if *value == a {
+				return
+			}
+		}
+		problems = append(problems, fmt.Sprintf("%s must be one of %s, got %q", field, strings.Join(allowed, ", "), *value))
+	}
+
+	require("root_cause", analysis.RootCause)
+	require("impact", analysis.Impact)
+	oneOf("confidence", &analysis.Confidence, analysisConfidences)
+	oneOf("suggested_severity", &analysis.SuggestedSeverity, analysisSeverities)
+	if len(analysis.Actions) == 0 {
+		problems = append(problems, "actions needs at least one step")
+	}
+	if len(analysis.Prevention) == 0 {
+		problems = append(problems, "prevention needs at least one measure")
+	}
+	if len(analysis.Evidence) == 0 {
+		problems = append(problems, "evidence needs at least one quote")
+	}
+	for i, n := range analysis.AlertNotes {
+		if strings.TrimSpace(n.Alert) == "" || strings.TrimSpace(n.Note) == "" {
+			problems = append(problems, fmt.Sprintf("alert_notes[%d] needs both alert and note", i))
+		}
+	}
+	for i, s := range analysis.RunbookSteps {
+		if strings.TrimSpace(s.Step) == "" || strings.TrimSpace(s.Reason) == "" {
+			problems = append(problems, fmt.Sprintf("runbook_steps[%d] needs both step and reason", i))
+		}
+	}
+	for i, a := range analysis.ProposedActions {
+		if strings.TrimSpace(a.Action) == "" || strings.TrimSpace(a.Kind) == "" || strings.TrimSpace(a.Name) == "" {
+			problems = append(problems, fmt.Sprintf("proposed_actions[%d] needs action, kind and name", i))
+		}
+	}
+	for i := range analysis.Evidence {
+		e := &analysis.Evidence[i]
+		if strings.TrimSpace(e.Quote) == "" {
+			problems = append(problems, fmt.Sprintf("evidence[%d].quote is required", i))
+		}
+		if title, ok := matchSection(e.Section, sections); ok {
+			e.Section = title
+		} else {
+			problems = append(problems, fmt.Sprintf("evidence[%d].section %q is not a debug info section title", i, e.Section))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return &analysis, nil
+}
+
+// matchSection finds the section a citation refers to: its exact title, case
+// aside, or the only title it's the leading words of (e.g. "Pod Logs" for
+// "Pod Logs: web-1", but not "Tool Call 1" for "Tool Call 12: ..."). Any
+// citation matches when sections is nil.
+func matchSection(cited string, sections []string) (string, bool) {
+	if sections == nil {
+		return cited, true
+	}
+	cited = strings.TrimSpace(strings.Trim(strings.TrimSpace(cited), "="))
+	if cited == "" {
+		return "", false
+	}
+
+	var prefixed []string
+	for _, s := range sections {
+		if strings.EqualFold(s, cited) {
+			return s, true
+		}
+		if len(s) > len(cited) && strings.EqualFold(s[:len(cited)], cited) && strings.ContainsRune(": (", rune(s[len(cited)])) {
+			prefixed = append(prefixed, s)
+		}
+	}
+	if len(prefixed) == 1 {
+		return prefixed[0], true
+	}
+	return "", false
+}
+
+// extractJSON returns the outermost JSON object of a reply, dropping Markdown
+// fences or prose around it
+func extractJSON(text string) string {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return ""
+	}
+	return text[start : end+1]
+}
@@ -2,6 +2,7 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -17,6 +18,7 @@ type AnthropicProvider struct {
 	apiKey string
 	model  string
 	client *http.Client
+	contextWindow
 }
 
 // NewAnthropicProvider creates a new Anthropic provider
@@ -25,9 +27,10 @@ func NewAnthropicProvider(apiKey, model string) *AnthropicProvider {
 		model = "claude-3-5-sonnet-20241022" // Default to Claude 3.5 Sonnet
 	}
 	return &AnthropicProvider{
-		apiKey: apiKey,
-		model:  model,
-		client: &http.Client{},
+		apiKey:        apiKey,
+		model:         model,
+		client:        &http.Client{},
+		contextWindow: contextWindow{ModelContextWindow(model)},
 	}
 }
 
@@ -81,7 +84,7 @@ type anthropicResponse struct {
 }
 
 // CategorizeAlert asks Claude to categorize the alert
-func (p *AnthropicProvider) CategorizeAlert(alert types.Alert) (string, error) {
+func (p *AnthropicProvider) CategorizeAlert(ctx context.Context, alert types.Alert) (string, error) {
 	alertName := alert.Labels["alertname"]
 	severity := alert.Labels["severity"]
 	summary := alert.Annotations["summary"]
@@ -131,7 +134,7 @@ Category:`, alertName, severity, summary, description)
 		return "unknown", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "unknown", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -193,15 +196,18 @@ Category:`, alertName, severity, summary, description)
 }
 
 // AnalyzeDebugInfoStream performs streaming analysis
-func (p *AnthropicProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedback []types.Feedback, updateFn func(chunk string)) error {
-	prompt := BuildAnalysisPrompt(debugInfo, pastFeedback)
+func (p *AnthropicProvider) AnalyzeDebugInfoStream(ctx context.Context, debugInfo string, pastFeedback []types.Feedback, updateFn func(chunk string)) error {
+	return p.StreamPrompt(ctx, BuildAnalysisPrompt(debugInfo, pastFeedback), updateFn)
+}
 
+// StreamPrompt streams the response to a prompt
+func (p *AnthropicProvider) StreamPrompt(ctx context.Context, prompt string, updateFn func(chunk string)) error {
 	reqBody := anthropicRequest{
 		Model: p.model,
 		Messages: []anthropicMessage{
 			{Role: "user", Content: prompt},
 		},
-		MaxTokens: 4096,
+		MaxTokens: AnalysisMaxTokens,
 		Stream:    true,
 	}
 
@@ -210,7 +216,7 @@ func (p *AnthropicProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedbac
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -271,10 +277,10 @@ func (p *AnthropicProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedbac
 }
 
 // AnalyzeDebugInfo performs non-streaming analysis
-func (p *AnthropicProvider) AnalyzeDebugInfo(debugInfo string, pastFeedback []types.Feedback) (string, error) {
+func (p *AnthropicProvider) AnalyzeDebugInfo(ctx context.Context, debugInfo string, pastFeedback []types.Feedback) (string, error) {
 	var fullResponse strings.Builder
 
-	err := p.AnalyzeDebugInfoStream(debugInfo, pastFeedback, func(chunk string) {
+	err := p.AnalyzeDebugInfoStream(ctx, debugInfo, pastFeedback, func(chunk string) {
 		fullResponse.WriteString(chunk)
 	})
 
@@ -284,3 +290,203 @@ func (p *AnthropicProvider) AnalyzeDebugInfo(debugInfo string, pastFeedback []ty
 
 	return fullResponse.String(), nil
 }
+
+// Anthropic tool-use structures (also used by Claude models on Bedrock)
+type anthropicToolBlock struct {
+	Type      string                 `json:"type"` // "text", "tool_use" or "tool_result"
+	Text      string                 `json:"text,omitempty"`
+	ID        string                 `json:"id,omitempty"`
+	Name      string                 `json:"name,omitempty"`
+	Input     map[string]interface{} `json:"input,omitempty"`
+	ToolUseID string                 `json:"tool_use_id,omitempty"`
+	Content   string                 `json:"content,omitempty"`
+}
+
+type anthropicToolMessage struct {
+	Role    string               `json:"role"`
+	Content []anthropicToolBlock `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type anthropicToolRequest struct {
+	Model            string                 `json:"model,omitempty"`
+	AnthropicVersion string                 `json:"anthropic_version,omitempty"` // Bedrock only
+	System           string                 `json:"system,omitempty"`
+	Messages         []anthropicToolMessage `json:"messages"`
+	Tools            []anthropicTool        `json:"tools,omitempty"`
+	ToolChoice       *anthropicToolChoice   `json:"tool_choice,omitempty"`
+	MaxTokens        int                    `json:"max_tokens"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"` // "auto", "any" or "tool"
+	Name string `json:"name,omitempty"`
+}
+
+type anthropicToolResponse struct {
+	Content    []anthropicToolBlock `json:"content"`
+	StopReason string               `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// buildAnthropicToolRequest converts a tool-calling conversation to the Anthropic
+// messages format. Consecutive tool results and user text are merged into one user turn.
+func buildAnthropicToolRequest(system string, messages []Message, tools []ToolDefinition) anthropicToolRequest {
+	req := anthropicToolRequest{
+		System:    system,
+		MaxTokens: AnalysisMaxTokens,
+	}
+
+	for _, tool := range tools {
+		req.Tools = append(req.Tools, anthropicTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.Parameters,
+		})
+	}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case "tool":
+			block := anthropicToolBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content}
+			if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == "user" &&
+				req.Messages[n-1].Content[0].Type == "tool_result" {
+				req.Messages[n-1].Content = append(req.Messages[n-1].Content, block)
+			} else {
+				req.Messages = append(req.Messages, anthropicToolMessage{Role: "user", Content: []anthropicToolBlock{block}})
+			}
+		case "assistant":
+			var blocks []anthropicToolBlock
+			if msg.Content != "" {
+				blocks = append(blocks, anthropicToolBlock{Type: "text", Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				input := call.Arguments
+				if input == nil {
+					input = map[string]interface{}{}
+				}
+				blocks = append(blocks, anthropicToolBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: input})
+			}
+			req.Messages = append(req.Messages, anthropicToolMessage{Role: "assistant", Content: blocks})
+		default:
+			block := anthropicToolBlock{Type: "text", Text: msg.Content}
+			if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == "user" {
+				req.Messages[n-1].Content = append(req.Messages[n-1].Content, block)
+			} else {
+				req.Messages = append(req.Messages, anthropicToolMessage{Role: "user", Content: []anthropicToolBlock{block}})
+			}
+		}
+	}
+
+	return req
+}
+
+// jsonToolName is the tool Claude is forced to call to return a JSON object
+const jsonToolName = "submit_analysis"
+
+// buildAnthropicJSONRequest asks for a JSON object matching schema by forcing
+// a call to a tool that takes it as input
+func buildAnthropicJSONRequest(prompt string, schema map[string]interface{}) anthropicToolRequest {
+	req := buildAnthropicToolRequest("", []Message{{Role: "user", Content: prompt}}, []ToolDefinition{{
+		Name:        jsonToolName,
+		Description: "Submit the incident analysis",
+		Parameters:  schema,
+	}})
+	req.ToolChoice = &anthropicToolChoice{Type: "tool", Name: jsonToolName}
+	return req
+}
+
+// anthropicJSONOutput returns the input of the forced tool call as JSON
+func anthropicJSONOutput(resp *ChatResponse) (string, error) {
+	for _, call := range resp.ToolCalls {
+		if call.Name == jsonToolName {
+			data, err := json.Marshal(call.Arguments)
+			if err != nil {
+				return "", fmt.Errorf("failed to marshal tool input: %w", err)
+			}
+			return string(data), nil
+		}
+	}
+	// No tool call: hand back the text so validation can report it
+	return resp.Content, nil
+}
+
+// parseAnthropicToolResponse extracts text and tool calls from a response
+func parseAnthropicToolResponse(resp anthropicToolResponse) *ChatResponse {
+	result := &ChatResponse{
+		InputTokens:  resp.Usage.InputTokens,
+		OutputTokens: resp.Usage.OutputTokens,
+	}
+
+	var text strings.Builder
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			result.ToolCalls = append(result.ToolCalls, ToolCall{ID: block.ID, Name: block.Name, Arguments: block.Input})
+		}
+	}
+	result.Content = text.String()
+
+	return result
+}
+
+// ChatWithTools sends a tool-calling conversation to Claude
+func (p *AnthropicProvider) ChatWithTools(ctx context.Context, system string, messages []Message, tools []ToolDefinition) (*ChatResponse, error) {
+	return p.sendToolRequest(ctx, buildAnthropicToolRequest(system, messages, tools))
+}
+
+// GenerateJSON asks Claude for a JSON object matching schema through a forced tool call
+func (p *AnthropicProvider) GenerateJSON(ctx context.Context, prompt string, schema map[string]interface{}) (string, error) {
+	resp, err := p.sendToolRequest(ctx, buildAnthropicJSONRequest(prompt, schema))
+	if err != nil {
+		return "", err
+	}
+	return anthropicJSONOutput(resp)
+}
+
+// sendToolRequest sends a tool request to the Messages API
+func (p *AnthropicProvider) sendToolRequest(ctx context.Context, reqBody anthropicToolRequest) (*ChatResponse, error) {
+	reqBody.Model = p.model
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Anthropic API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Anthropic API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var anthropicResp anthropicToolResponse
+	if err := json.NewDecoder(resp.Body).Decode(&anthropicResp); err != nil {
+		return nil, fmt.Errorf("failed to decode Anthropic response: %w", err)
+	}
+
+	return parseAnthropicToolResponse(anthropicResp), nil
+}
@@ -19,6 +19,7 @@ type BedrockProvider struct {
 	client *bedrockruntime.Client
 	model  string
 	region string
+	contextWindow
 }
 
 // NewBedrockProvider creates a new AWS Bedrock provider
@@ -39,9 +40,10 @@ func NewBedrockProvider(region, model string) (*BedrockProvider, error) {
 	client := bedrockruntime.NewFromConfig(cfg)
 
 	return &BedrockProvider{
-		client: client,
-		model:  model,
-		region: region,
+		client:        client,
+		model:         model,
+		region:        region,
+		contextWindow: contextWindow{ModelContextWindow(model)},
 	}, nil
 }
 
@@ -76,7 +78,7 @@ type bedrockClaudeResponse struct {
 }
 
 // CategorizeAlert asks Bedrock to categorize the alert
-func (p *BedrockProvider) CategorizeAlert(alert types.Alert) (string, error) {
+func (p *BedrockProvider) CategorizeAlert(ctx context.Context, alert types.Alert) (string, error) {
 	alertName := alert.Labels["alertname"]
 	severity := alert.Labels["severity"]
 	summary := alert.Annotations["summary"]
@@ -126,7 +128,6 @@ Category:`, alertName, severity, summary, description)
 		return "unknown", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	ctx := context.Background()
 	resp, err := p.client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
 		ModelId:     aws.String(p.model),
 		ContentType: aws.String("application/json"),
@@ -180,14 +181,17 @@ Category:`, alertName, severity, summary, description)
 }
 
 // AnalyzeDebugInfoStream performs streaming analysis
-func (p *BedrockProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedback []types.Feedback, updateFn func(chunk string)) error {
-	prompt := BuildAnalysisPrompt(debugInfo, pastFeedback)
+func (p *BedrockProvider) AnalyzeDebugInfoStream(ctx context.Context, debugInfo string, pastFeedback []types.Feedback, updateFn func(chunk string)) error {
+	return p.StreamPrompt(ctx, BuildAnalysisPrompt(debugInfo, pastFeedback), updateFn)
+}
 
+// StreamPrompt streams the response to a prompt
+func (p *BedrockProvider) StreamPrompt(ctx context.Context, prompt string, updateFn func(chunk string)) error {
 	reqBody := bedrockClaudeRequest{
 		Messages: []bedrockClaudeMessage{
 			{Role: "user", Content: prompt},
 		},
-		MaxTokens:        4096,
+		MaxTokens:        AnalysisMaxTokens,
 		Temperature:      0.0,
 		AnthropicVersion: "bedrock-2023-05-31",
 	}
@@ -197,8 +201,6 @@ func (p *BedrockProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedback
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	ctx := context.Background()
-
 	// Use InvokeModelWithResponseStream for streaming
 	resp, err := p.client.InvokeModelWithResponseStream(ctx, &bedrockruntime.InvokeModelWithResponseStreamInput{
 		ModelId:     aws.String(p.model),
@@ -215,6 +217,17 @@ func (p *BedrockProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedback
 	stream := resp.GetStream()
 	defer stream.Close()
 
+	// Close the stream when ctx is cancelled so the event loop below unblocks
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			stream.Close()
+		case <-done:
+		}
+	}()
+
 	for event := range stream.Events() {
 		switch v := event.(type) {
 		case *brtypes.ResponseStreamMemberChunk:
@@ -242,6 +255,10 @@ func (p *BedrockProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedback
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("stream cancelled: %w", err)
+	}
+
 	if err := stream.Err(); err != nil {
 		return fmt.Errorf("stream error: %w", err)
 	}
@@ -250,10 +267,10 @@ func (p *BedrockProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedback
 }
 
 // AnalyzeDebugInfo performs non-streaming analysis
-func (p *BedrockProvider) AnalyzeDebugInfo(debugInfo string, pastFeedback []types.Feedback) (string, error) {
+func (p *BedrockProvider) AnalyzeDebugInfo(ctx context.Context, debugInfo string, pastFeedback []types.Feedback) (string, error) {
 	var fullResponse strings.Builder
 
-	err := p.AnalyzeDebugInfoStream(debugInfo, pastFeedback, func(chunk string) {
+	err := p.AnalyzeDebugInfoStream(ctx, debugInfo, pastFeedback, func(chunk string) {
 		fullResponse.WriteString(chunk)
 	})
 
@@ -263,3 +280,45 @@ func (p *BedrockProvider) AnalyzeDebugInfo(debugInfo string, pastFeedback []type
 
 	return fullResponse.String(), nil
 }
+
+// ChatWithTools sends a tool-calling conversation to a Claude model on Bedrock
+func (p *BedrockProvider) ChatWithTools(ctx context.Context, system string, messages []Message, tools []ToolDefinition) (*ChatResponse, error) {
+	return p.invokeTools(ctx, buildAnthropicToolRequest(system, messages, tools))
+}
+
+// GenerateJSON asks a Claude model on Bedrock for a JSON object matching
+// schema through a forced tool call
+func (p *BedrockProvider) GenerateJSON(ctx context.Context, prompt string, schema map[string]interface{}) (string, error) {
+	resp, err := p.invokeTools(ctx, buildAnthropicJSONRequest(prompt, schema))
+	if err != nil {
+		return "", err
+	}
+	return anthropicJSONOutput(resp)
+}
+
+// invokeTools sends a tool request to a Claude model on Bedrock
+func (p *BedrockProvider) invokeTools(ctx context.Context, reqBody anthropicToolRequest) (*ChatResponse, error) {
+	reqBody.AnthropicVersion = "bedrock-2023-05-31"
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := p.client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(p.model),
+		ContentType: aws.String("application/json"),
+		Accept:      aws.String("application/json"),
+		Body:        jsonData,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Bedrock API: %w", err)
+	}
+
+	var bedrockResp anthropicToolResponse
+	if err := json.Unmarshal(resp.Body, &bedrockResp); err != nil {
+		return nil, fmt.Errorf("failed to decode Bedrock response: %w", err)
+	}
+
+	return parseAnthropicToolResponse(bedrockResp), nil
+}
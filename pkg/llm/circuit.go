@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitBreaker stops calls to a provider after repeated failures.
+// Closed: calls allowed. Open: calls rejected until the cooldown expires.
+// Half-open: one trial call is allowed; success closes, failure re-opens.
+type circuitBreaker struct {
+	threshold int           // Consecutive failures before opening
+	cooldown  time.Duration // How long the breaker stays open
+	failures  int
+	openUntil time.Time
+	trial     bool // A half-open trial call is in flight
+	mu        sync.Mutex
+}
+
+// newCircuitBreaker creates a closed circuit breaker
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		threshold = 3
+	}
+	if cooldown <= 0 {
+		cooldown = 5 * time.Minute
+	}
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// allow reports whether a call may be made now
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openUntil.IsZero() {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.trial {
+		return false
+	}
+
+	// Cooldown expired: let a single trial call through
+	b.trial = true
+	return true
+}
+
+// success records a successful call and closes the breaker
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.trial = false
+}
+
+// failure records a failed call and returns true if the breaker opened
+func (b *circuitBreaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.trial || b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+		b.trial = false
+		return true
+	}
+	return false
+}
+
+// release ends a half-open trial that didn't test the provider, e.g. a call
+// it doesn't support or that was canceled, so the next call can be the trial
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+// trip opens the breaker immediately (e.g. on rate limiting)
+func (b *circuitBreaker) trip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.openUntil = time.Now().Add(b.cooldown)
+	b.trial = false
+}
+
+// state returns a short description for logging
+func (b *circuitBreaker) state() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case b.openUntil.IsZero():
+		return "closed"
+	case time.Now().Before(b.openUntil):
+		return "open"
+	default:
+		return "half-open"
+	}
+}
@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"log"
+	"strings"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// AnalysisMaxTokens is the output budget requested for an analysis
+const AnalysisMaxTokens = 4096
+
+// defaultContextWindow is assumed for models missing from the table below
+const defaultContextWindow = 8192
+
+// defaultOllamaContextWindow is the context Ollama is asked to allocate (num_ctx).
+// Ollama silently drops the start of longer prompts, and a larger context costs
+// memory on the host, so it's not taken from the model's maximum.
+const defaultOllamaContextWindow = 8192
+
+// modelContextWindows maps model name fragments to their context window in
+// tokens. Entries are matched in order, so specific fragments come first.
+var modelContextWindows = []struct {
+	fragment string
+	tokens   int
+}{
+	// OpenAI
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-1106", 128000},
+	{"gpt-4-0125", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	// Anthropic, directly or through Bedrock
+	{"claude-2", 100000},
+	{"claude", 200000},
+	// Google
+	{"gemini-1.5-pro", 2097152},
+	{"gemini-1.5", 1048576},
+	{"gemini-2", 1048576},
+	{"gemini", 32760},
+	// Other Bedrock models
+	{"titan-text-lite", 4096},
+	{"titan-text-express", 8192},
+	{"titan-text-premier", 32000},
+	{"llama3-1", 128000},
+	{"llama3", 8192},
+	{"mistral", 32000},
+}
+
+// ModelContextWindow returns the context window of a model in tokens
+func ModelContextWindow(model string) int {
+	model = strings.ToLower(model)
+	for _, m := range modelContextWindows {
+		if strings.Contains(model, m.fragment) {
+			return m.tokens
+		}
+	}
+	return defaultContextWindow
+}
+
+// contextWindow is embedded by providers to report their context window
+type contextWindow struct {
+	tokens int
+}
+
+// ContextWindow returns the number of tokens the model accepts, prompt and output combined
+func (w *contextWindow) ContextWindow() int { return w.tokens }
+
+func (w *contextWindow) setContextWindow(tokens int) { w.tokens = tokens }
+
+// CharsPerToken is the ratio EstimateTokens assumes. Logs, YAML and IDs
+// tokenize worse than prose, so it's lower than the usual 4 to stay on the
+// safe side.
+const CharsPerToken = 3
+
+// EstimateTokens approximates the token count of text
+func EstimateTokens(text string) int {
+	return (len(text) + CharsPerToken - 1) / CharsPerToken
+}
+
+// sectionListTokens is reserved for the section titles listed in a structured
+// analysis prompt
+const sectionListTokens = 200
+
+// DebugInfoBudget returns how many tokens of debug info fit in the provider's
+// analysis prompt, after the instructions, past feedback and the output budget.
+// Structured prompts also carry the JSON schema and the section titles.
+func DebugInfoBudget(provider Provider, pastFeedback []types.Feedback, structured bool) int {
+	window := provider.ContextWindow()
+	overhead := EstimateTokens(BuildAnalysisPrompt("", pastFeedback))
+	if structured {
+		overhead = EstimateTokens(BuildStructuredAnalysisPrompt("", pastFeedback, nil)) + sectionListTokens
+	}
+
+	// Keep 10% of the window as a margin for the estimate's error
+	return remaining(provider, window*9/10-AnalysisMaxTokens-overhead, "analysis")
+}
+
+// FollowUpBudget returns how many tokens of debug info fit in the provider's
+// follow-up prompt, after the analysis, the previous questions and the output
+// budget
+func FollowUpBudget(provider Provider, analysis string, history []types.FollowUp, question string) int {
+	window := provider.ContextWindow()
+	overhead := EstimateTokens(BuildFollowUpPrompt("", analysis, history, question))
+
+	return remaining(provider, window*9/10-AnalysisMaxTokens-overhead, "follow-up")
+}
+
+// remaining returns what's left of the window for debug info. Raising it
+// would overflow the window, and Ollama would silently drop the start of the
+// prompt, so when nothing is left the evidence is cut down as far as it goes.
+func remaining(provider Provider, budget int, prompt string) int {
+	if budget <= 0 {
+		log.Printf("ERROR: the %s prompt and %d output tokens leave no room for debug info in the %d-token context window of %s; raise LLM_CONTEXT_WINDOW",
+			prompt, AnalysisMaxTokens, provider.ContextWindow(), provider.Name())
+		return 0
+	}
+	return budget
+}
@@ -3,6 +3,7 @@ package llm
 import (
 	"fmt"
 	"log"
+	"strings"
 )
 
 // Factory creates LLM providers based on configuration
@@ -15,10 +16,87 @@ func NewFactory(config Config) *Factory {
 	return &Factory{config: config}
 }
 
-// CreateProvider creates the configured LLM provider
+// Override returns a factory for another provider (or fallback chain), with
+// model replacing the configured model of every provider it names. Empty
+// arguments keep the configured values.
+func (f *Factory) Override(provider, model string) *Factory {
+	config := f.config
+	if provider != "" {
+		config.Provider = provider
+	}
+	if model != "" {
+		for _, name := range strings.Split(config.Provider, ",") {
+			switch strings.TrimSpace(name) {
+			case "ollama", "":
+				config.OllamaModel = model
+			case "openai":
+				config.OpenAIModel = model
+			case "anthropic", "claude":
+				config.AnthropicModel = model
+			case "gemini", "google":
+				config.GeminiModel = model
+			case "bedrock", "aws":
+				config.BedrockModel = model
+			}
+		}
+	}
+	return &Factory{config: config}
+}
+
+// CreateProvider creates the configured LLM provider. When Provider lists
+// several comma-separated names (e.g. "anthropic,bedrock,ollama"), the
+// providers are wrapped in a FallbackProvider tried in that order.
 func (f *Factory) CreateProvider() (Provider, error) {
-	switch f.config.Provider {
-	case "ollama":
+	names := strings.Split(f.config.Provider, ",")
+	if len(names) == 1 {
+		return f.createProvider(strings.TrimSpace(names[0]))
+	}
+
+	var providers []Provider
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		provider, err := f.createProvider(name)
+		if err != nil {
+			log.Printf("WARNING: Skipping provider %q in fallback chain: %v", name, err)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+
+	switch len(providers) {
+	case 0:
+		return nil, fmt.Errorf("no usable provider in fallback chain: %s", f.config.Provider)
+	case 1:
+		return providers[0], nil
+	}
+
+	chain := NewFallbackProvider(providers, f.config.BreakerThreshold, f.config.BreakerCooldown)
+	log.Printf("Using provider fallback chain: %s", chain.Name())
+	return chain, nil
+}
+
+// createProvider creates a single LLM provider by name, applying the context
+// window override if configured
+func (f *Factory) createProvider(name string) (Provider, error) {
+	provider, err := f.newProvider(name)
+	if err != nil {
+		return nil, err
+	}
+	if f.config.ContextWindow > 0 {
+		if w, ok := provider.(interface{ setContextWindow(int) }); ok {
+			w.setContextWindow(f.config.ContextWindow)
+		}
+	}
+	return provider, nil
+}
+
+// newProvider creates a single LLM provider by name
+func (f *Factory) newProvider(name string) (Provider, error) {
+	switch name {
+	case "ollama", "":
 		if f.config.OllamaURL == "" {
 			return nil, fmt.Errorf("ollama URL not configured")
 		}
@@ -69,6 +147,6 @@ func (f *Factory) CreateProvider() (Provider, error) {
 		return NewBedrockProvider(f.config.BedrockRegion, f.config.BedrockModel)
 
 	default:
-		return nil, fmt.Errorf("unknown provider: %s (supported: ollama, openai, anthropic, gemini, bedrock)", f.config.Provider)
+		return nil, fmt.Errorf("unknown provider: %s (supported: ollama, openai, anthropic, gemini, bedrock)", name)
 	}
 }
@@ -0,0 +1,254 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// FallbackProvider tries an ordered list of providers, failing over to the
+// next one on errors or rate limits. Each provider has its own circuit breaker
+// so a provider that keeps failing is skipped until its cooldown expires.
+type FallbackProvider struct {
+	providers []Provider
+	breakers  []*circuitBreaker
+}
+
+// NewFallbackProvider creates a provider chain tried in the given order
+func NewFallbackProvider(providers []Provider, breakerThreshold int, breakerCooldown time.Duration) *FallbackProvider {
+	breakers := make([]*circuitBreaker, len(providers))
+	for i := range providers {
+		breakers[i] = newCircuitBreaker(breakerThreshold, breakerCooldown)
+	}
+	return &FallbackProvider{
+		providers: providers,
+		breakers:  breakers,
+	}
+}
+
+// Name returns the provider chain (for logging)
+func (p *FallbackProvider) Name() string {
+	names := make([]string, len(p.providers))
+	for i, provider := range p.providers {
+		names[i] = provider.Name()
+	}
+	return strings.Join(names, " → ")
+}
+
+// ContextWindow returns the smallest context window in the chain, so a prompt
+// that fits one provider fits them all
+func (p *FallbackProvider) ContextWindow() int {
+	smallest := 0
+	for _, provider := range p.providers {
+		if w := provider.ContextWindow(); smallest == 0 || w < smallest {
+			smallest = w
+		}
+	}
+	return smallest
+}
+
+// CategorizeAlert categorizes the alert with the first available provider
+func (p *FallbackProvider) CategorizeAlert(ctx context.Context, alert types.Alert) (string, error) {
+	var category string
+	err := p.try(ctx, "categorize", func(provider Provider) error {
+		var err error
+		category, err = provider.CategorizeAlert(ctx, alert)
+		return err
+	})
+	if err != nil {
+		return "unknown", err
+	}
+	return category, nil
+}
+
+// AnalyzeDebugInfoStream streams the analysis from the first available provider
+func (p *FallbackProvider) AnalyzeDebugInfoStream(ctx context.Context, debugInfo string, pastFeedback []types.Feedback, updateFn func(chunk string)) error {
+	return p.stream(ctx, "analyze", updateFn, func(provider Provider, updateFn func(chunk string)) error {
+		return provider.AnalyzeDebugInfoStream(ctx, debugInfo, pastFeedback, updateFn)
+	})
+}
+
+// StreamPrompt streams the response to a prompt from the first available provider
+func (p *FallbackProvider) StreamPrompt(ctx context.Context, prompt string, updateFn func(chunk string)) error {
+	return p.stream(ctx, "prompt", updateFn, func(provider Provider, updateFn func(chunk string)) error {
+		return provider.StreamPrompt(ctx, prompt, updateFn)
+	})
+}
+
+// stream tries fn with each provider. If a provider fails after streaming part
+// of its response, a notice is streamed before the next provider's output so
+// readers can tell the two apart.
+func (p *FallbackProvider) stream(ctx context.Context, phase string, updateFn func(chunk string), fn func(provider Provider, updateFn func(chunk string)) error) error {
+	streamed := false
+	var failedName string
+
+	return p.try(ctx, phase, func(provider Provider) error {
+		if streamed {
+			updateFn(fmt.Sprintf("\n\n_⚠️ %s failed mid-response, continuing with %s_\n\n", failedName, provider.Name()))
+			streamed = false
+		}
+
+		err := fn(provider, func(chunk string) {
+			streamed = true
+			updateFn(chunk)
+		})
+		if err != nil {
+			failedName = provider.Name()
+		}
+		return err
+	})
+}
+
+// AnalyzeDebugInfo performs non-streaming analysis with the first available provider
+func (p *FallbackProvider) AnalyzeDebugInfo(ctx context.Context, debugInfo string, pastFeedback []types.Feedback) (string, error) {
+	var analysis string
+	err := p.try(ctx, "analyze", func(provider Provider) error {
+		var err error
+		analysis, err = provider.AnalyzeDebugInfo(ctx, debugInfo, pastFeedback)
+		return err
+	})
+	return analysis, err
+}
+
+// GenerateJSON asks the first available provider for a JSON object
+func (p *FallbackProvider) GenerateJSON(ctx context.Context, prompt string, schema map[string]interface{}) (string, error) {
+	var output string
+	err := p.try(ctx, "analyze", func(provider Provider) error {
+		var err error
+		output, err = provider.GenerateJSON(ctx, prompt, schema)
+		return err
+	})
+	return output, err
+}
+
+// ChatWithTools sends a tool-calling conversation to the first available provider
+// that supports function calling. When none of them could take it, the
+// conversation is answered single-shot, without tool calls, so an
+// investigation degrades to a plain analysis instead of failing.
+func (p *FallbackProvider) ChatWithTools(ctx context.Context, system string, messages []Message, tools []ToolDefinition) (*ChatResponse, error) {
+	var resp *ChatResponse
+	supported := false
+	err := p.try(ctx, "investigate", func(provider Provider) error {
+		toolProvider, ok := provider.(ToolCallingProvider)
+		if !ok {
+			return errNoToolSupport
+		}
+		supported = true
+		var err error
+		resp, err = toolProvider.ChatWithTools(ctx, system, messages, tools)
+		return err
+	})
+	if err == nil || supported || ctx.Err() != nil {
+		return resp, err
+	}
+
+	log.Printf("No provider in %s could call tools, answering single-shot", p.Name())
+	return p.chat(ctx, messages)
+}
+
+// chat answers a tool-calling conversation with a single prompt made of its
+// user and tool turns. The system prompt is left out since it offers tools.
+func (p *FallbackProvider) chat(ctx context.Context, messages []Message) (*ChatResponse, error) {
+	var prompt []string
+	for _, m := range messages {
+		if m.Role != "assistant" && m.Content != "" {
+			prompt = append(prompt, m.Content)
+		}
+	}
+
+	var answer strings.Builder
+	err := p.stream(ctx, "investigate", func(chunk string) {
+		answer.WriteString(chunk)
+	}, func(provider Provider, updateFn func(chunk string)) error {
+		return provider.StreamPrompt(ctx, strings.Join(prompt, "\n\n"), updateFn)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ChatResponse{Content: answer.String()}, nil
+}
+
+// try calls fn for each provider in order until one succeeds
+func (p *FallbackProvider) try(ctx context.Context, phase string, fn func(provider Provider) error) error {
+	var errs []string
+
+	for i, provider := range p.providers {
+		breaker := p.breakers[i]
+		if !breaker.allow() {
+			log.Printf("Skipping %s for %s: circuit breaker open", provider.Name(), phase)
+			errs = append(errs, fmt.Sprintf("%s: circuit open", provider.Name()))
+			continue
+		}
+
+		err := fn(provider)
+		if errors.Is(err, errNoToolSupport) {
+			// Not a provider failure; don't count it against the breaker
+			breaker.release()
+			errs = append(errs, fmt.Sprintf("%s: %v", provider.Name(), err))
+			continue
+		}
+		if err == nil {
+			breaker.success()
+			recordProvider(ctx, provider.Name())
+			return nil
+		}
+
+		errs = append(errs, fmt.Sprintf("%s: %v", provider.Name(), err))
+
+		// The phase deadline covers the whole chain, so running out of it
+		// isn't the provider's failure, and there's no point trying the next
+		if ctx.Err() != nil {
+			breaker.release()
+			log.Printf("%s interrupted during %s: %v", provider.Name(), phase, ctx.Err())
+			return fmt.Errorf("%s: %w", strings.Join(errs, "; "), ctx.Err())
+		}
+
+		if isRateLimited(err) {
+			breaker.trip()
+			log.Printf("%s rate limited during %s, circuit opened: %v", provider.Name(), phase, err)
+		} else if breaker.failure() {
+			log.Printf("%s failed during %s, circuit opened after repeated failures: %v", provider.Name(), phase, err)
+		} else {
+			log.Printf("%s failed during %s (breaker %s): %v", provider.Name(), phase, breaker.state(), err)
+		}
+	}
+
+	return fmt.Errorf("all providers failed: %s", strings.Join(errs, "; "))
+}
+
+// errNoToolSupport is returned for providers without native function calling
+var errNoToolSupport = errors.New("function calling not supported")
+
+// isRateLimited reports whether an error looks like a provider rate limit
+func isRateLimited(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "status 429") ||
+		strings.Contains(msg, "ThrottlingException") ||
+		strings.Contains(msg, "rate_limit") ||
+		strings.Contains(msg, "RESOURCE_EXHAUSTED")
+}
+
+// providerUsedKey is the context key for recording which provider served a call
+type providerUsedKey struct{}
+
+// WithProviderTracking returns a context that records which provider in a
+// FallbackProvider chain served the call, and a function to read it back.
+// The function returns "" if no call succeeded or the provider isn't a chain.
+func WithProviderTracking(ctx context.Context) (context.Context, func() string) {
+	used := new(string)
+	return context.WithValue(ctx, providerUsedKey{}, used), func() string {
+		return *used
+	}
+}
+
+// recordProvider stores the provider name in a tracking context, if any
+func recordProvider(ctx context.Context, name string) {
+	if used, ok := ctx.Value(providerUsedKey{}).(*string); ok {
+		*used = name
+	}
+}
@@ -0,0 +1,253 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// fakeProvider answers every call with answer, or fails with err
+type fakeProvider struct {
+	name   string
+	answer string
+	err    error
+	calls  int
+	prompt string // Last prompt streamed
+}
+
+func (f *fakeProvider) CategorizeAlert(ctx context.Context, alert types.Alert) (string, error) {
+	f.calls++
+	return f.answer, f.err
+}
+
+func (f *fakeProvider) AnalyzeDebugInfoStream(ctx context.Context, debugInfo string, pastFeedback []types.Feedback, updateFn func(chunk string)) error {
+	return f.StreamPrompt(ctx, debugInfo, updateFn)
+}
+
+func (f *fakeProvider) AnalyzeDebugInfo(ctx context.Context, debugInfo string, pastFeedback []types.Feedback) (string, error) {
+	f.calls++
+	return f.answer, f.err
+}
+
+func (f *fakeProvider) StreamPrompt(ctx context.Context, prompt string, updateFn func(chunk string)) error {
+	f.calls++
+	f.prompt = prompt
+	if f.err != nil {
+		return f.err
+	}
+	updateFn(f.answer)
+	return nil
+}
+
+func (f *fakeProvider) GenerateJSON(ctx context.Context, prompt string, schema map[string]interface{}) (string, error) {
+	f.calls++
+	return f.answer, f.err
+}
+
+func (f *fakeProvider) Name() string { return f.name }
+
+func (f *fakeProvider) ContextWindow() int { return 8192 }
+
+// fakeToolProvider is a fakeProvider supporting function calling
+type fakeToolProvider struct {
+	fakeProvider
+}
+
+func (f *fakeToolProvider) ChatWithTools(ctx context.Context, system string, messages []Message, tools []ToolDefinition) (*ChatResponse, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ChatResponse{Content: f.answer, ToolCalls: []ToolCall{{ID: "1", Name: "get_pod_logs"}}}, nil
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	b := newCircuitBreaker(2, time.Minute)
+
+	if b.failure() {
+		t.Error("breaker opened below the threshold")
+	}
+	if !b.failure() {
+		t.Error("breaker didn't open at the threshold")
+	}
+	if b.allow() || b.state() != "open" {
+		t.Errorf("open breaker allowed a call (state %s)", b.state())
+	}
+
+	// Once the cooldown expires a single trial call goes through
+	b.openUntil = time.Now().Add(-time.Second)
+	if !b.allow() {
+		t.Fatal("half-open breaker rejected the trial call")
+	}
+	if b.allow() {
+		t.Error("half-open breaker allowed a second call during the trial")
+	}
+
+	// A failed trial re-opens the breaker at once
+	if !b.failure() {
+		t.Error("failed trial didn't re-open the breaker")
+	}
+	if b.allow() {
+		t.Error("re-opened breaker allowed a call")
+	}
+
+	// A released trial lets the next call be the trial
+	b.openUntil = time.Now().Add(-time.Second)
+	b.allow()
+	b.release()
+	if !b.allow() {
+		t.Error("released trial blocked the next call")
+	}
+
+	// A successful trial closes the breaker
+	b.success()
+	if !b.allow() || !b.allow() || b.state() != "closed" {
+		t.Errorf("breaker didn't close after a successful trial (state %s)", b.state())
+	}
+
+	b.trip()
+	if b.allow() {
+		t.Error("tripped breaker allowed a call")
+	}
+}
+
+func TestFallbackProviderFailsOver(t *testing.T) {
+	tests := []struct {
+		name     string
+		firstErr error
+		wantOpen bool
+	}{
+		{name: "error", firstErr: errors.New("status 500"), wantOpen: false},
+		{name: "rate limit", firstErr: errors.New("status 429: rate_limit_error"), wantOpen: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := &fakeProvider{name: "first", err: tt.firstErr}
+			second := &fakeProvider{name: "second", answer: "analysis"}
+			chain := NewFallbackProvider([]Provider{first, second}, 3, time.Minute)
+
+			ctx, providerUsed := WithProviderTracking(context.Background())
+			got, err := chain.AnalyzeDebugInfo(ctx, "debug info", nil)
+			if err != nil || got != "analysis" {
+				t.Fatalf("AnalyzeDebugInfo() = %q, %v, want the second provider's analysis", got, err)
+			}
+			if providerUsed() != "second" {
+				t.Errorf("provider used = %q, want second", providerUsed())
+			}
+			if open := chain.breakers[0].state() == "open"; open != tt.wantOpen {
+				t.Errorf("first breaker open = %v, want %v", open, tt.wantOpen)
+			}
+		})
+	}
+}
+
+func TestFallbackProviderSkipsOpenBreaker(t *testing.T) {
+	first := &fakeProvider{name: "first", err: errors.New("status 500")}
+	second := &fakeProvider{name: "second", answer: "other"}
+	chain := NewFallbackProvider([]Provider{first, second}, 2, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if _, err := chain.CategorizeAlert(context.Background(), types.Alert{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if first.calls != 2 {
+		t.Errorf("first provider called %d times, want 2 before its breaker opened", first.calls)
+	}
+
+	// The provider recovers after the cooldown
+	first.err, first.answer = nil, "network"
+	chain.breakers[0].openUntil = time.Now().Add(-time.Second)
+	if got, _ := chain.CategorizeAlert(context.Background(), types.Alert{}); got != "network" {
+		t.Errorf("CategorizeAlert() = %q, want the recovered provider's answer", got)
+	}
+	if chain.breakers[0].state() != "closed" {
+		t.Errorf("breaker is %s after a successful trial, want closed", chain.breakers[0].state())
+	}
+}
+
+func TestFallbackProviderCancellationIsNotAFailure(t *testing.T) {
+	first := &fakeProvider{name: "first", err: context.Canceled}
+	second := &fakeProvider{name: "second", answer: "analysis"}
+	chain := NewFallbackProvider([]Provider{first, second}, 1, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := chain.AnalyzeDebugInfo(ctx, "debug info", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("AnalyzeDebugInfo() error = %v, want context.Canceled", err)
+	}
+	if second.calls != 0 {
+		t.Error("the next provider was tried after the deadline")
+	}
+	if chain.breakers[0].state() != "closed" {
+		t.Errorf("breaker is %s after a cancellation, want closed", chain.breakers[0].state())
+	}
+}
+
+func TestChatWithTools(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Content: "Analyze this alert"},
+	}
+
+	t.Run("tool provider", func(t *testing.T) {
+		plain := &fakeProvider{name: "ollama", answer: "plain"}
+		tools := &fakeToolProvider{fakeProvider{name: "openai", answer: "checking logs"}}
+		chain := NewFallbackProvider([]Provider{plain, tools}, 3, time.Minute)
+
+		resp, err := chain.ChatWithTools(context.Background(), "system", messages, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.ToolCalls) != 1 || plain.calls != 0 {
+			t.Errorf("ChatWithTools() = %+v, want the tool provider's calls", resp)
+		}
+	})
+
+	t.Run("no tool support answers single-shot", func(t *testing.T) {
+		first := &fakeProvider{name: "ollama", answer: "single-shot analysis"}
+		second := &fakeProvider{name: "ollama-backup", answer: "unused"}
+		chain := NewFallbackProvider([]Provider{first, second}, 3, time.Minute)
+
+		resp, err := chain.ChatWithTools(context.Background(), "system", messages, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Content != "single-shot analysis" || len(resp.ToolCalls) != 0 {
+			t.Errorf("ChatWithTools() = %+v, want the single-shot answer without tool calls", resp)
+		}
+		if first.prompt != "Analyze this alert" {
+			t.Errorf("prompt = %q, want the conversation without the system prompt", first.prompt)
+		}
+	})
+
+	t.Run("tool provider failure isn't answered single-shot", func(t *testing.T) {
+		plain := &fakeProvider{name: "ollama", answer: "plain"}
+		tools := &fakeToolProvider{fakeProvider{name: "openai", err: errors.New("status 500")}}
+		chain := NewFallbackProvider([]Provider{tools, plain}, 3, time.Minute)
+
+		if _, err := chain.ChatWithTools(context.Background(), "system", messages, nil); err == nil || !strings.Contains(err.Error(), "status 500") {
+			t.Errorf("ChatWithTools() error = %v, want the tool provider's error", err)
+		}
+		if plain.calls != 0 {
+			t.Error("the failed investigation was answered single-shot")
+		}
+	})
+
+	t.Run("no tool support releases the half-open trial", func(t *testing.T) {
+		plain := &fakeProvider{name: "ollama", answer: "plain"}
+		chain := NewFallbackProvider([]Provider{plain}, 1, time.Minute)
+		chain.breakers[0].trip()
+		chain.breakers[0].openUntil = time.Now().Add(-time.Second)
+
+		if _, err := chain.ChatWithTools(context.Background(), "system", messages, nil); err != nil {
+			t.Fatal(err)
+		}
+		if chain.breakers[0].state() != "closed" {
+			t.Errorf("breaker is %s, want closed by the single-shot answer", chain.breakers[0].state())
+		}
+	})
+}
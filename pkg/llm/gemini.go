@@ -2,6 +2,7 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -17,6 +18,7 @@ type GeminiProvider struct {
 	apiKey string
 	model  string
 	client *http.Client
+	contextWindow
 }
 
 // NewGeminiProvider creates a new Gemini provider
@@ -25,9 +27,10 @@ func NewGeminiProvider(apiKey, model string) *GeminiProvider {
 		model = "gemini-1.5-pro" // Default to Gemini 1.5 Pro
 	}
 	return &GeminiProvider{
-		apiKey: apiKey,
-		model:  model,
-		client: &http.Client{},
+		apiKey:        apiKey,
+		model:         model,
+		client:        &http.Client{},
+		contextWindow: contextWindow{ModelContextWindow(model)},
 	}
 }
 
@@ -52,8 +55,9 @@ type geminiRequest struct {
 }
 
 type geminiGenerationConfig struct {
-	Temperature     float64 `json:"temperature,omitempty"`
-	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
+	Temperature      float64 `json:"temperature,omitempty"`
+	MaxOutputTokens  int     `json:"maxOutputTokens,omitempty"`
+	ResponseMimeType string  `json:"responseMimeType,omitempty"`
 }
 
 type geminiCandidate struct {
@@ -65,7 +69,7 @@ type geminiResponse struct {
 }
 
 // CategorizeAlert asks Gemini to categorize the alert
-func (p *GeminiProvider) CategorizeAlert(alert types.Alert) (string, error) {
+func (p *GeminiProvider) CategorizeAlert(ctx context.Context, alert types.Alert) (string, error) {
 	alertName := alert.Labels["alertname"]
 	severity := alert.Labels["severity"]
 	summary := alert.Annotations["summary"]
@@ -118,7 +122,13 @@ Category:`, alertName, severity, summary, description)
 	}
 
 	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", p.model, p.apiKey)
-	resp, err := p.client.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "unknown", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
 	if err != nil {
 		return "unknown", fmt.Errorf("failed to call Gemini API: %w", err)
 	}
@@ -171,9 +181,12 @@ Category:`, alertName, severity, summary, description)
 }
 
 // AnalyzeDebugInfoStream performs streaming analysis
-func (p *GeminiProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedback []types.Feedback, updateFn func(chunk string)) error {
-	prompt := BuildAnalysisPrompt(debugInfo, pastFeedback)
+func (p *GeminiProvider) AnalyzeDebugInfoStream(ctx context.Context, debugInfo string, pastFeedback []types.Feedback, updateFn func(chunk string)) error {
+	return p.StreamPrompt(ctx, BuildAnalysisPrompt(debugInfo, pastFeedback), updateFn)
+}
 
+// StreamPrompt streams the response to a prompt
+func (p *GeminiProvider) StreamPrompt(ctx context.Context, prompt string, updateFn func(chunk string)) error {
 	reqBody := geminiRequest{
 		Contents: []geminiContent{
 			{
@@ -181,7 +194,7 @@ func (p *GeminiProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedback [
 			},
 		},
 		GenerationConfig: &geminiGenerationConfig{
-			MaxOutputTokens: 4096,
+			MaxOutputTokens: AnalysisMaxTokens,
 		},
 	}
 
@@ -192,7 +205,13 @@ func (p *GeminiProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedback [
 
 	// Use streamGenerateContent endpoint for streaming
 	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:streamGenerateContent?key=%s&alt=sse", p.model, p.apiKey)
-	resp, err := p.client.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to call Gemini API: %w", err)
 	}
@@ -241,10 +260,10 @@ func (p *GeminiProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedback [
 }
 
 // AnalyzeDebugInfo performs non-streaming analysis
-func (p *GeminiProvider) AnalyzeDebugInfo(debugInfo string, pastFeedback []types.Feedback) (string, error) {
+func (p *GeminiProvider) AnalyzeDebugInfo(ctx context.Context, debugInfo string, pastFeedback []types.Feedback) (string, error) {
 	var fullResponse strings.Builder
 
-	err := p.AnalyzeDebugInfoStream(debugInfo, pastFeedback, func(chunk string) {
+	err := p.AnalyzeDebugInfoStream(ctx, debugInfo, pastFeedback, func(chunk string) {
 		fullResponse.WriteString(chunk)
 	})
 
@@ -254,3 +273,209 @@ func (p *GeminiProvider) AnalyzeDebugInfo(debugInfo string, pastFeedback []types
 
 	return fullResponse.String(), nil
 }
+
+// GenerateJSON asks Gemini for a JSON object. The schema is described in the
+// prompt: Gemini's responseSchema supports only part of JSON Schema.
+func (p *GeminiProvider) GenerateJSON(ctx context.Context, prompt string, schema map[string]interface{}) (string, error) {
+	reqBody := geminiRequest{
+		Contents: []geminiContent{
+			{
+				Parts: []geminiPart{{Text: prompt}},
+			},
+		},
+		GenerationConfig: &geminiGenerationConfig{
+			MaxOutputTokens:  AnalysisMaxTokens,
+			ResponseMimeType: "application/json",
+		},
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", p.model, p.apiKey)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call Gemini API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("Gemini API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var geminiResp geminiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&geminiResp); err != nil {
+		return "", fmt.Errorf("failed to decode Gemini response: %w", err)
+	}
+
+	if len(geminiResp.Candidates) == 0 {
+		return "", fmt.Errorf("Gemini returned no candidates")
+	}
+
+	var text strings.Builder
+	for _, part := range geminiResp.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+	return text.String(), nil
+}
+
+// Gemini function-calling structures
+type geminiFunctionCall struct {
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+type geminiToolPart struct {
+	Text             string                  `json:"text,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiToolContent struct {
+	Role  string           `json:"role"`
+	Parts []geminiToolPart `json:"parts"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiToolRequest struct {
+	SystemInstruction *geminiToolContent  `json:"systemInstruction,omitempty"`
+	Contents          []geminiToolContent `json:"contents"`
+	Tools             []geminiTool        `json:"tools,omitempty"`
+}
+
+type geminiToolResponse struct {
+	Candidates []struct {
+		Content geminiToolContent `json:"content"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// ChatWithTools sends a tool-calling conversation to Gemini.
+// Gemini doesn't assign call IDs, so calls are numbered by position.
+func (p *GeminiProvider) ChatWithTools(ctx context.Context, system string, messages []Message, tools []ToolDefinition) (*ChatResponse, error) {
+	var reqBody geminiToolRequest
+
+	if system != "" {
+		reqBody.SystemInstruction = &geminiToolContent{Role: "user", Parts: []geminiToolPart{{Text: system}}}
+	}
+	for _, msg := range messages {
+		switch msg.Role {
+		case "tool":
+			part := geminiToolPart{FunctionResponse: &geminiFunctionResponse{
+				Name:     msg.ToolName,
+				Response: map[string]interface{}{"content": msg.Content},
+			}}
+			// Responses to parallel calls go in a single turn
+			if n := len(reqBody.Contents); n > 0 && reqBody.Contents[n-1].Parts[0].FunctionResponse != nil {
+				reqBody.Contents[n-1].Parts = append(reqBody.Contents[n-1].Parts, part)
+			} else {
+				reqBody.Contents = append(reqBody.Contents, geminiToolContent{Role: "user", Parts: []geminiToolPart{part}})
+			}
+		case "assistant":
+			var parts []geminiToolPart
+			if msg.Content != "" {
+				parts = append(parts, geminiToolPart{Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				parts = append(parts, geminiToolPart{FunctionCall: &geminiFunctionCall{Name: call.Name, Args: call.Arguments}})
+			}
+			reqBody.Contents = append(reqBody.Contents, geminiToolContent{Role: "model", Parts: parts})
+		default:
+			part := geminiToolPart{Text: msg.Content}
+			if n := len(reqBody.Contents); n > 0 && reqBody.Contents[n-1].Role == "user" {
+				reqBody.Contents[n-1].Parts = append(reqBody.Contents[n-1].Parts, part)
+			} else {
+				reqBody.Contents = append(reqBody.Contents, geminiToolContent{Role: "user", Parts: []geminiToolPart{part}})
+			}
+		}
+	}
+	if len(tools) > 0 {
+		var decls []geminiFunctionDeclaration
+		for _, tool := range tools {
+			decls = append(decls, geminiFunctionDeclaration{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			})
+		}
+		reqBody.Tools = []geminiTool{{FunctionDeclarations: decls}}
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", p.model, p.apiKey)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Gemini API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Gemini API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var geminiResp geminiToolResponse
+	if err := json.NewDecoder(resp.Body).Decode(&geminiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode Gemini response: %w", err)
+	}
+
+	if len(geminiResp.Candidates) == 0 {
+		return nil, fmt.Errorf("Gemini returned no candidates")
+	}
+
+	result := &ChatResponse{
+		InputTokens:  geminiResp.UsageMetadata.PromptTokenCount,
+		OutputTokens: geminiResp.UsageMetadata.CandidatesTokenCount,
+	}
+	var text strings.Builder
+	for i, part := range geminiResp.Candidates[0].Content.Parts {
+		if part.FunctionCall != nil {
+			result.ToolCalls = append(result.ToolCalls, ToolCall{
+				ID:        fmt.Sprintf("call_%d_%s", i, part.FunctionCall.Name),
+				Name:      part.FunctionCall.Name,
+				Arguments: part.FunctionCall.Args,
+			})
+		} else {
+			text.WriteString(part.Text)
+		}
+	}
+	result.Content = text.String()
+
+	return result, nil
+}
@@ -2,6 +2,7 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -17,6 +18,7 @@ type OllamaProvider struct {
 	baseURL string
 	model   string
 	client  *http.Client
+	contextWindow
 }
 
 // NewOllamaProvider creates a new Ollama provider
@@ -25,9 +27,10 @@ func NewOllamaProvider(baseURL, model string) *OllamaProvider {
 		model = "llama3" // Default model
 	}
 	return &OllamaProvider{
-		baseURL: baseURL,
-		model:   model,
-		client:  &http.Client{},
+		baseURL:       baseURL,
+		model:         model,
+		client:        &http.Client{},
+		contextWindow: contextWindow{defaultOllamaContextWindow},
 	}
 }
 
@@ -37,7 +40,7 @@ func (p *OllamaProvider) Name() string {
 }
 
 // CategorizeAlert asks Ollama to categorize the alert
-func (p *OllamaProvider) CategorizeAlert(alert types.Alert) (string, error) {
+func (p *OllamaProvider) CategorizeAlert(ctx context.Context, alert types.Alert) (string, error) {
 	alertName := alert.Labels["alertname"]
 	severity := alert.Labels["severity"]
 	summary := alert.Annotations["summary"]
@@ -84,7 +87,13 @@ Category:`, alertName, severity, summary, description)
 		return "unknown", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := p.client.Post(p.baseURL+"/api/generate", "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/generate", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "unknown", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
 	if err != nil {
 		return "unknown", fmt.Errorf("failed to call Ollama API: %w", err)
 	}
@@ -133,13 +142,19 @@ Category:`, alertName, severity, summary, description)
 }
 
 // AnalyzeDebugInfoStream performs streaming analysis
-func (p *OllamaProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedback []types.Feedback, updateFn func(chunk string)) error {
-	prompt := BuildAnalysisPrompt(debugInfo, pastFeedback)
+func (p *OllamaProvider) AnalyzeDebugInfoStream(ctx context.Context, debugInfo string, pastFeedback []types.Feedback, updateFn func(chunk string)) error {
+	return p.StreamPrompt(ctx, BuildAnalysisPrompt(debugInfo, pastFeedback), updateFn)
+}
 
+// StreamPrompt streams the response to a prompt
+func (p *OllamaProvider) StreamPrompt(ctx context.Context, prompt string, updateFn func(chunk string)) error {
 	reqBody := types.OllamaRequest{
 		Model:  p.model,
 		Prompt: prompt,
 		Stream: true, // Enable streaming
+		// Ollama's default context is smaller than the prompt budget and it
+		// silently drops what doesn't fit
+		Options: map[string]interface{}{"num_ctx": p.ContextWindow()},
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -147,7 +162,13 @@ func (p *OllamaProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedback [
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := p.client.Post(p.baseURL+"/api/generate", "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/generate", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to call Ollama API: %w", err)
 	}
@@ -184,10 +205,10 @@ func (p *OllamaProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedback [
 }
 
 // AnalyzeDebugInfo performs non-streaming analysis
-func (p *OllamaProvider) AnalyzeDebugInfo(debugInfo string, pastFeedback []types.Feedback) (string, error) {
+func (p *OllamaProvider) AnalyzeDebugInfo(ctx context.Context, debugInfo string, pastFeedback []types.Feedback) (string, error) {
 	var fullResponse strings.Builder
 
-	err := p.AnalyzeDebugInfoStream(debugInfo, pastFeedback, func(chunk string) {
+	err := p.AnalyzeDebugInfoStream(ctx, debugInfo, pastFeedback, func(chunk string) {
 		fullResponse.WriteString(chunk)
 	})
 
@@ -197,3 +218,44 @@ func (p *OllamaProvider) AnalyzeDebugInfo(debugInfo string, pastFeedback []types
 
 	return fullResponse.String(), nil
 }
+
+// GenerateJSON asks Ollama for a JSON object in JSON mode. The schema is
+// described in the prompt for compatibility with older Ollama versions.
+func (p *OllamaProvider) GenerateJSON(ctx context.Context, prompt string, schema map[string]interface{}) (string, error) {
+	reqBody := types.OllamaRequest{
+		Model:   p.model,
+		Prompt:  prompt,
+		Stream:  false,
+		Format:  "json",
+		Options: map[string]interface{}{"num_ctx": p.ContextWindow()},
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/generate", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call Ollama API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("Ollama API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var ollamaResp types.OllamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
+		return "", fmt.Errorf("failed to decode Ollama response: %w", err)
+	}
+
+	return ollamaResp.Response, nil
+}
@@ -2,6 +2,7 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -17,6 +18,7 @@ type OpenAIProvider struct {
 	apiKey string
 	model  string
 	client *http.Client
+	contextWindow
 }
 
 // NewOpenAIProvider creates a new OpenAI provider
@@ -25,9 +27,10 @@ func NewOpenAIProvider(apiKey, model string) *OpenAIProvider {
 		model = "gpt-4-turbo-preview" // Default to GPT-4 Turbo
 	}
 	return &OpenAIProvider{
-		apiKey: apiKey,
-		model:  model,
-		client: &http.Client{},
+		apiKey:        apiKey,
+		model:         model,
+		client:        &http.Client{},
+		contextWindow: contextWindow{ModelContextWindow(model)},
 	}
 }
 
@@ -43,9 +46,14 @@ type openAIMessage struct {
 }
 
 type openAIRequest struct {
-	Model    string          `json:"model"`
-	Messages []openAIMessage `json:"messages"`
-	Stream   bool            `json:"stream"`
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	Stream         bool                  `json:"stream"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+type openAIResponseFormat struct {
+	Type string `json:"type"` // "json_object" for JSON mode
 }
 
 type openAIChoice struct {
@@ -63,7 +71,7 @@ type openAIResponse struct {
 }
 
 // CategorizeAlert asks OpenAI to categorize the alert
-func (p *OpenAIProvider) CategorizeAlert(alert types.Alert) (string, error) {
+func (p *OpenAIProvider) CategorizeAlert(ctx context.Context, alert types.Alert) (string, error) {
 	alertName := alert.Labels["alertname"]
 	severity := alert.Labels["severity"]
 	summary := alert.Annotations["summary"]
@@ -112,7 +120,7 @@ Category:`, alertName, severity, summary, description)
 		return "unknown", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "unknown", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -173,9 +181,12 @@ Category:`, alertName, severity, summary, description)
 }
 
 // AnalyzeDebugInfoStream performs streaming analysis
-func (p *OpenAIProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedback []types.Feedback, updateFn func(chunk string)) error {
-	prompt := BuildAnalysisPrompt(debugInfo, pastFeedback)
+func (p *OpenAIProvider) AnalyzeDebugInfoStream(ctx context.Context, debugInfo string, pastFeedback []types.Feedback, updateFn func(chunk string)) error {
+	return p.StreamPrompt(ctx, BuildAnalysisPrompt(debugInfo, pastFeedback), updateFn)
+}
 
+// StreamPrompt streams the response to a prompt
+func (p *OpenAIProvider) StreamPrompt(ctx context.Context, prompt string, updateFn func(chunk string)) error {
 	reqBody := openAIRequest{
 		Model: p.model,
 		Messages: []openAIMessage{
@@ -190,7 +201,7 @@ func (p *OpenAIProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedback [
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -256,10 +267,10 @@ func (p *OpenAIProvider) AnalyzeDebugInfoStream(debugInfo string, pastFeedback [
 }
 
 // AnalyzeDebugInfo performs non-streaming analysis
-func (p *OpenAIProvider) AnalyzeDebugInfo(debugInfo string, pastFeedback []types.Feedback) (string, error) {
+func (p *OpenAIProvider) AnalyzeDebugInfo(ctx context.Context, debugInfo string, pastFeedback []types.Feedback) (string, error) {
 	var fullResponse strings.Builder
 
-	err := p.AnalyzeDebugInfoStream(debugInfo, pastFeedback, func(chunk string) {
+	err := p.AnalyzeDebugInfoStream(ctx, debugInfo, pastFeedback, func(chunk string) {
 		fullResponse.WriteString(chunk)
 	})
 
@@ -269,3 +280,175 @@ func (p *OpenAIProvider) AnalyzeDebugInfo(debugInfo string, pastFeedback []types
 
 	return fullResponse.String(), nil
 }
+
+// GenerateJSON asks OpenAI for a JSON object in JSON mode. JSON mode doesn't
+// take a schema, so the prompt must describe it.
+func (p *OpenAIProvider) GenerateJSON(ctx context.Context, prompt string, schema map[string]interface{}) (string, error) {
+	reqBody := openAIRequest{
+		Model: p.model,
+		Messages: []openAIMessage{
+			{Role: "system", Content: "You are an expert Kubernetes SRE analyzing production incidents. Reply with JSON only."},
+			{Role: "user", Content: prompt},
+		},
+		ResponseFormat: &openAIResponseFormat{Type: "json_object"},
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call OpenAI API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("OpenAI API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var openAIResp openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&openAIResp); err != nil {
+		return "", fmt.Errorf("failed to decode OpenAI response: %w", err)
+	}
+
+	if len(openAIResp.Choices) == 0 {
+		return "", fmt.Errorf("OpenAI returned no choices")
+	}
+
+	return openAIResp.Choices[0].Message.Content, nil
+}
+
+// OpenAI function-calling structures
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // JSON-encoded arguments object
+	} `json:"function"`
+}
+
+type openAIToolMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAITool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string                 `json:"name"`
+		Description string                 `json:"description"`
+		Parameters  map[string]interface{} `json:"parameters"`
+	} `json:"function"`
+}
+
+type openAIToolRequest struct {
+	Model    string              `json:"model"`
+	Messages []openAIToolMessage `json:"messages"`
+	Tools    []openAITool        `json:"tools,omitempty"`
+}
+
+type openAIToolResponse struct {
+	Choices []struct {
+		Message struct {
+			Content   string           `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// ChatWithTools sends a tool-calling conversation to OpenAI
+func (p *OpenAIProvider) ChatWithTools(ctx context.Context, system string, messages []Message, tools []ToolDefinition) (*ChatResponse, error) {
+	reqBody := openAIToolRequest{Model: p.model}
+
+	if system != "" {
+		reqBody.Messages = append(reqBody.Messages, openAIToolMessage{Role: "system", Content: system})
+	}
+	for _, msg := range messages {
+		m := openAIToolMessage{Role: msg.Role, Content: msg.Content, ToolCallID: msg.ToolCallID}
+		for _, call := range msg.ToolCalls {
+			args, err := json.Marshal(call.Arguments)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal tool arguments: %w", err)
+			}
+			tc := openAIToolCall{ID: call.ID, Type: "function"}
+			tc.Function.Name = call.Name
+			tc.Function.Arguments = string(args)
+			m.ToolCalls = append(m.ToolCalls, tc)
+		}
+		reqBody.Messages = append(reqBody.Messages, m)
+	}
+	for _, tool := range tools {
+		t := openAITool{Type: "function"}
+		t.Function.Name = tool.Name
+		t.Function.Description = tool.Description
+		t.Function.Parameters = tool.Parameters
+		reqBody.Tools = append(reqBody.Tools, t)
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call OpenAI API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("OpenAI API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var openAIResp openAIToolResponse
+	if err := json.NewDecoder(resp.Body).Decode(&openAIResp); err != nil {
+		return nil, fmt.Errorf("failed to decode OpenAI response: %w", err)
+	}
+
+	if len(openAIResp.Choices) == 0 {
+		return nil, fmt.Errorf("OpenAI returned no choices")
+	}
+
+	choice := openAIResp.Choices[0].Message
+	result := &ChatResponse{
+		Content:      choice.Content,
+		InputTokens:  openAIResp.Usage.PromptTokens,
+		OutputTokens: openAIResp.Usage.CompletionTokens,
+	}
+	for _, tc := range choice.ToolCalls {
+		result.ToolCalls = append(result.ToolCalls, ToolCall{
+			ID:        tc.ID,
+			Name:      tc.Function.Name,
+			Arguments: parseToolArguments(tc.Function.Arguments),
+		})
+	}
+
+	return result, nil
+}
@@ -2,34 +2,17 @@ package llm
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/valentinpelus/k8flex/pkg/types"
 )
 
-// BuildAnalysisPrompt creates the analysis prompt shared across all providers
-func BuildAnalysisPrompt(debugInfo string, pastFeedback []types.Feedback) string {
-	// Build compact feedback context if available
-	var feedbackContext string
-	if len(pastFeedback) > 0 {
-		feedbackContext = "\n=== PAST FEEDBACK ===\n"
-		for i, fb := range pastFeedback {
-			status := "✅ CORRECT"
-			if !fb.IsCorrect {
-				status = "❌ WRONG"
-			}
-			// Truncate analysis to first 200 chars
-			analysis := fb.Analysis
-			if len(analysis) > 200 {
-				analysis = analysis[:200] + "..."
-			}
-			feedbackContext += fmt.Sprintf("%d. %s (%s): %s - %s\n", i+1, fb.AlertName, fb.Category, status, analysis)
-		}
-		feedbackContext += "\n"
-	}
+// feedbackExcerptChars is how much of a past analysis is quoted as feedback;
+// the verdict matters more than the full text
+const feedbackExcerptChars = 200
 
-	prompt := fmt.Sprintf(`K8s SRE expert: Analyze this incident. Debug info is pre-filtered for this alert only.
-%s
-ANALYSIS RULES:
+// analysisRules are the grounding rules shared by the text and JSON analysis prompts
+const analysisRules = `ANALYSIS RULES:
 1. Base ALL conclusions on the Debug Info below - cite specific evidence
 2. You MAY make logical inferences from the provided metrics and logs
 3. Cross-reference patterns with past incidents (see feedback above) if similar
@@ -50,10 +33,19 @@ ANALYSIS RULES:
 14. Use conditional language for inferences: "may have", "could be", "likely", "suggests"
 15. Check actual pod/node STATUS before claiming current state
 16. Quote specific log lines, errors, or metrics when citing evidence
+17. If the debug info has an Incident Members section, its alerts fired together: find their shared root cause and give a one-line note per alert on how it relates to it (an *Alert Notes:* list after *Impact:*, or alert_notes in JSON)
+18. If the debug info has a Runbook section, follow it: for each of its diagnosis and remediation steps, say whether the evidence calls for it and why (a *Runbook Steps:* list after *Actions:*, marking each step ✅ applies or ➖ doesn't, or runbook_steps in JSON)
+19. If the debug info has an Available Actions section, propose only its actions that the evidence clearly calls for, on resources named in the debug info; an operator approves them before they run (a *Proposed Actions:* list after *Actions:*, one per line: the command in backticks, like restart deployment/api or scale deployment/api 5, then " — " and the reason; or proposed_actions in JSON). Propose none if unsure
 
 Example:
 - WRONG: "The pod has been terminated" (if status shows Running)
-- RIGHT: "The pod experienced an OOMKill event (see logs), but current status shows Running"
+- RIGHT: "The pod experienced an OOMKill event (see logs), but current status shows Running"`
+
+// BuildAnalysisPrompt creates the analysis prompt shared across all providers
+func BuildAnalysisPrompt(debugInfo string, pastFeedback []types.Feedback) string {
+	prompt := fmt.Sprintf(`K8s SRE expert: Analyze this incident. Debug info is pre-filtered for this alert only.
+%s
+%s
 
 Provide analysis using this format (use *text* for bold, not **text**):
 
@@ -73,13 +65,86 @@ Use bullet points (•). Use *bold* for headers. Ground everything in provided d
 Debug Info:
 %s
 
-Analysis:`, feedbackContext,
-		func() string {
-			if len(pastFeedback) > 0 {
-				return " Apply lessons from past feedback - use similar patterns if applicable."
-			}
-			return ""
-		}(), debugInfo)
+Analysis:`, feedbackContext(pastFeedback), analysisRules, feedbackHint(pastFeedback), debugInfo)
 
 	return prompt
 }
+
+// feedbackContext lists past feedback for the prompt, or returns "" if there's none
+func feedbackContext(pastFeedback []types.Feedback) string {
+	if len(pastFeedback) == 0 {
+		return ""
+	}
+	context := "\n=== PAST FEEDBACK ===\n"
+	for i, fb := range pastFeedback {
+		status := "✅ CORRECT"
+		if !fb.IsCorrect {
+			status = "❌ WRONG"
+		}
+		analysis := fb.Analysis
+		if len(analysis) > feedbackExcerptChars {
+			analysis = analysis[:feedbackExcerptChars] + "..."
+		}
+		context += fmt.Sprintf("%d. %s (%s): %s - %s\n", i+1, fb.AlertName, fb.Category, status, analysis)
+		if len(fb.Comments) > 0 {
+			context += "   Operator notes: " + strings.Join(fb.Comments, " | ") + "\n"
+		}
+	}
+	return context + "\n"
+}
+
+// feedbackHint asks the model to apply past feedback, if any
+func feedbackHint(pastFeedback []types.Feedback) string {
+	if len(pastFeedback) > 0 {
+		return " Apply lessons from past feedback - use similar patterns if applicable."
+	}
+	return ""
+}
+
+// BuildInvestigationSystemPrompt creates the system prompt for tool-calling investigations
+func BuildInvestigationSystemPrompt(namespace string, maxSteps int) string {
+	return fmt.Sprintf(`You are a Kubernetes SRE investigating an alert in namespace %q.
+The user message contains the initial debug info gathered for the alert.
+You can call read-only tools to gather more evidence from the cluster.
+
+INVESTIGATION RULES:
+1. Only call a tool when the debug info you already have is insufficient
+2. Prefer targeted calls (a specific pod or service) over broad ones
+3. Don't repeat a call you've already made with the same arguments
+4. You have at most %d tool calls; stop as soon as the evidence supports a conclusion
+5. When done, answer in the exact format requested in the user message, without calling tools
+6. Cite a tool result as the section "Tool Call N", N being the call's number in the order you made them`, namespace, maxSteps)
+}
+
+// followUpRules ground answers to follow-up questions like analyses
+const followUpRules = `ANSWER RULES:
+1. Answer the question asked, not the whole incident again
+2. Base the answer on the Debug Info, the tool results if any, and the analysis; quote log lines, errors or metric values as evidence
+3. If the evidence doesn't answer the question, say what's missing and how to get it instead of guessing
+4. Use conditional language for inferences: "may have", "could be", "likely"
+5. Be brief: a few sentences or bullet points (•), *text* for bold, not **text**`
+
+// BuildFollowUpPrompt creates the prompt answering a question asked in the
+// Slack thread of an analysis, with the previous questions and answers
+func BuildFollowUpPrompt(debugInfo, analysis string, history []types.FollowUp, question string) string {
+	var previous strings.Builder
+	if len(history) > 0 {
+		previous.WriteString("\n=== PREVIOUS QUESTIONS ===\n")
+	}
+	for _, turn := range history {
+		previous.WriteString(fmt.Sprintf("Q: %s\nA: %s\n\n", turn.Question, turn.Answer))
+	}
+
+	return fmt.Sprintf(`K8s SRE expert: An engineer asks a follow-up question about an incident you analyzed.
+%s
+
+Debug Info:
+%s
+
+=== YOUR ANALYSIS ===
+%s
+%s
+Question: %s
+
+Answer:`, followUpRules, debugInfo, analysis, previous.String(), question)
+}
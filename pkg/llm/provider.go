@@ -1,26 +1,52 @@
 package llm
 
-import "github.com/valentinpelus/k8flex/pkg/types"
+import (
+	"context"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
 
 // Provider defines the interface for LLM providers (Ollama, OpenAI, Claude, Gemini)
+// All methods abort the underlying API call when ctx is cancelled or its deadline expires
 type Provider interface {
 	// CategorizeAlert analyzes an alert and returns its category
-	CategorizeAlert(alert types.Alert) (string, error)
+	CategorizeAlert(ctx context.Context, alert types.Alert) (string, error)
 
 	// AnalyzeDebugInfoStream performs streaming analysis with real-time updates
 	// updateFn is called with each chunk of the response
-	AnalyzeDebugInfoStream(debugInfo string, pastFeedback []types.Feedback, updateFn func(chunk string)) error
+	AnalyzeDebugInfoStream(ctx context.Context, debugInfo string, pastFeedback []types.Feedback, updateFn func(chunk string)) error
 
 	// AnalyzeDebugInfo performs non-streaming analysis and returns the full response
-	AnalyzeDebugInfo(debugInfo string, pastFeedback []types.Feedback) (string, error)
+	AnalyzeDebugInfo(ctx context.Context, debugInfo string, pastFeedback []types.Feedback) (string, error)
+
+	// StreamPrompt streams the response to a free-form prompt, such as a
+	// follow-up question about an analysis
+	StreamPrompt(ctx context.Context, prompt string, updateFn func(chunk string)) error
+
+	// GenerateJSON returns a JSON object answering prompt, constrained to
+	// schema where the API supports it. Callers validate the output.
+	GenerateJSON(ctx context.Context, prompt string, schema map[string]interface{}) (string, error)
 
 	// Name returns the provider name (for logging)
 	Name() string
+
+	// ContextWindow returns the number of tokens the model accepts, prompt and
+	// output combined, used to fit the debug info into the prompt
+	ContextWindow() int
 }
 
 // Config holds common configuration for LLM providers
 type Config struct {
-	Provider string // "ollama", "openai", "anthropic", "gemini"
+	Provider string // "ollama", "openai", "anthropic", "gemini", "bedrock", or a comma-separated fallback chain
+
+	// Circuit breaker settings for fallback chains
+	BreakerThreshold int           // Consecutive failures before a provider is skipped
+	BreakerCooldown  time.Duration // How long a tripped provider is skipped
+
+	// ContextWindow overrides the context window of every provider's model, in
+	// tokens; 0 uses the built-in table (see ModelContextWindow)
+	ContextWindow int
 
 	// Ollama-specific
 	OllamaURL   string
@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ToolDefinition describes a function the model may call
+type ToolDefinition struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{} // JSON Schema of the arguments object
+}
+
+// ToolCall is a function call requested by the model
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments map[string]interface{}
+}
+
+// Message is one turn of a tool-calling conversation
+type Message struct {
+	Role       string     // "user", "assistant" or "tool"
+	Content    string     // Text content (tool output for role "tool")
+	ToolCalls  []ToolCall // Calls requested by the assistant
+	ToolCallID string     // For role "tool": the call being answered
+	ToolName   string     // For role "tool": the tool that produced the output
+}
+
+// ChatResponse is the model's reply in a tool-calling conversation
+type ChatResponse struct {
+	Content      string     // Text content, if any
+	ToolCalls    []ToolCall // Calls the model wants made; empty when it has answered
+	InputTokens  int
+	OutputTokens int
+}
+
+// ToolCallingProvider is implemented by providers that support native function calling
+type ToolCallingProvider interface {
+	Provider
+
+	// ChatWithTools sends the conversation with the available tools and returns
+	// the model's next turn
+	ChatWithTools(ctx context.Context, system string, messages []Message, tools []ToolDefinition) (*ChatResponse, error)
+}
+
+// StringArg returns a string argument of a tool call, or "" if absent
+func (c ToolCall) StringArg(name string) string {
+	switch v := c.Arguments[name].(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// IntArg returns an integer argument of a tool call, or def if absent or invalid
+func (c ToolCall) IntArg(name string, def int) int {
+	switch v := c.Arguments[name].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case string:
+		var i int
+		if _, err := fmt.Sscanf(v, "%d", &i); err == nil {
+			return i
+		}
+	}
+	return def
+}
+
+// String formats the call for logs and reports, e.g. get_pod_logs(namespace=x, pod=y)
+func (c ToolCall) String() string {
+	args := make([]string, 0, len(c.Arguments))
+	for k, v := range c.Arguments {
+		args = append(args, fmt.Sprintf("%s=%v", k, v))
+	}
+	sort.Strings(args)
+	return fmt.Sprintf("%s(%s)", c.Name, strings.Join(args, ", "))
+}
+
+// parseToolArguments decodes a JSON-encoded arguments object
+func parseToolArguments(raw string) map[string]interface{} {
+	args := make(map[string]interface{})
+	if raw == "" {
+		return args
+	}
+	if err := json.Unmarshal([]byte(raw), &args); err != nil {
+		return map[string]interface{}{}
+	}
+	return args
+}
@@ -0,0 +1,223 @@
+package logsummary
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxTraceLines bounds how much of one stack trace is kept
+	maxTraceLines = 50
+	// maxLineBytes bounds how much of one log line is shown
+	maxLineBytes = 1000
+)
+
+// Variable parts of a line, masked to get its template
+var (
+	isoTimestamp = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`)
+	clockTime    = regexp.MustCompile(`\b\d{2}:\d{2}:\d{2}(?:[.,]\d+)?\b`)
+	uuid         = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	hexID        = regexp.MustCompile(`(?i)\b(?:0x[0-9a-f]+|[0-9a-f]{7,})\b`)
+	number       = regexp.MustCompile(`\d+(?:\.\d+)?`)
+)
+
+var (
+	// errorLine matches error-level lines in plain, logfmt, JSON and klog formats
+	errorLine = regexp.MustCompile(`(?i)\b(error|err|fatal|panic|critical|crit|exception|failed|failure)\b|"(level|severity)"\s*:\s*"(error|fatal|critical)"|\blevel=(error|fatal|critical)\b|^[EF]\d{4} `)
+	// traceStart matches the first line of a Go, Java or Python stack trace
+	traceStart = regexp.MustCompile(`^(panic: |fatal error: |goroutine \d+ \[|Traceback \(most recent call last\):|Exception in thread |[\w.$]+(Exception|Error)(: |$))`)
+	// traceContinuation matches unindented lines that still belong to a trace
+	traceContinuation = regexp.MustCompile(`^(Caused by: |goroutine \d+ \[|created by |\.\.\. \d+ more|\[signal )`)
+)
+
+// Template is a group of log lines that differ only in numbers, UUIDs,
+// hex IDs and timestamps
+type Template struct {
+	Count     int
+	First     string // First occurrence, unmasked
+	Last      string // Last occurrence, unmasked
+	FirstLine int    // 1-based line number of the first occurrence
+	LastLine  int
+	Error     bool // Error-level line
+	Trace     bool // Stack trace; First and Last hold the whole trace
+}
+
+// Summary is the clustered form of a log
+type Summary struct {
+	Lines     int
+	Traces    []*Template // In order of last occurrence, most recent first
+	Errors    []*Template
+	Others    []*Template
+	templates map[string]*Template
+}
+
+// Summarize clusters the lines of a log by template and extracts its stack traces
+func Summarize(text string) *Summary {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	s := &Summary{Lines: len(lines), templates: make(map[string]*Template)}
+	if text == "" {
+		s.Lines = 0
+		return s
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if traceStart.MatchString(line) {
+			if end := traceEnd(lines, i); end > i+1 {
+				s.add(strings.Join(lines[i:min(end, i+maxTraceLines)], "\n"), i+1, false, true)
+				i = end - 1
+				continue
+			}
+		}
+		s.add(line, i+1, errorLine.MatchString(line), false)
+	}
+
+	byRecency := func(t []*Template) {
+		sort.SliceStable(t, func(a, b int) bool { return t[a].LastLine > t[b].LastLine })
+	}
+	byRecency(s.Traces)
+	byRecency(s.Errors)
+	byRecency(s.Others)
+	return s
+}
+
+// traceEnd returns the index of the first line after the trace starting at start
+func traceEnd(lines []string, start int) int {
+	python := strings.HasPrefix(lines[start], "Traceback")
+	i := start + 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case line == "":
+			// Go separates goroutines with a blank line
+			if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "goroutine ") {
+				continue
+			}
+			return i
+		case line[0] == ' ' || line[0] == '\t', traceContinuation.MatchString(line):
+		case i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\t"):
+			// Go frame: the function, followed by its indented file and line
+		case python:
+			// The exception ends a Python traceback
+			return i + 1
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// add counts one occurrence of a line or trace
+func (s *Summary) add(text string, lineNumber int, isError, isTrace bool) {
+	key := Mask(text)
+	t, ok := s.templates[key]
+	if !ok {
+		t = &Template{First: text, FirstLine: lineNumber, Error: isError, Trace: isTrace}
+		s.templates[key] = t
+		switch {
+		case isTrace:
+			s.Traces = append(s.Traces, t)
+		case isError:
+			s.Errors = append(s.Errors, t)
+		default:
+			s.Others = append(s.Others, t)
+		}
+	}
+	t.Count++
+	t.Last = text
+	t.LastLine = lineNumber
+}
+
+// Mask replaces the numbers, UUIDs, hex IDs and timestamps of a line, so lines
+// of the same template compare equal
+func Mask(line string) string {
+	// Timestamps and UUIDs go before plain numbers so they're masked whole
+	line = isoTimestamp.ReplaceAllString(line, "<TS>")
+	line = clockTime.ReplaceAllString(line, "<TS>")
+	line = uuid.ReplaceAllString(line, "<UUID>")
+	line = hexID.ReplaceAllStringFunc(line, func(id string) string {
+		if strings.Trim(id, "0123456789") == "" {
+			return id // A plain number, masked below
+		}
+		return "<HEX>"
+	})
+	return number.ReplaceAllString(line, "<N>")
+}
+
+// String renders the summary: stack traces first, then error lines, then the
+// other lines, each most recent first
+func (s *Summary) String() string {
+	return s.Render(0)
+}
+
+// Render renders the summary within maxBytes, leaving out the oldest
+// templates of each group that don't fit; 0 means no limit
+func (s *Summary) Render(maxBytes int) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("[%d lines summarized: %d stack traces, %d error templates, %d other templates]\n",
+		s.Lines, len(s.Traces), len(s.Errors), len(s.Others)))
+
+	groups := []struct {
+		title     string
+		templates []*Template
+	}{
+		{"Stack traces", s.Traces},
+		{"Errors", s.Errors},
+		{"Other lines", s.Others},
+	}
+	omitted := 0
+	for _, g := range groups {
+		if len(g.templates) == 0 {
+			continue
+		}
+		if maxBytes > 0 && b.Len()+len(g.title)+2 > maxBytes {
+			omitted += len(g.templates)
+			continue
+		}
+		b.WriteString(g.title + ":\n")
+		for _, t := range g.templates {
+			entry := t.render()
+			if maxBytes > 0 && b.Len()+len(entry) > maxBytes {
+				omitted++
+				continue
+			}
+			b.WriteString(entry)
+		}
+	}
+	if omitted > 0 {
+		b.WriteString(fmt.Sprintf("[%d older templates omitted to fit the log budget]\n", omitted))
+	}
+	return b.String()
+}
+
+// render formats one template with its count and first and last occurrence
+func (t *Template) render() string {
+	if t.Trace {
+		if t.Count == 1 {
+			return fmt.Sprintf("(line %d)\n%s\n", t.FirstLine, t.Last)
+		}
+		// Traces of one template differ only in addresses and IDs
+		return fmt.Sprintf("×%d (lines %d-%d, last shown)\n%s\n", t.Count, t.FirstLine, t.LastLine, t.Last)
+	}
+	if t.Count == 1 {
+		return fmt.Sprintf("(line %d) %s\n", t.FirstLine, shorten(t.First))
+	}
+	if t.First == t.Last {
+		return fmt.Sprintf("×%d (lines %d-%d) %s\n", t.Count, t.FirstLine, t.LastLine, shorten(t.First))
+	}
+	return fmt.Sprintf("×%d first (line %d): %s\n   last (line %d): %s\n",
+		t.Count, t.FirstLine, shorten(t.First), t.LastLine, shorten(t.Last))
+}
+
+// shorten cuts a line to maxLineBytes
+func shorten(line string) string {
+	if len(line) <= maxLineBytes {
+		return line
+	}
+	return line[:maxLineBytes] + "…"
+}
@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// slackLink matches a Slack link, e.g. <https://example.com|Runbook>
+	slackLink = regexp.MustCompile(`<(https?://[^|>\s]+)\|([^>]+)>`)
+	// slackBareLink matches a Slack link without text, e.g. <https://example.com>
+	slackBareLink = regexp.MustCompile(`<(https?://[^|>\s]+)>`)
+	// slackBold matches Slack bold text, e.g. *Root Cause:*
+	slackBold = regexp.MustCompile(`(^|[^*\w])\*([^*\n]+)\*([^*\w]|$)`)
+)
+
+// Markdown converts the Slack mrkdwn of analyses to the Markdown of other
+// destinations. Text already in Markdown is kept.
+func Markdown(text string) string {
+	text = strings.ReplaceAll(text, "**", "*")
+	text = slackLink.ReplaceAllString(text, "[$2]($1)")
+	text = slackBareLink.ReplaceAllString(text, "$1")
+	// Matches can't overlap, so adjacent bold spans need a second pass
+	for i := 0; i < 2; i++ {
+		text = slackBold.ReplaceAllString(text, "$1**$2**$3")
+	}
+	return text
+}
+
+// truncate cuts text to maxLen bytes on a line boundary where possible
+func truncate(text string, maxLen int) string {
+	if len(text) <= maxLen {
+		return text
+	}
+	cut := strings.LastIndex(text[:maxLen], "\n")
+	if cut < maxLen/2 {
+		cut = maxLen
+	}
+	return strings.ToValidUTF8(text[:cut], "") + "\n... (truncated)"
+}
+
+// formatDuration formats a resolution duration for display
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return "an unknown duration"
+	}
+	return d.Round(time.Second).String()
+}
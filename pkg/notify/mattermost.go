@@ -0,0 +1,276 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// maxMattermostText bounds a post; Mattermost rejects posts over 16383 characters
+const maxMattermostText = 15000
+
+// Mattermost posts alerts and analyses to a Mattermost channel. With a bot
+// token, analyses are streamed in the alert's thread and rated with ✅ and ❌
+// reactions, like in Slack. With an incoming webhook only, every notification
+// is a post of its own.
+type Mattermost struct {
+	url        string // Server URL, e.g. https://mattermost.example.com
+	token      string // Bot or personal access token
+	channelID  string
+	webhookURL string
+	client     *http.Client
+}
+
+// NewMattermost creates a Mattermost notifier posting with a bot token to a
+// channel, or to an incoming webhook
+func NewMattermost(url, token, channelID, webhookURL string) *Mattermost {
+	return &Mattermost{
+		url:        strings.TrimRight(url, "/"),
+		token:      token,
+		channelID:  channelID,
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// mattermostPost is a post of the REST API
+// Reference: https://api.mattermost.com/#tag/posts
+type mattermostPost struct {
+	ID        string `json:"id,omitempty"`
+	ChannelID string `json:"channel_id,omitempty"`
+	RootID    string `json:"root_id,omitempty"`
+	Message   string `json:"message"`
+}
+
+// Name returns "mattermost"
+func (m *Mattermost) Name() string {
+	return "mattermost"
+}
+
+// IsConfigured checks if a bot token and channel, or a webhook URL, are set
+func (m *Mattermost) IsConfigured() bool {
+	return m.hasToken() || m.webhookURL != ""
+}
+
+// hasToken checks if posts go through the REST API, with threads
+func (m *Mattermost) hasToken() bool {
+	return m.url != "" && m.token != "" && m.channelID != ""
+}
+
+// SendAlert posts an alert and returns the thread of its analysis
+func (m *Mattermost) SendAlert(ctx context.Context, alert types.Alert) (string, error) {
+	return m.sendRoot(ctx, alertText(alert, alert.Labels["severity"], "🚨", "🤖 _AI debugging in progress..._"))
+}
+
+// SendIncident posts the alerts of an incident and returns the thread of its
+// analysis
+func (m *Mattermost) SendIncident(ctx context.Context, incident *types.Incident) (string, error) {
+	return m.sendRoot(ctx, incidentText(incident, "🔗 "+incident.Title(), "🤖 _AI debugging in progress..._"))
+}
+
+// SendAnalysisInThread starts an analysis streamed in a thread (requires bot
+// token)
+func (m *Mattermost) SendAnalysisInThread(ctx context.Context, _ types.Alert, text, threadTS string) (string, error) {
+	if !m.hasToken() {
+		return "", nil
+	}
+	return m.createPost(ctx, threadTS, Markdown(text))
+}
+
+// UpdateMessage replaces the text of a post (requires bot token)
+func (m *Mattermost) UpdateMessage(ctx context.Context, messageTS, text string) error {
+	if !m.hasToken() {
+		return fmt.Errorf("bot token required for message updates")
+	}
+	return m.patchPost(ctx, messageTS, Markdown(text))
+}
+
+// PostAnalysis posts an analysis in a thread, replacing the streamed post if
+// set, and returns the post its rating applies to. Without bot token, the
+// analysis is sent with the webhook and can't be rated.
+func (m *Mattermost) PostAnalysis(ctx context.Context, threadTS, messageTS string, msg types.AnalysisMessage) (string, error) {
+	text := msg.Title + "\n\n" + msg.Analysis
+	if msg.Footer != "" {
+		text += "\n\n" + msg.Footer
+	}
+	text = Markdown(text)
+
+	if !m.hasToken() {
+		header := fmt.Sprintf("#### 🔍 AI Debug Analysis: `%s`\n", msg.Alert.Labels["alertname"])
+		return "", m.sendWebhook(ctx, header+truncate(text, maxMattermostText))
+	}
+	text = truncate(text, maxMattermostText)
+	if messageTS != "" {
+		return messageTS, m.patchPost(ctx, messageTS, text)
+	}
+	return m.createPost(ctx, threadTS, text)
+}
+
+// ReplyToThread posts a message in a thread, or a post of its own without bot
+// token
+func (m *Mattermost) ReplyToThread(ctx context.Context, threadTS, text string) error {
+	if !m.hasToken() {
+		return m.sendWebhook(ctx, Markdown(text))
+	}
+	_, err := m.createPost(ctx, threadTS, Markdown(text))
+	return err
+}
+
+// SendResolution posts a resolution notice in the alert thread and marks the
+// alert post as resolved
+func (m *Mattermost) SendResolution(ctx context.Context, alert types.Alert, threadTS string, duration time.Duration) error {
+	if !m.hasToken() {
+		return m.sendWebhook(ctx, fmt.Sprintf("✅ **[RESOLVED] %s** (%s) after %s",
+			alert.Labels["alertname"], alert.Labels["namespace"], formatDuration(duration)))
+	}
+
+	if _, err := m.createPost(ctx, threadTS, fmt.Sprintf("✅ **Resolved** after %s", formatDuration(duration))); err != nil {
+		return fmt.Errorf("failed to post resolution: %w", err)
+	}
+	text := alertText(alert, fmt.Sprintf("~~%s~~ resolved", alert.Labels["severity"]), "✅ [RESOLVED]",
+		fmt.Sprintf("✅ _Resolved after %s — see thread for the analysis_", formatDuration(duration)))
+	if err := m.patchPost(ctx, threadTS, text); err != nil {
+		return fmt.Errorf("failed to update alert post: %w", err)
+	}
+	return nil
+}
+
+// SendIncidentResolution posts a resolution notice in the incident thread and
+// marks the incident post as resolved
+func (m *Mattermost) SendIncidentResolution(ctx context.Context, incident *types.Incident, threadTS string, duration time.Duration) error {
+	if !m.hasToken() {
+		return m.sendWebhook(ctx, fmt.Sprintf("✅ **[RESOLVED] %s** after %s", incident.Title(), formatDuration(duration)))
+	}
+
+	resolvedMsg := fmt.Sprintf("✅ **All %d alerts resolved** after %s", len(incident.Members), formatDuration(duration))
+	if _, err := m.createPost(ctx, threadTS, resolvedMsg); err != nil {
+		return fmt.Errorf("failed to post resolution: %w", err)
+	}
+	text := incidentText(incident, "✅ [RESOLVED] "+incident.Title(),
+		fmt.Sprintf("✅ _Resolved after %s — see thread for the analysis_", formatDuration(duration)))
+	if err := m.patchPost(ctx, threadTS, text); err != nil {
+		return fmt.Errorf("failed to update incident post: %w", err)
+	}
+	return nil
+}
+
+// GetMessageReactions returns the emoji names of the reactions on a post
+// (requires bot token)
+func (m *Mattermost) GetMessageReactions(ctx context.Context, messageTS string) ([]string, error) {
+	if !m.hasToken() {
+		return nil, fmt.Errorf("bot token required for getting reactions")
+	}
+
+	body, err := sendJSON(ctx, m.client, "GET", m.url+"/api/v4/posts/"+messageTS+"/reactions", m.token, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Mattermost reactions: %w", err)
+	}
+	var reactions []struct {
+		EmojiName string `json:"emoji_name"`
+	}
+	if err := json.Unmarshal(body, &reactions); err != nil {
+		return nil, fmt.Errorf("failed to parse reactions: %w", err)
+	}
+
+	names := make([]string, 0, len(reactions))
+	for _, r := range reactions {
+		names = append(names, r.EmojiName)
+	}
+	return names, nil
+}
+
+// sendRoot posts the root post of a thread. Without bot token, it's sent with
+// the webhook and its ID only groups the alert's notifications.
+func (m *Mattermost) sendRoot(ctx context.Context, text string) (string, error) {
+	if m.hasToken() {
+		return m.createPost(ctx, "", text)
+	}
+	if err := m.sendWebhook(ctx, text); err != nil {
+		return "", err
+	}
+	return newThreadID(), nil
+}
+
+// createPost creates a post, in a thread if rootID is set, and returns its ID
+func (m *Mattermost) createPost(ctx context.Context, rootID, text string) (string, error) {
+	body, err := sendJSON(ctx, m.client, "POST", m.url+"/api/v4/posts", m.token, mattermostPost{
+		ChannelID: m.channelID,
+		RootID:    rootID,
+		Message:   text,
+	})
+	if err != nil {
+		return "", fmt.Errorf("Mattermost post: %w", err)
+	}
+
+	var post mattermostPost
+	if err := json.Unmarshal(body, &post); err != nil {
+		return "", fmt.Errorf("failed to parse Mattermost post: %w", err)
+	}
+	log.Printf("Message sent to Mattermost, post: %s", post.ID)
+	return post.ID, nil
+}
+
+// patchPost replaces the message of a post
+func (m *Mattermost) patchPost(ctx context.Context, postID, text string) error {
+	_, err := sendJSON(ctx, m.client, "PUT", m.url+"/api/v4/posts/"+postID+"/patch", m.token, mattermostPost{Message: text})
+	if err != nil {
+		return fmt.Errorf("Mattermost update: %w", err)
+	}
+	return nil
+}
+
+// sendWebhook posts a message to the incoming webhook
+func (m *Mattermost) sendWebhook(ctx context.Context, text string) error {
+	if _, err := sendJSON(ctx, m.client, "POST", m.webhookURL, "", map[string]string{"text": text}); err != nil {
+		return fmt.Errorf("Mattermost webhook: %w", err)
+	}
+	log.Printf("Message sent to Mattermost")
+	return nil
+}
+
+// alertText renders an alert post, ending with a status line
+func alertText(alert types.Alert, severity, prefix, status string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#### %s %s\n", prefix, alert.Labels["alertname"])
+	b.WriteString("| Severity | Namespace | Pod | Service |\n|:--|:--|:--|:--|\n")
+	pod := alert.Labels["pod"]
+	if pod != "" {
+		pod = "`" + pod + "`"
+	}
+	fmt.Fprintf(&b, "| %s | %s | %s | %s |\n\n", severity, alert.Labels["namespace"], pod, alert.Labels["service"])
+
+	if summary := alert.Annotations["summary"]; summary != "" {
+		fmt.Fprintf(&b, "**Summary:** %s\n", summary)
+	}
+	if description := alert.Annotations["description"]; description != "" {
+		fmt.Fprintf(&b, "**Description:** %s\n", description)
+	}
+	fmt.Fprintf(&b, "_Started: %s_", alert.StartsAt.Format("2006-01-02 15:04:05 MST"))
+	if runbookURL := alert.Annotations["runbook_url"]; runbookURL != "" {
+		fmt.Fprintf(&b, " · [📖 Runbook](%s)", runbookURL)
+	}
+	b.WriteString("\n\n---\n" + status)
+	return b.String()
+}
+
+// incidentText renders the post listing the alerts of an incident, ending
+// with a status line
+func incidentText(incident *types.Incident, title, status string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#### %s\n", title)
+	for _, m := range incident.Members {
+		fmt.Fprintf(&b, "- **%s** · %s", m.Alert.Labels["alertname"], types.AlertTarget(m.Alert))
+		if keys := incident.SharedKeys(m); len(keys) > 0 {
+			fmt.Fprintf(&b, " · `%s`", strings.Join(keys, "`, `"))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n---\n" + status)
+	return b.String()
+}
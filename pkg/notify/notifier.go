@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// Notifier delivers alerts and their analyses to a chat or webhook
+// destination. Thread and message IDs are opaque to callers; a destination
+// without threads returns IDs that only group the notifications of an alert.
+// Methods taking a ctx abort their request when it's cancelled.
+type Notifier interface {
+	// Name returns the name routes refer to the notifier by, e.g. "teams"
+	Name() string
+
+	// IsConfigured reports whether notifications are sent
+	IsConfigured() bool
+
+	// SendAlert posts a firing alert and returns the thread its analysis is
+	// posted in
+	SendAlert(ctx context.Context, alert types.Alert) (string, error)
+
+	// SendIncident posts correlated alerts as one incident and returns the
+	// thread its analysis is posted in
+	SendIncident(ctx context.Context, incident *types.Incident) (string, error)
+
+	// SendAnalysisInThread starts an analysis streamed in a thread and returns
+	// the message to update, or "" if the destination can't update messages
+	SendAnalysisInThread(ctx context.Context, alert types.Alert, text, threadTS string) (string, error)
+
+	// UpdateMessage replaces the text of a streamed analysis
+	UpdateMessage(ctx context.Context, messageTS, text string) error
+
+	// PostAnalysis posts a complete analysis in a thread, replacing the
+	// streamed message messageTS if set. It returns the message its rating
+	// applies to, or "" if it can't be rated.
+	PostAnalysis(ctx context.Context, threadTS, messageTS string, msg types.AnalysisMessage) (string, error)
+
+	// ReplyToThread posts a message in a thread
+	ReplyToThread(ctx context.Context, threadTS, text string) error
+
+	// SendResolution reports a resolved alert in its thread
+	SendResolution(ctx context.Context, alert types.Alert, threadTS string, duration time.Duration) error
+
+	// SendIncidentResolution reports an incident whose alerts are all resolved
+	SendIncidentResolution(ctx context.Context, incident *types.Incident, threadTS string, duration time.Duration) error
+}
+
+// FeedbackCollector is a notifier whose analyses are rated with ✅ and ❌
+// reactions, polled when the destination doesn't deliver them as events
+type FeedbackCollector interface {
+	Notifier
+
+	// GetMessageReactions returns the names of the reactions on a message
+	GetMessageReactions(ctx context.Context, messageTS string) ([]string, error)
+}
+
+// ChannelTracker is a notifier posting to several channels, whose messages
+// are only reachable once their channel is known again after a restart
+type ChannelTracker interface {
+	Notifier
+
+	// ChannelOf returns the channel a message or thread was posted in
+	ChannelOf(ts string) string
+
+	// TrackChannel records the channel of a message
+	TrackChannel(ts, channel string)
+}
+
+// newThreadID returns an ID grouping the notifications of an alert, for
+// destinations without threads
+func newThreadID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// sendJSON sends a request with a JSON body, if set, and a bearer token, if
+// set, and returns the response body
+func sendJSON(ctx context.Context, client *http.Client, method, url, token string, body interface{}) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal message: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("returned status %d: %s", resp.StatusCode, truncate(string(respBody), 200))
+	}
+	return respBody, nil
+}
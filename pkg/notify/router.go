@@ -0,0 +1,175 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// RouteConfig maps alert labels to notifiers
+type RouteConfig struct {
+	// Default is the notifier of alerts matching no route; empty picks the
+	// first configured one of slack, teams, mattermost and webhook
+	Default string `json:"default"`
+	// Routes are checked in order; the first match picks the notifier
+	Routes []Route `json:"routes"`
+}
+
+// Route sends the alerts matching its labels to a notifier
+type Route struct {
+	Match      map[string]string `json:"match"`      // Labels that must equal these values
+	MatchRegex map[string]string `json:"matchRegex"` // Labels that must fully match a regex
+	Notifier   string            `json:"notifier"`   // slack, teams, mattermost or webhook
+
+	regexes  map[string]*regexp.Regexp
+	notifier Notifier
+}
+
+// LoadRouteConfig reads a routes config file. A missing file returns nil,
+// meaning every alert goes to the default notifier.
+func LoadRouteConfig(path string) (*RouteConfig, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notifier routes: %w", err)
+	}
+
+	var cfg RouteConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse notifier routes %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// matches reports whether the route matches the alert
+func (r *Route) matches(alert types.Alert) bool {
+	for label, value := range r.Match {
+		if alert.Labels[label] != value {
+			return false
+		}
+	}
+	for label, re := range r.regexes {
+		if !re.MatchString(alert.Labels[label]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Router picks the notifier of each alert
+type Router struct {
+	notifiers map[string]Notifier
+	ordered   []Notifier
+	routes    []Route
+	fallback  Notifier
+}
+
+// NewRouter routes alerts to notifiers, the first of which is also the
+// notifier of threads recorded without one. Routes must name configured
+// notifiers. A nil config sends every alert to the default notifier.
+func NewRouter(notifiers []Notifier, cfg *RouteConfig) (*Router, error) {
+	if len(notifiers) == 0 {
+		return nil, fmt.Errorf("no notifiers")
+	}
+	if cfg == nil {
+		cfg = &RouteConfig{}
+	}
+
+	r := &Router{notifiers: make(map[string]Notifier), ordered: notifiers}
+	for _, n := range notifiers {
+		r.notifiers[n.Name()] = n
+	}
+	configured := func(name string) (Notifier, error) {
+		n, ok := r.notifiers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown notifier %q", name)
+		}
+		if !n.IsConfigured() {
+			return nil, fmt.Errorf("notifier %q isn't configured", name)
+		}
+		return n, nil
+	}
+
+	for i, route := range cfg.Routes {
+		if len(route.Match) == 0 && len(route.MatchRegex) == 0 {
+			return nil, fmt.Errorf("route %d: match or matchRegex is required", i)
+		}
+		n, err := configured(route.Notifier)
+		if err != nil {
+			return nil, fmt.Errorf("route %d: %w", i, err)
+		}
+		route.notifier = n
+		route.regexes = make(map[string]*regexp.Regexp)
+		for label, pattern := range route.MatchRegex {
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("route %d: matchRegex %s: %w", i, label, err)
+			}
+			route.regexes[label] = re
+		}
+		r.routes = append(r.routes, route)
+	}
+
+	if cfg.Default != "" {
+		n, err := configured(cfg.Default)
+		if err != nil {
+			return nil, fmt.Errorf("default: %w", err)
+		}
+		r.fallback = n
+	} else {
+		r.fallback = notifiers[0]
+		for _, n := range notifiers {
+			if n.IsConfigured() {
+				r.fallback = n
+				break
+			}
+		}
+	}
+	return r, nil
+}
+
+// Route returns the notifier of an alert
+func (r *Router) Route(alert types.Alert) Notifier {
+	for i := range r.routes {
+		if r.routes[i].matches(alert) {
+			return r.routes[i].notifier
+		}
+	}
+	return r.fallback
+}
+
+// Get returns a notifier by name. Threads recorded before alerts were routed
+// have no notifier name and belong to the first notifier.
+func (r *Router) Get(name string) Notifier {
+	if n, ok := r.notifiers[name]; ok {
+		return n
+	}
+	return r.ordered[0]
+}
+
+// Default returns the notifier of alerts matching no route
+func (r *Router) Default() Notifier {
+	return r.fallback
+}
+
+// Configured returns the notifiers that send notifications
+func (r *Router) Configured() []Notifier {
+	var configured []Notifier
+	for _, n := range r.ordered {
+		if n.IsConfigured() {
+			configured = append(configured, n)
+		}
+	}
+	return configured
+}
+
+// Routes returns the number of routes
+func (r *Router) Routes() int {
+	return len(r.routes)
+}
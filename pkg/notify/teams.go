@@ -0,0 +1,254 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// maxTeamsText bounds the analysis in a card; Teams rejects cards over 28 KB
+const maxTeamsText = 20000
+
+// newlines matches line breaks, which Teams only renders as paragraphs
+var newlines = regexp.MustCompile(`\n+`)
+
+// Teams posts alerts and analyses to a Microsoft Teams channel as Adaptive
+// Cards, through an incoming webhook or a Workflows webhook. Teams webhooks
+// can't reply in threads or update messages, so every notification is a card
+// of its own and analyses aren't streamed.
+type Teams struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewTeams creates a Teams notifier posting to a webhook URL
+func NewTeams(webhookURL string) *Teams {
+	return &Teams{
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// teamsMessage is the webhook payload carrying an Adaptive Card
+// Reference: https://learn.microsoft.com/en-us/microsoftteams/platform/webhooks-and-connectors/how-to/connectors-using
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	Content     adaptiveCard `json:"content"`
+}
+
+// adaptiveCard is an Adaptive Card
+// Reference: https://adaptivecards.io/explorer/
+type adaptiveCard struct {
+	Schema  string            `json:"$schema"`
+	Type    string            `json:"type"`
+	Version string            `json:"version"`
+	Body    []cardElement     `json:"body"`
+	Actions []cardAction      `json:"actions,omitempty"`
+	MSTeams map[string]string `json:"msteams,omitempty"`
+}
+
+// cardElement is a TextBlock or a FactSet
+type cardElement struct {
+	Type      string     `json:"type"`
+	Text      string     `json:"text,omitempty"`
+	Weight    string     `json:"weight,omitempty"`
+	Size      string     `json:"size,omitempty"`
+	Color     string     `json:"color,omitempty"`
+	IsSubtle  bool       `json:"isSubtle,omitempty"`
+	Wrap      bool       `json:"wrap,omitempty"`
+	Separator bool       `json:"separator,omitempty"`
+	Facts     []cardFact `json:"facts,omitempty"`
+}
+
+type cardFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type cardAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Name returns "teams"
+func (t *Teams) Name() string {
+	return "teams"
+}
+
+// IsConfigured checks if a webhook URL is set
+func (t *Teams) IsConfigured() bool {
+	return t.webhookURL != ""
+}
+
+// SendAlert posts an alert card
+func (t *Teams) SendAlert(ctx context.Context, alert types.Alert) (string, error) {
+	body := []cardElement{
+		heading("🚨 "+alert.Labels["alertname"], severityColor(alert.Labels["severity"])),
+		{Type: "FactSet", Facts: alertFacts(alert)},
+	}
+	if summary := alert.Annotations["summary"]; summary != "" {
+		body = append(body, textBlock("**Summary:** "+summary))
+	}
+	if description := alert.Annotations["description"]; description != "" {
+		body = append(body, textBlock("**Description:** "+description))
+	}
+	body = append(body, subtle("🤖 _AI debugging in progress..._"))
+
+	var actions []cardAction
+	if runbookURL := alert.Annotations["runbook_url"]; runbookURL != "" {
+		actions = append(actions, cardAction{Type: "Action.OpenUrl", Title: "📖 Runbook", URL: runbookURL})
+	}
+	if err := t.send(ctx, body, actions); err != nil {
+		return "", err
+	}
+	return newThreadID(), nil
+}
+
+// SendIncident posts a card listing the alerts of an incident
+func (t *Teams) SendIncident(ctx context.Context, incident *types.Incident) (string, error) {
+	if err := t.send(ctx, incidentCard(incident, "🔗 "+incident.Title(),
+		severityColor(incident.Lead().Labels["severity"]), "🤖 _AI debugging in progress..._"), nil); err != nil {
+		return "", err
+	}
+	return newThreadID(), nil
+}
+
+// SendAnalysisInThread does nothing, since cards can't be updated as an
+// analysis streams
+func (t *Teams) SendAnalysisInThread(_ context.Context, _ types.Alert, _, _ string) (string, error) {
+	return "", nil
+}
+
+// UpdateMessage does nothing, since cards can't be updated
+func (t *Teams) UpdateMessage(_ context.Context, _, _ string) error {
+	return nil
+}
+
+// PostAnalysis posts an analysis card. Teams analyses can't be rated, so no
+// message is returned.
+func (t *Teams) PostAnalysis(ctx context.Context, _, _ string, msg types.AnalysisMessage) (string, error) {
+	body := []cardElement{
+		heading(strings.ReplaceAll(msg.Title, "*", ""), ""),
+		subtle(fmt.Sprintf("Alert `%s` · %s", msg.Alert.Labels["alertname"], types.AlertTarget(msg.Alert))),
+		textBlock(teamsText(truncate(msg.Analysis, maxTeamsText))),
+	}
+	if msg.Footer != "" {
+		footer := subtle(teamsText(msg.Footer))
+		footer.Separator = true
+		body = append(body, footer)
+	}
+	return "", t.send(ctx, body, nil)
+}
+
+// ReplyToThread posts a card of its own, since cards can't be threaded
+func (t *Teams) ReplyToThread(ctx context.Context, _, text string) error {
+	return t.send(ctx, []cardElement{textBlock(teamsText(text))}, nil)
+}
+
+// SendResolution posts a resolved card
+func (t *Teams) SendResolution(ctx context.Context, alert types.Alert, _ string, duration time.Duration) error {
+	return t.send(ctx, []cardElement{
+		heading("✅ [RESOLVED] "+alert.Labels["alertname"], "Good"),
+		{Type: "FactSet", Facts: alertFacts(alert)},
+		subtle("Resolved after " + formatDuration(duration)),
+	}, nil)
+}
+
+// SendIncidentResolution posts a resolved card listing the alerts of an
+// incident
+func (t *Teams) SendIncidentResolution(ctx context.Context, incident *types.Incident, _ string, duration time.Duration) error {
+	return t.send(ctx, incidentCard(incident, "✅ [RESOLVED] "+incident.Title(), "Good",
+		fmt.Sprintf("All %d alerts resolved after %s", len(incident.Members), formatDuration(duration))), nil)
+}
+
+// send posts a card to the webhook
+func (t *Teams) send(ctx context.Context, body []cardElement, actions []cardAction) error {
+	message := teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: adaptiveCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body:    body,
+				Actions: actions,
+				MSTeams: map[string]string{"width": "Full"},
+			},
+		}},
+	}
+	if _, err := sendJSON(ctx, t.client, "POST", t.webhookURL, "", message); err != nil {
+		return fmt.Errorf("Teams webhook: %w", err)
+	}
+	log.Printf("Message sent to Teams")
+	return nil
+}
+
+// incidentCard lists the alerts of an incident, ending with a status line
+func incidentCard(incident *types.Incident, title, color, status string) []cardElement {
+	facts := make([]cardFact, len(incident.Members))
+	for i, m := range incident.Members {
+		facts[i] = cardFact{Title: m.Alert.Labels["alertname"], Value: types.AlertTarget(m.Alert)}
+	}
+	body := []cardElement{heading(title, color), {Type: "FactSet", Facts: facts}}
+	if len(incident.Shared) > 0 {
+		body = append(body, subtle("Correlated by "+strings.Join(incident.Shared, ", ")))
+	}
+	return append(body, subtle(status))
+}
+
+// alertFacts lists the severity, namespace, pod and start of an alert
+func alertFacts(alert types.Alert) []cardFact {
+	facts := []cardFact{
+		{Title: "Severity", Value: alert.Labels["severity"]},
+		{Title: "Namespace", Value: alert.Labels["namespace"]},
+	}
+	if pod := alert.Labels["pod"]; pod != "" {
+		facts = append(facts, cardFact{Title: "Pod", Value: pod})
+	}
+	if service := alert.Labels["service"]; service != "" {
+		facts = append(facts, cardFact{Title: "Service", Value: service})
+	}
+	return append(facts, cardFact{Title: "Started", Value: alert.StartsAt.Format("2006-01-02 15:04:05 MST")})
+}
+
+// severityColor maps an alert severity to a card color
+func severityColor(severity string) string {
+	switch severity {
+	case "critical":
+		return "Attention"
+	case "warning":
+		return "Warning"
+	}
+	return "Accent"
+}
+
+func heading(text, color string) cardElement {
+	return cardElement{Type: "TextBlock", Text: text, Weight: "Bolder", Size: "Large", Color: color, Wrap: true}
+}
+
+func textBlock(text string) cardElement {
+	return cardElement{Type: "TextBlock", Text: text, Wrap: true}
+}
+
+func subtle(text string) cardElement {
+	return cardElement{Type: "TextBlock", Text: text, IsSubtle: true, Size: "Small", Wrap: true}
+}
+
+// teamsText converts mrkdwn to the Markdown of card text blocks, where each
+// line must be a paragraph to be shown on its own
+func teamsText(text string) string {
+	return newlines.ReplaceAllString(Markdown(text), "\n\n")
+}
@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// Webhook event types
+const (
+	EventAlert            = "alert"
+	EventIncident         = "incident"
+	EventAnalysis         = "analysis"
+	EventReply            = "reply"
+	EventResolved         = "resolved"
+	EventIncidentResolved = "incident_resolved"
+)
+
+// WebhookEvent is the JSON body posted to a generic webhook for each
+// notification. The events of an alert or incident share its thread ID.
+type WebhookEvent struct {
+	Event                string          `json:"event"`
+	ThreadID             string          `json:"thread_id"`
+	Timestamp            time.Time       `json:"timestamp"`
+	Alert                *types.Alert    `json:"alert,omitempty"`    // The alert, or the lead alert of an incident's analysis
+	Incident             *types.Incident `json:"incident,omitempty"` // Set for incident and incident_resolved events
+	Title                string          `json:"title,omitempty"`    // Analysis title, e.g. "✅ Analysis Complete"
+	Text                 string          `json:"text,omitempty"`     // Analysis or reply, in Markdown
+	Footer               string          `json:"footer,omitempty"`   // Analysis footer, e.g. the provider
+	ResolvedAfterSeconds float64         `json:"resolved_after_seconds,omitempty"`
+}
+
+// Webhook posts every notification as a JSON event to a URL, for tools
+// without a dedicated notifier. Analyses aren't streamed or rated.
+type Webhook struct {
+	url    string
+	token  string // Sent as a bearer token, if set
+	client *http.Client
+}
+
+// NewWebhook creates a generic webhook notifier
+func NewWebhook(url, token string) *Webhook {
+	return &Webhook{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns "webhook"
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+// IsConfigured checks if a URL is set
+func (w *Webhook) IsConfigured() bool {
+	return w.url != ""
+}
+
+// SendAlert posts an alert event and returns its new thread ID
+func (w *Webhook) SendAlert(ctx context.Context, alert types.Alert) (string, error) {
+	threadID := newThreadID()
+	return threadID, w.send(ctx, WebhookEvent{Event: EventAlert, ThreadID: threadID, Alert: &alert})
+}
+
+// SendIncident posts an incident event and returns its new thread ID
+func (w *Webhook) SendIncident(ctx context.Context, incident *types.Incident) (string, error) {
+	threadID := newThreadID()
+	return threadID, w.send(ctx, WebhookEvent{Event: EventIncident, ThreadID: threadID, Incident: incident})
+}
+
+// SendAnalysisInThread does nothing, since only complete analyses are posted
+func (w *Webhook) SendAnalysisInThread(_ context.Context, _ types.Alert, _, _ string) (string, error) {
+	return "", nil
+}
+
+// UpdateMessage does nothing, since only complete analyses are posted
+func (w *Webhook) UpdateMessage(_ context.Context, _, _ string) error {
+	return nil
+}
+
+// PostAnalysis posts an analysis event. Webhook analyses can't be rated, so
+// no message is returned.
+func (w *Webhook) PostAnalysis(ctx context.Context, threadTS, _ string, msg types.AnalysisMessage) (string, error) {
+	return "", w.send(ctx, WebhookEvent{
+		Event:    EventAnalysis,
+		ThreadID: threadTS,
+		Alert:    &msg.Alert,
+		Title:    strings.ReplaceAll(msg.Title, "*", ""),
+		Text:     Markdown(msg.Analysis),
+		Footer:   Markdown(msg.Footer),
+	})
+}
+
+// ReplyToThread posts a reply event
+func (w *Webhook) ReplyToThread(ctx context.Context, threadTS, text string) error {
+	return w.send(ctx, WebhookEvent{Event: EventReply, ThreadID: threadTS, Text: Markdown(text)})
+}
+
+// SendResolution posts a resolved event
+func (w *Webhook) SendResolution(ctx context.Context, alert types.Alert, threadTS string, duration time.Duration) error {
+	return w.send(ctx, WebhookEvent{
+		Event:                EventResolved,
+		ThreadID:             threadTS,
+		Alert:                &alert,
+		ResolvedAfterSeconds: duration.Seconds(),
+	})
+}
+
+// SendIncidentResolution posts an incident_resolved event
+func (w *Webhook) SendIncidentResolution(ctx context.Context, incident *types.Incident, threadTS string, duration time.Duration) error {
+	return w.send(ctx, WebhookEvent{
+		Event:                EventIncidentResolved,
+		ThreadID:             threadTS,
+		Incident:             incident,
+		ResolvedAfterSeconds: duration.Seconds(),
+	})
+}
+
+// send posts an event to the webhook
+func (w *Webhook) send(ctx context.Context, event WebhookEvent) error {
+	event.Timestamp = time.Now()
+	if _, err := sendJSON(ctx, w.client, "POST", w.url, w.token, event); err != nil {
+		return fmt.Errorf("webhook %s event: %w", event.Event, err)
+	}
+	log.Printf("Sent %s event to webhook (thread %s)", event.Event, event.ThreadID)
+	return nil
+}
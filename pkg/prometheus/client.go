@@ -0,0 +1,248 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client queries a Prometheus-compatible HTTP API (Prometheus, Thanos, Mimir, VictoriaMetrics)
+type Client struct {
+	baseURL     string
+	bearerToken string
+	client      *http.Client
+}
+
+// NewClient creates a new Prometheus client. bearerToken is optional.
+func NewClient(baseURL, bearerToken string) *Client {
+	return &Client{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		bearerToken: bearerToken,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Point is a single sample of a time series
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series is a labelled time series returned by a range query
+type Series struct {
+	Labels map[string]string
+	Points []Point
+}
+
+// Prometheus API response structures
+type queryRangeResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Values [][2]interface{}  `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// QueryRange evaluates a PromQL expression over a time range
+// Reference: https://prometheus.io/docs/prometheus/latest/querying/api/#range-queries
+func (c *Client) QueryRange(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]Series, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatInt(start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(end.Unix(), 10))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/v1/query_range", strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if c.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Prometheus: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Prometheus response: %w", err)
+	}
+
+	var result queryRangeResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("Prometheus returned status %d: %s", resp.StatusCode, string(body))
+		}
+		return nil, fmt.Errorf("failed to decode Prometheus response: %w", err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("Prometheus query failed (%s): %s", result.ErrorType, result.Error)
+	}
+
+	series := make([]Series, 0, len(result.Data.Result))
+	for _, r := range result.Data.Result {
+		s := Series{Labels: r.Metric, Points: make([]Point, 0, len(r.Values))}
+		for _, v := range r.Values {
+			ts, ok := v[0].(float64)
+			if !ok {
+				continue
+			}
+			str, ok := v[1].(string)
+			if !ok {
+				continue
+			}
+			value, err := strconv.ParseFloat(str, 64)
+			if err != nil || math.IsNaN(value) {
+				continue
+			}
+			s.Points = append(s.Points, Point{
+				Time:  time.Unix(int64(ts), 0),
+				Value: value,
+			})
+		}
+		series = append(series, s)
+	}
+
+	return series, nil
+}
+
+// ExprFromGeneratorURL extracts the PromQL expression from an alert's
+// generatorURL (e.g. http://prometheus/graph?g0.expr=...&g0.tab=1)
+func ExprFromGeneratorURL(generatorURL string) string {
+	u, err := url.Parse(generatorURL)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("g0.expr")
+}
+
+// sparkBlocks are the characters used to draw trends, lowest to highest
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Summarize renders series as compact text: one line per series with
+// min/avg/max/last and a sparkline, highest last value first. At most
+// maxSeries series are shown.
+func Summarize(series []Series, maxSeries int) string {
+	if len(series) == 0 {
+		return "No data\n"
+	}
+
+	sorted := make([]Series, len(series))
+	copy(sorted, series)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return lastValue(sorted[i]) > lastValue(sorted[j])
+	})
+
+	var out strings.Builder
+	for i, s := range sorted {
+		if i >= maxSeries {
+			out.WriteString(fmt.Sprintf("... %d more series\n", len(sorted)-i))
+			break
+		}
+		if len(s.Points) == 0 {
+			continue
+		}
+
+		lo, hi, sum := math.Inf(1), math.Inf(-1), 0.0
+		for _, p := range s.Points {
+			lo = math.Min(lo, p.Value)
+			hi = math.Max(hi, p.Value)
+			sum += p.Value
+		}
+		avg := sum / float64(len(s.Points))
+
+		out.WriteString(fmt.Sprintf("%s min=%s avg=%s max=%s last=%s %s\n",
+			formatLabels(s.Labels), formatValue(lo), formatValue(avg), formatValue(hi),
+			formatValue(lastValue(s)), sparkline(s.Points, 20, lo, hi)))
+	}
+
+	return out.String()
+}
+
+// lastValue returns the most recent value of a series
+func lastValue(s Series) float64 {
+	if len(s.Points) == 0 {
+		return math.Inf(-1)
+	}
+	return s.Points[len(s.Points)-1].Value
+}
+
+// sparkline draws points as a fixed-width trend, averaging into buckets
+func sparkline(points []Point, width int, lo, hi float64) string {
+	if len(points) < width {
+		width = len(points)
+	}
+
+	var line strings.Builder
+	for b := 0; b < width; b++ {
+		from := b * len(points) / width
+		to := (b + 1) * len(points) / width
+		sum := 0.0
+		for _, p := range points[from:to] {
+			sum += p.Value
+		}
+		v := sum / float64(to-from)
+
+		idx := 0
+		if hi > lo {
+			idx = int((v - lo) / (hi - lo) * float64(len(sparkBlocks)-1))
+		}
+		line.WriteRune(sparkBlocks[idx])
+	}
+	return line.String()
+}
+
+// formatLabels renders the identifying labels of a series, skipping noise
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		switch k {
+		case "__name__", "job", "instance", "endpoint", "service", "prometheus", "id", "image", "metrics_path":
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// formatValue renders a sample value compactly, with k/M/G suffixes for large numbers
+func formatValue(v float64) string {
+	abs := math.Abs(v)
+	switch {
+	case math.IsNaN(v) || math.IsInf(v, 0):
+		return fmt.Sprint(v)
+	case abs >= 1e9:
+		return fmt.Sprintf("%.2fG", v/1e9)
+	case abs >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case abs >= 1e4:
+		return fmt.Sprintf("%.1fk", v/1e3)
+	case abs >= 1 || abs == 0:
+		return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+	default:
+		return strconv.FormatFloat(v, 'g', 3, 64)
+	}
+}
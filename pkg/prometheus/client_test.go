@@ -0,0 +1,150 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantSeries int
+		wantPoints int
+		wantErr    string
+	}{
+		{
+			name:   "matrix",
+			status: http.StatusOK,
+			body: `{"status":"success","data":{"resultType":"matrix","result":[
+				{"metric":{"pod":"api-1"},"values":[[1700000000,"1"],[1700000060,"2.5"],[1700000120,"NaN"]]},
+				{"metric":{"pod":"api-2"},"values":[[1700000000,"0"]]}]}}`,
+			wantSeries: 2,
+			wantPoints: 2,
+		},
+		{
+			name:       "empty result",
+			status:     http.StatusOK,
+			body:       `{"status":"success","data":{"resultType":"matrix","result":[]}}`,
+			wantSeries: 0,
+		},
+		{
+			name:    "query error",
+			status:  http.StatusBadRequest,
+			body:    `{"status":"error","errorType":"bad_data","error":"parse error at char 5"}`,
+			wantErr: "bad_data",
+		},
+		{
+			name:    "non-JSON error",
+			status:  http.StatusBadGateway,
+			body:    "upstream unavailable",
+			wantErr: "status 502",
+		},
+		{
+			name:    "invalid JSON",
+			status:  http.StatusOK,
+			body:    "{",
+			wantErr: "failed to decode",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v1/query_range" {
+					t.Errorf("path = %s, want /api/v1/query_range", r.URL.Path)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer token" {
+					t.Errorf("Authorization = %q, want the bearer token", got)
+				}
+				if err := r.ParseForm(); err != nil {
+					t.Error(err)
+				}
+				if r.Form.Get("query") != "up" || r.Form.Get("step") != "60" || r.Form.Get("start") != "1700000000" {
+					t.Errorf("form = %v, want the query, start and step", r.Form)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			c := NewClient(server.URL+"/", "token")
+			start := time.Unix(1700000000, 0)
+			series, err := c.QueryRange(context.Background(), "up", start, start.Add(time.Hour), time.Minute)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("QueryRange() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(series) != tt.wantSeries {
+				t.Fatalf("got %d series, want %d", len(series), tt.wantSeries)
+			}
+			if tt.wantSeries > 0 {
+				if got := len(series[0].Points); got != tt.wantPoints {
+					t.Errorf("got %d points, want %d without NaN", got, tt.wantPoints)
+				}
+				if p := series[0].Points[1]; p.Value != 2.5 || !p.Time.Equal(time.Unix(1700000060, 0)) {
+					t.Errorf("point = %+v, want 2.5 at 1700000060", p)
+				}
+			}
+		})
+	}
+}
+
+func TestExprFromGeneratorURL(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{
+			name: "Prometheus graph link",
+			url:  "http://prometheus:9090/graph?g0.expr=rate%28http_requests_total%7Bcode%3D~%225..%22%7D%5B5m%5D%29+%3E+0.1&g0.tab=1",
+			want: `rate(http_requests_total{code=~"5.."}[5m]) > 0.1`,
+		},
+		{name: "no expression", url: "http://prometheus:9090/graph?g0.tab=1", want: ""},
+		{name: "empty", url: "", want: ""},
+		{name: "invalid URL", url: "http://[::1", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExprFromGeneratorURL(tt.url); got != tt.want {
+				t.Errorf("ExprFromGeneratorURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	if got := Summarize(nil, 5); got != "No data\n" {
+		t.Errorf("Summarize(nil) = %q, want No data", got)
+	}
+
+	series := []Series{
+		{Labels: map[string]string{"pod": "api-1", "job": "kubelet"}, Points: []Point{{Value: 1}, {Value: 3}}},
+		{Labels: map[string]string{"pod": "api-2"}, Points: []Point{{Value: 5}, {Value: 20000}}},
+		{Labels: map[string]string{"pod": "api-3"}, Points: []Point{{Value: 0}}},
+	}
+	got := Summarize(series, 2)
+	lines := strings.Split(strings.TrimSpace(got), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Summarize() = %q, want 2 series and a remainder line", got)
+	}
+	if !strings.HasPrefix(lines[0], `{pod="api-2"} min=5 avg=10.0k max=20.0k last=20.0k`) {
+		t.Errorf("first line = %q, want the highest last value first", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], `{pod="api-1"} min=1 avg=2 max=3 last=3`) {
+		t.Errorf("second line = %q, want noise labels dropped", lines[1])
+	}
+	if lines[2] != "... 1 more series" {
+		t.Errorf("last line = %q, want the remainder", lines[2])
+	}
+}
@@ -0,0 +1,332 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+// Config customizes redaction. The zero value enables every built-in detector.
+type Config struct {
+	// Disable lists built-in detectors turned off everywhere
+	Disable []string `json:"disable"`
+	// Rules are custom detectors applied after the built-in ones
+	Rules []Rule `json:"rules"`
+	// Namespaces are per-namespace policies; the first matching one applies
+	Namespaces []NamespacePolicy `json:"namespaces"`
+}
+
+// Rule is a custom regex detector
+type Rule struct {
+	Name        string `json:"name"`
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"` // Defaults to [REDACTED:<name>]; may use ${1} submatches
+}
+
+// NamespacePolicy adjusts redaction for matching namespaces
+type NamespacePolicy struct {
+	Match   string   `json:"match"`   // Namespace name or glob, e.g. "dev-*"
+	Skip    bool     `json:"skip"`    // No redaction at all
+	Disable []string `json:"disable"` // Additional detectors (built-in or rule names) turned off
+	Rules   []Rule   `json:"rules"`   // Additional rules for these namespaces
+}
+
+// LoadConfig reads a redaction config file. A missing file returns nil,
+// meaning the built-in detectors only.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction config: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Counts is the number of redacted values per detector
+type Counts map[string]int
+
+// Add merges other into c
+func (c Counts) Add(other Counts) {
+	for k, v := range other {
+		c[k] += v
+	}
+}
+
+// Total returns the number of redacted values
+func (c Counts) Total() int {
+	total := 0
+	for _, v := range c {
+		total += v
+	}
+	return total
+}
+
+// String renders the counts as "3 email, 1 jwt", most frequent first
+func (c Counts) String() string {
+	kinds := make([]string, 0, len(c))
+	for k := range c {
+		kinds = append(kinds, k)
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		if c[kinds[i]] != c[kinds[j]] {
+			return c[kinds[i]] > c[kinds[j]]
+		}
+		return kinds[i] < kinds[j]
+	})
+
+	parts := make([]string, len(kinds))
+	for i, k := range kinds {
+		parts[i] = fmt.Sprintf("%d %s", c[k], k)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Redactor replaces secrets and PII with [REDACTED:<kind>] placeholders
+type Redactor struct {
+	detectors  []*detector
+	namespaces []namespacePolicy
+
+	mu       sync.RWMutex
+	dynamic  []namespacePolicy // Set at runtime, evaluated before namespaces
+	fallback []namespacePolicy // Set at runtime, evaluated after namespaces
+}
+
+// namespacePolicy is a compiled NamespacePolicy
+type namespacePolicy struct {
+	match     string
+	skip      bool
+	disabled  map[string]bool
+	detectors []*detector
+}
+
+// New creates a redactor from a config; cfg may be nil
+func New(cfg *Config) (*Redactor, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	builtins := builtinDetectors()
+	known := make(map[string]bool, len(builtins))
+	for _, d := range builtins {
+		known[d.name] = true
+	}
+
+	disabled := make(map[string]bool)
+	for _, name := range cfg.Disable {
+		if !known[name] {
+			return nil, fmt.Errorf("invalid redaction config: unknown detector %q", name)
+		}
+		disabled[name] = true
+	}
+
+	r := &Redactor{}
+	for _, d := range builtins {
+		if !disabled[d.name] {
+			r.detectors = append(r.detectors, d)
+		}
+	}
+	rules, err := compileRules(cfg.Rules)
+	if err != nil {
+		return nil, err
+	}
+	r.detectors = append(r.detectors, rules...)
+
+	if r.namespaces, err = compilePolicies(cfg.Namespaces); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// SetNamespacePolicies replaces the policies set at runtime, such as those of
+// AnalysisPolicy resources. They are evaluated before the config file's, and
+// fallback, if set, applies to namespaces none of them match.
+func (r *Redactor) SetNamespacePolicies(policies []NamespacePolicy, fallback *NamespacePolicy) error {
+	compiled, err := compilePolicies(policies)
+	if err != nil {
+		return err
+	}
+	var compiledFallback []namespacePolicy
+	if fallback != nil {
+		if compiledFallback, err = compilePolicies([]NamespacePolicy{*fallback}); err != nil {
+			return err
+		}
+	}
+	r.mu.Lock()
+	r.dynamic = compiled
+	r.fallback = compiledFallback
+	r.mu.Unlock()
+	return nil
+}
+
+// ValidatePolicy reports whether a namespace policy compiles
+func ValidatePolicy(policy NamespacePolicy) error {
+	_, err := compilePolicies([]NamespacePolicy{policy})
+	return err
+}
+
+// compilePolicies turns namespace policies into their compiled form
+func compilePolicies(policies []NamespacePolicy) ([]namespacePolicy, error) {
+	compiled := make([]namespacePolicy, 0, len(policies))
+	for _, p := range policies {
+		if _, err := path.Match(p.Match, ""); err != nil {
+			return nil, fmt.Errorf("invalid redaction config: bad namespace pattern %q: %w", p.Match, err)
+		}
+		policy := namespacePolicy{match: p.Match, skip: p.Skip, disabled: make(map[string]bool)}
+		for _, name := range p.Disable {
+			policy.disabled[name] = true
+		}
+		var err error
+		if policy.detectors, err = compileRules(p.Rules); err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, policy)
+	}
+	return compiled, nil
+}
+
+// compileRules turns custom rules into detectors
+func compileRules(rules []Rule) ([]*detector, error) {
+	detectors := make([]*detector, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Name == "" || rule.Pattern == "" {
+			return nil, fmt.Errorf("invalid redaction config: rules need a name and a pattern")
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction config: rule %s: %w", rule.Name, err)
+		}
+		replacement := rule.Replacement
+		if replacement == "" {
+			replacement = placeholder(rule.Name)
+		}
+		detectors = append(detectors, &detector{name: rule.Name, re: re, replacement: replacement})
+	}
+	return detectors, nil
+}
+
+// policyFor returns the first policy matching a namespace, or nil
+func (r *Redactor) policyFor(namespace string) *namespacePolicy {
+	r.mu.RLock()
+	dynamic, fallback := r.dynamic, r.fallback
+	r.mu.RUnlock()
+
+	for _, policies := range [][]namespacePolicy{dynamic, r.namespaces, fallback} {
+		for i := range policies {
+			if ok, _ := path.Match(policies[i].match, namespace); ok {
+				return &policies[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Redact replaces sensitive values in text according to the namespace's policy
+func (r *Redactor) Redact(namespace, text string) (string, Counts) {
+	counts := Counts{}
+	if text == "" {
+		return text, counts
+	}
+
+	detectors := r.detectors
+	policy := r.policyFor(namespace)
+	if policy != nil {
+		if policy.skip {
+			return text, counts
+		}
+		detectors = append(append([]*detector{}, detectors...), policy.detectors...)
+	}
+
+	for _, d := range detectors {
+		if policy != nil && policy.disabled[d.name] {
+			continue
+		}
+		var n int
+		text, n = d.apply(text)
+		if n > 0 {
+			counts[d.name] += n
+		}
+	}
+	return text, counts
+}
+
+// RedactAlert returns a copy of the alert with redacted annotations. Labels
+// are kept since they identify the resources to debug.
+func (r *Redactor) RedactAlert(alert types.Alert) (types.Alert, Counts) {
+	counts := Counts{}
+	namespace := alert.Labels["namespace"]
+
+	annotations := make(map[string]string, len(alert.Annotations))
+	for k, v := range alert.Annotations {
+		redacted, c := r.Redact(namespace, v)
+		annotations[k] = redacted
+		counts.Add(c)
+	}
+	alert.Annotations = annotations
+	return alert, counts
+}
+
+// RedactResult redacts every section of a debug result in place, including
+// raw data, and adds the counts to result.Redactions
+func (r *Redactor) RedactResult(result *types.DebugResult) Counts {
+	namespace := result.Alert.Labels["namespace"]
+	counts := Counts{}
+
+	var c Counts
+	result.Alert, c = r.RedactAlert(result.Alert)
+	counts.Add(c)
+
+	for i := range result.Sections {
+		s := &result.Sections[i]
+		s.Content, c = r.Redact(namespace, s.Content)
+		counts.Add(c)
+		s.Error, c = r.Redact(namespace, s.Error)
+		counts.Add(c)
+		s.Data, c = r.redactData(namespace, s.Data)
+		counts.Add(c)
+	}
+
+	if result.Redactions == nil {
+		result.Redactions = make(map[string]int)
+	}
+	Counts(result.Redactions).Add(counts)
+	return counts
+}
+
+// redactData redacts raw data through its JSON form. Data that can't be
+// marshalled is dropped rather than passed on unredacted.
+func (r *Redactor) redactData(namespace string, data interface{}) (interface{}, Counts) {
+	if data == nil {
+		return nil, Counts{}
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, Counts{}
+	}
+
+	redacted, counts := r.Redact(namespace, string(raw))
+	if counts.Total() == 0 {
+		return data, counts
+	}
+
+	var out interface{}
+	if err := json.Unmarshal([]byte(redacted), &out); err != nil {
+		return nil, counts
+	}
+	return out, counts
+}
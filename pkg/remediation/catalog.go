@@ -0,0 +1,155 @@
+package remediation
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// Actions of the catalog
+const (
+	ActionRestart   = "restart"
+	ActionScale     = "scale"
+	ActionRollback  = "rollback"
+	ActionCordon    = "cordon"
+	ActionDrain     = "drain"
+	ActionDeletePod = "delete-pod"
+)
+
+// actionSpec describes an action of the catalog
+type actionSpec struct {
+	kinds       []string // Kinds it applies to
+	usage       string   // Command syntax shown to the LLM
+	description string
+}
+
+// catalog lists the actions that can be proposed and approved, in the order
+// they're offered
+var catalog = []struct {
+	name string
+	actionSpec
+}{
+	{ActionRestart, actionSpec{[]string{"Deployment", "StatefulSet", "DaemonSet"}, "restart deployment/<name>", "rolling restart of a Deployment, StatefulSet or DaemonSet"}},
+	{ActionScale, actionSpec{[]string{"Deployment", "StatefulSet"}, "scale deployment/<name> <replicas>", "set the replicas of a Deployment or StatefulSet (at least 1)"}},
+	{ActionRollback, actionSpec{[]string{"Deployment"}, "rollback deployment/<name>", "roll a Deployment back to its previous revision"}},
+	{ActionCordon, actionSpec{[]string{"Node"}, "cordon node/<name>", "mark a node unschedulable"}},
+	{ActionDrain, actionSpec{[]string{"Node"}, "drain node/<name>", "cordon a node and evict its pods, respecting PodDisruptionBudgets"}},
+	{ActionDeletePod, actionSpec{[]string{"Pod"}, "delete-pod pod/<name>", "delete a stuck pod so its controller recreates it"}},
+}
+
+// lookup returns the spec of an action
+func lookup(action string) (actionSpec, bool) {
+	for _, a := range catalog {
+		if a.name == action {
+			return a.actionSpec, true
+		}
+	}
+	return actionSpec{}, false
+}
+
+// IsAction reports whether name is an action of the catalog
+func IsAction(name string) bool {
+	_, ok := lookup(name)
+	return ok
+}
+
+// IsNodeAction reports whether an action of the catalog acts on nodes, which
+// are cluster-scoped
+func IsNodeAction(name string) bool {
+	spec, ok := lookup(name)
+	return ok && contains(spec.kinds, "Node")
+}
+
+// Validate checks a proposed action against the catalog, normalizing its
+// action and kind, e.g. "deployment" to "Deployment"
+func Validate(a *types.ProposedAction) error {
+	a.Action = strings.ToLower(strings.TrimSpace(a.Action))
+	spec, ok := lookup(a.Action)
+	if !ok {
+		return fmt.Errorf("unknown action %q", a.Action)
+	}
+
+	kind := ""
+	for _, k := range spec.kinds {
+		if strings.EqualFold(k, strings.TrimSpace(a.Kind)) {
+			kind = k
+		}
+	}
+	if kind == "" {
+		return fmt.Errorf("%s applies to %s, not %q", a.Action, strings.Join(spec.kinds, ", "), a.Kind)
+	}
+	a.Kind = kind
+
+	a.Name = strings.TrimSpace(a.Name)
+	if a.Name == "" || strings.ContainsAny(a.Name, "/ ") {
+		return fmt.Errorf("invalid %s name %q", a.Kind, a.Name)
+	}
+	if a.Action == ActionScale && a.Replicas < 1 {
+		return fmt.Errorf("scale needs replicas of at least 1")
+	}
+	if a.Action != ActionScale {
+		a.Replicas = 0
+	}
+	return nil
+}
+
+// Describe renders the actions in allowed for the "Available Actions" section
+// of the debug info
+func Describe(allowed []string) string {
+	var b strings.Builder
+	for _, a := range catalog {
+		if contains(allowed, a.name) {
+			b.WriteString(fmt.Sprintf("- `%s`: %s\n", a.usage, a.description))
+		}
+	}
+	return b.String()
+}
+
+var (
+	// proposedHeader starts the proposed actions of a free-form analysis
+	proposedHeader = regexp.MustCompile(`(?m)^\*Proposed Actions:\*`)
+	// nextHeader ends them
+	nextHeader = regexp.MustCompile(`(?m)^\*[A-Z][A-Za-z ]+:\*`)
+	// proposedCommand matches a command of the Proposed Actions list
+	proposedCommand = regexp.MustCompile("`([a-z-]+) ([A-Za-z]+)/([A-Za-z0-9.-]+)(?: ([0-9]+))?`(?:\\s*[—–-]\\s*(.*))?")
+)
+
+// Parse returns the actions of the *Proposed Actions:* list of a free-form
+// analysis. Commands outside the catalog are skipped.
+func Parse(analysis string) []types.ProposedAction {
+	loc := proposedHeader.FindStringIndex(analysis)
+	if loc == nil {
+		return nil
+	}
+	list := analysis[loc[1]:]
+	if next := nextHeader.FindStringIndex(list); next != nil {
+		list = list[:next[0]]
+	}
+
+	var actions []types.ProposedAction
+	for _, line := range strings.Split(list, "\n") {
+		m := proposedCommand.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		replicas, _ := strconv.Atoi(m[4])
+		a := types.ProposedAction{Action: m[1], Kind: m[2], Name: m[3], Replicas: replicas, Reason: strings.TrimSpace(m[5])}
+		if Validate(&a) == nil {
+			actions = append(actions, a)
+		}
+	}
+	return actions
+}
+
+// contains reports whether list contains s
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,526 @@
+package remediation
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/kubernetes"
+	"github.com/valentinpelus/k8flex/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// proposalTTL is how long a proposed action can be approved; after that
+	// the evidence it was based on is stale
+	proposalTTL = 24 * time.Hour
+	// actionTimeout bounds the dry run and the execution of an action
+	actionTimeout = 2 * time.Minute
+)
+
+// Outcomes of an approval
+const (
+	OutcomeExecuted     = "executed"
+	OutcomeDryRun       = "dry-run"        // Dry run passed; not applied in dry-run-only mode
+	OutcomeDryRunFailed = "dry-run-failed" // Nothing was changed
+	OutcomeFailed       = "failed"
+	OutcomeDenied       = "denied"
+)
+
+// Config is the action allowlist, read from the remediation config file
+type Config struct {
+	// Approvers are the Slack user IDs allowed to approve actions; empty
+	// allows anyone who can see the message
+	Approvers []string `json:"approvers"`
+	// Namespaces are per-namespace rules; the first matching one applies.
+	// Namespaces without one allow no action.
+	Namespaces []NamespaceRules `json:"namespaces"`
+	// Nodes are the node actions allowed cluster-wide. Nodes aren't owned by
+	// the alert's namespace, so neither namespace rules nor analysis policies
+	// can allow them.
+	Nodes *NodeRules `json:"nodes"`
+}
+
+// NodeRules are the node actions allowed and who may approve them
+type NodeRules struct {
+	Actions   []string `json:"actions"`   // cordon and drain
+	Approvers []string `json:"approvers"` // Required; the default approvers don't apply
+}
+
+// NamespaceRules are the actions allowed in matching namespaces
+type NamespaceRules struct {
+	Match     string   `json:"match"`     // Namespace name or glob, e.g. "team-*"
+	Actions   []string `json:"actions"`   // Actions of the catalog allowed
+	Approvers []string `json:"approvers"` // Replaces the default approvers
+}
+
+// LoadConfig reads a remediation config file. A missing file returns nil,
+// meaning no action is allowed unless an analysis policy allows some.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remediation config: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse remediation config %s: %w", path, err)
+	}
+	for i := range cfg.Namespaces {
+		if err := ValidateRules(&cfg.Namespaces[i]); err != nil {
+			return nil, fmt.Errorf("remediation config %s: namespaces[%d]: %w", path, i, err)
+		}
+	}
+	if cfg.Nodes != nil {
+		if err := validateNodeRules(cfg.Nodes); err != nil {
+			return nil, fmt.Errorf("remediation config %s: nodes: %w", path, err)
+		}
+	}
+	return &cfg, nil
+}
+
+// ValidateRules checks that the actions of rules are namespaced actions of
+// the catalog and its match is a valid glob
+func ValidateRules(rules *NamespaceRules) error {
+	if _, err := path.Match(rules.Match, ""); err != nil {
+		return fmt.Errorf("invalid match %q: %w", rules.Match, err)
+	}
+	return ValidateNamespacedActions(rules.Actions)
+}
+
+// ValidateNamespacedActions checks that actions are in the catalog and don't
+// act on nodes, which only the cluster-wide node rules allow
+func ValidateNamespacedActions(actions []string) error {
+	for _, action := range actions {
+		if !IsAction(action) {
+			return fmt.Errorf("unknown action %q", action)
+		}
+		if IsNodeAction(action) {
+			return fmt.Errorf("%s acts on nodes and can only be allowed in the nodes rules of the remediation config", action)
+		}
+	}
+	return nil
+}
+
+// validateNodeRules checks that node rules only allow node actions and name
+// their approvers
+func validateNodeRules(rules *NodeRules) error {
+	for _, action := range rules.Actions {
+		if !IsNodeAction(action) {
+			return fmt.Errorf("%q is not a node action", action)
+		}
+	}
+	if len(rules.Actions) > 0 && len(rules.Approvers) == 0 {
+		return fmt.Errorf("approvers are required for node actions")
+	}
+	return nil
+}
+
+// Request is a proposed action awaiting approval. It travels in the value of
+// the Slack approval button, so it's kept small.
+type Request struct {
+	ID         string    `json:"id"`
+	Action     string    `json:"action"`
+	Kind       string    `json:"kind"`
+	Name       string    `json:"name"`
+	Replicas   int       `json:"replicas,omitempty"`
+	Namespace  string    `json:"namespace"` // Alert's namespace; node actions use the node rules instead
+	Alert      string    `json:"alert"`
+	ThreadTS   string    `json:"thread_ts"`
+	ProposedAt time.Time `json:"proposed_at"`
+}
+
+// NewRequest creates the request of an action proposed for an alert
+func NewRequest(a types.ProposedAction, alert types.Alert, threadTS string) Request {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return Request{
+		ID:         hex.EncodeToString(id),
+		Action:     a.Action,
+		Kind:       a.Kind,
+		Name:       a.Name,
+		Replicas:   a.Replicas,
+		Namespace:  alert.Labels["namespace"],
+		Alert:      alert.Labels["alertname"],
+		ThreadTS:   threadTS,
+		ProposedAt: time.Now(),
+	}
+}
+
+// Command renders the request's action, e.g. "restart deployment/api"
+func (r Request) Command() string {
+	return types.ProposedAction{Action: r.Action, Kind: r.Kind, Name: r.Name, Replicas: r.Replicas}.Command()
+}
+
+// Encode serializes a request for a button value
+func (r Request) Encode() string {
+	data, _ := json.Marshal(r)
+	return string(data)
+}
+
+// DecodeRequest parses a request from a button value
+func DecodeRequest(value string) (Request, error) {
+	var r Request
+	if err := json.Unmarshal([]byte(value), &r); err != nil {
+		return r, fmt.Errorf("invalid action request: %w", err)
+	}
+	return r, nil
+}
+
+// Approver is the Slack user who approved an action
+type Approver struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Team string `json:"team,omitempty"`
+}
+
+// Result is the outcome of an approval
+type Result struct {
+	Outcome string
+	Message string
+}
+
+// Options configures an executor
+type Options struct {
+	// DryRunOnly stops after the server-side dry run, so approvals are
+	// rehearsed without changing anything
+	DryRunOnly bool
+	// AuditLog is the JSON lines file approvals are appended to; "" only logs them
+	AuditLog string
+	// NamespaceRules returns the rules an analysis policy sets for a
+	// namespace, replacing those of the config file; nil when it sets none
+	NamespaceRules func(namespace string) *NamespaceRules
+}
+
+// Executor checks approved actions against the allowlist, dry-runs them on
+// the API server, runs them and audit-logs every decision
+type Executor struct {
+	k8sClient *kubernetes.Client
+	config    *Config
+	options   Options
+	audit     *auditLog
+
+	mu      sync.Mutex
+	claimed map[string]claim // Request ID to its claim, while running or once executed
+}
+
+// claim records who approved a request, kept until the proposal expires
+type claim struct {
+	approver   string
+	proposedAt time.Time
+}
+
+// New creates an executor. A nil config allows no action outside analysis
+// policies. Requests the audit log records as run stay claimed, so a
+// proposal approved before a restart doesn't run again.
+func New(k8sClient *kubernetes.Client, config *Config, options Options) *Executor {
+	if config == nil {
+		config = &Config{}
+	}
+	e := &Executor{
+		k8sClient: k8sClient,
+		config:    config,
+		options:   options,
+		audit:     &auditLog{path: options.AuditLog},
+		claimed:   make(map[string]claim),
+	}
+	claims, err := e.audit.claims()
+	if err != nil {
+		log.Printf("Warning: failed to read claimed actions from the audit log: %v", err)
+	}
+	for _, entry := range claims {
+		e.claimed[entry.Request.ID] = claim{approver: entry.Approver.Name, proposedAt: entry.Request.ProposedAt}
+	}
+	return e
+}
+
+// DryRunOnly reports whether approved actions are only dry-run
+func (e *Executor) DryRunOnly() bool {
+	return e.options.DryRunOnly
+}
+
+// rules returns the rules applying to a namespace, or nil
+func (e *Executor) rules(namespace string) *NamespaceRules {
+	if e.options.NamespaceRules != nil {
+		if rules := e.options.NamespaceRules(namespace); rules != nil {
+			return rules
+		}
+	}
+	for i := range e.config.Namespaces {
+		if ok, _ := path.Match(e.config.Namespaces[i].Match, namespace); ok {
+			return &e.config.Namespaces[i]
+		}
+	}
+	return nil
+}
+
+// AllowedActions returns the actions allowed for the alerts of a namespace:
+// its namespaced actions and the node actions allowed cluster-wide
+func (e *Executor) AllowedActions(namespace string) []string {
+	if e == nil {
+		return nil
+	}
+	var allowed []string
+	if rules := e.rules(namespace); rules != nil {
+		for _, action := range rules.Actions {
+			if !IsNodeAction(action) {
+				allowed = append(allowed, action)
+			}
+		}
+	}
+	return append(allowed, e.nodeActions()...)
+}
+
+// nodeActions returns the node actions allowed cluster-wide. Without
+// approvers, none are.
+func (e *Executor) nodeActions() []string {
+	if e.config.Nodes == nil || len(e.config.Nodes.Approvers) == 0 {
+		return nil
+	}
+	return e.config.Nodes.Actions
+}
+
+// Allowed checks a proposed action against the catalog and the allowlist of a namespace
+func (e *Executor) Allowed(a *types.ProposedAction, namespace string) error {
+	if err := Validate(a); err != nil {
+		return err
+	}
+	if IsNodeAction(a.Action) {
+		if !contains(e.nodeActions(), a.Action) {
+			return fmt.Errorf("%s is not allowed on nodes", a.Action)
+		}
+		return nil
+	}
+	if !contains(e.AllowedActions(namespace), a.Action) {
+		return fmt.Errorf("%s is not allowed in namespace %s", a.Action, namespace)
+	}
+	return nil
+}
+
+// authorized checks that approver may approve an action in a namespace.
+// Node actions need one of the approvers of the node rules.
+func (e *Executor) authorized(approver Approver, action, namespace string) error {
+	if IsNodeAction(action) {
+		if len(e.nodeActions()) > 0 && contains(e.config.Nodes.Approvers, approver.ID) {
+			return nil
+		}
+		return fmt.Errorf("%s is not an approver for node actions", approver.Name)
+	}
+
+	approvers := e.config.Approvers
+	if rules := e.rules(namespace); rules != nil && len(rules.Approvers) > 0 {
+		approvers = rules.Approvers
+	}
+	if len(approvers) == 0 || contains(approvers, approver.ID) {
+		return nil
+	}
+	return fmt.Errorf("%s is not an approver for namespace %s", approver.Name, namespace)
+}
+
+// Execute runs an approved action: it's checked against the allowlist again,
+// since it may have changed since the proposal, dry-run on the API server,
+// then run unless the executor is dry-run only. Every outcome is audit-logged
+// with the approver.
+func (e *Executor) Execute(ctx context.Context, req Request, approver Approver) Result {
+	result := e.execute(ctx, req, approver)
+	ran := (result.Outcome == OutcomeExecuted || result.Outcome == OutcomeFailed) && e.isClaimed(req.ID)
+	e.audit.record(req, approver, result, ran)
+	return result
+}
+
+// execute implements Execute
+func (e *Executor) execute(ctx context.Context, req Request, approver Approver) Result {
+	if time.Since(req.ProposedAt) > proposalTTL {
+		return Result{OutcomeDenied, fmt.Sprintf("the proposal expired after %s", proposalTTL)}
+	}
+	if err := e.authorized(approver, req.Action, req.Namespace); err != nil {
+		return Result{OutcomeDenied, err.Error()}
+	}
+	action := types.ProposedAction{Action: req.Action, Kind: req.Kind, Name: req.Name, Replicas: req.Replicas}
+	if err := e.Allowed(&action, req.Namespace); err != nil {
+		return Result{OutcomeDenied, err.Error()}
+	}
+
+	// A second click, by the same or another approver, mustn't run it twice
+	if by, claimed := e.claim(req, approver.Name); claimed {
+		return Result{OutcomeDenied, "already approved by " + by}
+	}
+	executed := false
+	defer func() {
+		if !executed {
+			e.release(req.ID)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, actionTimeout)
+	defer cancel()
+
+	if _, err := e.run(ctx, req, true); err != nil {
+		return Result{OutcomeDryRunFailed, err.Error()}
+	}
+	if e.options.DryRunOnly {
+		return Result{OutcomeDryRun, "dry run passed; dry-run-only mode, nothing was changed"}
+	}
+
+	message, err := e.run(ctx, req, false)
+	if err != nil {
+		// A partial drain has still changed the cluster
+		if message != "" {
+			executed = true
+			return Result{OutcomeFailed, message + ": " + err.Error()}
+		}
+		return Result{OutcomeFailed, err.Error()}
+	}
+	executed = true
+	return Result{OutcomeExecuted, message}
+}
+
+// run performs an action on the API server, or only dry-runs it
+func (e *Executor) run(ctx context.Context, req Request, dryRun bool) (string, error) {
+	workload := &kubernetes.Workload{Kind: req.Kind, Name: req.Name, Namespace: req.Namespace}
+	switch req.Action {
+	case ActionRestart:
+		return e.k8sClient.RestartWorkload(ctx, workload, dryRun)
+	case ActionScale:
+		return e.k8sClient.ScaleWorkload(ctx, workload, int32(req.Replicas), dryRun)
+	case ActionRollback:
+		return e.k8sClient.RollbackDeployment(ctx, req.Namespace, req.Name, dryRun)
+	case ActionCordon:
+		return e.k8sClient.CordonNode(ctx, req.Name, dryRun)
+	case ActionDrain:
+		return e.k8sClient.DrainNode(ctx, req.Name, dryRun)
+	case ActionDeletePod:
+		return e.k8sClient.DeletePod(ctx, req.Namespace, req.Name, dryRun)
+	}
+	return "", fmt.Errorf("unknown action %q", req.Action)
+}
+
+// claim marks a request as being handled by approver, or returns who already
+// is. Claims of expired proposals are pruned, since those are denied anyway.
+func (e *Executor) claim(req Request, approver string) (string, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for id, c := range e.claimed {
+		if time.Since(c.proposedAt) > proposalTTL {
+			delete(e.claimed, id)
+		}
+	}
+	if c, ok := e.claimed[req.ID]; ok {
+		return c.approver, true
+	}
+	e.claimed[req.ID] = claim{approver: approver, proposedAt: req.ProposedAt}
+	return "", false
+}
+
+// isClaimed reports whether a request is claimed
+func (e *Executor) isClaimed(id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, ok := e.claimed[id]
+	return ok
+}
+
+// release lets a request that didn't run be approved again, e.g. once a
+// PodDisruptionBudget allows it
+func (e *Executor) release(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.claimed, id)
+}
+
+// auditLog appends approvals to a JSON lines file
+type auditLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// auditEntry is a line of the audit log
+type auditEntry struct {
+	Time     time.Time `json:"time"`
+	Request  Request   `json:"request"`
+	Command  string    `json:"command"`
+	Approver Approver  `json:"approver"`
+	DryRun   bool      `json:"dry_run"`
+	Outcome  string    `json:"outcome"`
+	Message  string    `json:"message"`
+	Ran      bool      `json:"ran,omitempty"` // The action changed the cluster, so it can't be approved again
+}
+
+// record logs an approval and appends it to the audit file
+func (a *auditLog) record(req Request, approver Approver, result Result, ran bool) {
+	entry := auditEntry{
+		Time:     time.Now(),
+		Request:  req,
+		Command:  req.Command(),
+		Approver: approver,
+		DryRun:   result.Outcome == OutcomeDryRun || result.Outcome == OutcomeDryRunFailed,
+		Outcome:  result.Outcome,
+		Message:  result.Message,
+		Ran:      ran,
+	}
+	log.Printf("AUDIT: %s (%s) approved %q in namespace %s for alert %s: %s - %s",
+		approver.Name, approver.ID, entry.Command, req.Namespace, req.Alert, result.Outcome, strings.TrimSpace(result.Message))
+
+	if a.path == "" {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Warning: failed to encode audit entry: %v", err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Printf("Warning: failed to open audit log: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		log.Printf("Warning: failed to write audit log: %v", err)
+	}
+}
+
+// claims returns the entries of requests that ran and whose proposal hasn't
+// expired yet
+func (a *auditLog) claims() ([]auditEntry, error) {
+	if a.path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(a.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var claims []auditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if entry.Ran && time.Since(entry.Request.ProposedAt) <= proposalTTL {
+			claims = append(claims, entry)
+		}
+	}
+	return claims, scanner.Err()
+}
@@ -0,0 +1,255 @@
+package remediation
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// testConfig allows restarts to anyone in team-* namespaces, scaling to
+// U0OPS in prod, and node actions to U0SRE
+func testConfig() *Config {
+	return &Config{
+		Namespaces: []NamespaceRules{
+			{Match: "prod", Actions: []string{ActionScale, ActionRestart}, Approvers: []string{"U0OPS"}},
+			{Match: "team-*", Actions: []string{ActionRestart}},
+		},
+		Nodes: &NodeRules{Actions: []string{ActionCordon, ActionDrain}, Approvers: []string{"U0SRE"}},
+	}
+}
+
+func TestAllowedActions(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *Config
+		policy    *NamespaceRules
+		namespace string
+		want      []string
+	}{
+		{name: "first matching rule", config: testConfig(), namespace: "prod", want: []string{ActionScale, ActionRestart, ActionCordon, ActionDrain}},
+		{name: "glob rule", config: testConfig(), namespace: "team-a", want: []string{ActionRestart, ActionCordon, ActionDrain}},
+		{name: "no rule allows only node actions", config: testConfig(), namespace: "default", want: []string{ActionCordon, ActionDrain}},
+		{name: "nil config allows nothing", config: nil, namespace: "prod", want: nil},
+		{name: "policy replaces the config rules", config: testConfig(), policy: &NamespaceRules{Actions: []string{ActionDeletePod}}, namespace: "prod", want: []string{ActionDeletePod, ActionCordon, ActionDrain}},
+		{name: "node actions in namespace rules are ignored", config: &Config{
+			Namespaces: []NamespaceRules{{Match: "*", Actions: []string{ActionRestart, ActionDrain}}},
+		}, namespace: "prod", want: []string{ActionRestart}},
+		{name: "node actions without approvers are ignored", config: &Config{
+			Nodes: &NodeRules{Actions: []string{ActionCordon}},
+		}, namespace: "prod", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options Options
+			if tt.policy != nil {
+				options.NamespaceRules = func(string) *NamespaceRules { return tt.policy }
+			}
+			got := New(nil, tt.config, options).AllowedActions(tt.namespace)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AllowedActions(%q) = %v, want %v", tt.namespace, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		action    types.ProposedAction
+		namespace string
+		wantErr   bool
+	}{
+		{name: "allowed", action: types.ProposedAction{Action: "restart", Kind: "deployment", Name: "api"}, namespace: "team-a"},
+		{name: "not allowed in namespace", action: types.ProposedAction{Action: "scale", Kind: "Deployment", Name: "api", Replicas: 2}, namespace: "team-a", wantErr: true},
+		{name: "namespace without rules", action: types.ProposedAction{Action: "restart", Kind: "Deployment", Name: "api"}, namespace: "default", wantErr: true},
+		{name: "unknown action", action: types.ProposedAction{Action: "exec", Kind: "Pod", Name: "api-0"}, namespace: "prod", wantErr: true},
+		{name: "wrong kind", action: types.ProposedAction{Action: "rollback", Kind: "StatefulSet", Name: "db"}, namespace: "prod", wantErr: true},
+		{name: "node action from node rules", action: types.ProposedAction{Action: "drain", Kind: "Node", Name: "node-1"}, namespace: "default"},
+	}
+	e := New(nil, testConfig(), Options{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := e.Allowed(&tt.action, tt.namespace)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Allowed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRulesRejectsNodeActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   NamespaceRules
+		wantErr bool
+	}{
+		{name: "namespaced actions", rules: NamespaceRules{Match: "team-*", Actions: []string{ActionRestart, ActionScale, ActionRollback, ActionDeletePod}}},
+		{name: "cordon", rules: NamespaceRules{Match: "team-*", Actions: []string{ActionCordon}}, wantErr: true},
+		{name: "drain", rules: NamespaceRules{Match: "team-*", Actions: []string{ActionRestart, ActionDrain}}, wantErr: true},
+		{name: "unknown action", rules: NamespaceRules{Match: "team-*", Actions: []string{"exec"}}, wantErr: true},
+		{name: "invalid glob", rules: NamespaceRules{Match: "team-[", Actions: []string{ActionRestart}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRules(&tt.rules)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigRequiresNodeApprovers(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "node rules with approvers", config: "nodes:\n  actions: [cordon, drain]\n  approvers: [U0SRE]\n"},
+		{name: "node rules without approvers", config: "nodes:\n  actions: [drain]\n", wantErr: true},
+		{name: "namespaced action in node rules", config: "nodes:\n  actions: [restart]\n  approvers: [U0SRE]\n", wantErr: true},
+		{name: "node action in namespace rules", config: "namespaces:\n  - match: prod\n    actions: [drain]\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "remediation.yaml")
+			if err := os.WriteFile(path, []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthorized(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *Config
+		approver  string
+		action    string
+		namespace string
+		wantErr   bool
+	}{
+		{name: "no approvers allows anyone", config: testConfig(), approver: "U0ANYONE", action: ActionRestart, namespace: "team-a"},
+		{name: "namespace approver", config: testConfig(), approver: "U0OPS", action: ActionScale, namespace: "prod"},
+		{name: "not a namespace approver", config: testConfig(), approver: "U0ANYONE", action: ActionScale, namespace: "prod", wantErr: true},
+		{name: "default approvers", config: &Config{Approvers: []string{"U0LEAD"}}, approver: "U0LEAD", action: ActionRestart, namespace: "team-a"},
+		{name: "not a default approver", config: &Config{Approvers: []string{"U0LEAD"}}, approver: "U0ANYONE", action: ActionRestart, namespace: "team-a", wantErr: true},
+		{name: "namespace approvers replace the defaults", config: &Config{
+			Approvers:  []string{"U0LEAD"},
+			Namespaces: []NamespaceRules{{Match: "prod", Actions: []string{ActionRestart}, Approvers: []string{"U0OPS"}}},
+		}, approver: "U0LEAD", action: ActionRestart, namespace: "prod", wantErr: true},
+		{name: "node approver", config: testConfig(), approver: "U0SRE", action: ActionDrain, namespace: "team-a"},
+		{name: "namespace approver can't approve node actions", config: testConfig(), approver: "U0OPS", action: ActionDrain, namespace: "prod", wantErr: true},
+		{name: "open namespace doesn't open node actions", config: testConfig(), approver: "U0ANYONE", action: ActionCordon, namespace: "team-a", wantErr: true},
+		{name: "default approvers can't approve node actions", config: &Config{
+			Approvers: []string{"U0LEAD"},
+			Nodes:     &NodeRules{Actions: []string{ActionCordon}, Approvers: []string{"U0SRE"}},
+		}, approver: "U0LEAD", action: ActionCordon, namespace: "prod", wantErr: true},
+		{name: "node actions without node rules", config: &Config{}, approver: "U0ANYONE", action: ActionCordon, namespace: "prod", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New(nil, tt.config, Options{})
+			err := e.authorized(Approver{ID: tt.approver, Name: tt.approver}, tt.action, tt.namespace)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("authorized() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteDenials(t *testing.T) {
+	restart := func(proposedAt time.Time) Request {
+		return Request{ID: "req-1", Action: ActionRestart, Kind: "Deployment", Name: "api", Namespace: "prod", Alert: "HighErrorRate", ProposedAt: proposedAt}
+	}
+	tests := []struct {
+		name     string
+		req      Request
+		approver string
+		want     string
+	}{
+		{name: "expired proposal", req: restart(time.Now().Add(-proposalTTL - time.Minute)), approver: "U0OPS", want: "expired"},
+		{name: "not an approver", req: restart(time.Now()), approver: "U0ANYONE", want: "not an approver"},
+		{name: "action removed from the allowlist", req: Request{ID: "req-2", Action: ActionRollback, Kind: "Deployment", Name: "api", Namespace: "prod", ProposedAt: time.Now()}, approver: "U0OPS", want: "not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := filepath.Join(t.TempDir(), "audit.jsonl")
+			e := New(nil, testConfig(), Options{AuditLog: audit})
+			result := e.Execute(context.Background(), tt.req, Approver{ID: tt.approver, Name: tt.approver})
+			if result.Outcome != OutcomeDenied || !strings.Contains(result.Message, tt.want) {
+				t.Errorf("Execute() = %+v, want %s containing %q", result, OutcomeDenied, tt.want)
+			}
+			if e.isClaimed(tt.req.ID) {
+				t.Error("a denied request stayed claimed")
+			}
+			data, err := os.ReadFile(audit)
+			if err != nil || !strings.Contains(string(data), `"outcome":"denied"`) {
+				t.Errorf("denial not audit-logged: %s (%v)", data, err)
+			}
+		})
+	}
+}
+
+func TestClaimsSurviveRestart(t *testing.T) {
+	audit := filepath.Join(t.TempDir(), "audit.jsonl")
+	req := Request{ID: "req-1", Action: ActionRestart, Kind: "Deployment", Name: "api", Namespace: "prod", ProposedAt: time.Now().Add(-time.Hour)}
+	expired := Request{ID: "req-old", Action: ActionRestart, Kind: "Deployment", Name: "api", Namespace: "prod", ProposedAt: time.Now().Add(-proposalTTL - time.Hour)}
+	released := Request{ID: "req-2", Action: ActionRestart, Kind: "Deployment", Name: "api", Namespace: "prod", ProposedAt: time.Now()}
+
+	var lines []string
+	for _, entry := range []auditEntry{
+		{Request: req, Approver: Approver{ID: "U0OPS", Name: "ops"}, Outcome: OutcomeExecuted, Ran: true},
+		{Request: expired, Approver: Approver{ID: "U0OPS", Name: "ops"}, Outcome: OutcomeExecuted, Ran: true},
+		{Request: released, Approver: Approver{ID: "U0OPS", Name: "ops"}, Outcome: OutcomeFailed},
+	} {
+		data, _ := json.Marshal(entry)
+		lines = append(lines, string(data))
+	}
+	lines = append(lines, "not json")
+	if err := os.WriteFile(audit, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	e := New(nil, testConfig(), Options{AuditLog: audit})
+	if !e.isClaimed(req.ID) {
+		t.Errorf("%s ran before the restart but isn't claimed", req.ID)
+	}
+	if e.isClaimed(expired.ID) {
+		t.Errorf("expired %s was claimed again", expired.ID)
+	}
+	if e.isClaimed(released.ID) {
+		t.Errorf("%s didn't run but is claimed", released.ID)
+	}
+
+	result := e.Execute(context.Background(), req, Approver{ID: "U0OPS", Name: "another"})
+	if result.Outcome != OutcomeDenied || !strings.Contains(result.Message, "already approved by ops") {
+		t.Errorf("second approval after restart = %+v, want it denied", result)
+	}
+}
+
+func TestClaimPrunesExpiredProposals(t *testing.T) {
+	e := New(nil, testConfig(), Options{})
+	e.claimed["old"] = claim{approver: "ops", proposedAt: time.Now().Add(-proposalTTL - time.Minute)}
+
+	if by, claimed := e.claim(Request{ID: "new", ProposedAt: time.Now()}, "ops"); claimed {
+		t.Fatalf("new request already claimed by %s", by)
+	}
+	if by, claimed := e.claim(Request{ID: "new", ProposedAt: time.Now()}, "lead"); !claimed || by != "ops" {
+		t.Errorf("second claim = %q, %v, want claimed by ops", by, claimed)
+	}
+	if e.isClaimed("old") {
+		t.Error("claim of an expired proposal wasn't pruned")
+	}
+}
@@ -0,0 +1,76 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// confidenceEmoji marks an analysis's confidence at a glance
+var confidenceEmoji = map[string]string{
+	"high":   "🟢",
+	"medium": "🟡",
+	"low":    "🔴",
+}
+
+// Analysis renders a structured analysis as Slack mrkdwn, in the same layout
+// as the free-form text analyses
+func Analysis(a *types.Analysis) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("*Root Cause:* %s\n", a.RootCause))
+	b.WriteString(fmt.Sprintf("*Confidence:* %s %s · *Suggested severity:* %s\n\n",
+		confidenceEmoji[a.Confidence], a.Confidence, a.SuggestedSeverity))
+
+	b.WriteString("*Key Evidence:*\n")
+	for _, e := range a.Evidence {
+		quote := strings.TrimSpace(e.Quote)
+		flag := ""
+		if e.Unverified {
+			flag = " ⚠️ _not found in the debug info_"
+		}
+		if strings.Contains(quote, "\n") {
+			b.WriteString(fmt.Sprintf("• _%s_%s\n```%s```\n", e.Section, flag, quote))
+		} else {
+			b.WriteString(fmt.Sprintf("• _%s_: `%s`%s\n", e.Section, quote, flag))
+		}
+	}
+
+	b.WriteString(fmt.Sprintf("\n*Impact:* %s\n\n", a.Impact))
+
+	if len(a.AlertNotes) > 0 {
+		b.WriteString("*Alert Notes:*\n")
+		for _, n := range a.AlertNotes {
+			b.WriteString(fmt.Sprintf("• `%s`: %s\n", n.Alert, n.Note))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("*Actions:*\n")
+	for _, action := range a.Actions {
+		b.WriteString("• " + action + "\n")
+	}
+	if len(a.RunbookSteps) > 0 {
+		b.WriteString("*Runbook Steps:*\n")
+		for _, s := range a.RunbookSteps {
+			mark := "➖"
+			if s.Applies {
+				mark = "✅"
+			}
+			b.WriteString(fmt.Sprintf("%s %s — %s\n", mark, s.Step, s.Reason))
+		}
+	}
+	if len(a.ProposedActions) > 0 {
+		b.WriteString("*Proposed Actions:*\n")
+		for _, p := range a.ProposedActions {
+			b.WriteString(fmt.Sprintf("• `%s` — %s\n", p.Command(), p.Reason))
+		}
+	}
+	b.WriteString("*Prevention:*\n")
+	for _, measure := range a.Prevention {
+		b.WriteString("• " + measure + "\n")
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,221 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/valentinpelus/k8flex/pkg/llm"
+	"github.com/valentinpelus/k8flex/pkg/logsummary"
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// sectionPriority ranks section sources per alert category, most valuable
+// first. Unlisted sources rank after the listed ones, and similar past cases
+// rank last since they're context rather than evidence. The member list of an
+// incident, the runbooks attached to the alert and the actions available for
+// it rank first in every category.
+var sectionPriority = map[string][]string{
+	"pod-crash":   {"pod-logs", "pod-details", "workload", "pod-resources", "events", "metrics"},
+	"pod-restart": {"pod-logs", "pod-details", "workload", "pod-resources", "events", "metrics"},
+	"memory":      {"metrics", "pod-resources", "pod-details", "node-resources", "events", "workload"},
+	"cpu":         {"metrics", "pod-resources", "pod-details", "node-resources", "events", "workload"},
+	"disk":        {"pod-details", "node-resources", "events", "metrics", "pod-resources"},
+	"hpa":         {"hpa", "metrics", "workload", "pod-resources", "events", "pod-details"},
+	"deployment":  {"workload", "events", "pod-details", "pod-resources", "metrics", "pod-logs"},
+	"node":        {"node-status", "node-resources", "events", "pod-details", "metrics"},
+	"network":     {"service", "pod-network", "events", "pod-details", "metrics", "pod-logs"},
+	"service":     {"service", "events", "pod-details", "metrics", "pod-logs"},
+}
+
+// defaultPriority ranks sections of categories missing from sectionPriority
+var defaultPriority = []string{"pod-logs", "pod-details", "events", "workload", "service", "metrics"}
+
+const (
+	// minTrimmedLines is the fewest lines worth keeping of a section; a section
+	// cut shorter is dropped
+	minTrimmedLines = 5
+	// noteTokens is reserved for the prompt's NOTE listing what was trimmed
+	noteTokens = 150
+)
+
+// Fit returns a copy of r whose prompt fits in maxTokens, recording each cut
+// in its Trimmed field. Repeated lines of chronological sections, such as raw
+// logs and events, are collapsed first; then the least
+// valuable sections for the alert's category are shortened, from the start
+// for chronological ones such as events, or dropped when too little of them
+// would be left.
+func Fit(r *types.DebugResult, maxTokens int) *types.DebugResult {
+	fitted := *r
+	fitted.Sections = append([]types.DebugSection(nil), r.Sections...)
+	fitted.Trimmed = nil
+
+	over := llm.EstimateTokens(Prompt(&fitted)) - maxTokens
+	if over <= 0 {
+		return &fitted
+	}
+	over += noteTokens
+
+	for i := range fitted.Sections {
+		s := &fitted.Sections[i]
+		if !s.Chronological {
+			continue
+		}
+		collapsed, n := collapseRepeats(s.Content)
+		if n == 0 {
+			continue
+		}
+		saved := llm.EstimateTokens(s.Content) - llm.EstimateTokens(collapsed)
+		s.Content = collapsed
+		over -= saved
+		fitted.Trimmed = append(fitted.Trimmed, types.Trim{
+			Section: s.Title,
+			Action:  fmt.Sprintf("collapsed %d repeated lines", n),
+			Tokens:  saved,
+		})
+	}
+
+	dropped := make(map[int]bool)
+	for _, i := range leastValuableFirst(fitted.Sections, r.Category) {
+		if over <= 0 {
+			break
+		}
+		s := &fitted.Sections[i]
+		tokens := llm.EstimateTokens(s.Content)
+		if tokens == 0 {
+			continue // Errors alone are short and say what's missing
+		}
+
+		content, kept, total := trimLines(s.Content, (tokens-over)*llm.CharsPerToken, s.Chronological)
+		if kept < minTrimmedLines {
+			saved := llm.EstimateTokens(sectionText(*s))
+			dropped[i] = true
+			over -= saved
+			fitted.Trimmed = append(fitted.Trimmed, types.Trim{Section: s.Title, Action: "dropped", Tokens: saved})
+			continue
+		}
+
+		saved := tokens - llm.EstimateTokens(content)
+		s.Content = content
+		over -= saved
+		action := fmt.Sprintf("kept the first %d of %d lines", kept, total)
+		switch {
+		case s.Source == "events":
+			action = fmt.Sprintf("kept the %d most recent of %d events", kept, total)
+		case s.Chronological:
+			action = fmt.Sprintf("kept the last %d of %d lines", kept, total)
+		}
+		fitted.Trimmed = append(fitted.Trimmed, types.Trim{Section: s.Title, Action: action, Tokens: saved})
+	}
+
+	if len(dropped) > 0 {
+		sections := make([]types.DebugSection, 0, len(fitted.Sections)-len(dropped))
+		for i, s := range fitted.Sections {
+			if !dropped[i] {
+				sections = append(sections, s)
+			}
+		}
+		fitted.Sections = sections
+	}
+	return &fitted
+}
+
+// Trimmed summarizes what Fit cut from a result, e.g. "Recent Events (kept the
+// 12 most recent of 50 events), Pod Details (dropped)", or returns "" if nothing
+func Trimmed(r *types.DebugResult) string {
+	parts := make([]string, len(r.Trimmed))
+	for i, t := range r.Trimmed {
+		parts[i] = fmt.Sprintf("%s (%s)", t.Section, t.Action)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// leastValuableFirst returns section indexes in trimming order. Among sections
+// of equal rank, later ones are trimmed first.
+func leastValuableFirst(sections []types.DebugSection, category string) []int {
+	order, ok := sectionPriority[category]
+	if !ok {
+		order = defaultPriority
+	}
+	rank := func(source string) int {
+		switch source {
+		case "incident", "runbook", "remediation":
+			return -1
+		case "knowledge-base":
+			return len(order) + 1
+		}
+		for i, s := range order {
+			if s == source {
+				return i
+			}
+		}
+		return len(order)
+	}
+
+	indexes := make([]int, len(sections))
+	for i := range sections {
+		indexes[i] = len(sections) - 1 - i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		return rank(sections[indexes[a]].Source) > rank(sections[indexes[b]].Source)
+	})
+	return indexes
+}
+
+// trimLines keeps whole lines of text within maxBytes, from the end if
+// fromEnd is set, and notes how many lines were omitted
+func trimLines(text string, maxBytes int, fromEnd bool) (string, int, int) {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	total := len(lines)
+
+	kept, size := 0, 0
+	for kept < total {
+		line := lines[kept]
+		if fromEnd {
+			line = lines[total-1-kept]
+		}
+		if size+len(line)+1 > maxBytes {
+			break
+		}
+		size += len(line) + 1
+		kept++
+	}
+
+	if fromEnd {
+		return fmt.Sprintf("[%d earlier lines omitted]\n", total-kept) + strings.Join(lines[total-kept:], "\n"), kept, total
+	}
+	return strings.Join(lines[:kept], "\n") + fmt.Sprintf("\n[%d more lines omitted]", total-kept), kept, total
+}
+
+// collapseRepeats replaces runs of three or more lines of the same template
+// (see logsummary.Mask) with the first line and a count, and returns how many
+// lines were collapsed
+func collapseRepeats(text string) (string, int) {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	removed := 0
+
+	for i := 0; i < len(lines); {
+		j := i + 1
+		key := logsummary.Mask(lines[i])
+		for j < len(lines) && lines[i] != "" && logsummary.Mask(lines[j]) == key {
+			j++
+		}
+		out = append(out, lines[i])
+		if repeats := j - i - 1; repeats >= 2 {
+			out = append(out, fmt.Sprintf("[similar line repeated %d more times]", repeats))
+			removed += repeats
+		} else {
+			out = append(out, lines[i+1:j]...)
+		}
+		i = j
+	}
+	return strings.Join(out, "\n"), removed
+}
+
+// sectionText renders a section as it appears in the prompt
+func sectionText(s types.DebugSection) string {
+	var b strings.Builder
+	writeSection(&b, s)
+	return b.String()
+}
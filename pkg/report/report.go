@@ -0,0 +1,214 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/redact"
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// Slack limits a section's text to 3000 characters and a message to 50 blocks
+const (
+	maxSlackSectionText = 3000
+	maxSlackBlocks      = 50
+)
+
+// Prompt renders a debug result as the plain text sent to the LLM
+func Prompt(r *types.DebugResult) string {
+	var b strings.Builder
+
+	b.WriteString("=== AI-Powered Debug Analysis ===\n")
+	b.WriteString(fmt.Sprintf("Alert: %s\n", r.Alert.Labels["alertname"]))
+	b.WriteString(fmt.Sprintf("Severity: %s\n", r.Alert.Labels["severity"]))
+	b.WriteString(fmt.Sprintf("Namespace: %s\n", r.Alert.Labels["namespace"]))
+	b.WriteString(fmt.Sprintf("Time: %s\n\n", r.Alert.StartsAt.Format(time.RFC3339)))
+
+	if summary := r.Alert.Annotations["summary"]; summary != "" {
+		b.WriteString(fmt.Sprintf("Summary: %s\n", summary))
+	}
+	if description := r.Alert.Annotations["description"]; description != "" {
+		b.WriteString(fmt.Sprintf("Description: %s\n", description))
+	}
+	if runbookURL := r.Alert.Annotations["runbook_url"]; runbookURL != "" {
+		b.WriteString(fmt.Sprintf("Runbook: %s\n", runbookURL))
+	}
+	b.WriteString("\n")
+
+	for _, s := range r.Sections {
+		writeSection(&b, s)
+	}
+
+	if timedOut := r.TimedOutSections(); len(timedOut) > 0 {
+		b.WriteString(fmt.Sprintf("NOTE: These sections did not finish in time and may be incomplete: %s\n\n",
+			strings.Join(timedOut, ", ")))
+	}
+	if redacted := Redactions(r); redacted != "" {
+		b.WriteString(fmt.Sprintf("NOTE: Sensitive values were replaced with [REDACTED:<kind>] placeholders (%s). "+
+			"They are not errors in the data.\n\n", redacted))
+	}
+	if trimmed := Trimmed(r); trimmed != "" {
+		b.WriteString(fmt.Sprintf("NOTE: Some evidence was left out to fit the context window: %s. "+
+			"Say so if it's needed for a conclusion.\n\n", trimmed))
+	}
+
+	return b.String()
+}
+
+// writeSection renders one section of the prompt
+func writeSection(b *strings.Builder, s types.DebugSection) {
+	b.WriteString(fmt.Sprintf("=== %s ===\n", s.Title))
+	if s.Content != "" {
+		b.WriteString(s.Content + "\n")
+	}
+	if s.Truncated {
+		b.WriteString("[truncated]\n")
+	}
+	switch {
+	case s.TimedOut:
+		b.WriteString(fmt.Sprintf("Timed out: %s\n", s.Error))
+	case s.Error != "":
+		b.WriteString(fmt.Sprintf("Error: %s\n", s.Error))
+	}
+	b.WriteString("\n")
+}
+
+// Redactions summarizes the redacted values of a result, e.g. "3 email, 1 jwt",
+// or returns "" if nothing was redacted
+func Redactions(r *types.DebugResult) string {
+	return redact.Counts(r.Redactions).String()
+}
+
+// Markdown renders a debug result as a Markdown document
+func Markdown(r *types.DebugResult) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("# Debug report: %s\n\n", r.Alert.Labels["alertname"]))
+	b.WriteString("| | |\n|---|---|\n")
+	b.WriteString(fmt.Sprintf("| Severity | %s |\n", r.Alert.Labels["severity"]))
+	b.WriteString(fmt.Sprintf("| Namespace | %s |\n", r.Alert.Labels["namespace"]))
+	b.WriteString(fmt.Sprintf("| Category | %s |\n", r.Category))
+	b.WriteString(fmt.Sprintf("| Started | %s |\n", r.Alert.StartsAt.Format(time.RFC3339)))
+	b.WriteString(fmt.Sprintf("| Gathered in | %s |\n", r.FinishedAt.Sub(r.StartedAt).Round(time.Millisecond)))
+	if runbookURL := r.Alert.Annotations["runbook_url"]; runbookURL != "" {
+		b.WriteString(fmt.Sprintf("| Runbook | %s |\n", runbookURL))
+	}
+	if redacted := Redactions(r); redacted != "" {
+		b.WriteString(fmt.Sprintf("| Redacted | %s |\n", redacted))
+	}
+	if trimmed := Trimmed(r); trimmed != "" {
+		b.WriteString(fmt.Sprintf("| Left out of the LLM prompt | %s |\n", trimmed))
+	}
+	b.WriteString("\n")
+
+	if summary := r.Alert.Annotations["summary"]; summary != "" {
+		b.WriteString(fmt.Sprintf("> %s\n\n", summary))
+	}
+
+	for _, s := range r.Sections {
+		b.WriteString(fmt.Sprintf("## %s\n\n", s.Title))
+		b.WriteString(fmt.Sprintf("_%s · %s%s_\n\n", s.Source, s.Timestamp.Format(time.RFC3339), statusSuffix(s)))
+		if s.Error != "" {
+			b.WriteString(fmt.Sprintf("**Error:** %s\n\n", s.Error))
+		}
+		if s.Content != "" {
+			b.WriteString("```\n" + s.Content + "\n```\n\n")
+		}
+	}
+
+	return b.String()
+}
+
+// JSON renders a debug result, including each section's raw data
+func JSON(r *types.DebugResult) ([]byte, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal debug result: %w", err)
+	}
+	return data, nil
+}
+
+// SlackBlocks renders a debug result as Block Kit blocks: one section per
+// piece of evidence, with its content cut to fit Slack's limits
+func SlackBlocks(r *types.DebugResult) []types.SlackBlock {
+	blocks := []types.SlackBlock{
+		{
+			Type: "header",
+			Text: &types.SlackTextObject{Type: "plain_text", Text: "🔎 Evidence: " + r.Alert.Labels["alertname"]},
+		},
+		{
+			Type: "context",
+			Elements: []types.SlackTextObject{{
+				Type: "mrkdwn",
+				Text: fmt.Sprintf("%d sections · category *%s* · gathered in %s",
+					len(r.Sections), r.Category, r.FinishedAt.Sub(r.StartedAt).Round(time.Millisecond)),
+			}},
+		},
+	}
+	if redacted := Redactions(r); redacted != "" {
+		blocks[1].Elements = append(blocks[1].Elements, types.SlackTextObject{Type: "mrkdwn", Text: "🔒 Redacted: " + redacted})
+	}
+	if len(r.Trimmed) > 0 {
+		blocks[1].Elements = append(blocks[1].Elements, types.SlackTextObject{
+			Type: "mrkdwn",
+			Text: fmt.Sprintf("✂️ %d cuts to fit the LLM's context window", len(r.Trimmed)),
+		})
+	}
+
+	for i, s := range r.Sections {
+		// Keep the last block to say how many sections didn't fit
+		if len(blocks) == maxSlackBlocks-1 {
+			blocks = append(blocks, types.SlackBlock{
+				Type:     "context",
+				Elements: []types.SlackTextObject{{Type: "mrkdwn", Text: fmt.Sprintf("_… %d more sections_", len(r.Sections)-i)}},
+			})
+			break
+		}
+
+		text := fmt.Sprintf("*%s*%s", s.Title, statusSuffix(s))
+		if s.Error != "" {
+			text += "\n" + s.Error
+		}
+		if s.Content != "" {
+			room := maxSlackSectionText - len(text) - len("\n``````")
+			text += "\n```" + truncate(s.Content, room) + "```"
+		}
+		blocks = append(blocks, types.SlackBlock{
+			Type: "section",
+			Text: &types.SlackTextObject{Type: "mrkdwn", Text: text},
+		})
+	}
+
+	return blocks
+}
+
+// statusSuffix flags sections that are incomplete
+func statusSuffix(s types.DebugSection) string {
+	switch {
+	case s.TimedOut:
+		return " ⏱ timed out"
+	case s.Error != "":
+		return " ⚠️ error"
+	case s.Truncated:
+		return " ✂️ truncated"
+	default:
+		return ""
+	}
+}
+
+// truncate keeps the most recent part of text (logs end with what matters)
+func truncate(text string, maxLen int) string {
+	if maxLen <= 0 {
+		return ""
+	}
+	if len(text) <= maxLen {
+		return text
+	}
+	const marker = "…\n"
+	if maxLen <= len(marker) {
+		return text[len(text)-maxLen:]
+	}
+	return marker + text[len(text)-maxLen+len(marker):]
+}
@@ -0,0 +1,116 @@
+package runbook
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxRedirects bounds the redirects followed for a runbook page
+const maxRedirects = 5
+
+// sharedNetwork is the carrier-grade NAT range, where some clouds serve
+// instance metadata
+var sharedNetwork = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// guard keeps runbook downloads to allowed hosts and public addresses, since
+// runbook_url annotations come from whoever can post alerts
+type guard struct {
+	hosts    []string     // Host globs, e.g. "*.example.com"; empty allows no download
+	networks []*net.IPNet // Private networks allowed anyway
+}
+
+// newGuard parses the allowed hosts and networks
+func newGuard(hosts, networks []string) (*guard, error) {
+	g := &guard{}
+	for _, host := range hosts {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host == "" {
+			continue
+		}
+		if _, err := path.Match(host, ""); err != nil {
+			return nil, fmt.Errorf("invalid runbook host %q: %w", host, err)
+		}
+		g.hosts = append(g.hosts, host)
+	}
+	for _, cidr := range networks {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid runbook network %q: %w", cidr, err)
+		}
+		g.networks = append(g.networks, network)
+	}
+	return g, nil
+}
+
+// checkHost checks a URL host against the allowed hosts
+func (g *guard) checkHost(host string) error {
+	if len(g.hosts) == 0 {
+		return fmt.Errorf("no runbook hosts are allowed")
+	}
+	host = strings.ToLower(host)
+	for _, pattern := range g.hosts {
+		if ok, _ := path.Match(pattern, host); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("runbook host %s is not allowed", host)
+}
+
+// checkIP rejects loopback, link-local, private and other non-public
+// addresses outside the allowed networks
+func (g *guard) checkIP(ip net.IP) error {
+	for _, network := range g.networks {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsPrivate() ||
+		ip.IsUnspecified() || ip.IsMulticast() || sharedNetwork.Contains(ip) {
+		return fmt.Errorf("runbook address %s is not public", ip)
+	}
+	return nil
+}
+
+// client returns an HTTP client checking the address it connects to, after
+// DNS resolution, and the host of every redirect
+func (g *guard) client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("unresolved runbook address %s", host)
+			}
+			return g.checkIP(ip)
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("unsupported redirect to %s", req.URL.Scheme)
+			}
+			return g.checkHost(req.URL.Hostname())
+		},
+	}
+}
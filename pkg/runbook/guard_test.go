@@ -0,0 +1,200 @@
+package runbook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+func TestGuardCheckIP(t *testing.T) {
+	tests := []struct {
+		name     string
+		ip       string
+		networks []string
+		wantErr  bool
+	}{
+		{name: "public", ip: "93.184.216.34"},
+		{name: "public IPv6", ip: "2606:2800:220:1:248:1893:25c8:1946"},
+		{name: "loopback", ip: "127.0.0.1", wantErr: true},
+		{name: "IPv6 loopback", ip: "::1", wantErr: true},
+		{name: "cloud metadata", ip: "169.254.169.254", wantErr: true},
+		{name: "private", ip: "10.0.0.5", wantErr: true},
+		{name: "carrier-grade NAT", ip: "100.100.100.200", wantErr: true},
+		{name: "unspecified", ip: "0.0.0.0", wantErr: true},
+		{name: "IPv4-mapped loopback", ip: "::ffff:127.0.0.1", wantErr: true},
+		{name: "allowed network", ip: "10.20.0.7", networks: []string{"10.20.0.0/16"}},
+		{name: "outside the allowed network", ip: "10.30.0.7", networks: []string{"10.20.0.0/16"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := newGuard(nil, tt.networks)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = g.checkIP(net.ParseIP(tt.ip))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkIP(%s) error = %v, wantErr %v", tt.ip, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGuardCheckHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		hosts   []string
+		host    string
+		wantErr bool
+	}{
+		{name: "no allowed hosts", hosts: nil, host: "github.com", wantErr: true},
+		{name: "exact host", hosts: []string{"github.com"}, host: "github.com"},
+		{name: "case insensitive", hosts: []string{"GitHub.com"}, host: "github.COM"},
+		{name: "glob", hosts: []string{"*.wiki.example.com"}, host: "ops.wiki.example.com"},
+		{name: "glob doesn't match the parent", hosts: []string{"*.wiki.example.com"}, host: "wiki.example.com", wantErr: true},
+		{name: "any host", hosts: []string{"*"}, host: "runbooks.example.org"},
+		{name: "other host", hosts: []string{"github.com"}, host: "evil.example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := newGuard(tt.hosts, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = g.checkHost(tt.host)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkHost(%s) error = %v, wantErr %v", tt.host, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewGuardRejectsInvalidConfig(t *testing.T) {
+	if _, err := newGuard([]string{"[github.com"}, nil); err == nil {
+		t.Error("invalid host glob was accepted")
+	}
+	if _, err := newGuard(nil, []string{"10.0.0.0/33"}); err == nil {
+		t.Error("invalid CIDR was accepted")
+	}
+}
+
+// runbookServer serves a runbook at /runbook.md and redirects /moved to
+// target
+func runbookServer(t *testing.T, target string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/runbook.md", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/markdown")
+		fmt.Fprint(w, "# Diagnosis\nCheck the pods\n")
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target, http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchGuardsAddressesAndRedirects(t *testing.T) {
+	server := runbookServer(t, "")
+	u, _ := url.Parse(server.URL)
+	port := u.Port()
+
+	tests := []struct {
+		name     string
+		link     string
+		hosts    []string
+		networks []string
+		redirect string
+		wantErr  string
+	}{
+		{name: "allowed host and network", link: server.URL + "/runbook.md", hosts: []string{"127.0.0.1"}, networks: []string{"127.0.0.0/8"}},
+		{name: "loopback outside the allowed networks", link: server.URL + "/runbook.md", hosts: []string{"127.0.0.1"}, wantErr: "not public"},
+		{name: "host not allowed", link: server.URL + "/runbook.md", hosts: []string{"github.com"}, networks: []string{"127.0.0.0/8"}, wantErr: "not allowed"},
+		{name: "no allowed hosts", link: server.URL + "/runbook.md", networks: []string{"127.0.0.0/8"}, wantErr: "no runbook hosts"},
+		{name: "redirect to a disallowed host", link: "/moved", hosts: []string{"127.0.0.1"}, networks: []string{"127.0.0.0/8"},
+			redirect: "http://localhost:" + port + "/runbook.md", wantErr: "not allowed"},
+		{name: "redirect to cloud metadata", link: "/moved", hosts: []string{"127.0.0.1", "169.254.169.254"}, networks: []string{"127.0.0.0/8"},
+			redirect: "http://169.254.169.254/latest/meta-data/", wantErr: "not public"},
+		{name: "redirect to another scheme", link: "/moved", hosts: []string{"127.0.0.1"}, networks: []string{"127.0.0.0/8"},
+			redirect: "file:///etc/passwd", wantErr: "unsupported redirect"},
+		{name: "unsupported scheme", link: "file:///etc/passwd", hosts: []string{"*"}, wantErr: "unsupported runbook URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link := tt.link
+			if tt.redirect != "" {
+				link = runbookServer(t, tt.redirect).URL + tt.link
+			}
+			l, err := New(nil, Config{AllowedHosts: tt.hosts, AllowedNetworks: tt.networks})
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err := l.Fetch(context.Background(), link)
+			if tt.wantErr == "" {
+				if err != nil || !strings.Contains(content, "Check the pods") {
+					t.Errorf("Fetch() = %q, %v, want the runbook", content, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Fetch() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFindIgnoresLinksWithoutAllowedHosts(t *testing.T) {
+	server := runbookServer(t, "")
+	l, err := New(nil, Config{AllowedNetworks: []string{"127.0.0.0/8"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alert := types.Alert{
+		Labels:      map[string]string{"alertname": "HighErrorRate"},
+		Annotations: map[string]string{"runbook_url": server.URL + "/runbook.md"},
+	}
+	if runbooks := l.Find(context.Background(), alert); len(runbooks) != 0 {
+		t.Errorf("Find() = %v, want no runbook", runbooks)
+	}
+	if len(l.cache) != 0 {
+		t.Errorf("the ignored link was cached: %v", l.cache)
+	}
+}
+
+func TestCacheEvictsExpiredAndOldestEntries(t *testing.T) {
+	l, err := New(nil, Config{CacheTTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.cache["https://expired.example.com"] = cacheEntry{fetchedAt: time.Now().Add(-2 * time.Hour)}
+	l.cache["https://failed.example.com"] = cacheEntry{err: fmt.Errorf("boom"), fetchedAt: time.Now().Add(-2 * errorTTL)}
+	l.store("https://fresh.example.com", cacheEntry{content: "fresh"})
+	if _, ok := l.cache["https://expired.example.com"]; ok {
+		t.Error("expired page wasn't evicted")
+	}
+	if _, ok := l.cache["https://failed.example.com"]; ok {
+		t.Error("expired failure wasn't evicted")
+	}
+
+	for i := 0; len(l.cache) < maxCacheEntries; i++ {
+		l.cache[fmt.Sprintf("https://page-%d.example.com", i)] = cacheEntry{fetchedAt: time.Now()}
+	}
+	l.store("https://new.example.com", cacheEntry{content: "new"})
+	if len(l.cache) != maxCacheEntries {
+		t.Errorf("cache has %d entries, want at most %d", len(l.cache), maxCacheEntries)
+	}
+	if _, ok := l.cache["https://fresh.example.com"]; ok {
+		t.Error("the oldest entry wasn't evicted from the full cache")
+	}
+	if _, ok := l.cache["https://new.example.com"]; !ok {
+		t.Error("the new entry wasn't cached")
+	}
+}
@@ -0,0 +1,345 @@
+package runbook
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/valentinpelus/k8flex/pkg/kubernetes"
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+const (
+	// fetchTimeout bounds a single runbook download
+	fetchTimeout = 10 * time.Second
+	// maxFetchBytes bounds how much of a runbook page is read
+	maxFetchBytes = 1024 * 1024
+	// errorTTL is how long a failed lookup is cached, shorter than successes
+	// so a fixed link or ConfigMap is picked up soon
+	errorTTL = time.Minute
+	// configMapKey caches the runbook ConfigMap, apart from the URLs
+	configMapKey = "configmap:"
+	// maxCacheEntries bounds the cache, since alerts may link to any number
+	// of pages
+	maxCacheEntries = 500
+)
+
+// Runbook is a runbook found for an alert
+type Runbook struct {
+	Name    string // Alert name, resource name or link the runbook was found by
+	Source  string // "annotation", "directory", "configmap" or "resource"
+	URL     string // Link to the full runbook, if any
+	Content string // Markdown text
+}
+
+// Config tells the library where runbooks are kept
+type Config struct {
+	Directory          string        // Markdown runbooks named <alertname>.md; "" disables
+	ConfigMapNamespace string        // Namespace of the runbook ConfigMap
+	ConfigMapName      string        // ConfigMap with <alertname>.md keys; "" disables
+	CacheTTL           time.Duration // How long fetched pages and the ConfigMap are reused
+	MaxBytes           int           // Budget of the sections kept per runbook; 0 keeps runbooks whole
+	AllowedHosts       []string      // Hosts runbook_url may link to, globs allowed, e.g. "*" for any; empty downloads nothing
+	AllowedNetworks    []string      // CIDRs of private addresses runbook_url may reach, e.g. an internal wiki's
+}
+
+// Library finds the runbooks of alerts in their runbook_url annotation, a
+// directory and a ConfigMap, caching what it downloads
+type Library struct {
+	config    Config
+	k8sClient *kubernetes.Client
+	client    *http.Client
+	guard     *guard
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry // Key: URL, or configMapKey
+}
+
+// cacheEntry is a cached page or ConfigMap
+type cacheEntry struct {
+	content   string
+	data      map[string]string
+	err       error
+	fetchedAt time.Time
+}
+
+// New creates a runbook library. Pages are only downloaded from the allowed
+// hosts, and from private addresses in the allowed networks; without allowed
+// hosts, runbook_url annotations are ignored.
+func New(k8sClient *kubernetes.Client, config Config) (*Library, error) {
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = 15 * time.Minute
+	}
+	guard, err := newGuard(config.AllowedHosts, config.AllowedNetworks)
+	if err != nil {
+		return nil, err
+	}
+	return &Library{
+		config:    config,
+		k8sClient: k8sClient,
+		client:    guard.client(fetchTimeout),
+		guard:     guard,
+		cache:     make(map[string]cacheEntry),
+	}, nil
+}
+
+// Find returns the runbooks of an alert: the page its runbook_url annotation
+// links to, then the alert's file in the directory and its key in the
+// ConfigMap. Sources that fail are logged and skipped.
+func (l *Library) Find(ctx context.Context, alert types.Alert) []Runbook {
+	alertName := alert.Labels["alertname"]
+	var runbooks []Runbook
+
+	if link := strings.TrimSpace(alert.Annotations["runbook_url"]); link != "" && l.Downloads() {
+		content, err := l.Fetch(ctx, link)
+		if err != nil {
+			log.Printf("Warning: failed to fetch runbook of %s: %v", alertName, err)
+		} else {
+			runbooks = append(runbooks, Runbook{Name: link, Source: "annotation", URL: link, Content: content})
+		}
+	}
+
+	if alertName == "" || strings.ContainsAny(alertName, `/\`) || strings.Contains(alertName, "..") {
+		return runbooks
+	}
+
+	if l.config.Directory != "" {
+		for _, name := range fileNames(alertName) {
+			data, err := os.ReadFile(filepath.Join(l.config.Directory, name))
+			if err == nil {
+				runbooks = append(runbooks, Runbook{Name: alertName, Source: "directory", Content: string(data)})
+				break
+			}
+			if !os.IsNotExist(err) {
+				log.Printf("Warning: failed to read runbook %s: %v", name, err)
+				break
+			}
+		}
+	}
+
+	if l.config.ConfigMapName != "" && l.k8sClient != nil {
+		data, err := l.configMap(ctx)
+		if err != nil {
+			log.Printf("Warning: failed to read runbook ConfigMap: %v", err)
+		}
+		for _, name := range fileNames(alertName) {
+			if content, ok := data[name]; ok {
+				runbooks = append(runbooks, Runbook{Name: alertName, Source: "configmap", Content: content})
+				break
+			}
+		}
+	}
+
+	return runbooks
+}
+
+// Downloads reports whether runbook pages may be downloaded, which needs
+// allowed hosts
+func (l *Library) Downloads() bool {
+	return len(l.guard.hosts) > 0
+}
+
+// Excerpt returns the sections of a runbook relevant to an alert, within the
+// library's budget. A nil library keeps the runbook whole.
+func (l *Library) Excerpt(runbook Runbook, alert types.Alert, category string) string {
+	maxBytes := 0
+	if l != nil {
+		maxBytes = l.config.MaxBytes
+	}
+	return Relevant(runbook.Content, alert, category, maxBytes)
+}
+
+// fileNames returns the file names an alert's runbook may have, e.g.
+// "KubePodCrashLooping.md", "kubepodcrashlooping.md" and
+// "kube-pod-crash-looping.md"
+func fileNames(alertName string) []string {
+	var kebab strings.Builder
+	for i, r := range alertName {
+		if unicode.IsUpper(r) && i > 0 {
+			kebab.WriteByte('-')
+		}
+		kebab.WriteRune(unicode.ToLower(r))
+	}
+
+	var names []string
+	for _, base := range []string{alertName, strings.ToLower(alertName), kebab.String()} {
+		for _, name := range []string{base + ".md", base} {
+			if !contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// configMap returns the runbook ConfigMap's data, cached for CacheTTL
+func (l *Library) configMap(ctx context.Context) (map[string]string, error) {
+	entry, ok := l.cached(configMapKey)
+	if ok {
+		return entry.data, entry.err
+	}
+	data, err := l.k8sClient.GetConfigMapData(ctx, l.config.ConfigMapNamespace, l.config.ConfigMapName)
+	l.store(configMapKey, cacheEntry{data: data, err: err})
+	return data, err
+}
+
+// Fetch downloads a runbook page, cached for CacheTTL. GitHub file links are
+// fetched raw, and HTML pages are converted to text with Markdown headings.
+func (l *Library) Fetch(ctx context.Context, link string) (string, error) {
+	if entry, ok := l.cached(link); ok {
+		return entry.content, entry.err
+	}
+	content, err := l.fetch(ctx, link)
+	l.store(link, cacheEntry{content: content, err: err})
+	return content, err
+}
+
+// fetch downloads a runbook page
+func (l *Library) fetch(ctx context.Context, link string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", fmt.Errorf("unsupported runbook URL %q", link)
+	}
+	rawURL(u)
+	if err := l.guard.checkHost(u.Hostname()); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/markdown, text/plain;q=0.9, text/html;q=0.8")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", link, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned status %d", link, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", link, err)
+	}
+
+	if strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		return htmlToText(string(body)), nil
+	}
+	return string(body), nil
+}
+
+// rawURL rewrites a GitHub file link, e.g.
+// github.com/org/repo/blob/main/runbooks/x.md, to its raw content
+func rawURL(u *url.URL) {
+	if u.Host != "github.com" {
+		return
+	}
+	parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 4)
+	if len(parts) == 4 && parts[2] == "blob" {
+		u.Host = "raw.githubusercontent.com"
+		u.Path = "/" + parts[0] + "/" + parts[1] + "/" + parts[3]
+		u.Fragment = ""
+	}
+}
+
+// cached returns a cache entry that hasn't expired
+func (l *Library) cached(key string) (cacheEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.cache[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	if l.expired(entry) {
+		delete(l.cache, key)
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// expired reports whether a cached lookup is past its TTL
+func (l *Library) expired(entry cacheEntry) bool {
+	ttl := l.config.CacheTTL
+	if entry.err != nil && errorTTL < ttl {
+		ttl = errorTTL
+	}
+	return time.Since(entry.fetchedAt) > ttl
+}
+
+// store caches a lookup, evicting expired entries and, when the cache is
+// full, the oldest one
+func (l *Library) store(key string, entry cacheEntry) {
+	entry.fetchedAt = time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for k, e := range l.cache {
+		if l.expired(e) {
+			delete(l.cache, k)
+		}
+	}
+	if _, ok := l.cache[key]; !ok && len(l.cache) >= maxCacheEntries {
+		oldest := ""
+		for k, e := range l.cache {
+			if oldest == "" || e.fetchedAt.Before(l.cache[oldest].fetchedAt) {
+				oldest = k
+			}
+		}
+		delete(l.cache, oldest)
+	}
+	l.cache[key] = entry
+}
+
+var (
+	htmlDropped  = regexp.MustCompile(`(?is)<(script|style|nav|header|footer)[^>]*>.*?</(script|style|nav|header|footer)>`)
+	htmlHeading  = regexp.MustCompile(`(?i)<h([1-6])[^>]*>`)
+	htmlListItem = regexp.MustCompile(`(?i)<li[^>]*>`)
+	htmlBreak    = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|h[1-6]|tr|pre|ul|ol)>`)
+	htmlTag      = regexp.MustCompile(`<[^>]+>`)
+	blankLines   = regexp.MustCompile(`\n\s*\n\s*\n+`)
+)
+
+// htmlToText reduces an HTML page to text, keeping headings as Markdown
+// headings so the page can be split into sections
+func htmlToText(page string) string {
+	page = htmlDropped.ReplaceAllString(page, "")
+	page = htmlHeading.ReplaceAllStringFunc(page, func(tag string) string {
+		level := htmlHeading.FindStringSubmatch(tag)[1]
+		return "\n\n" + strings.Repeat("#", int(level[0]-'0')) + " "
+	})
+	page = htmlListItem.ReplaceAllString(page, "\n- ")
+	page = htmlBreak.ReplaceAllString(page, "\n")
+	page = html.UnescapeString(htmlTag.ReplaceAllString(page, ""))
+
+	lines := strings.Split(page, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// contains reports whether list contains s
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package runbook
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// introBytes bounds the text before a runbook's first heading that is kept
+// when the runbook doesn't fit whole
+const introBytes = 600
+
+// actionHeadings are words of headings whose sections usually hold the steps
+// to follow, so they are preferred over background sections
+var actionHeadings = []string{"diagnos", "investigat", "troubleshoot", "mitigat", "remediat", "resolution", "resolve", "fix", "steps", "action", "check"}
+
+// stopWords are common words left out of the terms sections are matched by
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "this": true, "that": true, "from": true,
+	"has": true, "have": true, "been": true, "was": true, "are": true, "not": true, "than": true,
+	"more": true, "less": true, "over": true, "into": true, "alert": true, "firing": true,
+}
+
+var (
+	headingLine = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	wordPattern = regexp.MustCompile(`[A-Za-z][A-Za-z0-9]+`)
+)
+
+// section is a heading of a Markdown document and the text under it
+type section struct {
+	heading string // "" for the text before the first heading
+	text    string // Heading line included
+	index   int
+	score   int
+}
+
+// Relevant returns the sections of a runbook that matter most for an alert,
+// in document order, within maxBytes. A runbook that fits is returned whole.
+// Otherwise its introduction is kept, sections are ranked by the words of the
+// alert and its category they mention, action sections such as diagnosis and
+// mitigation first, and the headings of the sections left out are listed.
+func Relevant(content string, alert types.Alert, category string, maxBytes int) string {
+	content = strings.TrimSpace(content)
+	if maxBytes <= 0 || len(content) <= maxBytes {
+		return content
+	}
+
+	sections := split(content)
+	terms := alertTerms(alert, category)
+	for i := range sections {
+		sections[i].score = score(&sections[i], terms)
+	}
+
+	ranked := append([]section(nil), sections...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		// The introduction says what the runbook is about; keep it first
+		if (ranked[i].heading == "") != (ranked[j].heading == "") {
+			return ranked[i].heading == ""
+		}
+		return ranked[i].score > ranked[j].score
+	})
+
+	kept := make(map[int]bool)
+	used := 0
+	for _, s := range ranked {
+		text := s.text
+		if s.heading == "" && len(text) > introBytes {
+			text = text[:introBytes]
+			if cut := strings.LastIndex(text, "\n"); cut > 0 {
+				text = text[:cut]
+			}
+		}
+		if used+len(text) > maxBytes {
+			continue
+		}
+		kept[s.index] = true
+		sections[s.index].text = text
+		used += len(text)
+	}
+
+	var b strings.Builder
+	var omitted []string
+	for _, s := range sections {
+		if kept[s.index] {
+			b.WriteString(strings.TrimSpace(s.text) + "\n\n")
+		} else if s.heading != "" {
+			omitted = append(omitted, s.heading)
+		}
+	}
+	if len(omitted) > 0 {
+		b.WriteString(fmt.Sprintf("[Sections left out as less relevant: %s]", strings.Join(omitted, "; ")))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// split cuts a Markdown document at its headings, ignoring lines inside code
+// fences
+func split(content string) []section {
+	var sections []section
+	current := section{}
+	var text strings.Builder
+	inFence := false
+
+	flush := func() {
+		current.text = text.String()
+		if strings.TrimSpace(current.text) != "" {
+			current.index = len(sections)
+			sections = append(sections, current)
+		}
+		text.Reset()
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if m := headingLine.FindStringSubmatch(line); m != nil && !inFence && m[2] != "" {
+			flush()
+			current = section{heading: m[2]}
+		}
+		text.WriteString(line + "\n")
+	}
+	flush()
+	return sections
+}
+
+// alertTerms returns the lowercase words identifying an alert: its name split
+// at capitals, its category and the words of its summary, description and
+// labels such as container and reason
+func alertTerms(alert types.Alert, category string) map[string]bool {
+	terms := make(map[string]bool)
+	add := func(text string) {
+		for _, word := range wordPattern.FindAllString(splitCamel(text), -1) {
+			word = strings.ToLower(word)
+			if len(word) >= 3 && !stopWords[word] {
+				terms[word] = true
+			}
+		}
+	}
+
+	add(alert.Labels["alertname"])
+	add(strings.ReplaceAll(category, "-", " "))
+	add(alert.Annotations["summary"])
+	add(alert.Annotations["description"])
+	for _, label := range []string{"container", "reason", "job", "service", "phase", "condition", "resource"} {
+		add(alert.Labels[label])
+	}
+	return terms
+}
+
+// splitCamel separates the words of CamelCase names, e.g. "KubePodCrashLooping"
+// becomes "Kube Pod Crash Looping"
+func splitCamel(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && unicode.IsLower(runes[i-1]) {
+			b.WriteRune(' ')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// score counts the alert's terms a section mentions, those in its heading
+// counting three times, plus a bonus for action sections
+func score(s *section, terms map[string]bool) int {
+	heading := strings.ToLower(s.heading)
+	body := strings.ToLower(s.text)
+
+	total := 0
+	for term := range terms {
+		if strings.Contains(heading, term) {
+			total += 3
+		} else if strings.Contains(body, term) {
+			total++
+		}
+	}
+	for _, word := range actionHeadings {
+		if strings.Contains(heading, word) {
+			total += 5
+			break
+		}
+	}
+	return total
+}
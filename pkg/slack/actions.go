@@ -0,0 +1,103 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// ApproveActionID is the action ID of the buttons approving a remediation
+const ApproveActionID = "approve_remediation"
+
+// ActionProposal is a remediation offered for approval in an alert thread
+type ActionProposal struct {
+	BlockID string // Unique within the message; identifies the proposal when approved
+	Command string // e.g. "restart deployment/api"
+	Reason  string
+	Value   string // Sent back with the approval
+	Danger  bool   // Shows the button in red, e.g. for drains
+}
+
+// SendActionProposals posts remediations in a thread, each with a button
+// asking to confirm before approving it (requires Bot token)
+func (c *Client) SendActionProposals(ctx context.Context, threadTS string, proposals []ActionProposal, note string) (string, error) {
+	if !c.HasBotToken() {
+		return "", fmt.Errorf("Bot token required for action proposals")
+	}
+
+	blocks := []types.SlackBlock{
+		{
+			Type: "section",
+			Text: &types.SlackTextObject{Type: "mrkdwn", Text: "*🛠️ Proposed Actions*"},
+		},
+	}
+	for _, p := range proposals {
+		text := fmt.Sprintf("`%s`", p.Command)
+		if p.Reason != "" {
+			text += "\n" + truncateForSlack(p.Reason, 2000)
+		}
+		button := &types.SlackButton{
+			Type:     "button",
+			Text:     types.SlackTextObject{Type: "plain_text", Text: "Approve"},
+			ActionID: ApproveActionID,
+			Value:    p.Value,
+			Style:    "primary",
+			Confirm: &types.SlackConfirm{
+				Title:   types.SlackTextObject{Type: "plain_text", Text: "Run this action?"},
+				Text:    types.SlackTextObject{Type: "mrkdwn", Text: fmt.Sprintf("`%s` is dry-run on the API server first, then applied. Your approval is audit-logged.", p.Command)},
+				Confirm: types.SlackTextObject{Type: "plain_text", Text: "Run it"},
+				Deny:    types.SlackTextObject{Type: "plain_text", Text: "Cancel"},
+			},
+		}
+		if p.Danger {
+			button.Style = "danger"
+		}
+		blocks = append(blocks, types.SlackBlock{
+			Type:      "section",
+			BlockID:   p.BlockID,
+			Text:      &types.SlackTextObject{Type: "mrkdwn", Text: text},
+			Accessory: button,
+		})
+	}
+	if note != "" {
+		blocks = append(blocks, types.SlackBlock{
+			Type:     "context",
+			Elements: []types.SlackTextObject{{Type: "mrkdwn", Text: note}},
+		})
+	}
+
+	return c.postMessage(ctx, types.SlackMessage{
+		Channel:  c.ChannelOf(threadTS),
+		ThreadTS: threadTS,
+		Text:     fmt.Sprintf("🛠️ %d proposed actions", len(proposals)),
+		Blocks:   blocks,
+	})
+}
+
+// CompleteActionProposal replaces the approval button of a proposal with its
+// status, given the blocks of the proposals message as sent back with the
+// approval (requires Bot token)
+func (c *Client) CompleteActionProposal(ctx context.Context, channel, messageTS string, blocks []types.SlackBlock, blockID, status string) error {
+	if !c.HasBotToken() {
+		return fmt.Errorf("Bot token required for message updates")
+	}
+
+	updated := make([]types.SlackBlock, len(blocks))
+	for i, block := range blocks {
+		if block.BlockID == blockID && block.Text != nil {
+			text := *block.Text
+			text.Text += "\n" + status
+			block.Text = &text
+			block.Accessory = nil
+		}
+		updated[i] = block
+	}
+
+	return c.updateMessage(ctx, map[string]interface{}{
+		"channel": channel,
+		"ts":      messageTS,
+		"text":    "🛠️ Proposed actions",
+		"blocks":  updated,
+	})
+}
@@ -0,0 +1,248 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// Slack limits a section's text to 3000 characters and a message to 50 blocks
+const (
+	maxSectionText   = 3000
+	maxMessageBlocks = 50
+)
+
+// Action IDs of the buttons under an analysis. The rating buttons carry the
+// timestamp of the analysis message when they're under another message of a
+// split analysis, the others the thread's.
+const (
+	RateCorrectActionID   = "rate_correct"
+	RateIncorrectActionID = "rate_incorrect"
+	ReanalyzeActionID     = "reanalyze"
+	ShowEvidenceActionID  = "show_evidence"
+)
+
+// partHeader matches the bold header starting a part of an analysis, e.g.
+// "*Root Cause:*" or "*Key Evidence:*"
+var partHeader = regexp.MustCompile(`^\*[^*\n]{1,40}:\*`)
+
+// PostAnalysis posts an analysis in a thread as Block Kit sections, replacing
+// the message messageTS if set, e.g. the one the analysis was streamed into.
+// An analysis over Slack's block limit continues in more messages, the last
+// one ending with the rating, re-analyze and show-evidence buttons if the app
+// is interactive. It returns the timestamp of the first message, which the
+// ratings apply to. Without Bot token, the analysis is sent with the webhook
+// and no timestamp is returned.
+func (c *Client) PostAnalysis(ctx context.Context, threadTS, messageTS string, msg types.AnalysisMessage) (string, error) {
+	if !c.HasBotToken() {
+		if c.webhookURL == "" {
+			return "", nil
+		}
+		return "", c.sendAnalysisWithWebhook(ctx, msg.Alert, msg.Title+"\n\n"+msg.Analysis+"\n\n"+msg.Footer, threadTS)
+	}
+
+	lead := []types.SlackBlock{
+		{Type: "section", Text: &types.SlackTextObject{Type: "mrkdwn", Text: msg.Title}},
+		{Type: "divider"},
+	}
+	var trailer []types.SlackBlock
+	if msg.Footer != "" {
+		trailer = append(trailer, types.SlackBlock{
+			Type:     "context",
+			Elements: []types.SlackTextObject{{Type: "mrkdwn", Text: truncateForSlack(msg.Footer, maxSectionText-20)}},
+		})
+	}
+	if c.IsInteractive() {
+		// The buttons' value is only known once the first message is posted
+		trailer = append(trailer, types.SlackBlock{Type: "actions", BlockID: "analysis_actions"})
+	}
+	messages := splitMessages(lead, textSections(ConvertMarkdownToSlack(msg.Analysis)), trailer)
+
+	fallback := truncateForSlack(msg.Title+"\n\n"+msg.Analysis, maxSectionText)
+	first := messageTS
+	for i, blocks := range messages {
+		if i == len(messages)-1 && c.IsInteractive() {
+			blocks[len(blocks)-1].Buttons = analysisButtons(first, threadTS)
+		}
+
+		if i == 0 && messageTS != "" {
+			err := c.updateMessage(ctx, map[string]interface{}{
+				"channel": c.ChannelOf(messageTS),
+				"ts":      messageTS,
+				"text":    fallback,
+				"blocks":  blocks,
+			})
+			if err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		text := fallback
+		if i > 0 {
+			text = "Analysis continued"
+		}
+		ts, err := c.postMessage(ctx, types.SlackMessage{
+			Channel:  c.ChannelOf(threadTS),
+			ThreadTS: threadTS,
+			Text:     text,
+			Blocks:   blocks,
+		})
+		if err != nil {
+			return first, err
+		}
+		if i == 0 {
+			first = ts
+		}
+	}
+	return first, nil
+}
+
+// PostBlocks posts Block Kit blocks in a thread and returns the message
+// timestamp. text is the notification fallback (requires Bot token).
+func (c *Client) PostBlocks(ctx context.Context, threadTS, text string, blocks []types.SlackBlock) (string, error) {
+	if !c.HasBotToken() {
+		return "", fmt.Errorf("Bot token required for thread replies")
+	}
+
+	return c.postMessage(ctx, types.SlackMessage{
+		Channel:  c.ChannelOf(threadTS),
+		ThreadTS: threadTS,
+		Text:     text,
+		Blocks:   blocks,
+	})
+}
+
+// analysisButtons returns the buttons under an analysis
+func analysisButtons(analysisTS, threadTS string) []types.SlackButton {
+	return []types.SlackButton{
+		{
+			Type:     "button",
+			Text:     types.SlackTextObject{Type: "plain_text", Text: "✅ Correct"},
+			ActionID: RateCorrectActionID,
+			Value:    analysisTS,
+			Style:    "primary",
+		},
+		{
+			Type:     "button",
+			Text:     types.SlackTextObject{Type: "plain_text", Text: "❌ Incorrect"},
+			ActionID: RateIncorrectActionID,
+			Value:    analysisTS,
+			Style:    "danger",
+		},
+		{
+			Type:     "button",
+			Text:     types.SlackTextObject{Type: "plain_text", Text: "🔄 Re-analyze"},
+			ActionID: ReanalyzeActionID,
+			Value:    threadTS,
+			Confirm: &types.SlackConfirm{
+				Title:   types.SlackTextObject{Type: "plain_text", Text: "Analyze again?"},
+				Text:    types.SlackTextObject{Type: "mrkdwn", Text: "Debug info is gathered again and a new analysis is posted in this thread."},
+				Confirm: types.SlackTextObject{Type: "plain_text", Text: "Re-analyze"},
+				Deny:    types.SlackTextObject{Type: "plain_text", Text: "Cancel"},
+			},
+		},
+		{
+			Type:     "button",
+			Text:     types.SlackTextObject{Type: "plain_text", Text: "🔎 Show evidence"},
+			ActionID: ShowEvidenceActionID,
+			Value:    threadTS,
+		},
+	}
+}
+
+// splitMessages lays out blocks in as many messages as Slack's block limit
+// requires: lead opens the first message and trailer closes the last one
+func splitMessages(lead, sections, trailer []types.SlackBlock) [][]types.SlackBlock {
+	continued := types.SlackBlock{
+		Type:     "context",
+		Elements: []types.SlackTextObject{{Type: "mrkdwn", Text: "_… analysis continued_"}},
+	}
+
+	var messages [][]types.SlackBlock
+	current := append([]types.SlackBlock(nil), lead...)
+	for _, section := range sections {
+		if len(current) == maxMessageBlocks {
+			messages = append(messages, current)
+			current = []types.SlackBlock{continued}
+		}
+		current = append(current, section)
+	}
+	if len(current)+len(trailer) > maxMessageBlocks {
+		messages = append(messages, current)
+		current = []types.SlackBlock{continued}
+	}
+	return append(messages, append(current, trailer...))
+}
+
+// textSections splits mrkdwn text into sections: one per part led by a bold
+// header, each cut at line boundaries to fit a section's text. Code blocks
+// cut in two are closed and reopened.
+func textSections(text string) []types.SlackBlock {
+	var sections []types.SlackBlock
+	var current strings.Builder
+	inCode := false
+
+	flush := func() {
+		chunk := current.String()
+		current.Reset()
+		if inCode {
+			chunk += "```"
+			current.WriteString("```\n")
+		}
+		if strings.Trim(chunk, "` \n") == "" {
+			return
+		}
+		sections = append(sections, types.SlackBlock{
+			Type: "section",
+			Text: &types.SlackTextObject{Type: "mrkdwn", Text: strings.TrimRight(chunk, "\n")},
+		})
+	}
+
+	// Room for closing a code block cut in two
+	room := maxSectionText - len("\n```")
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if partHeader.MatchString(line) && !inCode && current.Len() > 0 {
+			flush()
+		}
+		for len(line) > room {
+			if current.Len() > 0 {
+				flush()
+			}
+			cut := room - current.Len()
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			current.WriteString(line[:cut])
+			line = line[cut:]
+			flush()
+		}
+		if current.Len()+len(line)+1 > room {
+			flush()
+		}
+		current.WriteString(line + "\n")
+		if strings.Count(line, "```")%2 == 1 {
+			inCode = !inCode
+		}
+	}
+	inCode = false
+	flush()
+	return sections
+}
+
+// messageSections renders text as the sections of a single message, noting
+// what didn't fit
+func messageSections(text string, reserved int) []types.SlackBlock {
+	sections := textSections(text)
+	if room := maxMessageBlocks - reserved; len(sections) > room {
+		sections = append(sections[:room-1], types.SlackBlock{
+			Type:     "context",
+			Elements: []types.SlackTextObject{{Type: "mrkdwn", Text: fmt.Sprintf("_… %d more parts_", len(sections)-room+1)}},
+		})
+	}
+	return sections
+}
@@ -2,23 +2,50 @@ package slack
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/valentinpelus/k8flex/pkg/types"
 )
 
+// DefaultAPIURL is the base URL of the Slack Web API
+const DefaultAPIURL = "https://slack.com/api"
+
+// channelTTL is how long the channel of a message is remembered after it was
+// last tracked. Older messages get theirs again from the thread and pending
+// feedback stores.
+const channelTTL = 24 * time.Hour
+
 // Client wraps the Slack API client
 type Client struct {
 	webhookURL  string
 	botToken    string
 	channelID   string
 	workspaceID string // Added for building Slack links
+	botUserID   string // User ID of the bot, from auth.test, to recognize mentions
+	interactive bool   // Slack delivers button clicks to the app
+	uploads     bool   // Debug reports are uploaded as files
+	apiURL      string // Web API base URL, a fake server's in tests
 	client      *http.Client
+
+	resolveChannel func(types.Alert) string // Channel an alert is posted to; nil or "" falls back to routes
+	routes         *ChannelRoutes           // Channels and owners of alerts by label; unmatched alerts use channelID
+	mu             sync.Mutex
+	channels       map[string]trackedChannel // Message timestamp to channel, for messages outside channelID
+	pruned         time.Time                 // Last time expired channels were evicted
+}
+
+// trackedChannel is the channel of a message and when it was last tracked
+type trackedChannel struct {
+	channel string
+	at      time.Time
 }
 
 // NewClient creates a new Slack client
@@ -27,8 +54,96 @@ func NewClient(webhookURL, botToken, channelID string) *Client {
 		webhookURL: webhookURL,
 		botToken:   botToken,
 		channelID:  channelID,
-		client:     &http.Client{},
+		apiURL:     DefaultAPIURL,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		channels:   make(map[string]trackedChannel),
+	}
+}
+
+// SetAPIURL points the client at another Web API, e.g. a local fake Slack
+// server
+func (c *Client) SetAPIURL(apiURL string) {
+	c.apiURL = strings.TrimRight(apiURL, "/")
+}
+
+// SetChannelResolver sets how the channel of an alert is picked, e.g. from
+// its namespace's policy. Alerts it returns "" for go to the default channel.
+func (c *Client) SetChannelResolver(resolve func(types.Alert) string) {
+	c.resolveChannel = resolve
+}
+
+// SetChannelRoutes sets the channels and owning teams of alerts by label.
+// The channel resolver takes precedence over the routes' channels.
+func (c *Client) SetChannelRoutes(routes *ChannelRoutes) {
+	c.routes = routes
+}
+
+// alertChannel returns the channel an alert is posted to: the resolver's,
+// the first matching route's, or the default channel
+func (c *Client) alertChannel(alert types.Alert) string {
+	if c.resolveChannel != nil {
+		if channel := c.resolveChannel(alert); channel != "" {
+			return channel
+		}
+	}
+	if route := c.routes.Route(alert); route != nil && route.Channel != "" {
+		return route.Channel
 	}
+	return c.channelID
+}
+
+// SetInteractive sets whether Slack delivers button clicks to the app, so
+// analyses get rating, re-analyze and show-evidence buttons
+func (c *Client) SetInteractive(interactive bool) {
+	c.interactive = interactive
+}
+
+// IsInteractive reports whether analyses get buttons
+func (c *Client) IsInteractive() bool {
+	return c.interactive
+}
+
+// SetReportUploads sets whether UploadReport uploads debug reports
+func (c *Client) SetReportUploads(enabled bool) {
+	c.uploads = enabled
+}
+
+// ChannelOf returns the channel a message or thread was posted in
+func (c *Client) ChannelOf(ts string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if tracked, ok := c.channels[ts]; ok {
+		return tracked.channel
+	}
+	return c.channelID
+}
+
+// TrackChannel records the channel of a message posted before a restart, so
+// replies and updates reach it
+func (c *Client) TrackChannel(ts, channel string) {
+	if ts == "" || channel == "" || channel == c.channelID {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.channels[ts] = trackedChannel{channel: channel, at: now}
+
+	// Evict the channels of messages not tracked for a while, at most hourly
+	if now.Sub(c.pruned) < time.Hour {
+		return
+	}
+	c.pruned = now
+	for ts, tracked := range c.channels {
+		if now.Sub(tracked.at) > channelTTL {
+			delete(c.channels, ts)
+		}
+	}
+}
+
+// Name returns the notifier name alerts are routed to Slack by
+func (c *Client) Name() string {
+	return "slack"
 }
 
 // IsConfigured checks if Slack notifications are configured
@@ -42,32 +157,32 @@ func (c *Client) HasBotToken() bool {
 }
 
 // SendAlert sends an alert to Slack and returns the thread timestamp
-func (c *Client) SendAlert(alert types.Alert) (string, error) {
+func (c *Client) SendAlert(ctx context.Context, alert types.Alert) (string, error) {
 	if c.HasBotToken() {
-		return c.sendAlertWithBot(alert)
+		return c.sendAlertWithBot(ctx, alert)
 	} else if c.webhookURL != "" {
-		return c.sendAlertWithWebhook(alert)
+		return c.sendAlertWithWebhook(ctx, alert)
 	}
 	return "", nil
 }
 
 // SendAnalysis sends the analysis to Slack as a threaded reply
-func (c *Client) SendAnalysis(alert types.Alert, analysis string, threadTS string) error {
+func (c *Client) SendAnalysis(ctx context.Context, alert types.Alert, analysis string, threadTS string) error {
 	if c.HasBotToken() && threadTS != "" {
-		_, err := c.sendAnalysisWithBot(alert, analysis, threadTS)
+		_, err := c.sendAnalysisWithBot(ctx, alert, analysis, threadTS)
 		return err
 	} else if c.webhookURL != "" {
-		return c.sendAnalysisWithWebhook(alert, analysis, threadTS)
+		return c.sendAnalysisWithWebhook(ctx, alert, analysis, threadTS)
 	}
 	return nil
 }
 
 // SendAnalysisInThread sends analysis in a thread and returns the message timestamp for updates
-func (c *Client) SendAnalysisInThread(alert types.Alert, analysis string, threadTS string) (string, error) {
+func (c *Client) SendAnalysisInThread(ctx context.Context, alert types.Alert, analysis string, threadTS string) (string, error) {
 	if c.HasBotToken() && threadTS != "" {
-		return c.sendAnalysisWithBot(alert, analysis, threadTS)
+		return c.sendAnalysisWithBot(ctx, alert, analysis, threadTS)
 	} else if c.webhookURL != "" {
-		err := c.sendAnalysisWithWebhook(alert, analysis, threadTS)
+		err := c.sendAnalysisWithWebhook(ctx, alert, analysis, threadTS)
 		return "", err
 	}
 	return "", nil
@@ -75,25 +190,29 @@ func (c *Client) SendAnalysisInThread(alert types.Alert, analysis string, thread
 
 // sendAlertWithBot sends an alert using the Slack Bot token API
 // Reference: https://api.slack.com/methods/chat.postMessage
-func (c *Client) sendAlertWithBot(alert types.Alert) (string, error) {
+func (c *Client) sendAlertWithBot(ctx context.Context, alert types.Alert) (string, error) {
 	severity := alert.Labels["severity"]
 	message := c.buildAlertMessage(alert, severity)
-	message.Channel = c.channelID
+	message.Channel = c.alertChannel(alert)
 
-	return c.postMessage(message)
+	return c.postMessage(ctx, message)
 }
 
 // sendAlertWithWebhook sends an alert using Slack incoming webhook
-func (c *Client) sendAlertWithWebhook(alert types.Alert) (string, error) {
+func (c *Client) sendAlertWithWebhook(ctx context.Context, alert types.Alert) (string, error) {
 	severity := alert.Labels["severity"]
-	message := c.buildAlertMessage(alert, severity)
+	return c.postWebhook(ctx, c.buildAlertMessage(alert, severity))
+}
 
+// postWebhook sends a top-level message using Slack incoming webhook and
+// returns its timestamp when the response includes one
+func (c *Client) postWebhook(ctx context.Context, message types.SlackMessage) (string, error) {
 	jsonData, err := json.Marshal(message)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal Slack message: %w", err)
 	}
 
-	resp, err := c.client.Post(c.webhookURL, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := c.post(ctx, c.webhookURL, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("failed to send to Slack: %w", err)
 	}
@@ -112,7 +231,7 @@ func (c *Client) sendAlertWithWebhook(alert types.Alert) (string, error) {
 
 	// Incoming webhooks typically just return "ok"
 	if strings.TrimSpace(string(body)) == "ok" {
-		log.Printf("Alert sent to Slack successfully")
+		log.Printf("Message sent to Slack successfully")
 		return "", nil
 	}
 
@@ -130,37 +249,32 @@ func (c *Client) sendAlertWithWebhook(alert types.Alert) (string, error) {
 }
 
 // sendAnalysisWithBot sends analysis using the Slack Bot token API and returns message timestamp
-func (c *Client) sendAnalysisWithBot(_ types.Alert, analysis string, threadTS string) (string, error) {
+func (c *Client) sendAnalysisWithBot(ctx context.Context, _ types.Alert, analysis string, threadTS string) (string, error) {
+	blocks := []types.SlackBlock{
+		{
+			Type: "section",
+			Text: &types.SlackTextObject{
+				Type: "mrkdwn",
+				Text: "*🔍 AI Debug Analysis*",
+			},
+		},
+		{
+			Type: "divider",
+		},
+	}
 	message := types.SlackMessage{
-		Channel:     c.channelID,
+		Channel:     c.ChannelOf(threadTS),
 		ThreadTS:    threadTS,
+		Text:        truncateForSlack(ConvertMarkdownToSlack(analysis), maxSectionText),
 		UnfurlLinks: false,
-		Blocks: []types.SlackBlock{
-			{
-				Type: "section",
-				Text: &types.SlackTextObject{
-					Type: "mrkdwn",
-					Text: "*🔍 AI Debug Analysis*",
-				},
-			},
-			{
-				Type: "divider",
-			},
-			{
-				Type: "section",
-				Text: &types.SlackTextObject{
-					Type: "mrkdwn",
-					Text: truncateForSlack(ConvertMarkdownToSlack(analysis), 2900),
-				},
-			},
-		},
+		Blocks:      append(blocks, messageSections(ConvertMarkdownToSlack(analysis), len(blocks))...),
 	}
 
-	return c.postMessage(message)
+	return c.postMessage(ctx, message)
 }
 
 // sendAnalysisWithWebhook sends analysis using Slack incoming webhook
-func (c *Client) sendAnalysisWithWebhook(alert types.Alert, analysis string, threadTS string) error {
+func (c *Client) sendAnalysisWithWebhook(ctx context.Context, alert types.Alert, analysis string, threadTS string) error {
 	message := types.SlackMessage{
 		UnfurlLinks: false,
 		Blocks: []types.SlackBlock{
@@ -174,15 +288,9 @@ func (c *Client) sendAnalysisWithWebhook(alert types.Alert, analysis string, thr
 			{
 				Type: "divider",
 			},
-			{
-				Type: "section",
-				Text: &types.SlackTextObject{
-					Type: "mrkdwn",
-					Text: fmt.Sprintf("```\n%s\n```", truncateForSlack(analysis, 2900)),
-				},
-			},
 		},
 	}
+	message.Blocks = append(message.Blocks, messageSections(ConvertMarkdownToSlack(analysis), len(message.Blocks))...)
 
 	if threadTS != "" {
 		message.ThreadTS = threadTS
@@ -193,7 +301,7 @@ func (c *Client) sendAnalysisWithWebhook(alert types.Alert, analysis string, thr
 		return fmt.Errorf("failed to marshal Slack message: %w", err)
 	}
 
-	resp, err := c.client.Post(c.webhookURL, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := c.post(ctx, c.webhookURL, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to send to Slack: %w", err)
 	}
@@ -209,13 +317,13 @@ func (c *Client) sendAnalysisWithWebhook(alert types.Alert, analysis string, thr
 }
 
 // postMessage sends a message using the Slack chat.postMessage API
-func (c *Client) postMessage(message types.SlackMessage) (string, error) {
+func (c *Client) postMessage(ctx context.Context, message types.SlackMessage) (string, error) {
 	jsonData, err := json.Marshal(message)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal Slack message: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://slack.com/api/chat.postMessage", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.apiURL+"/chat.postMessage", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -243,10 +351,21 @@ func (c *Client) postMessage(message types.SlackMessage) (string, error) {
 		return "", fmt.Errorf("Slack error: %s", slackResp.Error)
 	}
 
+	c.TrackChannel(slackResp.TS, message.Channel)
 	log.Printf("Message sent to Slack, thread_ts: %s", slackResp.TS)
 	return slackResp.TS, nil
 }
 
+// post sends a body to a URL outside the Web API, such as an incoming webhook
+func (c *Client) post(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.client.Do(req)
+}
+
 // buildAlertMessage creates a Slack message with blocks for an alert
 func (c *Client) buildAlertMessage(alert types.Alert, severity string) types.SlackMessage {
 	message := types.SlackMessage{
@@ -313,15 +432,25 @@ func (c *Client) buildAlertMessage(alert types.Alert, severity string) types.Sla
 		})
 	}
 
-	// Add timestamp
-	message.Blocks = append(message.Blocks, types.SlackBlock{
-		Type: "context",
-		Elements: []types.SlackTextObject{
-			{
-				Type: "mrkdwn",
-				Text: fmt.Sprintf("Started: %s", alert.StartsAt.Format("2006-01-02 15:04:05 MST")),
-			},
+	// Add timestamp, and the runbook link if the alert has one
+	context := []types.SlackTextObject{
+		{
+			Type: "mrkdwn",
+			Text: fmt.Sprintf("Started: %s", alert.StartsAt.Format("2006-01-02 15:04:05 MST")),
 		},
+	}
+	if runbookURL := alert.Annotations["runbook_url"]; runbookURL != "" {
+		context = append(context, types.SlackTextObject{
+			Type: "mrkdwn",
+			Text: fmt.Sprintf("📖 <%s|Runbook>", runbookURL),
+		})
+	}
+	if owner := c.routes.Route(alert).owner(); owner != "" {
+		context = append(context, types.SlackTextObject{Type: "mrkdwn", Text: owner})
+	}
+	message.Blocks = append(message.Blocks, types.SlackBlock{
+		Type:     "context",
+		Elements: context,
 	})
 
 	// Add divider and status message
@@ -339,6 +468,37 @@ func (c *Client) buildAlertMessage(alert types.Alert, severity string) types.Sla
 	return message
 }
 
+// buildResolvedMessage rebuilds the alert message with a resolved header and status
+func (c *Client) buildResolvedMessage(alert types.Alert, duration time.Duration) types.SlackMessage {
+	severity := alert.Labels["severity"]
+	message := c.buildAlertMessage(alert, fmt.Sprintf("~%s~ resolved", severity))
+
+	// Header is always the first block
+	message.Blocks[0].Text = &types.SlackTextObject{
+		Type: "plain_text",
+		Text: fmt.Sprintf("✅ [RESOLVED] %s", alert.Labels["alertname"]),
+	}
+
+	// Replace the trailing "in progress" status with the resolution
+	message.Blocks[len(message.Blocks)-1] = types.SlackBlock{
+		Type: "section",
+		Text: &types.SlackTextObject{
+			Type: "mrkdwn",
+			Text: fmt.Sprintf("✅ _Resolved after %s — see thread for the analysis_", formatDuration(duration)),
+		},
+	}
+
+	return message
+}
+
+// formatDuration formats a resolution duration for display
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return "an unknown duration"
+	}
+	return d.Round(time.Second).String()
+}
+
 // truncateForSlack truncates text to fit within Slack message limits
 func truncateForSlack(text string, maxLen int) string {
 	if len(text) <= maxLen {
@@ -357,23 +517,87 @@ func ConvertMarkdownToSlack(text string) string {
 }
 
 // UpdateMessage updates an existing Slack message (requires Bot token)
-func (c *Client) UpdateMessage(messageTS, newText string) error {
+func (c *Client) UpdateMessage(ctx context.Context, messageTS, newText string) error {
 	if !c.HasBotToken() {
 		return fmt.Errorf("Bot token required for message updates")
 	}
 
+	// Sections hold up to 50 times what the text field shows
+	newText = ConvertMarkdownToSlack(newText)
 	updatePayload := map[string]interface{}{
-		"channel": c.channelID,
+		"channel": c.ChannelOf(messageTS),
 		"ts":      messageTS,
-		"text":    truncateForSlack(ConvertMarkdownToSlack(newText), 3000),
+		"text":    truncateForSlack(newText, maxSectionText),
+		"blocks":  messageSections(newText, 0),
 	}
 
+	return c.updateMessage(ctx, updatePayload)
+}
+
+// SendResolution posts a resolution notice in the alert thread and marks the
+// parent alert message as resolved (requires Bot token)
+func (c *Client) SendResolution(ctx context.Context, alert types.Alert, threadTS string, duration time.Duration) error {
+	if !c.HasBotToken() {
+		if c.webhookURL == "" {
+			return nil
+		}
+		return c.sendResolutionWithWebhook(ctx, alert, duration)
+	}
+
+	resolvedMsg := fmt.Sprintf("✅ *Resolved* after %s", formatDuration(duration))
+	if err := c.ReplyToThread(ctx, threadTS, resolvedMsg); err != nil {
+		return fmt.Errorf("failed to post resolution: %w", err)
+	}
+
+	message := c.buildResolvedMessage(alert, duration)
+	updatePayload := map[string]interface{}{
+		"channel": c.ChannelOf(threadTS),
+		"ts":      threadTS,
+		"text":    fmt.Sprintf("✅ [RESOLVED] %s", alert.Labels["alertname"]),
+		"blocks":  message.Blocks,
+	}
+
+	if err := c.updateMessage(ctx, updatePayload); err != nil {
+		return fmt.Errorf("failed to update alert message: %w", err)
+	}
+	return nil
+}
+
+// sendResolutionWithWebhook sends a resolution notice using Slack incoming webhook
+func (c *Client) sendResolutionWithWebhook(ctx context.Context, alert types.Alert, duration time.Duration) error {
+	message := types.SlackMessage{
+		Text: fmt.Sprintf("✅ *[RESOLVED] %s* (%s) after %s",
+			alert.Labels["alertname"], alert.Labels["namespace"], formatDuration(duration)),
+	}
+
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Slack message: %w", err)
+	}
+
+	resp, err := c.post(ctx, c.webhookURL, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to send to Slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Slack API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// updateMessage sends a payload to the Slack chat.update API
+// Reference: https://api.slack.com/methods/chat.update
+func (c *Client) updateMessage(ctx context.Context, updatePayload map[string]interface{}) error {
 	jsonData, err := json.Marshal(updatePayload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal update payload: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://slack.com/api/chat.update", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.apiURL+"/chat.update", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -401,14 +625,15 @@ func (c *Client) UpdateMessage(messageTS, newText string) error {
 }
 
 // GetMessageReactions retrieves reactions on a specific message
-func (c *Client) GetMessageReactions(messageTS string) ([]string, error) {
+func (c *Client) GetMessageReactions(ctx context.Context, messageTS string) ([]string, error) {
 	if !c.HasBotToken() {
 		return nil, fmt.Errorf("Bot token required for getting reactions")
 	}
 
-	url := fmt.Sprintf("https://slack.com/api/reactions.get?channel=%s&timestamp=%s", c.channelID, messageTS)
+	channel := c.ChannelOf(messageTS)
+	url := fmt.Sprintf("%s/reactions.get?channel=%s&timestamp=%s", c.apiURL, channel, messageTS)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -444,9 +669,9 @@ func (c *Client) GetMessageReactions(messageTS string) ([]string, error) {
 		}
 		// Special handling for not_in_channel error
 		if result.Error == "not_in_channel" {
-			return nil, fmt.Errorf("bot not in channel - invite bot to channel with: /invite @bot-name (channel: %s)", c.channelID)
+			return nil, fmt.Errorf("bot not in channel - invite bot to channel with: /invite @bot-name (channel: %s)", channel)
 		}
-		return nil, fmt.Errorf("Slack error: %s (channel: %s, ts: %s)", result.Error, c.channelID, messageTS)
+		return nil, fmt.Errorf("Slack error: %s (channel: %s, ts: %s)", result.Error, channel, messageTS)
 	}
 
 	reactions := make([]string, 0, len(result.Message.Reactions))
@@ -458,19 +683,25 @@ func (c *Client) GetMessageReactions(messageTS string) ([]string, error) {
 }
 
 // ReplyToThread sends a message as a reply in a thread
-func (c *Client) ReplyToThread(threadTS, text string) error {
+func (c *Client) ReplyToThread(ctx context.Context, threadTS, text string) error {
+	_, err := c.PostThreadReply(ctx, threadTS, text)
+	return err
+}
+
+// PostThreadReply sends a message as a reply in a thread and returns its
+// timestamp for updates
+func (c *Client) PostThreadReply(ctx context.Context, threadTS, text string) (string, error) {
 	if !c.HasBotToken() {
-		return fmt.Errorf("Bot token required for thread replies")
+		return "", fmt.Errorf("Bot token required for thread replies")
 	}
 
 	message := types.SlackMessage{
-		Channel:  c.channelID,
+		Channel:  c.ChannelOf(threadTS),
 		ThreadTS: threadTS,
 		Text:     text,
 	}
 
-	_, err := c.postMessage(message)
-	return err
+	return c.postMessage(ctx, message)
 }
 
 // GetChannelID returns the configured channel ID
@@ -490,14 +721,27 @@ func (c *Client) SetWorkspaceID(workspaceID string) {
 	c.workspaceID = workspaceID
 }
 
+// BotUserID returns the user ID of the bot, known once ValidateScopes succeeded
+func (c *Client) BotUserID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.botUserID
+}
+
+// MentionsBot reports whether a message's text mentions the bot
+func (c *Client) MentionsBot(text string) bool {
+	botUserID := c.BotUserID()
+	return botUserID != "" && strings.Contains(text, "<@"+botUserID+">")
+}
+
 // ValidateScopes checks if the bot token has required scopes for feedback detection
-func (c *Client) ValidateScopes() error {
+func (c *Client) ValidateScopes(ctx context.Context) error {
 	if !c.HasBotToken() {
 		return fmt.Errorf("bot token not configured")
 	}
 
 	// Call auth.test to verify token and get bot info
-	req, err := http.NewRequest("GET", "https://slack.com/api/auth.test", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.apiURL+"/auth.test", nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -513,8 +757,9 @@ func (c *Client) ValidateScopes() error {
 	body, _ := io.ReadAll(resp.Body)
 
 	var result struct {
-		OK    bool   `json:"ok"`
-		Error string `json:"error,omitempty"`
+		OK     bool   `json:"ok"`
+		Error  string `json:"error,omitempty"`
+		UserID string `json:"user_id"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
@@ -525,10 +770,14 @@ func (c *Client) ValidateScopes() error {
 		return fmt.Errorf("token validation failed: %s", result.Error)
 	}
 
+	c.mu.Lock()
+	c.botUserID = result.UserID
+	c.mu.Unlock()
+
 	// Try a test call to reactions.get to check if scope exists
 	// We use a fake timestamp, expecting either success or message_not_found (which means scope is OK)
-	testURL := fmt.Sprintf("https://slack.com/api/reactions.get?channel=%s&timestamp=0000000000.000000", c.channelID)
-	req, _ = http.NewRequest("GET", testURL, nil)
+	testURL := fmt.Sprintf("%s/reactions.get?channel=%s&timestamp=0000000000.000000", c.apiURL, c.channelID)
+	req, _ = http.NewRequestWithContext(ctx, "GET", testURL, nil)
 	req.Header.Set("Authorization", "Bearer "+c.botToken)
 
 	resp, err = c.client.Do(req)
@@ -0,0 +1,83 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// mentionPattern matches user mentions in message text, e.g. <@U024BE7LH>
+var mentionPattern = regexp.MustCompile(`<@[A-Z0-9]+(\|[^>]*)?>`)
+
+// EventCallback is a request of the Events API, or the payload of an
+// events_api envelope in Socket Mode
+// Reference: https://api.slack.com/apis/connections/events-api
+type EventCallback struct {
+	Type      string `json:"type"`      // "url_verification" or "event_callback"
+	Challenge string `json:"challenge"` // Echoed back to verify the Request URL
+	TeamID    string `json:"team_id"`
+	EventID   string `json:"event_id"`
+	Event     Event  `json:"event"`
+}
+
+// Event is an event the app subscribes to: reaction_added, message or
+// app_mention
+type Event struct {
+	Type     string `json:"type"`
+	Subtype  string `json:"subtype"`
+	User     string `json:"user"`
+	BotID    string `json:"bot_id"` // Set on messages posted by apps, k8flex included
+	Reaction string `json:"reaction"`
+	Item     struct {
+		Type    string `json:"type"` // "message" for reactions to messages
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	} `json:"item"`
+	Channel  string `json:"channel"`
+	TS       string `json:"ts"`
+	ThreadTS string `json:"thread_ts"`
+	Text     string `json:"text"`
+}
+
+// IsThreadReply reports whether the event is a person's reply in a thread
+func (e *Event) IsThreadReply() bool {
+	if e.Type != "message" || e.BotID != "" || e.User == "" {
+		return false
+	}
+	if e.Subtype != "" && e.Subtype != "thread_broadcast" {
+		return false
+	}
+	return e.ThreadTS != "" && e.ThreadTS != e.TS
+}
+
+// IsThreadMention reports whether the event is a person mentioning the app
+// in a thread
+func (e *Event) IsThreadMention() bool {
+	return e.Type == "app_mention" && e.BotID == "" && e.User != "" &&
+		e.ThreadTS != "" && e.ThreadTS != e.TS
+}
+
+// StripMentions removes user mentions from message text
+func StripMentions(text string) string {
+	return strings.TrimSpace(mentionPattern.ReplaceAllString(text, ""))
+}
+
+// ParseEventCallback decodes the JSON body of an Events API request
+func ParseEventCallback(body []byte) (*EventCallback, error) {
+	var callback EventCallback
+	if err := json.Unmarshal(body, &callback); err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+	if callback.Type == "" {
+		return nil, fmt.Errorf("missing event type")
+	}
+	return &callback, nil
+}
+
+// EventHandler handles what Slack delivers to the app, whether received on
+// the HTTP endpoints or over Socket Mode
+type EventHandler interface {
+	OnEvent(callback *EventCallback)
+	OnInteraction(interaction *Interaction)
+}
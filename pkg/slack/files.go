@@ -0,0 +1,106 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// UploadReport uploads a debug report as a file snippet in a thread, if report
+// uploads are enabled (requires Bot token and the files:write scope)
+// Reference: https://api.slack.com/messaging/files#uploading_files
+func (c *Client) UploadReport(ctx context.Context, threadTS, filename, title, content string) error {
+	if !c.uploads {
+		return nil
+	}
+	if !c.HasBotToken() {
+		return fmt.Errorf("Bot token required for file uploads")
+	}
+
+	// 1. Reserve an upload URL for the file
+	form := url.Values{
+		"filename":     {filename},
+		"length":       {strconv.Itoa(len(content))},
+		"snippet_type": {"markdown"},
+	}
+	var reserved struct {
+		UploadURL string `json:"upload_url"`
+		FileID    string `json:"file_id"`
+	}
+	if err := c.callAPI(ctx, "files.getUploadURLExternal", "application/x-www-form-urlencoded",
+		strings.NewReader(form.Encode()), &reserved); err != nil {
+		return fmt.Errorf("failed to reserve upload: %w", err)
+	}
+
+	// 2. Send the content
+	resp, err := c.post(ctx, reserved.UploadURL, "text/markdown", strings.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("file upload returned status %d", resp.StatusCode)
+	}
+
+	// 3. Share it in the thread
+	complete, err := json.Marshal(map[string]interface{}{
+		"files":      []map[string]string{{"id": reserved.FileID, "title": title}},
+		"channel_id": c.ChannelOf(threadTS),
+		"thread_ts":  threadTS,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal upload: %w", err)
+	}
+	if err := c.callAPI(ctx, "files.completeUploadExternal", "application/json", bytes.NewReader(complete), nil); err != nil {
+		return fmt.Errorf("failed to share file: %w", err)
+	}
+
+	log.Printf("Uploaded %s to Slack thread %s", filename, threadTS)
+	return nil
+}
+
+// callAPI posts a Web API method and decodes its response into result, if
+// set, once checked for an error
+func (c *Client) callAPI(ctx context.Context, method, contentType string, body io.Reader, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.apiURL+"/"+method, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+c.botToken)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error,omitempty"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if !status.OK {
+		return fmt.Errorf("Slack error: %s", status.Error)
+	}
+	if result != nil {
+		if err := json.Unmarshal(data, result); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+	return nil
+}
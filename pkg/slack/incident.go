@@ -0,0 +1,136 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// maxListedKeys bounds the correlation keys shown on an incident message
+const maxListedKeys = 5
+
+// SendIncident posts one message for a group of correlated alerts and returns
+// the thread timestamp the incident analysis is posted in
+func (c *Client) SendIncident(ctx context.Context, incident *types.Incident) (string, error) {
+	message := c.buildIncidentMessage(incident, false, "🤖 _AI debugging in progress..._")
+	if c.HasBotToken() {
+		message.Channel = c.alertChannel(incident.Lead())
+		return c.postMessage(ctx, message)
+	} else if c.webhookURL != "" {
+		return c.postWebhook(ctx, message)
+	}
+	return "", nil
+}
+
+// SendIncidentResolution posts a resolution notice in the incident thread and
+// marks the parent message as resolved once all of its alerts are (requires
+// Bot token)
+func (c *Client) SendIncidentResolution(ctx context.Context, incident *types.Incident, threadTS string, duration time.Duration) error {
+	if !c.HasBotToken() {
+		if c.webhookURL == "" {
+			return nil
+		}
+		_, err := c.postWebhook(ctx, types.SlackMessage{
+			Text: fmt.Sprintf("✅ *[RESOLVED] %s* after %s", incident.Title(), formatDuration(duration)),
+		})
+		return err
+	}
+
+	resolvedMsg := fmt.Sprintf("✅ *All %d alerts resolved* after %s", len(incident.Members), formatDuration(duration))
+	if err := c.ReplyToThread(ctx, threadTS, resolvedMsg); err != nil {
+		return fmt.Errorf("failed to post resolution: %w", err)
+	}
+
+	message := c.buildIncidentMessage(incident, true,
+		fmt.Sprintf("✅ _Resolved after %s — see thread for the analysis_", formatDuration(duration)))
+	updatePayload := map[string]interface{}{
+		"channel": c.ChannelOf(threadTS),
+		"ts":      threadTS,
+		"text":    "✅ [RESOLVED] " + incident.Title(),
+		"blocks":  message.Blocks,
+	}
+
+	if err := c.updateMessage(ctx, updatePayload); err != nil {
+		return fmt.Errorf("failed to update incident message: %w", err)
+	}
+	return nil
+}
+
+// buildIncidentMessage creates a Slack message listing the alerts of an
+// incident and the keys they were correlated by, ending with a status line
+func (c *Client) buildIncidentMessage(incident *types.Incident, resolved bool, status string) types.SlackMessage {
+	lead := incident.Lead()
+
+	header := "🔗 " + incident.Title()
+	severity := lead.Labels["severity"]
+	if resolved {
+		header = "✅ [RESOLVED] " + incident.Title()
+		severity = fmt.Sprintf("~%s~ resolved", severity)
+	}
+
+	shared := incident.Shared
+	if len(shared) > maxListedKeys {
+		shared = shared[:maxListedKeys]
+	}
+	correlatedBy := "`" + strings.Join(shared, "`, `") + "`"
+	if len(shared) == 0 {
+		correlatedBy = "_no shared key_"
+	}
+
+	var members strings.Builder
+	startedAt := lead.StartsAt
+	for _, m := range incident.Members {
+		alert := m.Alert
+		members.WriteString(fmt.Sprintf("• `%s`", alert.Labels["alertname"]))
+		if target := types.AlertTarget(alert); target != "" {
+			members.WriteString(" · " + target)
+		}
+		if summary := alert.Annotations["summary"]; summary != "" {
+			members.WriteString(" — " + summary)
+		}
+		members.WriteString("\n")
+		if !alert.StartsAt.IsZero() && alert.StartsAt.Before(startedAt) {
+			startedAt = alert.StartsAt
+		}
+	}
+
+	context := []types.SlackTextObject{
+		{Type: "mrkdwn", Text: fmt.Sprintf("Started: %s", startedAt.Format("2006-01-02 15:04:05 MST"))},
+	}
+	if owner := c.routes.Route(lead).owner(); owner != "" {
+		context = append(context, types.SlackTextObject{Type: "mrkdwn", Text: owner})
+	}
+
+	return types.SlackMessage{
+		UnfurlLinks: false,
+		Blocks: []types.SlackBlock{
+			{
+				Type: "header",
+				Text: &types.SlackTextObject{Type: "plain_text", Text: truncateForSlack(header, 140)},
+			},
+			{
+				Type: "section",
+				Fields: []types.SlackTextObject{
+					{Type: "mrkdwn", Text: fmt.Sprintf("*Severity:*\n%s", severity)},
+					{Type: "mrkdwn", Text: fmt.Sprintf("*Correlated by:*\n%s", correlatedBy)},
+				},
+			},
+			{
+				Type: "section",
+				Text: &types.SlackTextObject{
+					Type: "mrkdwn",
+					Text: truncateForSlack(fmt.Sprintf("*Alerts (%d):*\n%s", len(incident.Members), members.String()), 2900),
+				},
+			},
+			{Type: "context", Elements: context},
+			{Type: "divider"},
+			{
+				Type: "section",
+				Text: &types.SlackTextObject{Type: "mrkdwn", Text: status},
+			},
+		},
+	}
+}
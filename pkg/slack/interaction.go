@@ -0,0 +1,111 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// maxRequestAge rejects signed requests older than this, so a captured
+// request can't be replayed later
+const maxRequestAge = 5 * time.Minute
+
+// Interaction is the payload Slack sends to the interactivity endpoint when
+// a button is clicked
+// Reference: https://api.slack.com/reference/interaction-payloads/block-actions
+type Interaction struct {
+	Type string `json:"type"` // "block_actions" for buttons
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+		Name     string `json:"name"`
+	} `json:"user"`
+	Team struct {
+		ID     string `json:"id"`
+		Domain string `json:"domain"`
+	} `json:"team"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	Message struct {
+		TS       string             `json:"ts"`
+		ThreadTS string             `json:"thread_ts"`
+		Blocks   []types.SlackBlock `json:"blocks"`
+	} `json:"message"`
+	Actions []InteractionAction `json:"actions"`
+}
+
+// InteractionAction is a clicked button
+type InteractionAction struct {
+	ActionID string `json:"action_id"`
+	BlockID  string `json:"block_id"`
+	Value    string `json:"value"`
+}
+
+// UserName returns the name of the user who clicked, falling back to their ID
+func (i *Interaction) UserName() string {
+	if i.User.Username != "" {
+		return i.User.Username
+	}
+	if i.User.Name != "" {
+		return i.User.Name
+	}
+	return i.User.ID
+}
+
+// ParseInteraction decodes the form-encoded body of an interactivity request
+func ParseInteraction(body []byte) (*Interaction, error) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid form body: %w", err)
+	}
+	payload := form.Get("payload")
+	if payload == "" {
+		return nil, fmt.Errorf("missing payload")
+	}
+
+	var interaction Interaction
+	if err := json.Unmarshal([]byte(payload), &interaction); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	return &interaction, nil
+}
+
+// VerifySignature checks that a request was signed by Slack with the app's
+// signing secret and is recent
+// Reference: https://api.slack.com/authentication/verifying-requests-from-slack
+func VerifySignature(signingSecret string, header http.Header, body []byte, now time.Time) error {
+	if signingSecret == "" {
+		return fmt.Errorf("no signing secret configured")
+	}
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	signature := header.Get("X-Slack-Signature")
+	if timestamp == "" || signature == "" {
+		return fmt.Errorf("missing signature headers")
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", timestamp)
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > maxRequestAge || age < -maxRequestAge {
+		return fmt.Errorf("request timestamp is %s off", age.Round(time.Second))
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
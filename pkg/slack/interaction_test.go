@@ -0,0 +1,59 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// sign returns the v0 signature headers of a body signed at a time
+func sign(secret string, body []byte, at time.Time) http.Header {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+
+	header := http.Header{}
+	header.Set("X-Slack-Request-Timestamp", timestamp)
+	header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return header
+}
+
+func TestVerifySignature(t *testing.T) {
+	const secret = "signing-secret"
+	body := []byte(`{"type":"event_callback","event_id":"Ev1"}`)
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  http.Header
+		body    []byte
+		wantErr bool
+	}{
+		{name: "valid", secret: secret, header: sign(secret, body, now), body: body},
+		{name: "clock skew within limit", secret: secret, header: sign(secret, body, now.Add(-4*time.Minute)), body: body},
+		{name: "bad signature", secret: secret, header: sign("other-secret", body, now), body: body, wantErr: true},
+		{name: "tampered body", secret: secret, header: sign(secret, body, now), body: []byte(`{"type":"event_callback","event_id":"Ev2"}`), wantErr: true},
+		{name: "stale timestamp", secret: secret, header: sign(secret, body, now.Add(-6*time.Minute)), body: body, wantErr: true},
+		{name: "future timestamp", secret: secret, header: sign(secret, body, now.Add(6*time.Minute)), body: body, wantErr: true},
+		{name: "empty secret", secret: "", header: sign("", body, now), body: body, wantErr: true},
+		{name: "missing headers", secret: secret, header: http.Header{}, body: body, wantErr: true},
+		{name: "invalid timestamp", secret: secret, header: http.Header{
+			"X-Slack-Request-Timestamp": {"yesterday"},
+			"X-Slack-Signature":         {"v0=00"},
+		}, body: body, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(tt.secret, tt.header, tt.body, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifySignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package slack
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/valentinpelus/k8flex/pkg/types"
+)
+
+// ChannelRoutes maps alert labels to the channels of the teams owning them
+type ChannelRoutes struct {
+	// Routes are checked in order; the first match picks the channel
+	Routes []ChannelRoute `json:"routes"`
+}
+
+// ChannelRoute posts the alerts matching its labels to a team's channel
+type ChannelRoute struct {
+	Match      map[string]string `json:"match"`      // Labels that must equal these values
+	MatchRegex map[string]string `json:"matchRegex"` // Labels that must fully match a regex
+	Channel    string            `json:"channel"`    // Channel ID; empty keeps the default channel
+	Team       string            `json:"team"`       // Team owning the alerts, shown on their messages
+	Mention    string            `json:"mention"`    // User or user group ID notified of new alerts, e.g. S0123ABCD
+
+	regexes map[string]*regexp.Regexp
+}
+
+// LoadChannelRoutes reads a channel routes file. A missing file returns nil,
+// meaning every alert goes to the default channel.
+func LoadChannelRoutes(path string) (*ChannelRoutes, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Slack channel routes: %w", err)
+	}
+
+	var routes ChannelRoutes
+	if err := yaml.UnmarshalStrict(data, &routes); err != nil {
+		return nil, fmt.Errorf("failed to parse Slack channel routes %s: %w", path, err)
+	}
+	if err := routes.compile(); err != nil {
+		return nil, fmt.Errorf("invalid Slack channel routes %s: %w", path, err)
+	}
+	return &routes, nil
+}
+
+// compile validates the routes and compiles their regexes
+func (r *ChannelRoutes) compile() error {
+	for i := range r.Routes {
+		route := &r.Routes[i]
+		if len(route.Match) == 0 && len(route.MatchRegex) == 0 {
+			return fmt.Errorf("route %d: match or matchRegex is required", i)
+		}
+		if route.Channel == "" && route.Team == "" && route.Mention == "" {
+			return fmt.Errorf("route %d: channel, team or mention is required", i)
+		}
+		route.regexes = make(map[string]*regexp.Regexp)
+		for label, pattern := range route.MatchRegex {
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return fmt.Errorf("route %d: matchRegex %s: %w", i, label, err)
+			}
+			route.regexes[label] = re
+		}
+	}
+	return nil
+}
+
+// Route returns the first route matching an alert, or nil
+func (r *ChannelRoutes) Route(alert types.Alert) *ChannelRoute {
+	if r == nil {
+		return nil
+	}
+	for i := range r.Routes {
+		if r.Routes[i].matches(alert) {
+			return &r.Routes[i]
+		}
+	}
+	return nil
+}
+
+// matches reports whether the route matches the alert
+func (r *ChannelRoute) matches(alert types.Alert) bool {
+	for label, value := range r.Match {
+		if alert.Labels[label] != value {
+			return false
+		}
+	}
+	for label, re := range r.regexes {
+		if !re.MatchString(alert.Labels[label]) {
+			return false
+		}
+	}
+	return true
+}
+
+// owner renders the team owning an alert, mentioning its on-call user or
+// group, or "" without one
+func (r *ChannelRoute) owner() string {
+	if r == nil || (r.Team == "" && r.Mention == "") {
+		return ""
+	}
+	owner := "👥 "
+	if r.Team != "" {
+		owner += "*" + r.Team + "*"
+	}
+	if r.Mention != "" {
+		if r.Team != "" {
+			owner += " "
+		}
+		owner += mention(r.Mention)
+	}
+	return owner
+}
+
+// mention renders a user or user group ID as a Slack mention
+// Reference: https://api.slack.com/reference/surfaces/formatting#mentioning-groups
+func mention(id string) string {
+	switch {
+	case len(id) > 0 && id[0] == 'S':
+		return "<!subteam^" + id + ">"
+	case id == "here" || id == "channel":
+		return "<!" + id + ">"
+	}
+	return "<@" + id + ">"
+}
@@ -0,0 +1,430 @@
+// Package slacktest is a local fake of the Slack Web API, Events API,
+// interactivity and Socket Mode, to exercise k8flex's Slack integration without a workspace
+package slacktest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/valentinpelus/k8flex/pkg/slack"
+)
+
+// Message is a message posted or updated through the fake Web API
+type Message struct {
+	Channel  string          `json:"channel"`
+	TS       string          `json:"ts"`
+	ThreadTS string          `json:"thread_ts"`
+	Text     string          `json:"text"`
+	Blocks   json.RawMessage `json:"blocks"`
+	Updated  bool            `json:"-"` // Changed with chat.update since it was posted
+}
+
+// File is a file uploaded and shared through the fake Web API
+type File struct {
+	ID       string
+	Name     string
+	Title    string
+	Content  string
+	Channel  string
+	ThreadTS string
+}
+
+// Server is a fake Slack. Point a client at it with Client.SetAPIURL(URL).
+type Server struct {
+	URL           string // Web API base URL
+	SigningSecret string // Signs the events sent with PostEvent
+	BotUserID     string // User ID auth.test returns for the bot
+
+	server    *httptest.Server
+	mu        sync.Mutex
+	messages  []Message
+	reactions map[string][]string // Message TS to reaction names
+	sockets   []*websocket.Conn
+	files     []File
+	acked     map[string]bool // Envelope IDs acknowledged over Socket Mode
+	sequence  int
+}
+
+// NewServer starts a fake Slack
+func NewServer() *Server {
+	s := &Server{
+		SigningSecret: "slacktest-signing-secret",
+		BotUserID:     "U0K8FLEX",
+		reactions:     make(map[string][]string),
+		acked:         make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/chat.postMessage", s.postMessage)
+	mux.HandleFunc("/api/chat.update", s.updateMessage)
+	mux.HandleFunc("/api/reactions.get", s.getReactions)
+	mux.HandleFunc("/api/auth.test", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, map[string]interface{}{"ok": true, "user_id": s.BotUserID})
+	})
+	mux.HandleFunc("/api/apps.connections.open", s.openConnection)
+	mux.HandleFunc("/api/files.getUploadURLExternal", s.getUploadURL)
+	mux.HandleFunc("/api/files.completeUploadExternal", s.completeUpload)
+	mux.HandleFunc("/upload/", s.upload)
+	mux.Handle("/socket", websocket.Handler(s.serveSocket))
+
+	s.server = httptest.NewServer(mux)
+	s.URL = s.server.URL + "/api"
+	return s
+}
+
+// Close stops the server and its Socket Mode connections
+func (s *Server) Close() {
+	s.mu.Lock()
+	for _, ws := range s.sockets {
+		ws.Close()
+	}
+	s.mu.Unlock()
+	s.server.Close()
+}
+
+// Messages returns the messages posted so far, with their latest content
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Files returns the files uploaded so far; only shared ones have a channel
+func (s *Server) Files() []File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]File(nil), s.files...)
+}
+
+// AddReaction adds a reaction returned by reactions.get for a message
+func (s *Server) AddReaction(ts, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reactions[ts] = append(s.reactions[ts], name)
+}
+
+// ReactionAdded returns the reaction_added event of a reaction to a message
+func (s *Server) ReactionAdded(channel, ts, reaction, user string) *slack.EventCallback {
+	callback := s.callback("reaction_added")
+	callback.Event.User = user
+	callback.Event.Reaction = reaction
+	callback.Event.Item.Type = "message"
+	callback.Event.Item.Channel = channel
+	callback.Event.Item.TS = ts
+	return callback
+}
+
+// ThreadReply returns the message event of a person's reply in a thread
+func (s *Server) ThreadReply(channel, threadTS, text, user string) *slack.EventCallback {
+	callback := s.callback("message")
+	callback.Event.User = user
+	callback.Event.Channel = channel
+	callback.Event.TS = s.nextTS()
+	callback.Event.ThreadTS = threadTS
+	callback.Event.Text = text
+	return callback
+}
+
+// Mention returns the app_mention event of a person mentioning the bot in a
+// thread. Slack also delivers the reply as a message event, see ThreadReply.
+func (s *Server) Mention(channel, threadTS, text, user string) *slack.EventCallback {
+	callback := s.ThreadReply(channel, threadTS, "<@"+s.BotUserID+"> "+text, user)
+	callback.Event.Type = "app_mention"
+	return callback
+}
+
+// ButtonClick returns the interaction of a person clicking a button of a
+// message posted through the fake Web API
+func (s *Server) ButtonClick(messageTS, actionID, value, user string) *slack.Interaction {
+	interaction := &slack.Interaction{Type: "block_actions"}
+	interaction.User.ID = user
+	interaction.User.Username = user
+	interaction.Team.ID = "T0SLACKTEST"
+	interaction.Actions = []slack.InteractionAction{{ActionID: actionID, Value: value}}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, message := range s.messages {
+		if message.TS == messageTS {
+			interaction.Channel.ID = message.Channel
+			interaction.Message.TS = message.TS
+			interaction.Message.ThreadTS = message.ThreadTS
+			json.Unmarshal(message.Blocks, &interaction.Message.Blocks)
+		}
+	}
+	return interaction
+}
+
+// PostInteraction sends an interaction to an interactivity endpoint, signed
+// with SigningSecret
+func (s *Server) PostInteraction(endpoint string, interaction *slack.Interaction) (*http.Response, error) {
+	payload, err := json.Marshal(interaction)
+	if err != nil {
+		return nil, err
+	}
+	body := []byte(url.Values{"payload": {string(payload)}}.Encode())
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	s.Sign(req.Header, body)
+	return http.DefaultClient.Do(req)
+}
+
+// PostEvent sends an event to an Events API endpoint, signed with
+// SigningSecret
+func (s *Server) PostEvent(endpoint string, callback *slack.EventCallback) (*http.Response, error) {
+	body, err := json.Marshal(callback)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	s.Sign(req.Header, body)
+	return http.DefaultClient.Do(req)
+}
+
+// Sign sets the signature headers of a request body
+func (s *Server) Sign(header http.Header, body []byte) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(s.SigningSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	header.Set("X-Slack-Request-Timestamp", timestamp)
+	header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+}
+
+// SendSocketEvent delivers an event over the open Socket Mode connections
+// and returns the envelope ID to check with Acked
+func (s *Server) SendSocketEvent(callback *slack.EventCallback) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.sockets) == 0 {
+		return "", fmt.Errorf("no Socket Mode connection")
+	}
+	envelopeID := fmt.Sprintf("envelope-%d", s.sequence)
+	s.sequence++
+	envelope := map[string]interface{}{
+		"envelope_id": envelopeID,
+		"type":        "events_api",
+		"payload":     callback,
+	}
+	for _, ws := range s.sockets {
+		if err := websocket.JSON.Send(ws, envelope); err != nil {
+			return "", err
+		}
+	}
+	return envelopeID, nil
+}
+
+// Acked reports whether an envelope was acknowledged
+func (s *Server) Acked(envelopeID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.acked[envelopeID]
+}
+
+// callback returns an event callback with a new event ID
+func (s *Server) callback(eventType string) *slack.EventCallback {
+	s.mu.Lock()
+	id := fmt.Sprintf("Ev%06d", s.sequence)
+	s.sequence++
+	s.mu.Unlock()
+
+	callback := &slack.EventCallback{Type: "event_callback", TeamID: "T0SLACKTEST", EventID: id}
+	callback.Event.Type = eventType
+	return callback
+}
+
+// nextTS returns a new message timestamp
+func (s *Server) nextTS() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sequence++
+	return fmt.Sprintf("%d.%06d", time.Now().Unix(), s.sequence)
+}
+
+// postMessage fakes chat.postMessage
+func (s *Server) postMessage(w http.ResponseWriter, r *http.Request) {
+	var message Message
+	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		reply(w, map[string]interface{}{"ok": false, "error": "invalid_json"})
+		return
+	}
+	message.TS = s.nextTS()
+
+	s.mu.Lock()
+	s.messages = append(s.messages, message)
+	s.mu.Unlock()
+
+	reply(w, map[string]interface{}{"ok": true, "channel": message.Channel, "ts": message.TS})
+}
+
+// updateMessage fakes chat.update
+func (s *Server) updateMessage(w http.ResponseWriter, r *http.Request) {
+	var update Message
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		reply(w, map[string]interface{}{"ok": false, "error": "invalid_json"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.messages {
+		if s.messages[i].TS == update.TS {
+			s.messages[i].Text = update.Text
+			if update.Blocks != nil {
+				s.messages[i].Blocks = update.Blocks
+			}
+			s.messages[i].Updated = true
+			reply(w, map[string]interface{}{"ok": true, "channel": update.Channel, "ts": update.TS})
+			return
+		}
+	}
+	reply(w, map[string]interface{}{"ok": false, "error": "message_not_found"})
+}
+
+// getReactions fakes reactions.get
+func (s *Server) getReactions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	names := s.reactions[r.URL.Query().Get("timestamp")]
+	s.mu.Unlock()
+
+	reactions := make([]map[string]string, 0, len(names))
+	for _, name := range names {
+		reactions = append(reactions, map[string]string{"name": name})
+	}
+	reply(w, map[string]interface{}{"ok": true, "message": map[string]interface{}{"reactions": reactions}})
+}
+
+// getUploadURL fakes files.getUploadURLExternal
+func (s *Server) getUploadURL(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	name := r.Form.Get("filename")
+	if name == "" || r.Form.Get("length") == "" {
+		reply(w, map[string]interface{}{"ok": false, "error": "invalid_arguments"})
+		return
+	}
+
+	s.mu.Lock()
+	id := fmt.Sprintf("F%06d", s.sequence)
+	s.sequence++
+	s.files = append(s.files, File{ID: id, Name: name})
+	s.mu.Unlock()
+
+	reply(w, map[string]interface{}{"ok": true, "file_id": id, "upload_url": s.server.URL + "/upload/" + id})
+}
+
+// upload receives the content of a file at its upload URL
+func (s *Server) upload(w http.ResponseWriter, r *http.Request) {
+	content, _ := io.ReadAll(r.Body)
+	id := strings.TrimPrefix(r.URL.Path, "/upload/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.files {
+		if s.files[i].ID == id {
+			s.files[i].Content = string(content)
+			fmt.Fprintf(w, "OK - %d", len(content))
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// completeUpload fakes files.completeUploadExternal
+func (s *Server) completeUpload(w http.ResponseWriter, r *http.Request) {
+	var complete struct {
+		Files []struct {
+			ID    string `json:"id"`
+			Title string `json:"title"`
+		} `json:"files"`
+		ChannelID string `json:"channel_id"`
+		ThreadTS  string `json:"thread_ts"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&complete); err != nil {
+		reply(w, map[string]interface{}{"ok": false, "error": "invalid_json"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range complete.Files {
+		for i := range s.files {
+			if s.files[i].ID == f.ID {
+				s.files[i].Title = f.Title
+				s.files[i].Channel = complete.ChannelID
+				s.files[i].ThreadTS = complete.ThreadTS
+			}
+		}
+	}
+	reply(w, map[string]interface{}{"ok": true})
+}
+
+// openConnection fakes apps.connections.open, requiring an app-level token
+func (s *Server) openConnection(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer xapp-") {
+		reply(w, map[string]interface{}{"ok": false, "error": "not_allowed_token_type"})
+		return
+	}
+	url := "ws" + strings.TrimPrefix(s.server.URL, "http") + "/socket"
+	reply(w, map[string]interface{}{"ok": true, "url": url})
+}
+
+// serveSocket greets a Socket Mode connection and records its acknowledgements
+func (s *Server) serveSocket(ws *websocket.Conn) {
+	if err := websocket.JSON.Send(ws, map[string]string{"type": "hello"}); err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.sockets = append(s.sockets, ws)
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, open := range s.sockets {
+			if open == ws {
+				s.sockets = append(s.sockets[:i], s.sockets[i+1:]...)
+				break
+			}
+		}
+	}()
+
+	for {
+		var ack struct {
+			EnvelopeID string `json:"envelope_id"`
+		}
+		if err := websocket.JSON.Receive(ws, &ack); err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.acked[ack.EnvelopeID] = true
+		s.mu.Unlock()
+	}
+}
+
+// reply writes a Web API response
+func reply(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,209 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// socketPingInterval is how often the connection is pinged, so a dead
+	// one fails a write and is replaced
+	socketPingInterval = 30 * time.Second
+	// socketWriteTimeout bounds acknowledgements and pings
+	socketWriteTimeout = 10 * time.Second
+	// socketMaxBackoff caps the delay between reconnection attempts
+	socketMaxBackoff = time.Minute
+)
+
+// SocketMode receives events and interactions over a WebSocket the app opens
+// to Slack, for clusters Slack can't reach
+// Reference: https://api.slack.com/apis/connections/socket
+type SocketMode struct {
+	appToken string // App-level token (xapp-...) with the connections:write scope
+	apiURL   string
+	client   *http.Client
+	handler  EventHandler
+}
+
+// socketEnvelope is a message received over Socket Mode
+type socketEnvelope struct {
+	EnvelopeID string          `json:"envelope_id"`
+	Type       string          `json:"type"` // "hello", "disconnect", "events_api" or "interactive"
+	Reason     string          `json:"reason"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// NewSocketMode creates a Socket Mode receiver using the client's Web API
+func (c *Client) NewSocketMode(appToken string, handler EventHandler) *SocketMode {
+	return &SocketMode{
+		appToken: appToken,
+		apiURL:   c.apiURL,
+		client:   c.client,
+		handler:  handler,
+	}
+}
+
+// Run receives events until ctx is done, reconnecting when Slack asks to or
+// the connection drops
+func (s *SocketMode) Run(ctx context.Context) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		connected, err := s.connect(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = time.Second
+		}
+		if err != nil {
+			log.Printf("Slack Socket Mode connection lost, reconnecting in %s: %v", backoff, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if !connected {
+			backoff = min(backoff*2, socketMaxBackoff)
+		}
+	}
+}
+
+// connect opens a connection and reads it until it's closed. connected
+// reports whether Slack greeted it.
+func (s *SocketMode) connect(ctx context.Context) (connected bool, err error) {
+	url, err := s.openConnection(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	config, err := websocket.NewConfig(url, "https://slack.com")
+	if err != nil {
+		return false, fmt.Errorf("invalid Socket Mode URL: %w", err)
+	}
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer ws.Close()
+
+	// Closing the connection unblocks Receive on shutdown or a failed ping
+	done := make(chan struct{})
+	defer close(done)
+	go s.keepAlive(ctx, ws, done)
+
+	for {
+		var envelope socketEnvelope
+		if err := websocket.JSON.Receive(ws, &envelope); err != nil {
+			return connected, err
+		}
+
+		switch envelope.Type {
+		case "hello":
+			connected = true
+			log.Printf("Slack Socket Mode connected")
+			continue
+		case "disconnect":
+			log.Printf("Slack Socket Mode disconnect requested (%s)", envelope.Reason)
+			return connected, nil
+		}
+
+		// Slack redelivers envelopes that aren't acknowledged within 3 seconds
+		if envelope.EnvelopeID != "" {
+			ws.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+			if err := websocket.JSON.Send(ws, map[string]string{"envelope_id": envelope.EnvelopeID}); err != nil {
+				return connected, fmt.Errorf("failed to acknowledge envelope: %w", err)
+			}
+		}
+		go s.dispatch(envelope)
+	}
+}
+
+// keepAlive pings the connection and closes it when ctx is done or a ping fails
+func (s *SocketMode) keepAlive(ctx context.Context, ws *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(socketPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			ws.Close()
+			return
+		case <-ticker.C:
+			ws.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+			ws.PayloadType = websocket.PingFrame
+			if _, err := ws.Write(nil); err != nil {
+				log.Printf("Slack Socket Mode ping failed: %v", err)
+				ws.Close()
+				return
+			}
+		}
+	}
+}
+
+// dispatch hands an envelope's payload to the handler
+func (s *SocketMode) dispatch(envelope socketEnvelope) {
+	switch envelope.Type {
+	case "events_api":
+		callback, err := ParseEventCallback(envelope.Payload)
+		if err != nil {
+			log.Printf("Failed to parse Slack event: %v", err)
+			return
+		}
+		s.handler.OnEvent(callback)
+	case "interactive":
+		var interaction Interaction
+		if err := json.Unmarshal(envelope.Payload, &interaction); err != nil {
+			log.Printf("Failed to parse Slack interaction: %v", err)
+			return
+		}
+		s.handler.OnInteraction(&interaction)
+	default:
+		log.Printf("Ignoring Slack Socket Mode envelope of type %q", envelope.Type)
+	}
+}
+
+// openConnection asks Slack for the URL of a new WebSocket connection
+// Reference: https://api.slack.com/methods/apps.connections.open
+func (s *SocketMode) openConnection(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.apiURL+"/apps.connections.open", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+s.appToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to open Socket Mode connection: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error,omitempty"`
+		URL   string `json:"url"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if !result.OK {
+		return "", fmt.Errorf("Slack error: %s", result.Error)
+	}
+	return result.URL, nil
+}
@@ -0,0 +1,83 @@
+package slack_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/valentinpelus/k8flex/pkg/slack"
+	"github.com/valentinpelus/k8flex/pkg/slack/slacktest"
+)
+
+// recorder is an event handler recording what it receives
+type recorder struct {
+	events chan *slack.EventCallback
+}
+
+func (r *recorder) OnEvent(callback *slack.EventCallback) {
+	r.events <- callback
+}
+
+func (r *recorder) OnInteraction(*slack.Interaction) {}
+
+func TestSocketModeDeliversAndAcknowledgesEvents(t *testing.T) {
+	server := slacktest.NewServer()
+	defer server.Close()
+
+	client := slack.NewClient("", "xoxb-test", "C0DEFAULT")
+	client.SetAPIURL(server.URL)
+	handler := &recorder{events: make(chan *slack.EventCallback, 1)}
+	socket := client.NewSocketMode("xapp-test", handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go socket.Run(ctx)
+
+	// The connection is opened in the background
+	event := server.ReactionAdded("C0DEFAULT", "1700000000.000100", "white_check_mark", "U0ALICE")
+	var envelopeID string
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var err error
+		if envelopeID, err = server.SendSocketEvent(event); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Socket Mode never connected: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	select {
+	case got := <-handler.events:
+		if got.EventID != event.EventID || got.Event.Reaction != "white_check_mark" {
+			t.Errorf("got event %s (%s), want %s (white_check_mark)", got.EventID, got.Event.Reaction, event.EventID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not delivered")
+	}
+
+	for !server.Acked(envelopeID) {
+		if time.Now().After(deadline) {
+			t.Fatalf("envelope %s was not acknowledged", envelopeID)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestSocketModeRequiresAppToken(t *testing.T) {
+	server := slacktest.NewServer()
+	defer server.Close()
+
+	client := slack.NewClient("", "xoxb-test", "C0DEFAULT")
+	client.SetAPIURL(server.URL)
+	socket := client.NewSocketMode("xoxb-test", &recorder{events: make(chan *slack.EventCallback, 1)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	socket.Run(ctx)
+
+	if _, err := server.SendSocketEvent(server.ReactionAdded("C0DEFAULT", "1.0", "x", "U0ALICE")); err == nil {
+		t.Error("a bot token opened a Socket Mode connection")
+	}
+}
@@ -0,0 +1,229 @@
+package threads
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// retention is how long resolved threads are kept before being pruned
+const retention = 7 * 24 * time.Hour
+
+// maxAge is how long threads are kept when no resolution was ever received,
+// e.g. for alerts silenced or deleted before resolving
+const maxAge = 30 * 24 * time.Hour
+
+// Record links an alert fingerprint to the thread it was posted in
+type Record struct {
+	Fingerprint string    `json:"fingerprint"`
+	ThreadTS    string    `json:"thread_ts"`
+	AlertName   string    `json:"alert_name"`
+	Namespace   string    `json:"namespace"`
+	Severity    string    `json:"severity"`
+	Category    string    `json:"category"`
+	StartsAt    time.Time `json:"starts_at"`
+	ResolvedAt  time.Time `json:"resolved_at,omitempty"`
+	CaseID      string    `json:"case_id,omitempty"`  // Knowledge base case, once validated
+	Incident    bool      `json:"incident,omitempty"` // The thread covers several correlated alerts
+	Channel     string    `json:"channel,omitempty"`  // Slack channel the thread was posted in
+	Notifier    string    `json:"notifier,omitempty"` // Notifier the thread was posted with; empty for Slack
+	// Labels and correlation keys of an incident member, to rebuild the
+	// incident message when it's resolved
+	Labels map[string]string `json:"labels,omitempty"`
+	Keys   []string          `json:"keys,omitempty"`
+}
+
+// IsResolved reports whether a resolution was recorded for the thread
+func (r *Record) IsResolved() bool {
+	return !r.ResolvedAt.IsZero()
+}
+
+// ResolutionDuration returns the time from the alert starting to its resolution
+func (r *Record) ResolutionDuration() time.Duration {
+	if !r.IsResolved() {
+		return 0
+	}
+	return r.ResolvedAt.Sub(r.StartsAt)
+}
+
+// Store persists the fingerprint to thread mapping
+type Store struct {
+	filePath string
+	records  map[string]*Record // Key: alert fingerprint
+	mu       sync.RWMutex
+}
+
+// NewStore creates a new thread store backed by the given file
+func NewStore(filePath string) *Store {
+	s := &Store{
+		filePath: filePath,
+		records:  make(map[string]*Record),
+	}
+
+	if err := s.load(); err != nil {
+		log.Printf("No existing thread store, starting fresh: %v", err)
+	}
+
+	return s
+}
+
+// Save creates or updates the record for a fingerprint. Resolution and case
+// information already known for the fingerprint is kept.
+func (s *Store) Save(record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.records[record.Fingerprint]; ok {
+		if record.CaseID == "" {
+			record.CaseID = existing.CaseID
+		}
+		if record.ResolvedAt.IsZero() && existing.ThreadTS == record.ThreadTS {
+			record.ResolvedAt = existing.ResolvedAt
+		}
+	}
+	s.records[record.Fingerprint] = &record
+
+	return s.save()
+}
+
+// Get returns a copy of the record for a fingerprint
+func (s *Store) Get(fingerprint string) (Record, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.records[fingerprint]
+	if !ok {
+		return Record{}, false
+	}
+	return *record, true
+}
+
+// FindByThread returns a copy of the record posted in the given thread
+func (s *Store) FindByThread(threadTS string) (Record, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, record := range s.records {
+		if record.ThreadTS == threadTS {
+			return *record, true
+		}
+	}
+	return Record{}, false
+}
+
+// ListByThread returns copies of every record posted in the given thread,
+// several for an incident, ordered by start time
+func (s *Store) ListByThread(threadTS string) []Record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var records []Record
+	for _, record := range s.records {
+		if record.ThreadTS == threadTS {
+			records = append(records, *record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].StartsAt.Before(records[j].StartsAt)
+	})
+	return records
+}
+
+// Resolution returns when the alerts of a thread were all resolved and how
+// long that took from the first one starting. ok is false while any is firing.
+func Resolution(records []Record) (resolvedAt time.Time, duration time.Duration, ok bool) {
+	if len(records) == 0 {
+		return time.Time{}, 0, false
+	}
+	startsAt := records[0].StartsAt
+	for _, record := range records {
+		if !record.IsResolved() {
+			return time.Time{}, 0, false
+		}
+		if record.ResolvedAt.After(resolvedAt) {
+			resolvedAt = record.ResolvedAt
+		}
+		if record.StartsAt.Before(startsAt) {
+			startsAt = record.StartsAt
+		}
+	}
+	return resolvedAt, resolvedAt.Sub(startsAt), true
+}
+
+// MarkResolved records when the alert for a fingerprint was resolved
+func (s *Store) MarkResolved(fingerprint string, resolvedAt time.Time) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[fingerprint]
+	if !ok {
+		return Record{}, fmt.Errorf("no thread recorded for fingerprint %s", fingerprint)
+	}
+	record.ResolvedAt = resolvedAt
+
+	return *record, s.save()
+}
+
+// SetCaseID links a knowledge base case to the thread for a fingerprint
+func (s *Store) SetCaseID(fingerprint, caseID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[fingerprint]
+	if !ok {
+		return fmt.Errorf("no thread recorded for fingerprint %s", fingerprint)
+	}
+	record.CaseID = caseID
+
+	return s.save()
+}
+
+// expired reports whether a record is past its retention: resolved threads
+// after retention, threads never resolved after maxAge from the alert starting
+func (r *Record) expired() bool {
+	if r.IsResolved() {
+		return time.Since(r.ResolvedAt) > retention
+	}
+	return !r.StartsAt.IsZero() && time.Since(r.StartsAt) > maxAge
+}
+
+// load reads records from disk
+func (s *Store) load() error {
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		return err
+	}
+
+	var records []*Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		s.records[record.Fingerprint] = record
+	}
+	return nil
+}
+
+// save prunes old threads and writes records to disk
+func (s *Store) save() error {
+	records := make([]*Record, 0, len(s.records))
+	for fingerprint, record := range s.records {
+		if record.expired() {
+			delete(s.records, fingerprint)
+			continue
+		}
+		records = append(records, record)
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal thread records: %w", err)
+	}
+
+	return os.WriteFile(s.filePath, data, 0644)
+}
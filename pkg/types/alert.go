@@ -1,6 +1,11 @@
 package types
 
-import "time"
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+)
 
 // AlertmanagerWebhook represents the webhook payload from Alertmanager
 // Reference: https://prometheus.io/docs/alerting/latest/configuration/#webhook_config
@@ -19,16 +24,89 @@ type Alert struct {
 	StartsAt     time.Time         `json:"startsAt"`
 	EndsAt       time.Time         `json:"endsAt"`
 	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
 }
 
-// DebugResult contains all debug information gathered for an alert
+// DebugResult contains all debug information gathered for an alert, as one
+// section per collector in gathering order
 type DebugResult struct {
-	Alert           Alert
-	PodLogs         string
-	PodDescription  string
-	EventsList      string
-	ServiceCheck    string
-	NetworkCheck    string
-	ResourceMetrics string
-	Analysis        string
+	Alert      Alert          `json:"alert"`
+	Category   string         `json:"category"`
+	Sections   []DebugSection `json:"sections"`
+	StartedAt  time.Time      `json:"startedAt"`
+	FinishedAt time.Time      `json:"finishedAt"`
+	Redactions map[string]int `json:"redactions,omitempty"` // Redacted values per detector, e.g. "email": 3
+	Trimmed    []Trim         `json:"trimmed,omitempty"`    // Evidence left out of the LLM prompt to fit the model's context window
+}
+
+// Trim records evidence cut from a section to fit a token budget
+type Trim struct {
+	Section string `json:"section"` // Section title
+	Action  string `json:"action"`  // What was cut, e.g. "dropped" or "kept the last 40 of 900 lines"
+	Tokens  int    `json:"tokens"`  // Estimated tokens saved
+}
+
+// DebugSection is one piece of evidence, e.g. pod logs or a metric summary
+type DebugSection struct {
+	Title     string    `json:"title"`
+	Source    string    `json:"source"`    // Name of the collector (or tool) that produced it
+	Timestamp time.Time `json:"timestamp"` // When it was collected
+	Content   string    `json:"content,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	TimedOut  bool      `json:"timedOut,omitempty"`
+	Truncated bool      `json:"truncated,omitempty"` // Content was cut to the collector's byte limit
+	// Chronological sections list lines oldest first, so their start is cut first
+	Chronological bool        `json:"chronological,omitempty"`
+	Data          interface{} `json:"data,omitempty"` // Raw data behind Content, when the source provides it
+}
+
+// TimedOutSections returns the titles of sections that didn't finish in time
+func (r *DebugResult) TimedOutSections() []string {
+	var titles []string
+	for _, s := range r.Sections {
+		if s.TimedOut {
+			titles = append(titles, s.Title)
+		}
+	}
+	return titles
+}
+
+// AlertFingerprint returns the Alertmanager fingerprint of the alert, or a
+// stable hash of its labels when the payload did not include one
+func AlertFingerprint(alert Alert) string {
+	if alert.Fingerprint != "" {
+		return alert.Fingerprint
+	}
+
+	keys := make([]string, 0, len(alert.Labels))
+	for k := range alert.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := fnv.New64a()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0xff})
+		h.Write([]byte(alert.Labels[k]))
+		h.Write([]byte{0xff})
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// AlertTarget describes what an alert is about, e.g. "shop/web-7d9f" or "node worker-3"
+func AlertTarget(alert Alert) string {
+	namespace := alert.Labels["namespace"]
+	for _, label := range []string{"pod", "deployment", "statefulset", "daemonset", "job_name", "service"} {
+		if name := alert.Labels[label]; name != "" {
+			if namespace == "" {
+				return name
+			}
+			return namespace + "/" + name
+		}
+	}
+	if node := alert.Labels["node"]; node != "" {
+		return "node " + node
+	}
+	return namespace
 }
@@ -0,0 +1,77 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Analysis is the structured result of an LLM analysis
+type Analysis struct {
+	RootCause         string           `json:"root_cause"`
+	Confidence        string           `json:"confidence"`         // "high", "medium" or "low"
+	SuggestedSeverity string           `json:"suggested_severity"` // "critical", "warning" or "info"
+	Evidence          []Evidence       `json:"evidence"`
+	Impact            string           `json:"impact"`
+	AlertNotes        []AlertNote      `json:"alert_notes,omitempty"` // Incidents only: one per member alert
+	Actions           []string         `json:"actions"`
+	RunbookSteps      []RunbookStep    `json:"runbook_steps,omitempty"` // Only when a runbook was provided
+	Prevention        []string         `json:"prevention"`
+	ProposedActions   []ProposedAction `json:"proposed_actions,omitempty"` // Catalog actions to approve from Slack
+}
+
+// ProposedAction is a remediation from the action catalog the analysis
+// suggests running, in the alert's namespace
+type ProposedAction struct {
+	Action   string `json:"action"`             // "restart", "scale", "rollback", "cordon", "drain" or "delete-pod"
+	Kind     string `json:"kind"`               // Deployment, StatefulSet, DaemonSet, Node or Pod
+	Name     string `json:"name"`               // Name of the workload, node or pod
+	Replicas int    `json:"replicas,omitempty"` // Scale only
+	Reason   string `json:"reason"`             // Evidence calling for it
+}
+
+// Command renders the action the way analyses propose it, e.g.
+// "scale deployment/api 5"
+func (a ProposedAction) Command() string {
+	command := fmt.Sprintf("%s %s/%s", a.Action, strings.ToLower(a.Kind), a.Name)
+	if a.Replicas > 0 {
+		command += fmt.Sprintf(" %d", a.Replicas)
+	}
+	return command
+}
+
+// RunbookStep says whether a step of the alert's runbook applies to the evidence
+type RunbookStep struct {
+	Step    string `json:"step"`    // The step, as worded in the runbook
+	Applies bool   `json:"applies"` // Whether the evidence calls for it
+	Reason  string `json:"reason"`  // Evidence for or against it
+}
+
+// AlertNote says how one alert of an incident relates to its root cause
+type AlertNote struct {
+	Alert string `json:"alert"` // Alert name, as listed in the incident members
+	Note  string `json:"note"`
+}
+
+// Evidence is a quote from the debug info supporting an analysis
+type Evidence struct {
+	Section    string `json:"section"`              // Title of the debug section quoted
+	Quote      string `json:"quote"`                // Verbatim line(s) from that section
+	Unverified bool   `json:"unverified,omitempty"` // Set when the quote isn't in the debug info
+}
+
+// Grounding records how many of the quotes and values an analysis claims from
+// the debug info were actually found there
+type Grounding struct {
+	Checked    int      `json:"checked"`              // Quotes and values looked up
+	Verified   int      `json:"verified"`             // Found in the debug info
+	Unverified []string `json:"unverified,omitempty"` // Those that weren't found
+}
+
+// Score returns the share of verified quotes and values, from 0 to 1, or 1 if
+// there was nothing to check
+func (g *Grounding) Score() float64 {
+	if g.Checked == 0 {
+		return 1
+	}
+	return float64(g.Verified) / float64(g.Checked)
+}
@@ -0,0 +1,12 @@
+package types
+
+import "time"
+
+// FollowUp is a question asked about an analysis in its Slack thread, and
+// the answer k8flex gave
+type FollowUp struct {
+	User      string    `json:"user"` // Slack user ID of the person who asked
+	Question  string    `json:"question"`
+	Answer    string    `json:"answer"`
+	Timestamp time.Time `json:"timestamp"`
+}
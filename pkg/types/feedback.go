@@ -10,9 +10,11 @@ type Feedback struct {
 	Namespace   string            `json:"namespace"`
 	Summary     string            `json:"summary"`
 	Analysis    string            `json:"analysis"`
-	IsCorrect   bool              `json:"is_correct"`   // true for ✅, false for ❌
-	SlackThread string            `json:"slack_thread"` // For reference
-	Labels      map[string]string `json:"labels"`       // Alert labels for context
+	IsCorrect   bool              `json:"is_correct"`          // true for ✅, false for ❌
+	SlackThread string            `json:"slack_thread"`        // For reference
+	Labels      map[string]string `json:"labels"`              // Alert labels for context
+	Grounding   *Grounding        `json:"grounding,omitempty"` // Quotes and values verified against the debug info
+	Comments    []string          `json:"comments,omitempty"`  // Replies posted in the alert thread, e.g. why the analysis was wrong
 }
 
 // FeedbackStore holds all collected feedback
@@ -0,0 +1,46 @@
+package types
+
+import "fmt"
+
+// Incident is a group of correlated alerts analyzed together
+type Incident struct {
+	Members []IncidentMember `json:"members"` // Lead alert first
+	Shared  []string         `json:"shared"`  // Correlation keys shared by at least two members
+}
+
+// IncidentMember is an alert of an incident with the correlation keys it was grouped by
+type IncidentMember struct {
+	Alert Alert    `json:"alert"`
+	Keys  []string `json:"keys"` // e.g. "node:worker-3" or "workload:shop/Deployment/web"
+}
+
+// Lead returns the alert the incident is investigated from
+func (i *Incident) Lead() Alert {
+	return i.Members[0].Alert
+}
+
+// SharedKeys returns the keys of a member that it shares with another member
+func (i *Incident) SharedKeys(m IncidentMember) []string {
+	var keys []string
+	for _, k := range m.Keys {
+		for _, s := range i.Shared {
+			if k == s {
+				keys = append(keys, k)
+				break
+			}
+		}
+	}
+	return keys
+}
+
+// Title names an incident after its lead alert, e.g. "Incident:
+// KubePodCrashLooping + 4 related alerts"
+func (i *Incident) Title() string {
+	title := "Incident: " + i.Lead().Labels["alertname"]
+	if related := len(i.Members) - 1; related == 1 {
+		title += " + 1 related alert"
+	} else if related > 1 {
+		title += fmt.Sprintf(" + %d related alerts", related)
+	}
+	return title
+}
@@ -0,0 +1,9 @@
+package types
+
+// AnalysisMessage is a complete analysis to post for an alert
+type AnalysisMessage struct {
+	Alert    Alert  // The alert, or the lead alert of an incident
+	Title    string // e.g. "✅ *Analysis Complete*"
+	Analysis string // Slack mrkdwn; each part led by a bold header, e.g. *Root Cause:*
+	Footer   string // Shown under the analysis, e.g. the provider and how to rate it
+}
@@ -8,6 +8,10 @@ type OllamaRequest struct {
 	Model  string `json:"model"`
 	Prompt string `json:"prompt"`
 	Stream bool   `json:"stream"`
+	// Format constrains the response, "json" for a JSON object
+	Format string `json:"format,omitempty"`
+	// Options are model parameters such as num_ctx
+	Options map[string]interface{} `json:"options,omitempty"`
 }
 
 // OllamaResponse represents the response from Ollama API
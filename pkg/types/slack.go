@@ -1,5 +1,7 @@
 package types
 
+import "encoding/json"
+
 // SlackMessage represents a Slack webhook message
 // Reference: https://api.slack.com/messaging/webhooks
 type SlackMessage struct {
@@ -12,10 +14,65 @@ type SlackMessage struct {
 
 // SlackBlock represents a Slack Block Kit element
 type SlackBlock struct {
-	Type     string            `json:"type"`
-	Text     *SlackTextObject  `json:"text,omitempty"`
-	Fields   []SlackTextObject `json:"fields,omitempty"`
-	Elements []SlackTextObject `json:"elements,omitempty"`
+	Type      string            `json:"type"`
+	BlockID   string            `json:"block_id,omitempty"`
+	Text      *SlackTextObject  `json:"text,omitempty"`
+	Fields    []SlackTextObject `json:"fields,omitempty"`
+	Elements  []SlackTextObject `json:"elements,omitempty"`
+	Accessory *SlackButton      `json:"accessory,omitempty"`
+	Buttons   []SlackButton     `json:"-"` // Elements of an "actions" block
+}
+
+// MarshalJSON sends the buttons of an actions block as its elements
+func (b SlackBlock) MarshalJSON() ([]byte, error) {
+	type block SlackBlock
+	if b.Type != "actions" {
+		return json.Marshal(block(b))
+	}
+	return json.Marshal(struct {
+		block
+		Elements []SlackButton `json:"elements"`
+	}{block(b), b.Buttons})
+}
+
+// UnmarshalJSON reads the elements of an actions block as its buttons, e.g.
+// in the message of an interaction
+func (b *SlackBlock) UnmarshalJSON(data []byte) error {
+	type block SlackBlock
+	var raw struct {
+		block
+		Elements json.RawMessage `json:"elements"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*b = SlackBlock(raw.block)
+	if len(raw.Elements) == 0 {
+		return nil
+	}
+	if b.Type == "actions" {
+		return json.Unmarshal(raw.Elements, &b.Buttons)
+	}
+	return json.Unmarshal(raw.Elements, &b.Elements)
+}
+
+// SlackButton is an interactive button, sent back to the interactivity
+// endpoint with its action ID and value when clicked
+type SlackButton struct {
+	Type     string          `json:"type"` // "button"
+	Text     SlackTextObject `json:"text"` // plain_text
+	ActionID string          `json:"action_id"`
+	Value    string          `json:"value,omitempty"`
+	Style    string          `json:"style,omitempty"` // "primary" or "danger"
+	Confirm  *SlackConfirm   `json:"confirm,omitempty"`
+}
+
+// SlackConfirm is the dialog a button asks to confirm before it's sent
+type SlackConfirm struct {
+	Title   SlackTextObject `json:"title"`
+	Text    SlackTextObject `json:"text"`
+	Confirm SlackTextObject `json:"confirm"`
+	Deny    SlackTextObject `json:"deny"`
 }
 
 // SlackTextObject represents text within a Slack block
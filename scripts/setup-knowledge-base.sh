@@ -53,16 +53,20 @@ fi
 # Run migrations
 echo ""
 echo "📋 Running migrations..."
-MIGRATION_FILE="$(dirname "$0")/../deployments/migrations/001_init_knowledge_base.sql"
+MIGRATIONS_DIR="$(dirname "$0")/../deployments/migrations"
 
-if [ ! -f "$MIGRATION_FILE" ]; then
-    echo "❌ Error: Migration file not found: $MIGRATION_FILE"
+if [ ! -d "$MIGRATIONS_DIR" ]; then
+    echo "❌ Error: Migrations directory not found: $MIGRATIONS_DIR"
     exit 1
 fi
 
-PGPASSWORD=$DB_PASSWORD psql -h $DB_HOST -p $DB_PORT -U $DB_USER -d $DB_NAME -f "$MIGRATION_FILE"
+MIGRATION_STATUS=0
+for MIGRATION_FILE in "$MIGRATIONS_DIR"/*.sql; do
+    echo "   → $(basename "$MIGRATION_FILE")"
+    PGPASSWORD=$DB_PASSWORD psql -h $DB_HOST -p $DB_PORT -U $DB_USER -d $DB_NAME -v ON_ERROR_STOP=1 -f "$MIGRATION_FILE" || { MIGRATION_STATUS=1; break; }
+done
 
-if [ $? -eq 0 ]; then
+if [ $MIGRATION_STATUS -eq 0 ]; then
     echo ""
     echo "✅ Knowledge base initialized successfully!"
     echo ""